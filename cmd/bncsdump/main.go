@@ -8,6 +8,7 @@ package main
 import (
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -73,7 +74,7 @@ func dumpPackets(layer string, netFlow, transFlow gopacket.Flow, r io.Reader) er
 				logErr.Printf("Payload:\n%v", hex.Dump(raw))
 			}
 
-			if err == bncs.ErrInvalidPacketSize || err == bncs.ErrInvalidChecksum || err == bncs.ErrUnexpectedConst {
+			if errors.Is(err, bncs.ErrInvalidPacketSize) || errors.Is(err, bncs.ErrInvalidChecksum) || errors.Is(err, bncs.ErrUnexpectedConst) {
 				continue
 			} else {
 				return err
@@ -0,0 +1,120 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+)
+
+var csvHeader = []string{"game_time_ms", "record_type", "player_id", "player_name", "scope", "detail"}
+
+type csvWriter struct {
+	*csv.Writer
+	File       string
+	Filter     recordFilter
+	EventsOnly bool
+
+	ms    uint32
+	names map[uint8]string
+}
+
+func newCSVWriter(w io.Writer, file string, filter recordFilter, eventsOnly bool) *csvWriter {
+	var res = csvWriter{
+		Writer:     csv.NewWriter(w),
+		File:       file,
+		Filter:     filter,
+		EventsOnly: eventsOnly,
+		names:      map[uint8]string{},
+	}
+	return &res
+}
+
+// WriteHeader writes the column names
+func (c *csvWriter) WriteHeader() error {
+	if c.File != "" {
+		return c.Write(append([]string{"file"}, csvHeader...))
+	}
+	return c.Write(csvHeader)
+}
+
+func (c *csvWriter) row(typ string, pid uint8, scope string, detail string) error {
+	var id = ""
+	if pid != 0 {
+		id = strconv.Itoa(int(pid))
+	}
+	var row = []string{strconv.FormatUint(uint64(c.ms), 10), typ, id, c.names[pid], scope, detail}
+	if c.File != "" {
+		row = append([]string{c.File}, row...)
+	}
+	return c.Write(row)
+}
+
+func (c *csvWriter) WriteRecord(r w3g.Record) error {
+	var typ = recordType(r)
+
+	// Keep track of game time and player names, even if not all rows are printed
+	switch v := r.(type) {
+	case *w3g.GameInfo:
+		c.names[v.HostPlayer.ID] = v.HostPlayer.Name
+	case *w3g.PlayerInfo:
+		c.names[v.ID] = v.Name
+	case *w3g.TimeSlot:
+		defer func() { c.ms += uint32(v.TimeIncrementMS) }()
+	}
+
+	if !c.Filter.match(r) {
+		return nil
+	}
+
+	// Events
+	switch v := r.(type) {
+	case *w3g.TimeSlot:
+		for _, a := range v.Actions {
+			if err := c.row("action", a.PlayerID, "", hex.EncodeToString(a.Data)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *w3g.ChatMessage:
+		return c.row(typ, v.SenderID, v.ScopeString(), v.Content)
+	case *w3g.PlayerLeft:
+		return c.row(typ, v.PlayerID, "", v.Reason.String())
+	case *w3g.Desync:
+		return c.row(typ, 0, "", fmt.Sprintf("%08X", v.Checksum))
+	case *w3g.EndTimer:
+		return c.row(typ, 0, "", fmt.Sprintf("GameOver:%v CountDownSec:%d", v.GameOver, v.CountDownSec))
+	}
+
+	if c.EventsOnly {
+		return nil
+	}
+
+	// Summaries
+	switch v := r.(type) {
+	case *w3g.GameInfo:
+		return c.row(typ, v.HostPlayer.ID, "", v.GameName)
+	case *w3g.PlayerInfo:
+		return c.row(typ, v.ID, "", v.Race.String())
+	case *w3g.SlotInfo:
+		return c.row(typ, 0, "", fmt.Sprintf("Slots:%d NumPlayers:%d", len(v.Slots), v.NumPlayers))
+	case *w3g.PlayerExtra:
+		for _, p := range v.Profiles {
+			if err := c.row(typ, uint8(p.PlayerID), "", p.BattleTag); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *w3g.CountDownStart, *w3g.CountDownEnd, *w3g.GameStart:
+		return c.row(typ, 0, "", "")
+	default:
+		return nil
+	}
+}
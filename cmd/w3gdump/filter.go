@@ -0,0 +1,115 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+)
+
+var recordTypes = map[string][]uint8{
+	"gameinfo":  []uint8{w3g.RidGameInfo},
+	"player":    []uint8{w3g.RidPlayerInfo},
+	"leave":     []uint8{w3g.RidPlayerLeft},
+	"slot":      []uint8{w3g.RidSlotInfo},
+	"countdown": []uint8{w3g.RidCountDownStart, w3g.RidCountDownEnd},
+	"start":     []uint8{w3g.RidGameStart},
+	"timeslot":  []uint8{w3g.RidTimeSlot, w3g.RidTimeSlot2},
+	"chat":      []uint8{w3g.RidChatMessage},
+	"ack":       []uint8{w3g.RidTimeSlotAck},
+	"desync":    []uint8{w3g.RidDesync},
+	"endtimer":  []uint8{w3g.RidEndTimer},
+	"extra":     []uint8{w3g.RidPlayerExtra},
+}
+
+func recordType(r w3g.Record) string {
+	switch r.(type) {
+	case *w3g.GameInfo:
+		return "gameinfo"
+	case *w3g.PlayerInfo:
+		return "player"
+	case *w3g.PlayerLeft:
+		return "leave"
+	case *w3g.SlotInfo:
+		return "slot"
+	case *w3g.CountDownStart, *w3g.CountDownEnd:
+		return "countdown"
+	case *w3g.GameStart:
+		return "start"
+	case *w3g.TimeSlot:
+		return "timeslot"
+	case *w3g.ChatMessage:
+		return "chat"
+	case *w3g.TimeSlotAck:
+		return "ack"
+	case *w3g.Desync:
+		return "desync"
+	case *w3g.EndTimer:
+		return "endtimer"
+	case *w3g.PlayerExtra:
+		return "extra"
+	default:
+		return ""
+	}
+}
+
+func recordTypeNames() string {
+	var names []string
+	for n := range recordTypes {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+type recordFilter map[string]bool
+
+func parseFilter(s string) (recordFilter, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var res = recordFilter{}
+	for _, n := range strings.Split(s, ",") {
+		n = strings.ToLower(strings.TrimSpace(n))
+		if _, ok := recordTypes[n]; !ok {
+			return nil, fmt.Errorf("Unknown record type '%s' (valid: %s)", n, recordTypeNames())
+		}
+		res[n] = true
+	}
+
+	return res, nil
+}
+
+func (f recordFilter) match(r w3g.Record) bool {
+	return f == nil || f[recordType(r)]
+}
+
+func (f recordFilter) decodeOnly(gameVersion uint32) []uint8 {
+	if f == nil {
+		return nil
+	}
+
+	var res = []uint8{}
+	for n := range f {
+		for _, rid := range recordTypes[n] {
+			// TimeSlotAck used the ChatMessage record ID in early versions
+			if gameVersion != 0 && gameVersion <= 2 {
+				switch rid {
+				case w3g.RidChatMessage:
+					continue
+				case w3g.RidTimeSlotAck:
+					rid = w3g.RidChatMessage
+				}
+			}
+			res = append(res, rid)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,108 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"reflect"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+)
+
+// jsonArrayWriter streams a replay as a single JSON document with "file", "header", "records"
+// (array of {"type", "record"} objects) and "error" (only if decoding failed) fields.
+// Records are written as they are decoded, so memory use does not depend on replay size.
+type jsonArrayWriter struct {
+	w   *bufio.Writer
+	n   int
+	err error
+}
+
+type jsonRecord struct {
+	Type   string      `json:"type"`
+	Record interface{} `json:"record"`
+}
+
+// newJSONArrayWriter writes the document up to the opening bracket of the records array.
+// Nothing is written if hdr cannot be marshaled.
+func newJSONArrayWriter(w io.Writer, file string, hdr *w3g.Header) (*jsonArrayWriter, error) {
+	h, err := json.Marshal(newReleaseHeader(hdr))
+	if err != nil {
+		return nil, err
+	}
+
+	var j = jsonArrayWriter{w: bufio.NewWriter(w)}
+
+	j.w.WriteString("{")
+	if file != "" {
+		j.value("file", file)
+		j.w.WriteString(",")
+	}
+	j.raw("header", h)
+	j.w.WriteString(`,"records":[`)
+
+	return &j, nil
+}
+
+func (j *jsonArrayWriter) raw(key string, b []byte) {
+	k, _ := json.Marshal(key)
+	j.w.Write(k)
+	j.w.WriteString(":")
+	j.w.Write(b)
+}
+
+func (j *jsonArrayWriter) value(key string, v interface{}) {
+	if j.err != nil {
+		return
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		j.err = err
+		return
+	}
+	j.raw(key, b)
+}
+
+// WriteRecord appends r to the records array
+func (j *jsonArrayWriter) WriteRecord(r w3g.Record) error {
+	if j.err != nil {
+		return j.err
+	}
+
+	b, err := json.Marshal(jsonRecord{
+		Type:   reflect.TypeOf(r).Elem().Name(),
+		Record: r,
+	})
+	if err != nil {
+		j.err = err
+		return err
+	}
+
+	if j.n > 0 {
+		j.w.WriteString(",")
+	}
+	j.n++
+
+	_, j.err = j.w.Write(b)
+	return j.err
+}
+
+// Close the records array and document, adding an error field if err is not nil
+func (j *jsonArrayWriter) Close(err error) error {
+	j.w.WriteString("]")
+	if err != nil {
+		j.w.WriteString(",")
+		j.value("error", err.Error())
+	}
+	j.w.WriteString("}\n")
+
+	if e := j.w.Flush(); j.err == nil {
+		j.err = e
+	}
+	return j.err
+}
@@ -7,109 +7,426 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/nielsAD/gowarcraft3/file/w3g"
 	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
 )
 
 var (
 	sanitize = flag.String("sanitize", "", "Dump cleaned up replay to this file (no chat, sane colors)")
+	trimFrom = flag.String("from", "", "Only keep sanitized game records from this game time on ([hh:]mm:ss)")
+	trimTo   = flag.String("to", "", "Only keep sanitized game records up to this game time ([hh:]mm:ss)")
 	header   = flag.Bool("header", false, "Decode header only")
+	limit    = flag.Int("limit", 0, "Stop decoding after this many records")
+	until    = flag.String("until", "", "Stop decoding after this game time ([hh:]mm:ss)")
+	lenient  = flag.Bool("lenient", false, "Continue decoding records with unexpected constant values (reported as warnings)")
 	stream   = flag.Bool("stream", false, "Stream game to LAN")
+	maxConn  = flag.Int("clients", 1, "Maximum number of clients that can watch the stream")
+	netIface = flag.String("iface", "", "Network interfaces to advertise stream on (comma separated, defaults to all)")
+	hostCnt  = flag.Uint("host-counter", 1, "Host counter advertised for -stream")
+	entryKey = flag.Uint("entry-key", 0, "Entry key advertised for -stream (random if 0)")
+	mapPath  = flag.String("maps", "", "Map search path for -stream (directories separated by ':' or ';', also read from WC3_MAP_PATH)")
 	jsonout  = flag.Bool("json", false, "Print machine readable format")
+	verbose  = flag.Bool("verbose", false, "Print records field by field (nested structures, hex dump of binary data)")
+	depth    = flag.Int("depth", 0, "Maximum nesting depth for -verbose (0 for no limit)")
+	jsonarr  = flag.Bool("json-array", false, "Print a single JSON document per file (header and records array)")
+	summ     = flag.Bool("summary", false, "Print summary of players and game")
+	valid    = flag.Bool("validate", false, "Check replay for decoding errors and structural issues, fail if errors are found")
+	csvout   = flag.Bool("csv", false, "Print comma separated values")
+	csvevent = flag.Bool("csv-events-only", false, "Only print events (chat, actions, leavers) in CSV output")
+	numJobs  = flag.Int("jobs", 1, "Number of files to process in parallel")
+	filter   = flag.String("filter", "", "Only print these record types (comma separated, one of "+recordTypeNames()+")")
 )
 
 var logOut = log.New(os.Stdout, "", 0)
 var logErr = log.New(os.Stderr, "", 0)
 
-func print(v interface{}) {
-	var str = fmt.Sprintf("%+v", v)[1:]
+func print(out *log.Logger, v interface{}) {
+	if *verbose {
+		var buf bytes.Buffer
+		var p = verbosePrinter{w: &buf, depth: *depth}
+		p.print(v)
+		for _, l := range strings.SplitAfter(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+			out.Print(l)
+		}
+		return
+	}
+
+	var str = fmt.Sprintf("%+v", v)
+	if _, ok := v.(fmt.Stringer); !ok {
+		str = str[1:]
+	}
+	if *jsonout {
+		if json, err := json.Marshal(v); err == nil {
+			str = string(json)
+		}
+	}
+
+	out.Printf("%-14v %v\n", reflect.TypeOf(v).String()[5:], str)
+}
+
+// releaseHeader adds the (derived) client release to the printed header
+type releaseHeader struct {
+	w3g.Header
+	Release string
+}
+
+func newReleaseHeader(hdr *w3g.Header) *releaseHeader {
+	return &releaseHeader{Header: *hdr, Release: hdr.ReleaseString()}
+}
+
+func printHeader(out *log.Logger, hdr *w3g.Header) {
+	if *verbose {
+		print(out, hdr)
+		return
+	}
+
+	var v = newReleaseHeader(hdr)
+	var str = fmt.Sprintf("%+v", *v)
 	if *jsonout {
 		if json, err := json.Marshal(v); err == nil {
 			str = string(json)
 		}
 	}
 
-	logOut.Printf("%-14v %v\n", reflect.TypeOf(v).String()[5:], str)
+	out.Printf("%-14v %v\n", "Header", str)
+}
+
+// Expand arguments to list of files, for backwards compatibility also
+// accept a single path containing spaces split into multiple arguments.
+func files(args []string) ([]string, error) {
+	var res []string
+	for _, a := range args {
+		m, err := filepath.Glob(a)
+		if err != nil {
+			return nil, err
+		}
+		if len(m) == 0 {
+			if _, err := os.Stat(a); err != nil {
+				if joined := strings.Join(args, " "); len(args) > 1 {
+					if _, err := os.Stat(joined); err == nil {
+						return []string{joined}, nil
+					}
+				}
+				return nil, err
+			}
+			m = []string{a}
+		}
+		res = append(res, m...)
+	}
+	return res, nil
 }
 
 func main() {
 	flag.Parse()
-	var filename = strings.Join(flag.Args(), " ")
 
 	if *stream {
+		var filename = strings.Join(flag.Args(), " ")
 		if err := cast(filename); err != nil && !network.IsCloseError(err) {
 			logErr.Fatal("Stream error: ", err)
 		}
 		return
 	}
 
-	f, err := os.Open(filename)
+	names, err := files(flag.Args())
 	if err != nil {
 		logErr.Fatal("Open error: ", err)
 	}
+	if len(names) == 0 {
+		logErr.Fatal("No input files")
+	}
+	if len(names) > 1 && *sanitize != "" {
+		logErr.Fatal("Cannot sanitize multiple files")
+	}
+
+	if (*trimFrom != "" || *trimTo != "") && *sanitize == "" {
+		logErr.Fatal("Time range requires -sanitize")
+	}
+	if *jsonarr && (*csvout || *csvevent) {
+		logErr.Fatal("Cannot combine -json-array with -csv")
+	}
+	if *verbose && (*jsonout || *jsonarr || *csvout || *csvevent) {
+		logErr.Fatal("Cannot combine -verbose with -json or -csv")
+	}
+	if *valid && (*summ || *sanitize != "" || *csvout || *csvevent || *verbose) {
+		logErr.Fatal("Cannot combine -validate with -summary, -sanitize, -csv or -verbose")
+	}
+	if *lenient && (*valid || *summ) {
+		logErr.Fatal("Cannot combine -lenient with -validate or -summary")
+	}
+	if (*limit > 0 || *until != "") && (*valid || *summ) {
+		logErr.Fatal("Cannot combine -limit or -until with -validate or -summary")
+	}
+
+	rf, err := parseFilter(*filter)
+	if err != nil {
+		logErr.Fatal("Filter error: ", err)
+	}
+
+	if len(names) == 1 {
+		if err := dump(names[0], "", os.Stdout, rf); err != nil {
+			logErr.Fatal(err)
+		}
+		return
+	}
+
+	if *csvout || *csvevent {
+		// Write header once, rows of all files share the same columns
+		var cw = newCSVWriter(os.Stdout, "file", rf, *csvevent)
+		cw.WriteHeader()
+		cw.Flush()
+	}
+
+	var jobs = *numJobs
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	var failed int32
+	var outmut sync.Mutex
+	var wg sync.WaitGroup
+	var queue = make(chan string)
+
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var buf bytes.Buffer
+			for name := range queue {
+				var err error
+				if jobs == 1 {
+					// Stream output directly, there is nothing to interleave with
+					err = dump(name, name, os.Stdout, rf)
+					if err != nil {
+						logErr.Printf("%s: %v\n", name, err)
+					}
+				} else {
+					// Buffer output to keep the lines of a file together
+					buf.Reset()
+					err = dump(name, name, &buf, rf)
+
+					outmut.Lock()
+					os.Stdout.Write(buf.Bytes())
+					if err != nil {
+						logErr.Printf("%s: %v\n", name, err)
+					}
+					outmut.Unlock()
+				}
+
+				if err != nil {
+					atomic.AddInt32(&failed, 1)
+				}
+			}
+		}()
+	}
+
+	for _, name := range names {
+		queue <- name
+	}
+	close(queue)
+	wg.Wait()
+
+	if failed > 0 {
+		logErr.Fatalf("%d of %d files failed\n", failed, len(names))
+	}
+}
+
+// dump a single replay file to w, prefixing lines with prefix (if not empty)
+// JSON documents (-json-array, -validate -json and -summary -json) store prefix in a field instead
+func dump(filename string, prefix string, w io.Writer, rf recordFilter) error {
+	var out = log.New(w, "", 0)
+	if prefix != "" && !*jsonarr && !(*jsonout && (*valid || *summ)) {
+		out.SetPrefix(prefix + ": ")
+	}
+
+	if *valid {
+		var rep = validate(filename)
+		rep.File = prefix
+
+		var buf bytes.Buffer
+		if err := rep.print(&buf, *jsonout || *jsonarr); err != nil {
+			return fmt.Errorf("Print error: %v", err)
+		}
+		for _, l := range strings.SplitAfter(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+			out.Print(l)
+		}
+		if !rep.Valid {
+			return errInvalid
+		}
+		return nil
+	}
+
+	if *summ {
+		f, err := os.Open(filename)
+		if err != nil {
+			return fmt.Errorf("Open error: %v", err)
+		}
+		defer f.Close()
+
+		var b = bufio.NewReaderSize(f, 8192)
+		if err := w3g.StripNWG(b); err != nil {
+			return fmt.Errorf("Cannot find header: %v", err)
+		}
+
+		rep, stats, err := w3g.DecodeWithStats(b)
+		if err != nil {
+			return fmt.Errorf("Open error: %v", err)
+		}
+
+		var sum = summarize(rep)
+		sum.File = prefix
+		sum.Stats = stats
+
+		var buf bytes.Buffer
+		if err := sum.print(&buf, *jsonout || *jsonarr); err != nil {
+			return fmt.Errorf("Print error: %v", err)
+		}
+		for _, l := range strings.SplitAfter(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+			out.Print(l)
+		}
+		return nil
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("Open error: %v", err)
+	}
 	defer f.Close()
 
-	// Find header, nwg files have their own header prepended
+	// nwg files have their own header prepended
 	var b = bufio.NewReaderSize(f, 8192)
-	if _, err := w3g.FindHeader(b); err != nil {
-		logErr.Fatal("Cannot find header: ", err)
+	if err := w3g.StripNWG(b); err != nil {
+		return fmt.Errorf("Cannot find header: %v", err)
 	}
 
 	hdr, data, _, err := w3g.DecodeHeader(b, w3g.NewFactoryCache(w3g.DefaultFactory))
 	if err != nil {
-		logErr.Fatal("DecodeHeader error: ", err)
+		return fmt.Errorf("DecodeHeader error: %v", err)
+	}
+
+	data.Lenient = *lenient
+	data.Limit.Records = *limit
+	if ms, err := parseGameTime(*until); err != nil {
+		return err
+	} else if ms > 0 {
+		data.Limit.Time = time.Duration(ms) * time.Millisecond
 	}
+	defer func() {
+		for _, w := range data.Warnings {
+			logErr.Printf("%s: Warning: %v\n", filename, w)
+		}
+	}()
 
 	var enc *w3g.Encoder
+	var trim *trimmer
 	if *sanitize != "" {
 		o, err := os.Create(*sanitize)
 		if err != nil {
-			logErr.Fatal("Open error: ", err)
+			return fmt.Errorf("Open error: %v", err)
 		}
 		defer o.Close()
 
 		enc, err = w3g.NewEncoder(o, hdr.Encoding())
 		if err != nil {
-			logErr.Fatal("NewEncoder error: ", err)
+			return fmt.Errorf("NewEncoder error: %v", err)
 		}
 		enc.Header = *hdr
+
+		if *trimFrom != "" || *trimTo != "" {
+			trim = &trimmer{}
+			if trim.From, err = parseGameTime(*trimFrom); err != nil {
+				return err
+			}
+			if trim.To, err = parseGameTime(*trimTo); err != nil {
+				return err
+			}
+			if trim.To != 0 && trim.To <= trim.From {
+				return fmt.Errorf("Invalid time range %v-%v", *trimFrom, *trimTo)
+			}
+		}
 	}
 
-	var skip = false
-	var maxp uint8 = 24
-	if hdr.GameVersion.Version < 29 {
-		maxp = 12
+	var cw *csvWriter
+	if *csvout || *csvevent {
+		cw = newCSVWriter(w, prefix, rf, *csvevent)
+		if prefix == "" {
+			cw.WriteHeader()
+		}
 	}
 
-	print(hdr)
+	if enc != nil {
+		data.KeepRaw = true
+	} else {
+		// Sanitize needs every record, only skip decoding when just printing
+		data.DecodeOnly = rf.decodeOnly(hdr.GameVersion.Version)
+		if data.DecodeOnly != nil && *header {
+			// Needed to detect end of header
+			data.DecodeOnly = append(data.DecodeOnly, w3g.RidCountDownStart, w3g.RidCountDownEnd, w3g.RidGameStart)
+		}
+		if data.DecodeOnly != nil && cw != nil {
+			// Needed to keep track of game time and player names
+			data.DecodeOnly = append(data.DecodeOnly, w3g.RidGameInfo, w3g.RidPlayerInfo, w3g.RidTimeSlot, w3g.RidTimeSlot2)
+		}
+	}
+
+	var ja *jsonArrayWriter
+	if *jsonarr {
+		if ja, err = newJSONArrayWriter(w, prefix, hdr); err != nil {
+			return fmt.Errorf("JSON error: %v", err)
+		}
+	}
+
+	var skip = false
+
+	if cw == nil && ja == nil {
+		printHeader(out, hdr)
+	}
 	if err := data.ForEach(func(r w3g.Record) error {
 		if enc != nil {
-			var write = true
+			var inc uint16
+			if ts, ok := r.(*w3g.TimeSlot); ok {
+				inc = ts.TimeIncrementMS
+			}
+
+			var write = trim == nil || trim.keep(r)
+			var changed = false
 
 			switch v := r.(type) {
 			case *w3g.ChatMessage:
 				write = false
 			case *w3g.SlotInfo:
-				var c = uint8(0)
-				for i := range v.Slots {
-					if v.Slots[i].Team >= maxp {
-						continue
-					}
-					v.Slots[i].Color = c
-					c++
-				}
+				var slots = append([]w3gs.SlotData(nil), v.Slots...)
+				v.NormalizeColors(hdr.Encoding().MaxPlayers())
+				changed = !reflect.DeepEqual(slots, v.Slots)
+			case *w3g.TimeSlot:
+				changed = inc != v.TimeIncrementMS
 			}
 
 			if write {
-				if _, err := enc.WriteRecord(r); err != nil {
+				// Keep the exact byte layout of unchanged records
+				var err error
+				if raw := data.Raw(); raw != nil && !changed {
+					_, err = enc.WriteRawRecord(raw)
+				} else {
+					_, err = enc.WriteRecord(r)
+				}
+				if err != nil {
 					return err
 				}
 			}
@@ -125,17 +442,60 @@ func main() {
 			}
 		}
 
-		if !skip {
-			print(r)
+		if skip {
+			return nil
+		}
+		if cw != nil {
+			return cw.WriteRecord(r)
+		}
+		if !rf.match(r) {
+			return nil
 		}
+		if ja != nil {
+			return ja.WriteRecord(r)
+		}
+		print(out, r)
 		return nil
-	}); err != nil && err != errBreakEarly {
-		logErr.Fatal("Data error: ", err)
+	}); errors.Is(err, w3g.ErrTruncated) {
+		// Keep the valid portion of incomplete replays
+		logErr.Printf("%s: Warning: %v\n", filename, err)
+		if ja != nil {
+			if err := ja.Close(err); err != nil {
+				return fmt.Errorf("JSON error: %v", err)
+			}
+		}
+	} else if err != nil && err != errBreakEarly {
+		if ja != nil {
+			// Keep the document well-formed
+			ja.Close(err)
+		}
+		return fmt.Errorf("Data error: %v", err)
+	} else if ja != nil {
+		if err := ja.Close(nil); err != nil {
+			return fmt.Errorf("JSON error: %v", err)
+		}
+	}
+
+	if *jsonout && cw == nil && ja == nil {
+		var stats = data.Stats()
+		print(out, &stats)
+	}
+
+	if cw != nil {
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return fmt.Errorf("CSV error: %v", err)
+		}
 	}
 
 	if enc != nil {
+		if trim != nil {
+			enc.DurationMS = trim.kept
+		}
 		if err := enc.Close(); err != nil {
-			logErr.Fatal("Save error: ", err)
+			return fmt.Errorf("Save error: %v", err)
 		}
 	}
+
+	return nil
 }
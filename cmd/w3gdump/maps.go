@@ -0,0 +1,105 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/nielsAD/gowarcraft3/file/fs"
+	"github.com/nielsAD/gowarcraft3/file/w3m"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+var errMapNotFound = errors.New("Map not found")
+
+// mapSearchPath returns the user defined map directories (-maps flag and WC3_MAP_PATH), in order
+func mapSearchPath() []string {
+	var res []string
+	for _, list := range []string{*mapPath, os.Getenv("WC3_MAP_PATH")} {
+		for _, p := range strings.Split(list, ";") {
+			for _, dir := range filepath.SplitList(p) {
+				if dir != "" {
+					res = append(res, dir)
+				}
+			}
+		}
+	}
+	return res
+}
+
+// localMap describes where a map was found, Path is empty if it is not a plain file on disk
+type localMap struct {
+	Location string
+	Path     string
+	Size     uint32
+	CRC      uint32
+}
+
+// findMap looks up name (slash separated, see Replay.MapPath) in the map search path first
+// (both as relative path and as plain file name), then in the game storage.
+func findMap(name string) (*localMap, error) {
+	for _, dir := range mapSearchPath() {
+		for _, n := range []string{name, path.Base(name)} {
+			var p = filepath.Join(dir, filepath.FromSlash(n))
+			f, err := os.Open(p)
+			if err != nil {
+				continue
+			}
+			size, crc, err := w3m.FileChecksum(f)
+			f.Close()
+			if err != nil {
+				return nil, err
+			}
+			return &localMap{Location: p, Path: p, Size: size, CRC: crc}, nil
+		}
+	}
+
+	var install = fs.FindInstallationDir()
+	var stor = fs.Open(install, fs.UserDir())
+	defer stor.Close()
+
+	f, err := stor.Open(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = errMapNotFound
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	size, crc, err := w3m.FileChecksum(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return &localMap{Location: "game storage (" + install + ", " + fs.UserDir() + ")", Size: size, CRC: crc}, nil
+}
+
+// verifyMap compares the content hash of a local map file with the hash stored in the replay.
+// Only applicable for version < 1.32 replays, returns nil if the map cannot be verified.
+func verifyMap(m *localMap, gv uint32, gs *w3gs.GameSettings) error {
+	if m.Path == "" || gv >= 32 || gs.MapSha1 == [20]byte{} {
+		return nil
+	}
+
+	var stor = fs.Open(fs.FindInstallationDir())
+	defer stor.Close()
+
+	_, _, hash, err := w3m.Hashes(m.Path, stor)
+	if err != nil {
+		return err
+	}
+	if hash.Xoro != gs.MapXoro || hash.Sha1 != gs.MapSha1 {
+		var exp = w3m.Hash{Xoro: gs.MapXoro, Sha1: gs.MapSha1}
+		return fmt.Errorf("Map checksum mismatch (expected %s, got %s)", exp.String(), hash.String())
+	}
+
+	return nil
+}
@@ -5,13 +5,15 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"hash/crc32"
-	"io"
+	"math"
+	"math/rand"
 	"net"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -25,24 +27,11 @@ import (
 var (
 	errBreakEarly       = errors.New("Early break")
 	errUnexpectedPacket = errors.New("Unexpected packet")
-	errMapUnavailable   = errors.New("Map unavailable")
+	errLobbyFull        = errors.New("Lobby full")
 )
 
-func mapCRC(name string) (uint32, uint32) {
-	var stor = fs.Open(fs.FindInstallationDir(), fs.UserDir())
-	defer stor.Close()
-
-	if f, err := stor.Open(name); err == nil {
-		defer f.Close()
-
-		var crc = crc32.NewIEEE()
-		if size, err := io.Copy(crc, f); err == nil {
-			return uint32(size), crc.Sum32()
-		}
-	}
-
-	return 1, 1
-}
+// Time to wait for more clients after the first one joined
+const lobbyWait = 15 * time.Second
 
 func speedString(s int64) string {
 	if s < 0 {
@@ -51,88 +40,181 @@ func speedString(s int64) string {
 	return fmt.Sprintf("%dx", s+1)
 }
 
-func cast(name string) error {
-	replay, err := w3g.Open(name)
-	if err != nil {
-		return err
+type playCommandType int
+
+const (
+	cmdPause playCommandType = iota
+	cmdResume
+	cmdSeek
+)
+
+// playCommand controls the record playback loop
+type playCommand struct {
+	Type playCommandType
+	MS   uint32
+}
+
+// streamClient is a connection that took over one of the replay's player IDs
+type streamClient struct {
+	*network.W3GSConn
+	ID   uint8
+	Name string
+}
+
+// streamer fans out a replay to multiple clients
+type streamer struct {
+	replay *w3g.Replay
+	info   w3gs.GameInfo
+	size   uint32
+	crc    uint32
+
+	msec  int64
+	speed int64
+	cmd   chan playCommand
+
+	// Cancelled when playback ends, stops the client read loops
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mut     sync.Mutex
+	free    []uint8
+	started bool
+	clients map[*streamClient]struct{}
+}
+
+func (s *streamer) claimID() (uint8, bool) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.started || len(s.free) == 0 {
+		return 0, false
 	}
+	var id = s.free[0]
+	s.free = s.free[1:]
+	return id, true
+}
 
-	l, err := net.ListenTCP("tcp4", nil)
-	if err != nil {
-		return err
+func (s *streamer) releaseID(id uint8) {
+	s.mut.Lock()
+	s.free = append([]uint8{id}, s.free...)
+	s.mut.Unlock()
+}
+
+func (s *streamer) remove(c *streamClient) {
+	s.mut.Lock()
+	_, ok := s.clients[c]
+	delete(s.clients, c)
+	s.mut.Unlock()
+
+	if ok {
+		c.Close()
+		logOut.Printf("%s left the stream\n", c.Name)
 	}
-	defer l.Close()
-	adv, err := lan.NewAdvertiser(&w3gs.GameInfo{
-		GameVersion:    replay.GameVersion,
-		HostCounter:    1,
-		EntryKey:       0xDEADBEEF,
-		GameName:       replay.GameName,
-		GameSettings:   replay.GameSettings,
-		GameFlags:      replay.GameFlags,
-		SlotsTotal:     (uint32)(len(replay.Slots)),
-		SlotsUsed:      0,
-		SlotsAvailable: 1,
-		GamePort:       uint16(l.Addr().(*net.TCPAddr).Port),
-	})
-	if err != nil {
-		return err
+}
+
+func (s *streamer) list() []*streamClient {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	var res = make([]*streamClient, 0, len(s.clients))
+	for c := range s.clients {
+		res = append(res, c)
 	}
-	defer adv.Close()
+	return res
+}
 
-	go adv.Run()
-	logOut.Printf("Streaming game '%s' on %s (game version: %v), please join the lobby\n", replay.GameName, l.Addr(), replay.GameVersion)
+// broadcast pkt to all clients, skipping the client that took over player skip
+func (s *streamer) broadcast(pkt w3gs.Packet, skip uint8) {
+	for _, c := range s.list() {
+		if c.ID == skip {
+			continue
+		}
+		if _, err := c.Send(pkt); err != nil {
+			logErr.Printf("%s send error: %v\n", c.Name, err)
+			s.remove(c)
+		}
+	}
+}
 
-	l.SetDeadline(time.Now().Add(3 * time.Minute))
-	tcp, err := l.AcceptTCP()
-	if err != nil {
-		return err
+func (s *streamer) say(str string) {
+	for _, c := range s.list() {
+		if _, err := c.Send(&w3gs.MessageRelay{Message: *w3gs.NewChatToAll(c.ID, str)}); err != nil {
+			logErr.Println("Say error: ", err)
+			s.remove(c)
+		}
 	}
-	defer tcp.Close()
+}
 
-	tcp.SetNoDelay(true)
+// slotOf returns the index of the slot that holds player id
+func (s *streamer) slotOf(id uint8) uint8 {
+	for i := range s.replay.Slots {
+		if s.replay.Slots[i].PlayerID == id {
+			return uint8(i)
+		}
+	}
+	return 0
+}
 
-	conn := network.NewW3GSConn(tcp, w3gs.NewFactoryCache(w3gs.DefaultFactory), w3gs.Encoding{GameVersion: replay.GameVersion.Version})
+// whisper sends a private message to the client that took over player target
+func (s *streamer) whisper(target uint8, str string) {
+	for _, c := range s.list() {
+		if c.ID != target {
+			continue
+		}
+		if _, err := c.Send(&w3gs.MessageRelay{Message: *w3gs.NewChatToPlayer(c.ID, c.ID, s.slotOf(c.ID), str)}); err != nil {
+			logErr.Println("Whisper error: ", err)
+			s.remove(c)
+		}
+	}
+}
+
+// join performs the lobby handshake up until the map check
+func (s *streamer) join(conn *network.W3GSConn) (*streamClient, error) {
 	pkt, err := conn.NextPacket(10 * time.Second)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	var c = streamClient{W3GSConn: conn}
 	switch v := pkt.(type) {
 	case *w3gs.Join:
-		if v.HostCounter == 1 && v.EntryKey == 0xDEADBEEF {
-			logOut.Printf("%s joined the lobby, starting game..\n", v.PlayerName)
-			break
+		if reason, ok := s.info.ValidateJoin(v); !ok {
+			conn.Send(&w3gs.RejectJoin{Reason: reason})
+			return nil, errUnexpectedPacket
 		}
-		conn.Send(&w3gs.RejectJoin{Reason: w3gs.RejectJoinWrongKey})
-		return errUnexpectedPacket
+		c.Name = v.PlayerName
 	default:
 		conn.Send(&w3gs.RejectJoin{Reason: w3gs.RejectJoinInvalid})
-		return errUnexpectedPacket
+		return nil, errUnexpectedPacket
 	}
 
-	// Close advertiser early
-	adv.Close()
+	id, ok := s.claimID()
+	if !ok {
+		conn.Send(&w3gs.RejectJoin{Reason: w3gs.RejectJoinFull})
+		return nil, errLobbyFull
+	}
+	c.ID = id
 
-	var hostID = replay.HostPlayer.ID
-	for _, s := range replay.Slots {
-		if s.SlotStatus == w3gs.SlotOccupied && !s.Computer {
-			// Hope player in lowest slot is an observer
-			hostID = s.PlayerID
-		}
+	if err := s.handshake(&c); err != nil {
+		s.releaseID(id)
+		return nil, err
 	}
 
-	if _, err := conn.Send(&w3gs.SlotInfoJoin{
-		SlotInfo: replay.SlotInfo.SlotInfo,
-		PlayerID: hostID,
+	return &c, nil
+}
+
+func (s *streamer) handshake(c *streamClient) error {
+	if _, err := c.Send(&w3gs.SlotInfoJoin{
+		SlotInfo: s.replay.SlotInfo.SlotInfo,
+		PlayerID: c.ID,
 	}); err != nil {
 		return err
 	}
 
-	for _, p := range replay.PlayerInfo {
-		if p.ID == hostID {
+	for _, p := range s.replay.PlayerInfo {
+		if p.ID == c.ID {
 			continue
 		}
-		if _, err := conn.Send(&w3gs.PlayerInfo{
+		if _, err := c.Send(&w3gs.PlayerInfo{
 			JoinCounter: p.JoinCounter,
 			PlayerID:    p.ID,
 			PlayerName:  p.Name,
@@ -141,66 +223,50 @@ func cast(name string) error {
 		}
 	}
 
-	var size, crc = mapCRC(strings.Replace(replay.GameSettings.MapPath, "\\", "/", -1))
-	if _, err := conn.Send(&w3gs.MapCheck{
-		FilePath: replay.GameSettings.MapPath,
-		FileSize: size,
-		FileCRC:  crc,
-		MapXoro:  replay.GameSettings.MapXoro,
-		MapSha1:  replay.GameSettings.MapSha1,
-	}); err != nil {
+	if err := c.CheckMap(&w3gs.MapCheck{
+		FilePath: s.replay.GameSettings.MapPath,
+		FileSize: s.size,
+		FileCRC:  s.crc,
+		MapXoro:  s.replay.GameSettings.MapXoro,
+		MapSha1:  s.replay.GameSettings.MapSha1,
+	}, 10*time.Second); err != nil {
 		return err
 	}
 
-	pkt, err = conn.NextPacket(10 * time.Second)
-	for {
-		if err != nil {
+	for _, p := range s.replay.PlayerExtra {
+		if _, err := c.Send(&p.PlayerExtra); err != nil {
 			return err
 		}
-		switch m := pkt.(type) {
-		case *w3gs.PlayerExtra:
-			pkt, err = conn.NextPacket(network.NoTimeout)
-			continue
-		case *w3gs.MapState:
-			if !m.Ready {
-				return errMapUnavailable
-			}
-			// Break out of loop
-		default:
-			return errUnexpectedPacket
-		}
-		break
 	}
 
-	for _, p := range replay.PlayerExtra {
-		if _, err := conn.Send(&p.PlayerExtra); err != nil {
-			return err
-		}
-	}
+	return nil
+}
 
-	time.Sleep(1 * time.Second)
-	conn.Send(&w3gs.CountDownStart{})
-	conn.Send(&w3gs.CountDownEnd{})
+// load starts the game for a single client and waits until it finished loading
+func (s *streamer) load(c *streamClient) error {
+	if err := c.CountDown(); err != nil {
+		return err
+	}
 
-	for _, p := range replay.PlayerInfo {
-		if p.ID == hostID {
+	for _, p := range s.replay.PlayerInfo {
+		if p.ID == c.ID {
 			continue
 		}
-		if _, err := conn.Send(&w3gs.PlayerLoaded{
+		if _, err := c.Send(&w3gs.PlayerLoaded{
 			PlayerID: p.ID,
 		}); err != nil {
 			return err
 		}
 	}
 
-	pkt, err = conn.NextPacket(time.Minute * 5)
+	pkt, err := c.NextPacket(time.Minute * 5)
 	for {
 		if err != nil {
 			return err
 		}
 		switch pkt.(type) {
 		case *w3gs.PlayerExtra:
-			pkt, err = conn.NextPacket(network.NoTimeout)
+			pkt, err = c.NextPacket(network.NoTimeout)
 			continue
 		case *w3gs.GameLoaded:
 			// Break out of loop
@@ -210,25 +276,15 @@ func cast(name string) error {
 		break
 	}
 
-	var msec int64
-	var speed int64
-
-	var say = func(s string) {
-		if _, err := conn.Send(&w3gs.MessageRelay{Message: w3gs.Message{
-			SenderID: hostID,
-			Type:     w3gs.MsgChatExtra,
-			Scope:    w3gs.ScopeAll,
-			Content:  s,
-		}}); err != nil {
-			logErr.Println("Say error: ", err)
-			conn.Close()
-		}
-	}
+	return nil
+}
 
+// run handles incoming packets for a client that finished loading
+func (s *streamer) run(c *streamClient) error {
 	var events = network.EventEmitter{}
 	events.On(&w3gs.Leave{}, func(_ *network.Event) {
-		conn.Send(&w3gs.LeaveAck{})
-		conn.Close()
+		c.Send(&w3gs.LeaveAck{})
+		s.remove(c)
 	})
 	events.On(&w3gs.Message{}, func(ev *network.Event) {
 		var msg = ev.Arg.(*w3gs.Message)
@@ -239,75 +295,395 @@ func cast(name string) error {
 		var cmd = strings.Fields(msg.Content)
 		switch strings.ToLower(cmd[0]) {
 		case ".time":
-			var t = (time.Duration)(atomic.LoadInt64(&msec)) * time.Millisecond
-			say("Time: " + t.String())
+			var t = (time.Duration)(atomic.LoadInt64(&s.msec)) * time.Millisecond
+			s.whisper(c.ID, "Time: "+t.String())
 		case ".speed":
-			var s = atomic.LoadInt64(&speed)
+			var sp = atomic.LoadInt64(&s.speed)
 
 			if len(cmd) > 1 {
 				if strings.HasPrefix(cmd[1], "1/") {
 					if i, err := strconv.ParseInt(cmd[1][2:], 0, 64); err == nil {
-						s = -(i - 1)
+						sp = -(i - 1)
 					}
 				} else {
 					if i, err := strconv.ParseInt(cmd[1], 0, 64); err == nil {
-						s = i - 1
+						sp = i - 1
 					}
 				}
-				atomic.StoreInt64(&speed, s)
+				atomic.StoreInt64(&s.speed, sp)
+				s.say("Replay speed: " + speedString(sp))
+				return
 			}
 
-			say("Replay speed: " + speedString(s))
+			s.whisper(c.ID, "Replay speed: "+speedString(sp))
+		case ".pause":
+			s.control(playCommand{Type: cmdPause})
+		case ".resume":
+			s.control(playCommand{Type: cmdResume})
+		case ".seek":
+			if len(cmd) < 2 {
+				s.whisper(c.ID, "Usage: .seek <mm:ss>")
+				return
+			}
+			ms, err := parseGameTime(cmd[1])
+			if err != nil {
+				s.whisper(c.ID, err.Error())
+				return
+			}
+			if int64(ms) < atomic.LoadInt64(&s.msec) {
+				s.whisper(c.ID, "Cannot seek backwards")
+				return
+			}
+			s.control(playCommand{Type: cmdSeek, MS: ms})
 		}
 	})
 
 	go func() {
-		err := conn.Run(&events, 3*time.Second)
-		if err != nil && !network.IsCloseError(err) {
-			logErr.Println("Connection error: ", err)
-			conn.Close()
+		err := c.RunContext(s.ctx, &events, 3*time.Second)
+		if errors.Is(err, network.ErrShortRead) {
+			logErr.Printf("%s disconnected: %v\n", c.Name, err)
+		} else if err != nil && err != context.Canceled && !network.IsCloseError(err) {
+			logErr.Printf("%s connection error: %v\n", c.Name, err)
 		}
+		s.remove(c)
 	}()
 
-	if _, err := conn.Send(&w3gs.PlayerLoaded{
-		PlayerID: hostID,
-	}); err != nil {
+	_, err := c.Send(&w3gs.PlayerLoaded{
+		PlayerID: c.ID,
+	})
+	return err
+}
+
+// advertiseOn configures the interfaces (-iface) used by adv and logs them
+func advertiseOn(adv lan.Advertiser) error {
+	var ifaces = lan.BroadcastInterfaces()
+	if *netIface != "" {
+		ifaces = ifaces[:0]
+		for _, n := range strings.Split(*netIface, ",") {
+			iface, err := net.InterfaceByName(strings.TrimSpace(n))
+			if err != nil {
+				return fmt.Errorf("Interface %s: %v", n, err)
+			}
+			ifaces = append(ifaces, *iface)
+		}
+	}
+
+	var names []string
+	for _, iface := range ifaces {
+		names = append(names, iface.Name)
+	}
+
+	switch a := adv.(type) {
+	case *lan.UDPAdvertiser:
+		a.Interfaces = ifaces
+
+		var addrs []string
+		for _, addr := range a.Addrs() {
+			addrs = append(addrs, addr.String())
+		}
+		logOut.Printf("Advertising on [%s] (%s)\n", strings.Join(names, ", "), strings.Join(addrs, ", "))
+	case *lan.MDNSAdvertiser:
+		a.Interfaces = ifaces
+		logOut.Printf("Advertising on [%s]\n", strings.Join(names, ", "))
+	}
+
+	return nil
+}
+
+func cast(name string) error {
+	if *hostCnt > math.MaxUint32 {
+		return fmt.Errorf("Invalid host counter %d (exceeds %d)", *hostCnt, uint32(math.MaxUint32))
+	}
+	if *entryKey > math.MaxUint32 {
+		return fmt.Errorf("Invalid entry key %d (exceeds %d)", *entryKey, uint32(math.MaxUint32))
+	}
+
+	replay, err := w3g.Open(name)
+	if err != nil {
+		return err
+	}
+
+	// Clients only list games of their own product, advertise with the product of the replay
+	var product = w3gs.ProductName(replay.Product())
+	if product == "" {
+		return fmt.Errorf("Cannot stream replay of unknown product %v", replay.Product())
+	}
+
+	var s = streamer{
+		replay:  replay,
+		size:    1,
+		crc:     1,
+		clients: make(map[*streamClient]struct{}),
+		cmd:     make(chan playCommand, 8),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	defer s.cancel()
+
+	if m, err := findMap(replay.MapPath()); err == nil {
+		logOut.Printf("Using map %s\n", m.Location)
+		s.size, s.crc = m.Size, m.CRC
+		if err := verifyMap(m, replay.GameVersion.Version, &replay.GameSettings); err != nil {
+			logErr.Printf("WARNING: Map '%s' does not match the replay: %v\n", m.Location, err)
+		}
+	} else {
+		var dirs = mapSearchPath()
+		if dir := fs.FindInstallationDir(); dir != "" {
+			dirs = append(dirs, dir)
+		}
+		dirs = append(dirs, fs.UserDir())
+		logErr.Printf("WARNING: Cannot find map '%s' (%v) in [%s], players will not be able to load the game. Use -maps to add a map directory.\n", replay.GameSettings.MapPath, err, strings.Join(dirs, ", "))
+	}
+
+	// Clients take over observers first, then human players starting at the highest slot
+	for _, p := range replay.Observers() {
+		s.free = append(s.free, p.ID)
+	}
+	for i := len(replay.Slots) - 1; i >= 0; i-- {
+		if slot := &replay.Slots[i]; slot.IsHuman() && !slot.IsObserver(replay.MaxPlayers()) {
+			s.free = append(s.free, slot.PlayerID)
+		}
+	}
+	if len(s.free) == 0 {
+		s.free = append(s.free, replay.HostPlayer.ID)
+	}
+
+	var max = *maxConn
+	if max < 1 {
+		max = 1
+	}
+	if max > len(s.free) {
+		max = len(s.free)
+	}
+	s.free = s.free[:max]
+
+	l, err := network.ListenW3GS("", nil, w3gs.Encoding{GameVersion: replay.GameVersion.Version})
+	if err != nil {
 		return err
 	}
+	defer l.Close()
+
+	s.info = w3gs.GameInfo{
+		GameVersion:    replay.GameVersion,
+		HostCounter:    uint32(*hostCnt),
+		EntryKey:       uint32(*entryKey),
+		GameName:       replay.GameName,
+		GameSettings:   replay.GameSettings,
+		GameFlags:      replay.GameFlags,
+		SlotsTotal:     (uint32)(len(replay.Slots)),
+		SlotsUsed:      0,
+		SlotsAvailable: uint32(max),
+		GamePort:       uint16(l.Addr().(*net.TCPAddr).Port),
+	}
+	// Clients match join requests against the advertisement by host counter and entry key,
+	// use a random key so that multiple streams on one network do not collide
+	for s.info.EntryKey == 0 {
+		s.info.EntryKey = rand.New(rand.NewSource(time.Now().UnixNano())).Uint32()
+	}
+
+	adv, err := lan.NewAdvertiser(&s.info)
+	if err != nil {
+		return err
+	}
+	defer adv.Close()
+
+	if err := advertiseOn(adv); err != nil {
+		return err
+	}
+
+	var failed sync.Map
+	adv.On(&lan.BroadcastError{}, func(ev *network.Event) {
+		// Log once per address, broadcasts are retried periodically
+		var err = ev.Arg.(*lan.BroadcastError)
+		if _, dup := failed.LoadOrStore(err.Iface+"/"+err.Addr.String(), true); !dup {
+			logErr.Printf("WARNING: Cannot advertise on %v\n", err)
+		}
+	})
+	adv.On(&network.AsyncError{}, func(ev *network.Event) {
+		logErr.Printf("Advertise error: %v\n", ev.Arg)
+	})
+
+	go func() {
+		if err := adv.Run(); err != nil && !network.IsCloseError(err) {
+			logErr.Printf("Advertise error: %v\n", err)
+		}
+	}()
+	logOut.Printf("Streaming game '%s' on %s (game version: %v), please join the lobby with %s\n", replay.GameName, l.Addr(), replay.GameVersion, product)
+
+	var ready = make(chan *streamClient)
+	var accerr = make(chan error, 1)
+	var done = make(chan struct{})
 
-	for _, rec := range replay.Records {
-		var pkt w3gs.Packet
-		switch v := rec.(type) {
-		case *w3g.PlayerLeft:
-			if v.PlayerID == hostID {
-				continue
+	l.SetDeadline(time.Now().Add(3 * time.Minute))
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				accerr <- err
+				return
 			}
-			pkt = &w3gs.PlayerLeft{
-				PlayerID: v.PlayerID,
-				Reason:   v.Reason,
+
+			go func() {
+				c, err := s.join(conn)
+				if err != nil {
+					logErr.Println("Join error: ", err)
+					conn.Close()
+					return
+				}
+
+				select {
+				case ready <- c:
+				case <-done:
+					s.releaseID(c.ID)
+					c.Send(&w3gs.RejectJoin{Reason: w3gs.RejectJoinStarted})
+					c.Close()
+				}
+			}()
+		}
+	}()
+
+	var timeout <-chan time.Time
+	func() {
+		for len(s.list()) < max {
+			select {
+			case c := <-ready:
+				logOut.Printf("%s joined the lobby (player %d)\n", c.Name, c.ID)
+				s.mut.Lock()
+				s.clients[c] = struct{}{}
+				var n = len(s.clients)
+				s.mut.Unlock()
+
+				adv.Refresh(uint32(n), uint32(max-n))
+				if timeout == nil && n < max {
+					logOut.Printf("Waiting %v for more clients to join\n", lobbyWait)
+					timeout = time.After(lobbyWait)
+				}
+			case <-timeout:
+				return
+			case err = <-accerr:
+				return
 			}
-		case *w3g.TimeSlot:
-			var s = atomic.LoadInt64(&speed)
-			if s >= 0 {
-				time.Sleep(time.Duration(v.TimeIncrementMS) * time.Millisecond / (time.Duration)(s+1))
-			} else {
-				time.Sleep(time.Duration(v.TimeIncrementMS) * time.Millisecond * (time.Duration)(-s+1))
+		}
+	}()
+
+	close(done)
+	adv.Close()
+	l.Close()
+
+	s.mut.Lock()
+	s.started = true
+	s.mut.Unlock()
+
+	var n = len(s.list())
+	if n == 0 {
+		return err
+	}
+	logOut.Printf("Starting game with %d client(s)..\n", n)
+
+	time.Sleep(1 * time.Second)
+
+	var wg sync.WaitGroup
+	for _, c := range s.list() {
+		wg.Add(1)
+		go func(c *streamClient) {
+			defer wg.Done()
+			if err := s.load(c); err != nil {
+				logErr.Printf("%s load error: %v\n", c.Name, err)
+				s.remove(c)
 			}
-			atomic.AddInt64(&msec, int64(v.TimeIncrementMS))
+		}(c)
+	}
+	wg.Wait()
 
-			pkt = &v.TimeSlot
-		case *w3g.Desync:
-			pkt = &v.Desync
-		case *w3g.ChatMessage:
-			pkt = &w3gs.MessageRelay{Message: v.Message}
-		default:
-			continue
+	for _, c := range s.list() {
+		if err := s.run(c); err != nil {
+			logErr.Printf("%s send error: %v\n", c.Name, err)
+			s.remove(c)
+		}
+	}
+
+	s.play()
+	s.cancel()
+	for _, c := range s.list() {
+		s.remove(c)
+	}
+
+	return nil
+}
+
+// control sends a command to the playback loop, dropped if the loop is not listening
+func (s *streamer) control(c playCommand) {
+	select {
+	case s.cmd <- c:
+	default:
+	}
+}
+
+// play sends the replay records to all clients, controlled by commands on s.cmd
+func (s *streamer) play() {
+	var paused bool
+	var seek uint32
+
+	// Keep clients from timing out while paused
+	var keepAlive = time.NewTicker(time.Second)
+	defer keepAlive.Stop()
+
+	var apply = func(c playCommand) {
+		switch c.Type {
+		case cmdPause:
+			paused = true
+			s.say("Paused")
+		case cmdResume:
+			paused = false
+			s.say("Resumed")
+		case cmdSeek:
+			seek = c.MS
+			s.say("Seeking to " + (time.Duration(c.MS) * time.Millisecond).String())
 		}
+	}
 
-		if _, err := conn.Send(pkt); err != nil {
-			return err
+	var gone = func() bool {
+		if len(s.list()) > 0 {
+			return false
 		}
+		logOut.Println("All clients left, stopping stream")
+		return true
 	}
 
-	return nil
+	for _, tp := range s.replay.Playback() {
+		if gone() {
+			return
+		}
+
+		var skip uint8
+		switch v := tp.Packet.(type) {
+		case *w3gs.PlayerLeft:
+			// Do not kick the client that took over this player
+			skip = v.PlayerID
+		case *w3gs.TimeSlot:
+			var ms = atomic.LoadInt64(&s.msec)
+			for (paused && ms >= int64(seek)) || len(s.cmd) > 0 {
+				select {
+				case c := <-s.cmd:
+					apply(c)
+				case <-keepAlive.C:
+					if gone() {
+						return
+					}
+					s.broadcast(&w3gs.TimeSlot{}, 0)
+				}
+			}
+
+			if ms >= int64(seek) {
+				var sp = atomic.LoadInt64(&s.speed)
+				if sp >= 0 {
+					time.Sleep(tp.Delay / (time.Duration)(sp+1))
+				} else {
+					time.Sleep(tp.Delay * (time.Duration)(-sp+1))
+				}
+			}
+			atomic.AddInt64(&s.msec, int64(v.TimeIncrementMS))
+		}
+
+		s.broadcast(tp.Packet, skip)
+	}
 }
@@ -0,0 +1,200 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+type playerSummary struct {
+	ID        uint8
+	Name      string
+	Race      w3gs.RacePref
+	Color     uint8
+	ColorName string
+	Team      uint8
+	Observer  bool
+	Actions   int
+	APM       float64
+	Chat      int
+	LeftMS    uint32
+	Reason    w3gs.LeaveReason
+	Unusual   map[uint8]int `json:",omitempty"`
+	Paused    int           `json:",omitempty"`
+}
+
+type summary struct {
+	File       string `json:",omitempty"`
+	GameName   string
+	MapName    string
+	MapPath    string
+	Origin     string
+	GameFlags  w3gs.GameFlags
+	Language   string
+	SavedGame  bool
+	DurationMS uint32
+	RandomSeed uint32
+	Players    []*playerSummary
+	Stats      *w3g.ReplayStats `json:",omitempty"`
+}
+
+func gameOrigin(rep *w3g.Replay) string {
+	if rep.SinglePlayer {
+		return "SinglePlayer"
+	}
+	if rep.GameFlags&(w3gs.GameFlagLadder1v1|w3gs.GameFlagLadder2v2|w3gs.GameFlagLadder3v3|w3gs.GameFlagLadder4v4) != 0 {
+		return "Battle.net"
+	}
+	for _, e := range rep.PlayerExtra {
+		if len(e.Profiles) > 0 {
+			return "Battle.net"
+		}
+	}
+	return "Multiplayer"
+}
+
+func summarize(rep *w3g.Replay) *summary {
+	var res = summary{
+		GameName:   rep.GameName,
+		MapName:    rep.MapName(),
+		MapPath:    rep.MapPath(),
+		Origin:     gameOrigin(rep),
+		GameFlags:  rep.GameFlags,
+		Language:   w3gs.LocaleCode(rep.LanguageID),
+		SavedGame:  rep.IsSavedGame(),
+		DurationMS: rep.DurationMS,
+		RandomSeed: rep.RandomSeed,
+	}
+
+	var players = map[uint8]*playerSummary{}
+	for _, p := range rep.PlayerInfo {
+		var s = playerSummary{
+			ID:     p.ID,
+			Name:   p.Name,
+			Race:   p.Race,
+			LeftMS: rep.DurationMS,
+		}
+		if r, ok := rep.PlayerByID(p.ID); ok {
+			s.Race = r.Race
+			s.Color = r.Color
+			s.ColorName, _ = rep.Encoding().PlayerColor(r.Color)
+			s.Team = r.Team
+			s.Observer = r.Observer
+		}
+
+		players[p.ID] = &s
+		res.Players = append(res.Players, &s)
+	}
+
+	var ms uint32
+	rep.Accept(w3g.Visitor{
+		OnTimeSlot: func(v *w3g.TimeSlot) {
+			ms += uint32(v.TimeIncrementMS)
+		},
+		OnChat: func(v *w3g.ChatMessage) {
+			if p := players[v.SenderID]; p != nil {
+				p.Chat++
+			}
+		},
+		OnPlayerLeft: func(v *w3g.PlayerLeft) {
+			if p := players[v.PlayerID]; p != nil {
+				p.LeftMS = ms
+				p.Reason = v.Reason
+			}
+		},
+	})
+
+	var prof = rep.ActionProfile()
+	for _, p := range res.Players {
+		if a, ok := prof[p.ID]; ok {
+			p.Actions = a.Actions
+			if len(a.Unusual) > 0 {
+				p.Unusual = a.Unusual
+			}
+			p.Paused = a.Paused
+		}
+		if p.LeftMS > 0 {
+			p.APM = float64(p.Actions) / (float64(p.LeftMS) / float64(time.Minute/time.Millisecond))
+		}
+	}
+
+	return &res
+}
+
+func (s *summary) print(w io.Writer, jsonout bool) error {
+	if jsonout {
+		return json.NewEncoder(w).Encode(s)
+	}
+
+	fmt.Fprintf(w, "Game:     %s\n", s.GameName)
+	fmt.Fprintf(w, "Map:      %s (%s)\n", s.MapName, s.MapPath)
+	fmt.Fprintf(w, "Origin:   %s (%v)\n", s.Origin, s.GameFlags)
+	if s.Language != "" {
+		fmt.Fprintf(w, "Language: %s\n", s.Language)
+	}
+	fmt.Fprintf(w, "Duration: %v\n", time.Duration(s.DurationMS)*time.Millisecond)
+	fmt.Fprintf(w, "Seed:     %d\n", s.RandomSeed)
+	if s.Stats != nil {
+		var n = 0
+		for _, c := range s.Stats.Records {
+			n += c
+		}
+		fmt.Fprintf(w, "Data:     %d blocks, %d bytes (%d decompressed), %d records, %d action bytes\n", s.Stats.NumBlocks, s.Stats.SizeCompressed, s.Stats.SizeDecompressed, n, s.Stats.ActionBytes)
+	}
+	if s.SavedGame {
+		fmt.Fprintf(w, "Warning:  loaded from saved game, statistics only cover the game after loading\n")
+	}
+	fmt.Fprintln(w)
+
+	var t = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(t, "ID\tName\tRace\tTeam\tColor\tAPM\tChat\tLeft\tReason")
+	for _, p := range s.Players {
+		var team = fmt.Sprintf("%d", p.Team+1)
+		if p.Observer {
+			team = "Obs"
+		}
+		var reason = ""
+		if p.Reason != 0 {
+			reason = p.Reason.String()
+		}
+		fmt.Fprintf(t, "%d\t%s\t%v\t%s\t%s\t%.0f\t%d\t%v\t%s\n",
+			p.ID, p.Name, p.Race, team, p.ColorName, p.APM, p.Chat,
+			time.Duration(p.LeftMS)*time.Millisecond, reason,
+		)
+	}
+	if err := t.Flush(); err != nil {
+		return err
+	}
+
+	for _, p := range s.Players {
+		if len(p.Unusual) > 0 {
+			var ids = make([]int, 0, len(p.Unusual))
+			for id := range p.Unusual {
+				ids = append(ids, int(id))
+			}
+			sort.Ints(ids)
+
+			var str []string
+			for _, id := range ids {
+				str = append(str, fmt.Sprintf("0x%02X (%dx)", id, p.Unusual[uint8(id)]))
+			}
+			fmt.Fprintf(w, "Warning:  %s issued unusual actions %s\n", p.Name, strings.Join(str, ", "))
+		}
+		if p.Paused > 0 {
+			fmt.Fprintf(w, "Warning:  %s issued %d actions while paused\n", p.Name, p.Paused)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+)
+
+// parseGameTime parses [hh:]mm:ss or a Go duration string (i.e. "10m30s") to milliseconds
+func parseGameTime(s string) (uint32, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if !strings.Contains(s, ":") {
+		d, err := time.ParseDuration(s)
+		if err != nil || d < 0 {
+			return 0, fmt.Errorf("Invalid game time %q", s)
+		}
+		return uint32(d / time.Millisecond), nil
+	}
+
+	var parts = strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("Invalid game time %q", s)
+	}
+
+	var sec float64
+	for i, p := range parts {
+		var v, err = strconv.ParseFloat(p, 64)
+		if err != nil || v < 0 || (i > 0 && v >= 60) {
+			return 0, fmt.Errorf("Invalid game time %q", s)
+		}
+		sec = sec*60 + v
+	}
+
+	return uint32(sec * 1000), nil
+}
+
+// trimmer drops in-game records outside of the [From, To] window (in game time milliseconds),
+// setup records up to GameStart are always kept. To == 0 means no upper bound.
+type trimmer struct {
+	From uint32
+	To   uint32
+
+	started bool
+	ms      uint32
+	kept    uint32
+}
+
+func (t *trimmer) inWindow() bool {
+	return t.ms >= t.From && (t.To == 0 || t.ms <= t.To)
+}
+
+// keep returns whether r should be written, rebasing the time increment of
+// the first TimeSlot in the window so that playback starts at From.
+func (t *trimmer) keep(r w3g.Record) bool {
+	if !t.started {
+		_, t.started = r.(*w3g.GameStart)
+		return true
+	}
+
+	switch v := r.(type) {
+	case *w3g.TimeSlot:
+		var start = t.ms
+		t.ms += uint32(v.TimeIncrementMS)
+		if t.ms <= t.From || (t.To != 0 && start >= t.To) {
+			return false
+		}
+		if start < t.From {
+			v.TimeIncrementMS = uint16(t.ms - t.From)
+		}
+		t.kept += uint32(v.TimeIncrementMS)
+		return true
+	default:
+		return t.inWindow()
+	}
+}
@@ -0,0 +1,100 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+)
+
+var errInvalid = errors.New("Replay is invalid")
+
+type validationIssue struct {
+	Severity    string
+	Description string
+}
+
+type validationReport struct {
+	File     string `json:",omitempty"`
+	Valid    bool
+	Errors   int
+	Warnings int
+	Issues   []validationIssue
+}
+
+func (v *validationReport) add(s w3g.Severity, format string, a ...interface{}) {
+	switch s {
+	case w3g.SeverityError:
+		v.Errors++
+	default:
+		v.Warnings++
+	}
+	v.Issues = append(v.Issues, validationIssue{Severity: s.String(), Description: fmt.Sprintf(format, a...)})
+}
+
+// validate decodes a replay file and reports decoding errors (bad header, checksums,
+// truncated data) along with the structural issues found by Replay.Validate.
+// Structural checks are skipped for truncated replays, missing records would be reported twice.
+func validate(filename string) *validationReport {
+	var res = validationReport{Issues: []validationIssue{}}
+	defer func() { res.Valid = res.Errors == 0 }()
+
+	f, err := os.Open(filename)
+	if err != nil {
+		res.add(w3g.SeverityError, "Open error: %v", err)
+		return &res
+	}
+	defer f.Close()
+
+	var b = bufio.NewReaderSize(f, 8192)
+	if err := w3g.StripNWG(b); err != nil {
+		res.add(w3g.SeverityError, "Cannot find header: %v", err)
+		return &res
+	}
+
+	rep, err := w3g.Decode(b)
+	switch {
+	case errors.Is(err, w3g.ErrTruncated):
+		res.add(w3g.SeverityError, "%v", err)
+	case errors.Is(err, w3g.ErrInvalidChecksum):
+		res.add(w3g.SeverityError, "Checksum error: %v", err)
+	case err != nil:
+		res.add(w3g.SeverityError, "Decode error: %v", err)
+	}
+	if rep == nil || errors.Is(err, w3g.ErrTruncated) {
+		return &res
+	}
+
+	for _, v := range rep.Validate() {
+		res.add(v.Severity, "%s", v.Description)
+	}
+
+	return &res
+}
+
+func (v *validationReport) print(w io.Writer, jsonFormat bool) error {
+	if jsonFormat {
+		return json.NewEncoder(w).Encode(v)
+	}
+
+	for _, i := range v.Issues {
+		if _, err := fmt.Fprintf(w, "%s: %s\n", i.Severity, i.Description); err != nil {
+			return err
+		}
+	}
+
+	var status = "OK"
+	if !v.Valid {
+		status = "INVALID"
+	}
+	_, err := fmt.Fprintf(w, "%s (%d errors, %d warnings)\n", status, v.Errors, v.Warnings)
+	return err
+}
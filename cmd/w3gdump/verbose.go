@@ -0,0 +1,176 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// verbosePrinter writes values field by field, one line per field with nested structures indented
+type verbosePrinter struct {
+	w     io.Writer
+	depth int // Maximum nesting depth, 0 for no limit
+}
+
+func (p *verbosePrinter) print(v interface{}) {
+	var val = reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr && !val.IsNil() {
+		val = val.Elem()
+	}
+	fmt.Fprintln(p.w, val.Type().String())
+	p.fields(val, 1)
+}
+
+func (p *verbosePrinter) line(indent int, label string, format string, a ...interface{}) {
+	fmt.Fprintf(p.w, "%s%s: %s\n", strings.Repeat("  ", indent), label, fmt.Sprintf(format, a...))
+}
+
+// stringer returns the String() representation of v, if implemented
+func stringer(v reflect.Value) (string, bool) {
+	if !v.CanInterface() {
+		return "", false
+	}
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String(), true
+	}
+	if v.CanAddr() {
+		if s, ok := v.Addr().Interface().(fmt.Stringer); ok {
+			return s.String(), true
+		}
+	}
+	return "", false
+}
+
+// fields prints the content of v (a struct, slice, array or map) at indentation level indent
+func (p *verbosePrinter) fields(v reflect.Value, indent int) {
+	switch v.Kind() {
+	case reflect.Struct:
+		var t = v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			var f = t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			var label = f.Name
+			if f.Anonymous {
+				label = f.Type.String()
+			}
+			p.value(v.Field(i), indent, label)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			p.value(v.Index(i), indent, fmt.Sprintf("[%d]", i))
+		}
+	case reflect.Map:
+		var keys = v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, k := range keys {
+			p.value(v.MapIndex(k), indent, fmt.Sprint(k.Interface()))
+		}
+	}
+}
+
+// value prints a single (labeled) value, nested values are printed on the following lines
+func (p *verbosePrinter) value(v reflect.Value, indent int, label string) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			p.line(indent, label, "nil")
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s, ok := stringer(v); ok {
+			p.line(indent, label, "%s (%d)", s, v.Int())
+		} else {
+			p.line(indent, label, "%d", v.Int())
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if s, ok := stringer(v); ok {
+			p.line(indent, label, "%s (0x%02X)", s, v.Uint())
+		} else {
+			p.line(indent, label, "%d", v.Uint())
+		}
+	case reflect.String:
+		p.line(indent, label, "%q", v.String())
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			p.blob(v, indent, label)
+			return
+		}
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			p.line(indent, label, "nil")
+			return
+		}
+		if p.depth > 0 && indent >= p.depth {
+			p.line(indent, label, "%+v", v.Interface())
+			return
+		}
+		p.line(indent, label, "[%d]", v.Len())
+		p.fields(v, indent+1)
+	case reflect.Map:
+		if p.depth > 0 && indent >= p.depth {
+			p.line(indent, label, "%+v", v.Interface())
+			return
+		}
+		p.line(indent, label, "[%d]", v.Len())
+		p.fields(v, indent+1)
+	case reflect.Struct:
+		var s, str = stringer(v)
+		if p.depth > 0 && indent >= p.depth {
+			if !str {
+				s = fmt.Sprintf("%+v", v.Interface())
+			}
+			p.line(indent, label, "%s", s)
+			return
+		}
+		switch {
+		case str:
+			p.line(indent, label, "%s", s)
+		case label == v.Type().String():
+			// Embedded struct
+			fmt.Fprintf(p.w, "%s%s\n", strings.Repeat("  ", indent), label)
+		default:
+			p.line(indent, label, "%s", v.Type().String())
+		}
+		p.fields(v, indent+1)
+	default:
+		if !v.CanInterface() {
+			return
+		}
+		p.line(indent, label, "%v", v.Interface())
+	}
+}
+
+// blob prints binary data as hex dump with ASCII column
+func (p *verbosePrinter) blob(v reflect.Value, indent int, label string) {
+	var b = make([]byte, v.Len())
+	reflect.Copy(reflect.ValueOf(b), v)
+
+	if len(b) > 0 && len(b) <= 8 {
+		p.line(indent, label, "%X", b)
+		return
+	}
+
+	p.line(indent, label, "%d bytes", len(b))
+	if len(b) == 0 || (p.depth > 0 && indent >= p.depth) {
+		return
+	}
+
+	var pre = strings.Repeat("  ", indent+1)
+	for _, l := range strings.SplitAfter(strings.TrimSuffix(hex.Dump(b), "\n"), "\n") {
+		fmt.Fprint(p.w, pre+l)
+	}
+	fmt.Fprintln(p.w)
+}
@@ -28,7 +28,7 @@ import (
 var (
 	findlan  = flag.Bool("lan", false, "Find a game on LAN")
 	gametft  = flag.Bool("tft", true, "Search for TFT or ROC games (only used when searching local)")
-	gamevers = flag.Uint("v", uint(w3gs.CurrentGameVersion), "Game version (only used when searching local)")
+	gamevers = flag.String("v", (&w3gs.GameVersion{Product: w3gs.ProductTFT, Version: w3gs.CurrentGameVersion}).String(), "Game version (i.e. \"TFT 1.26\" or \"1.32\")")
 	entrykey = flag.Uint("e", 0, "Entry key (only used when entering local game)")
 
 	hostcounter = flag.Uint("c", 1, "Host counter")
@@ -48,6 +48,14 @@ func main() {
 	logOut.SetPrefix(fmt.Sprintf("[%v] ", *playername))
 	logErr.SetPrefix(fmt.Sprintf("[%v] ", *playername))
 
+	gv, err := w3gs.ParseGameVersion(*gamevers)
+	if err != nil {
+		logErr.Fatal("Invalid game version: ", err)
+	}
+	if !*gametft {
+		gv.Product = w3gs.ProductROC
+	}
+
 	var addr string
 	var hc = uint32(*hostcounter)
 	var ek = uint32(*entrykey)
@@ -56,13 +64,7 @@ func main() {
 		// Search local game for 75 seconds
 		var ctx, cancel = context.WithTimeout(context.Background(), 75*time.Second)
 
-		var p = w3gs.ProductTFT
-		if !*gametft {
-			p = w3gs.ProductROC
-		}
-
-		var err error
-		addr, hc, ek, err = lan.FindGame(ctx, w3gs.GameVersion{Product: p, Version: uint32(*gamevers)})
+		addr, hc, ek, err = lan.FindGame(ctx, gv)
 		cancel()
 
 		if err != nil {
@@ -76,7 +78,7 @@ func main() {
 	}
 
 	logOut.Println(color.MagentaString("Joining lobby at %s (ID: %d, key: %d)", addr, hc, ek))
-	d, err := dummy.Join(addr, *playername, hc, ek, *listen, w3gs.Encoding{GameVersion: uint32(*gamevers)})
+	d, err := dummy.Join(addr, *playername, hc, ek, *listen, w3gs.Encoding{GameVersion: gv.Version})
 	if err != nil {
 		logErr.Fatal("Join error: ", err)
 	}
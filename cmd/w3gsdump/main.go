@@ -8,6 +8,7 @@ package main
 import (
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -62,7 +63,7 @@ func dumpPackets(layer string, netFlow, transFlow gopacket.Flow, r io.Reader) er
 				logErr.Printf("Payload:\n%v", hex.Dump(raw))
 			}
 
-			if err == w3gs.ErrInvalidPacketSize || err == w3gs.ErrInvalidChecksum || err == w3gs.ErrUnexpectedConst {
+			if errors.Is(err, w3gs.ErrInvalidPacketSize) || errors.Is(err, w3gs.ErrInvalidChecksum) || errors.Is(err, w3gs.ErrUnexpectedConst) {
 				continue
 			} else {
 				return err
@@ -95,7 +96,10 @@ func dumpPackets(layer string, netFlow, transFlow gopacket.Flow, r io.Reader) er
 			p.Data = p.Data[:*bloblen]
 		}
 
-		var str = fmt.Sprintf("%+v", pkt)[1:]
+		var str = fmt.Sprintf("%+v", pkt)
+		if _, ok := pkt.(fmt.Stringer); !ok {
+			str = str[1:]
+		}
 		if *jsonout {
 			if json, err := json.Marshal(pkt); err == nil {
 				str = string(json)
@@ -0,0 +1,80 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+// w3gslan is a tool that finds and lists games hosted in the Local Area Network.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network/lan"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+var (
+	gametft  = flag.Bool("tft", true, "Search for TFT or ROC games")
+	gamevers = flag.String("v", (&w3gs.GameVersion{Product: w3gs.ProductTFT, Version: w3gs.CurrentGameVersion}).String(), "Game version (i.e. \"TFT 1.26\" or \"1.32\")")
+	timeout  = flag.Duration("t", 3*time.Second, "Time to wait for responses")
+	jsonout  = flag.Bool("json", false, "Print machine readable format")
+)
+
+var logOut = log.New(os.Stdout, "", 0)
+var logErr = log.New(os.Stderr, "", 0)
+
+func main() {
+	flag.Parse()
+
+	gv, err := w3gs.ParseGameVersion(*gamevers)
+	if err != nil {
+		logErr.Fatal("Invalid game version: ", err)
+	}
+	if !*gametft {
+		gv.Product = w3gs.ProductROC
+	}
+
+	games, err := lan.SearchGames(gv, *timeout)
+	if err != nil {
+		logErr.Fatal("Search error: ", err)
+	}
+
+	var addrs = make([]string, 0, len(games))
+	for a := range games {
+		addrs = append(addrs, a)
+	}
+	sort.Strings(addrs)
+
+	if *jsonout {
+		var res = make([]interface{}, 0, len(games))
+		for _, a := range addrs {
+			res = append(res, struct {
+				Address string
+				w3gs.GameInfo
+			}{a, games[a]})
+		}
+		if err := json.NewEncoder(os.Stdout).Encode(res); err != nil {
+			logErr.Fatal("JSON error: ", err)
+		}
+		return
+	}
+
+	if len(games) == 0 {
+		logOut.Println("No games found")
+		return
+	}
+
+	var w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Address\tName\tMap\tSlots\tHost counter\tEntry key\tUptime")
+	for _, a := range addrs {
+		var g = games[a]
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d/%d\t%d\t%d\t%v\n", a, g.GameName, g.GameSettings.MapPath, g.SlotsUsed, g.SlotsTotal, g.HostCounter, g.EntryKey, time.Duration(g.UptimeSec)*time.Second)
+	}
+	w.Flush()
+}
@@ -0,0 +1,136 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g
+
+import (
+	"bytes"
+	"compress/flate"
+	"hash/crc32"
+	"io"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// BlockInfo describes a compressed data block as stored in a replay file
+type BlockInfo struct {
+	Index  int   // Index of block in file
+	Offset int64 // Offset of block header from start of file
+
+	Data         []byte // Compressed data (only valid during callback)
+	Decompressed []byte // Decompressed data, up to the point of failure (only valid during callback)
+
+	SizeStored uint32 // Decompressed size stored in block header
+	SizeActual int    // Number of bytes that were decompressed (up to SizeStored)
+
+	CRCHeader   uint16 // Header checksum stored in block header
+	CRCData     uint16 // Data checksum stored in block header
+	HeaderValid bool   // Header checksum matches
+	DataValid   bool   // Data checksum matches
+
+	Err error // Decompression error (nil if the block decompressed cleanly)
+}
+
+// OK returns true if checksums are valid and the block decompressed to the stored size without error
+func (b *BlockInfo) OK() bool {
+	return b.HeaderValid && b.DataValid && b.Err == nil && uint32(b.SizeActual) == b.SizeStored
+}
+
+// EachBlock calls fn for each compressed data block in the replay file read from r, without decoding records.
+//
+// Damaged blocks are reported through BlockInfo instead of aborting iteration, so that tools can find
+// the exact location where a file went bad. Iteration stops at the number of blocks stated in the header,
+// or when the file ends unexpectedly (fn is called for the incomplete block, io.ErrUnexpectedEOF is returned).
+func EachBlock(r io.Reader, fn func(BlockInfo) error) error {
+	hdr, d, n, err := DecodeHeader(r, nil)
+	if err != nil {
+		return err
+	}
+
+	var off = int64(n)
+	var head [12]byte
+	var lenHead = len(head)
+	if hdr.GameVersion.Version > 0 && hdr.GameVersion.Version < 10032 {
+		lenHead -= 4
+	}
+
+	var data bytes.Buffer
+	var dec bytes.Buffer
+	var z io.ReadCloser
+
+	for i := 0; i < int(d.NumBlocks); i++ {
+		var info = BlockInfo{Index: i, Offset: off}
+
+		nn, err := io.ReadFull(r, head[:lenHead])
+		off += int64(nn)
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		} else if err != nil {
+			return err
+		}
+
+		var pbuf = protocol.Buffer{Bytes: head[:lenHead]}
+		var lenDeflate uint32
+		if lenHead == 12 {
+			lenDeflate = pbuf.ReadUInt32()
+			info.SizeStored = pbuf.ReadUInt32()
+		} else {
+			lenDeflate = uint32(pbuf.ReadUInt16())
+			info.SizeStored = uint32(pbuf.ReadUInt16())
+		}
+		info.CRCHeader = pbuf.ReadUInt16()
+		info.CRCData = pbuf.ReadUInt16()
+
+		head[lenHead-4], head[lenHead-3], head[lenHead-2], head[lenHead-1] = 0, 0, 0, 0
+		var crc = crc32.ChecksumIEEE(head[:lenHead])
+		info.HeaderValid = info.CRCHeader == uint16(crc^crc>>16)
+
+		data.Reset()
+		cn, err := io.CopyN(&data, r, int64(lenDeflate))
+		off += cn
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		} else if err != nil {
+			return err
+		}
+
+		info.Data = data.Bytes()
+		crc = crc32.ChecksumIEEE(info.Data)
+		info.DataValid = info.CRCData == uint16(crc^crc>>16)
+
+		dec.Reset()
+		var br = bytes.NewReader(info.Data)
+		var zerr = readZlibHeader(br)
+		if zerr == nil {
+			if z == nil {
+				z = flate.NewReader(br)
+			} else {
+				zerr = z.(flate.Resetter).Reset(br, nil)
+			}
+		}
+		if zerr == nil {
+			// Blocks are flushed, but the zlib stream is not terminated
+			_, zerr = io.CopyN(&dec, z, int64(info.SizeStored))
+			if zerr == io.EOF {
+				zerr = io.ErrUnexpectedEOF
+			}
+		}
+
+		info.Err = zerr
+		info.Decompressed = dec.Bytes()
+		info.SizeActual = len(info.Decompressed)
+		if err != nil {
+			info.Err = err
+		}
+
+		if ferr := fn(info); ferr != nil {
+			return ferr
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
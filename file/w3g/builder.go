@@ -0,0 +1,158 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g
+
+import (
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// Builder constructs a replay from scratch, i.e. to generate test data.
+//
+// Players are assigned IDs in the order they are added, starting at 1. The first player hosts the game.
+// Fields that are not set explicitly are filled with defaults of a regular multiplayer melee game.
+type Builder struct {
+	rep Replay
+	ids uint8
+}
+
+// NewBuilder for a replay of game version v
+func NewBuilder(v w3gs.GameVersion) *Builder {
+	return &Builder{rep: Replay{
+		Header: Header{
+			GameVersion: v,
+		},
+		GameInfo: GameInfo{
+			GameName: "Replay",
+			GameSettings: w3gs.GameSettings{
+				GameSettingFlags: w3gs.SettingSpeedFast | w3gs.SettingTerrainDefault | w3gs.SettingObsReferees | w3gs.SettingTeamsTogether | w3gs.SettingTeamsFixed,
+				MapWidth:         116,
+				MapHeight:        116,
+				MapPath:          "Maps\\FrozenThrone\\(2)EchoIsles.w3x",
+			},
+			GameFlags: w3gs.GameFlagCreatorUser | w3gs.GameFlagMapTypeMelee | w3gs.GameFlagObsFull,
+		},
+		SlotInfo: SlotInfo{SlotInfo: w3gs.SlotInfo{
+			RandomSeed: 0x12345678,
+			SlotLayout: w3gs.LayoutMelee,
+		}},
+	}}
+}
+
+// GameName sets the name of the game
+func (b *Builder) GameName(name string) *Builder {
+	b.rep.GameName = name
+	return b
+}
+
+// Map sets the path of the map (i.e. "Maps\FrozenThrone\(2)EchoIsles.w3x")
+func (b *Builder) Map(path string) *Builder {
+	b.rep.GameSettings.MapPath = path
+	return b
+}
+
+// Seed sets the random seed of the game
+func (b *Builder) Seed(seed uint32) *Builder {
+	b.rep.SlotInfo.RandomSeed = seed
+	return b
+}
+
+func (b *Builder) slot(s w3gs.SlotData) {
+	s.DownloadStatus = 100
+	s.SlotStatus = w3gs.SlotOccupied
+	s.Color = uint8(len(b.rep.Slots))
+	s.Handicap = 100
+	b.rep.Slots = append(b.rep.Slots, s)
+}
+
+func (b *Builder) player(name string, race w3gs.RacePref, team uint8) {
+	b.ids++
+
+	var p = &PlayerInfo{
+		ID:   b.ids,
+		Name: name,
+	}
+	if b.ids == 1 {
+		b.rep.HostPlayer = *p
+		b.rep.GameSettings.HostName = name
+		p = &b.rep.HostPlayer
+	}
+	b.rep.PlayerInfo = append(b.rep.PlayerInfo, p)
+
+	b.slot(w3gs.SlotData{
+		PlayerID: p.ID,
+		Team:     team,
+		Race:     race,
+	})
+}
+
+// Player adds a human player in team
+func (b *Builder) Player(name string, race w3gs.RacePref, team uint8) *Builder {
+	b.player(name, race|w3gs.RaceSelectable, team)
+	return b
+}
+
+// Observer adds an observer
+func (b *Builder) Observer(name string) *Builder {
+	b.player(name, w3gs.RaceRandom|w3gs.RaceSelectable, b.rep.MaxPlayers())
+	return b
+}
+
+// Computer adds a computer player in team
+func (b *Builder) Computer(race w3gs.RacePref, team uint8, ai w3gs.AI) *Builder {
+	b.slot(w3gs.SlotData{
+		Computer:     true,
+		Team:         team,
+		Race:         race | w3gs.RaceSelectable,
+		ComputerType: ai,
+	})
+	return b
+}
+
+// Chat adds a chat message from sender to all players
+func (b *Builder) Chat(sender uint8, text string) *Builder {
+	b.rep.Records = append(b.rep.Records, &ChatMessage{Message: *w3gs.NewChatToAll(sender, text)})
+	return b
+}
+
+// TimeSlot adds a time slot that advances the game by ms milliseconds and executes actions
+func (b *Builder) TimeSlot(ms uint16, actions ...w3gs.PlayerAction) *Builder {
+	b.rep.Records = append(b.rep.Records, &TimeSlot{TimeSlot: w3gs.TimeSlot{
+		TimeIncrementMS: ms,
+		Actions:         actions,
+	}})
+	return b
+}
+
+// Leave adds the departure of player id
+func (b *Builder) Leave(id uint8, reason w3gs.LeaveReason) *Builder {
+	b.rep.Records = append(b.rep.Records, &PlayerLeft{
+		PlayerID: id,
+		Reason:   reason,
+		Counter:  1,
+	})
+	return b
+}
+
+// Record adds an arbitrary record
+func (b *Builder) Record(rec Record) *Builder {
+	b.rep.Records = append(b.rep.Records, rec)
+	return b
+}
+
+// Build returns the replay, the builder can be used to continue adding records afterwards
+func (b *Builder) Build() *Replay {
+	var res = b.rep.Clone()
+
+	res.NumSlots = uint32(len(res.Slots))
+	res.NumPlayers = 0
+	for i := range res.Slots {
+		if !res.Slots[i].IsObserver(res.MaxPlayers()) {
+			res.NumPlayers++
+		}
+	}
+	res.DurationMS = duration(res.Records)
+
+	return res
+}
@@ -0,0 +1,129 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// BuildKind enum
+type BuildKind uint8
+
+// Build kinds
+const (
+	BuildStructure BuildKind = iota
+	TrainUnit
+	Research
+	BuildOther
+)
+
+func (k BuildKind) String() string {
+	switch k {
+	case BuildStructure:
+		return "Build"
+	case TrainUnit:
+		return "Train"
+	case Research:
+		return "Research"
+	case BuildOther:
+		return "Other"
+	default:
+		return fmt.Sprintf("BuildKind(%d)", uint8(k))
+	}
+}
+
+// BuildEvent is a single build, train or research order (or an order for an unknown object)
+type BuildEvent struct {
+	GameTime time.Duration
+	Kind     BuildKind
+	ItemID   w3gs.ItemID
+}
+
+// Name of the built object (four character code if unknown)
+func (e *BuildEvent) Name() string {
+	return e.ItemID.Name()
+}
+
+func buildEvent(act w3gs.Action) (BuildKind, w3gs.ItemID, bool) {
+	var id w3gs.ItemID
+
+	switch v := act.(type) {
+	case *w3gs.Ability:
+		id = v.ItemID
+	case *w3gs.AbilityTargetPos:
+		id = v.ItemID
+	default:
+		return 0, 0, false
+	}
+
+	if !id.IsObject() {
+		return 0, 0, false
+	}
+
+	switch id.Type() {
+	case w3gs.ObjectBuilding:
+		return BuildStructure, id, true
+	case w3gs.ObjectUnit, w3gs.ObjectHero:
+		return TrainUnit, id, true
+	case w3gs.ObjectUpgrade:
+		return Research, id, true
+	case w3gs.ObjectAbility, w3gs.ObjectItem:
+		// Learn hero skill, buy item
+		return 0, 0, false
+	default:
+		// Not in object table (i.e. custom map object)
+		return BuildOther, id, true
+	}
+}
+
+// playerActions calls f for every action issued by playerID (until f returns false)
+func (r *Replay) playerActions(playerID uint8, f func(t time.Duration, act w3gs.Action) bool) {
+	var enc = r.Encoding().Encoding
+	var ms uint32
+	for _, rec := range r.Records {
+		ts, ok := rec.(*TimeSlot)
+		if !ok {
+			continue
+		}
+		ms += uint32(ts.TimeIncrementMS)
+
+		for i := range ts.Actions {
+			if ts.Actions[i].PlayerID != playerID {
+				continue
+			}
+
+			// Keep parsed actions on error
+			acts, _ := ts.Actions[i].Actions(enc)
+			for _, a := range acts {
+				if !f(time.Duration(ms)*time.Millisecond, a) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// BuildOrder returns the first limit (all if limit <= 0) build, train and research orders issued by playerID.
+// Queued orders that were cancelled are included. Objects with an unknown four character code
+// (i.e. custom map objects) are reported as BuildOther.
+func (r *Replay) BuildOrder(playerID uint8, limit int) []BuildEvent {
+	var res []BuildEvent
+	r.playerActions(playerID, func(t time.Duration, act w3gs.Action) bool {
+		kind, id, ok := buildEvent(act)
+		if !ok {
+			return true
+		}
+		res = append(res, BuildEvent{
+			GameTime: t,
+			Kind:     kind,
+			ItemID:   id,
+		})
+		return limit <= 0 || len(res) < limit
+	})
+	return res
+}
@@ -0,0 +1,98 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g
+
+import (
+	"reflect"
+)
+
+// Clone returns a deep copy of r that can be modified without affecting r (and vice versa)
+//
+// All exported fields are copied, including the byte slices of every record. Unexported
+// fields of custom record types (see MapFactory.Register) are copied shallowly.
+func (r *Replay) Clone() *Replay {
+	var res = deepCopy(reflect.ValueOf(r)).Interface().(*Replay)
+
+	// Decode stores the host in both GameInfo and PlayerInfo
+	for i, p := range r.PlayerInfo {
+		if p == &r.GameInfo.HostPlayer {
+			res.PlayerInfo[i] = &res.GameInfo.HostPlayer
+		}
+	}
+
+	return res
+}
+
+// CloneRecord returns a deep copy of rec (see Replay.Clone)
+func CloneRecord(rec Record) Record {
+	if rec == nil {
+		return nil
+	}
+	return deepCopy(reflect.ValueOf(rec)).Interface().(Record)
+}
+
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		var res = reflect.New(v.Type().Elem())
+		res.Elem().Set(deepCopy(v.Elem()))
+		return res
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		var res = reflect.New(v.Type()).Elem()
+		res.Set(deepCopy(v.Elem()))
+		return res
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		var res = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		if plain(v.Type().Elem().Kind()) {
+			reflect.Copy(res, v)
+			return res
+		}
+		for i := 0; i < v.Len(); i++ {
+			res.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return res
+	case reflect.Array:
+		var res = reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			res.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return res
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		var res = reflect.MakeMapWithSize(v.Type(), v.Len())
+		var it = v.MapRange()
+		for it.Next() {
+			res.SetMapIndex(deepCopy(it.Key()), deepCopy(it.Value()))
+		}
+		return res
+	case reflect.Struct:
+		var res = reflect.New(v.Type()).Elem()
+		res.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if f := res.Field(i); f.CanSet() {
+				f.Set(deepCopy(v.Field(i)))
+			}
+		}
+		return res
+	default:
+		return v
+	}
+}
+
+// plain returns true for kinds that do not reference other memory
+func plain(k reflect.Kind) bool {
+	return k >= reflect.Bool && k <= reflect.Complex128 || k == reflect.String
+}
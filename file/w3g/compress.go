@@ -14,7 +14,11 @@ import (
 	"github.com/nielsAD/gowarcraft3/protocol"
 )
 
-const defaultBufSize = 8192
+// Block sizes
+const (
+	DefaultBlockSize = 8192                // As written by Warcraft III
+	MaxBlockSize     = math.MaxUint16 - 64 // Largest size supported by all block header versions (including compression overhead)
+)
 
 // BlockCompressor is an io.Writer that compresses data blocks
 type BlockCompressor struct {
@@ -27,16 +31,28 @@ type BlockCompressor struct {
 	w io.Writer
 	b protocol.Buffer
 	z *zlib.Writer
+
+	keep bool   // Keep a copy of the last uncompressed block
+	last []byte // Last uncompressed block (if keep)
 }
 
 // NewBlockCompressor for compressed w3g data
 func NewBlockCompressor(w io.Writer, e Encoding) *BlockCompressor {
-	z, _ := zlib.NewWriterLevelDict(nil, zlib.BestCompression, nil)
+	c, _ := NewBlockCompressorLevel(w, e, zlib.BestCompression)
+	return c
+}
+
+// NewBlockCompressorLevel for compressed w3g data with specified zlib compression level
+func NewBlockCompressorLevel(w io.Writer, e Encoding, level int) (*BlockCompressor, error) {
+	z, err := zlib.NewWriterLevelDict(nil, level, nil)
+	if err != nil {
+		return nil, ErrInvalidOption
+	}
 	return &BlockCompressor{
 		Encoding: e,
 		w:        w,
 		z:        z,
-	}
+	}, nil
 }
 
 // Write implements the io.Writer interface.
@@ -52,8 +68,8 @@ func (d *BlockCompressor) Write(b []byte) (int, error) {
 			d.b.WriteUInt32(0)
 			d.b.WriteUInt32(uint32(lenBuf))
 		} else {
-			if lenBuf > math.MaxUint16 {
-				lenBuf = math.MaxUint16
+			if lenBuf > MaxBlockSize {
+				lenBuf = MaxBlockSize
 			}
 			lenHdr = 8
 			d.b.WriteUInt16(0)
@@ -62,16 +78,23 @@ func (d *BlockCompressor) Write(b []byte) (int, error) {
 
 		d.b.WriteUInt32(0)
 
+		if d.keep {
+			d.last = append(d.last[:0], b[:lenBuf]...)
+		}
+
 		d.z.Reset(&d.b)
 		zn, err := d.z.Write(b[:lenBuf])
-		n += zn
-
 		if err != nil {
 			return n, err
 		}
 		if err := d.z.Flush(); err != nil {
 			return n, err
 		}
+		if lenHdr == 8 && d.b.Size()-lenHdr > math.MaxUint16 {
+			return n, ErrBlockSize
+		}
+
+		n += zn
 
 		// Update header
 		if d.GameVersion == 0 || d.GameVersion >= 10032 {
@@ -110,7 +133,10 @@ type Compressor struct {
 
 // NewCompressorSize for compressed w3g with specified buffer size
 func NewCompressorSize(w io.Writer, e Encoding, size int) *Compressor {
-	var c = NewBlockCompressor(w, e)
+	return newCompressor(NewBlockCompressor(w, e), e, size)
+}
+
+func newCompressor(c *BlockCompressor, e Encoding, size int) *Compressor {
 	var b = bufio.NewWriterSize(c, size)
 
 	return &Compressor{
@@ -124,7 +150,44 @@ func NewCompressorSize(w io.Writer, e Encoding, size int) *Compressor {
 
 // NewCompressor for compressed w3g with default buffer size
 func NewCompressor(w io.Writer, e Encoding) *Compressor {
-	return NewCompressorSize(w, e, defaultBufSize)
+	return NewCompressorSize(w, e, DefaultBlockSize)
+}
+
+// EncoderOptions control compression of w3g data blocks.
+// The zero value uses the same defaults as NewCompressor.
+type EncoderOptions struct {
+	CompressionLevel int  // zlib compression level (see compress/zlib), 0 for zlib.BestCompression
+	Uncompressed     bool // Store data without compression (ignores CompressionLevel)
+	BlockSize        int  // Uncompressed size of data blocks, 0 for DefaultBlockSize
+}
+
+// NewCompressorOptions for compressed w3g with specified options, uses the defaults of NewCompressor if o is nil
+func NewCompressorOptions(w io.Writer, e Encoding, o *EncoderOptions) (*Compressor, error) {
+	if o == nil {
+		return NewCompressor(w, e), nil
+	}
+
+	var size = o.BlockSize
+	if size == 0 {
+		size = DefaultBlockSize
+	}
+	if size < 0 || size > MaxBlockSize {
+		return nil, ErrInvalidOption
+	}
+
+	var level = o.CompressionLevel
+	if o.Uncompressed {
+		level = zlib.NoCompression
+	} else if level == 0 {
+		level = zlib.BestCompression
+	}
+
+	c, err := NewBlockCompressorLevel(w, e, level)
+	if err != nil {
+		return nil, err
+	}
+
+	return newCompressor(c, e, size), nil
 }
 
 // Write implements the io.Writer interface.
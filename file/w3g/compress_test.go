@@ -6,6 +6,7 @@ package w3g_test
 
 import (
 	"bytes"
+	"compress/zlib"
 	"io"
 	"math"
 	"reflect"
@@ -154,3 +155,65 @@ func BenchmarkDecompress(b *testing.B) {
 		d.Read(ref[:])
 	}
 }
+
+func TestEncoderOptions(t *testing.T) {
+	replay, err := w3g.Open("./test_130.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var opts = []*w3g.EncoderOptions{
+		nil,
+		&w3g.EncoderOptions{Uncompressed: true},
+		&w3g.EncoderOptions{CompressionLevel: zlib.HuffmanOnly},
+		&w3g.EncoderOptions{CompressionLevel: zlib.BestSpeed, BlockSize: 1024},
+		&w3g.EncoderOptions{CompressionLevel: zlib.DefaultCompression, BlockSize: w3g.MaxBlockSize},
+		&w3g.EncoderOptions{},
+		&w3g.EncoderOptions{Uncompressed: true, BlockSize: w3g.MaxBlockSize},
+	}
+
+	var sizes []int
+	for _, o := range opts {
+		var b bytes.Buffer
+		e, err := w3g.NewEncoderOptions(&b, replay.Encoding(), o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.Header = replay.Header
+		if err := replay.ForEach(func(r w3g.Record) error {
+			_, err := e.WriteRecord(r)
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := e.Close(); err != nil {
+			t.Fatal(err)
+		}
+		sizes = append(sizes, b.Len())
+
+		replay2, err := w3g.Decode(&b)
+		if err != nil {
+			t.Fatal(o, err)
+		}
+		if !reflect.DeepEqual(replay.Records, replay2.Records) || !reflect.DeepEqual(replay.SlotInfo, replay2.SlotInfo) {
+			t.Fatalf("Round trip mismatch for options %+v", o)
+		}
+	}
+
+	if sizes[0] != sizes[5] {
+		t.Fatalf("Expected zero value options to match defaults (%d != %d)", sizes[0], sizes[5])
+	}
+	if sizes[1] <= sizes[5] {
+		t.Fatalf("Expected stored replay to be larger than compressed replay (%d <= %d)", sizes[1], sizes[5])
+	}
+
+	for _, o := range []*w3g.EncoderOptions{
+		&w3g.EncoderOptions{CompressionLevel: 10},
+		&w3g.EncoderOptions{BlockSize: -1},
+		&w3g.EncoderOptions{BlockSize: w3g.MaxBlockSize + 1},
+	} {
+		if _, err := w3g.NewEncoderOptions(&bytes.Buffer{}, replay.Encoding(), o); err != w3g.ErrInvalidOption {
+			t.Fatalf("Expected ErrInvalidOption for %+v, got %v", o, err)
+		}
+	}
+}
@@ -14,6 +14,12 @@ var (
 	ErrInvalidChecksum = errors.New("w3g: Checksum invalid")
 	ErrUnexpectedConst = errors.New("w3g: Unexpected constant value")
 	ErrUnknownRecord   = errors.New("w3g: Unknown record ID")
+	ErrInvalidOption   = errors.New("w3g: Invalid encoder option")
+	ErrBlockSize       = errors.New("w3g: Compressed block too large for header")
+	ErrTruncated       = errors.New("w3g: Unexpected end of replay data")
+	ErrInvalidSequence = errors.New("w3g: Invalid record sequence")
+	ErrReplayMismatch  = errors.New("w3g: Replays are not of the same game")
+	ErrCompression     = errors.New("w3g: Unsupported compression method")
 )
 
 // Signature constant for w3g files
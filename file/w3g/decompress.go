@@ -6,11 +6,15 @@ package w3g
 
 import (
 	"bufio"
-	"compress/zlib"
+	"compress/flate"
+	"errors"
+	"fmt"
 	"hash"
 	"hash/crc32"
 	"io"
 	"io/ioutil"
+	"reflect"
+	"time"
 
 	"github.com/nielsAD/gowarcraft3/protocol"
 )
@@ -24,6 +28,20 @@ type Decompressor struct {
 	SizeBlock uint32 // Decompressed size left to read current block
 	NumBlocks uint32 // Blocks left to read
 
+	// Progress is called after every data block with the number of compressed bytes read so far,
+	// and the compressed size in total (-1 if unknown, i.e. when not created by DecodeHeader).
+	Progress func(bytesRead, bytesTotal int64)
+
+	// Limit makes ForEach stop early (without error) after a number of records or amount of game time
+	Limit DecodeLimit
+
+	sizeFile   int64
+	sizeData   uint32
+	numBlocks  uint32
+	numRecords int
+	gameTime   time.Duration
+	stats      ReplayStats
+
 	r   io.Reader
 	z   io.ReadCloser
 	tee io.Reader
@@ -31,6 +49,7 @@ type Decompressor struct {
 
 	crc     hash.Hash32
 	crcData uint16
+	err     error
 	buf     [12]byte
 	bufr    *bufio.Reader
 }
@@ -48,6 +67,9 @@ func NewDecompressor(r io.Reader, e Encoding, f RecordFactory, numBlocks uint32,
 		},
 		SizeTotal: sizeTotal,
 		NumBlocks: numBlocks,
+		sizeFile:  -1,
+		sizeData:  sizeTotal,
+		numBlocks: numBlocks,
 		r:         r,
 		tee:       tee,
 		lim:       &lim,
@@ -55,7 +77,7 @@ func NewDecompressor(r io.Reader, e Encoding, f RecordFactory, numBlocks uint32,
 	}
 }
 
-// For some reason, zlib wants a flate.Reader (io.Reader + io.ByteReader), otherwise
+// For some reason, flate wants a flate.Reader (io.Reader + io.ByteReader), otherwise
 // it implicitly uses a bufio.Reader. Use our own straightforward implementation to
 // reduce allocations and prevent reading more than necessary.
 type toByteReader struct {
@@ -113,16 +135,42 @@ func (d *Decompressor) nextBlock() error {
 	d.lim.N = int64(lenDeflate)
 	d.crc.Reset()
 
-	if d.z == nil {
-		d.z, err = zlib.NewReader(d.tee)
-	} else {
-		err = d.z.(zlib.Resetter).Reset(d.tee, nil)
-	}
+	err = readZlibHeader(d.tee)
 
 	// Account for zlib header
 	d.SizeRead += lenDeflate - uint32(d.lim.N)
 
-	return err
+	if err != nil {
+		return err
+	}
+
+	if d.z == nil {
+		d.z = flate.NewReader(d.tee)
+		return nil
+	}
+	return d.z.(flate.Resetter).Reset(d.tee, nil)
+}
+
+// readZlibHeader consumes the zlib header of a data block.
+//
+// Every block holds a zlib stream (deflate, no preset dictionary) that is flushed, but not
+// terminated. The header is checked here so that unexpected framing results in ErrCompression
+// instead of a generic inflate error. The deflate data that follows is inflated directly.
+func readZlibHeader(r io.Reader) error {
+	var h [2]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	// Compression method 8 (deflate), window size <= 32K, valid check bits, no dictionary
+	if h[0]&0x0F != 8 || h[0]>>4 > 7 || (uint16(h[0])<<8|uint16(h[1]))%31 != 0 || h[1]&0x20 != 0 {
+		return ErrCompression
+	}
+
+	return nil
 }
 
 func (d *Decompressor) closeBlock() error {
@@ -139,7 +187,17 @@ func (d *Decompressor) closeBlock() error {
 }
 
 // Read implements the io.Reader interface.
+// Errors are sticky, the stream cannot be resumed after a damaged block.
 func (d *Decompressor) Read(b []byte) (int, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+	n, err := d.read(b)
+	d.err = err
+	return n, err
+}
+
+func (d *Decompressor) read(b []byte) (int, error) {
 	if d.SizeTotal == 0 {
 		return 0, io.EOF
 	}
@@ -179,6 +237,9 @@ func (d *Decompressor) Read(b []byte) (int, error) {
 			if err := d.closeBlock(); err != nil {
 				return n, err
 			}
+			if d.Progress != nil {
+				d.Progress(int64(d.SizeRead), d.sizeFile)
+			}
 		default:
 			return n, err
 		}
@@ -187,23 +248,128 @@ func (d *Decompressor) Read(b []byte) (int, error) {
 	return n, nil
 }
 
+// TruncatedError is returned when replay data ends unexpectedly (i.e. for replays of crashed
+// games or incomplete downloads). All records before the truncation point were decoded successfully.
+type TruncatedError struct {
+	Records int // Number of records decoded before the truncation point
+	Err     error
+}
+
+func (e *TruncatedError) Error() string {
+	return fmt.Sprintf("%v after %d records (%v)", ErrTruncated, e.Records, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *TruncatedError) Unwrap() error {
+	return e.Err
+}
+
+// Is implements errors.Is, matches ErrTruncated
+func (e *TruncatedError) Is(target error) bool {
+	return target == ErrTruncated
+}
+
+// ReplayStats holds aggregate statistics of replay data
+type ReplayStats struct {
+	Records     map[string]int // Number of decoded records by type name
+	ActionBytes int            // Total size of player action data in decoded records
+
+	NumBlocks        uint32 // Number of data blocks
+	SizeCompressed   uint32 // Size of data blocks in file (including block headers)
+	SizeDecompressed uint32 // Size of data after decompression (excluding padding)
+}
+
+func (s *ReplayStats) add(rec Record) {
+	if s.Records == nil {
+		s.Records = make(map[string]int)
+	}
+
+	var t = reflect.TypeOf(rec)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	s.Records[t.Name()]++
+
+	if ts, ok := rec.(*TimeSlot); ok {
+		for _, a := range ts.Actions {
+			s.ActionBytes += len(a.Data)
+		}
+	}
+}
+
+// Stats returns statistics of the records passed to ForEach so far. Block count and sizes are
+// taken from the file header (if available), so they cover all data even if decoding stopped early.
+func (d *Decompressor) Stats() ReplayStats {
+	var res = d.stats
+	res.Records = make(map[string]int, len(d.stats.Records))
+	for k, v := range d.stats.Records {
+		res.Records[k] = v
+	}
+
+	res.NumBlocks = d.numBlocks
+	res.SizeDecompressed = d.sizeData
+	res.SizeCompressed = d.SizeRead
+	if d.sizeFile >= 0 {
+		res.SizeCompressed = uint32(d.sizeFile)
+	}
+	return res
+}
+
+// DecodeLimit for Decompressor.ForEach, zero values mean no limit
+type DecodeLimit struct {
+	Records int           // Stop after this many records were passed to the callback
+	Time    time.Duration // Stop after the time slot that reaches this game time
+}
+
+// Limited returns true if decoding stopped because Limit was reached
+func (d *Decompressor) Limited() bool {
+	return (d.Limit.Records > 0 && d.numRecords >= d.Limit.Records) ||
+		(d.Limit.Time > 0 && d.gameTime >= d.Limit.Time)
+}
+
 // ForEach record call f
+// Returns a *TruncatedError if data ends unexpectedly, after calling f for each decoded record
+//
+// ForEach returns nil once Limit is reached. Remaining records are left undecoded, so that
+// ForEach can be called again to continue decoding after raising Limit.
 func (d *Decompressor) ForEach(f func(r Record) error) error {
 	if d.bufr == nil {
 		d.bufr = bufio.NewReaderSize(d, 8192)
 	}
 
-	for {
+	// Time slots are needed to keep track of game time, but are not passed to f if filtered
+	var hideTime = d.Limit.Time > 0 && d.DecodeOnly != nil && !d.decode(RidTimeSlot)
+	if hideTime {
+		var only = d.DecodeOnly
+		d.DecodeOnly = append(append([]uint8(nil), only...), RidTimeSlot, RidTimeSlot2)
+		defer func() { d.DecodeOnly = only }()
+	}
+
+	var n = 0
+	for !d.Limited() {
 		rec, _, err := d.RecordDecoder.Read(d.bufr)
-		switch err {
-		case nil:
+		switch {
+		case err == nil:
+			if ts, ok := rec.(*TimeSlot); ok {
+				d.gameTime += time.Duration(ts.TimeIncrementMS) * time.Millisecond
+				if hideTime {
+					continue
+				}
+			}
 			if err := f(rec); err != nil {
 				return err
 			}
-		case io.EOF:
+			d.stats.add(rec)
+			d.numRecords++
+			n++
+		case err == io.EOF:
 			return nil
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return &TruncatedError{Records: n, Err: err}
 		default:
 			return err
 		}
 	}
+
+	return nil
 }
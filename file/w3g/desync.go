@@ -0,0 +1,116 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g
+
+import (
+	"bytes"
+	"sort"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// DesyncEvent is a Desync record correlated with the game clock
+type DesyncEvent struct {
+	GameTime time.Duration
+	w3gs.Desync
+}
+
+// Desyncs returns all Desync records in the replay
+func (r *Replay) Desyncs() []DesyncEvent {
+	var res []DesyncEvent
+	var ms uint32
+	r.Accept(Visitor{
+		OnTimeSlot: func(v *TimeSlot) {
+			ms += uint32(v.TimeIncrementMS)
+		},
+		OnDesync: func(v *Desync) {
+			res = append(res, DesyncEvent{
+				GameTime: time.Duration(ms) * time.Millisecond,
+				Desync:   v.Desync,
+			})
+		},
+	})
+	return res
+}
+
+// Checksum of the game state after a time slot, as calculated by the recording player
+type Checksum struct {
+	Tick     int // Index of time slot
+	GameTime time.Duration
+	Checksum []byte
+}
+
+// Checksums reads all TimeSlotAck checksums from d (these are not stored in Replay)
+func Checksums(d *Decompressor) ([]Checksum, error) {
+	var res []Checksum
+	var tick = -1
+	var ms uint32
+	var err = d.Accept(Visitor{
+		OnTimeSlot: func(v *TimeSlot) {
+			tick++
+			ms += uint32(v.TimeIncrementMS)
+		},
+		OnTimeSlotAck: func(v *TimeSlotAck) {
+			res = append(res, Checksum{
+				Tick:     tick,
+				GameTime: time.Duration(ms) * time.Millisecond,
+				Checksum: append([]byte(nil), v.Checksum...),
+			})
+		},
+	})
+	return res, err
+}
+
+// ChecksumEvent marks a time slot for which the checksums of the players diverged
+type ChecksumEvent struct {
+	Tick      int
+	GameTime  time.Duration
+	Checksums [][]byte // Checksum per input (nil if missing)
+}
+
+// ChecksumMismatches compares the Checksums() of different replays of the same game (each recorded
+// by a different player) per time slot, and returns the time slots for which they diverged.
+// A replay only contains the checksums of its recording player.
+func ChecksumMismatches(checksums ...[]Checksum) []ChecksumEvent {
+	var ticks = make(map[int]*ChecksumEvent)
+	var order []int
+
+	for i, list := range checksums {
+		for _, c := range list {
+			var ev = ticks[c.Tick]
+			if ev == nil {
+				ev = &ChecksumEvent{
+					Tick:      c.Tick,
+					GameTime:  c.GameTime,
+					Checksums: make([][]byte, len(checksums)),
+				}
+				ticks[c.Tick] = ev
+				order = append(order, c.Tick)
+			}
+			ev.Checksums[i] = c.Checksum
+		}
+	}
+
+	var res []ChecksumEvent
+	for _, t := range order {
+		var ev = ticks[t]
+		var ref []byte
+		for _, c := range ev.Checksums {
+			if c == nil {
+				continue
+			}
+			if ref == nil {
+				ref = c
+			} else if !bytes.Equal(ref, c) {
+				res = append(res, *ev)
+				break
+			}
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Tick < res[j].Tick })
+	return res
+}
@@ -13,6 +13,7 @@ type RecordFactory interface {
 type FactoryFunc func(enc *Encoding) Record
 
 // MapFactory implements RecordFactory using a map
+// It is safe for concurrent use as long as the map is not modified.
 type MapFactory map[uint8]FactoryFunc
 
 // NewRecord implements RecordFactory interface
@@ -24,12 +25,33 @@ func (f MapFactory) NewRecord(rid uint8, enc *Encoding) Record {
 	return fun(enc)
 }
 
+// Register ctor for record ID rid, replacing any previously registered constructor for rid
+// Registering in DefaultFactory affects all users of the package, use CloneDefaultFactory instead.
+func (f MapFactory) Register(rid uint8, ctor FactoryFunc) {
+	f[rid] = ctor
+}
+
+// Clone returns a shallow copy of f that can be modified without affecting f
+func (f MapFactory) Clone() MapFactory {
+	var res = make(MapFactory, len(f))
+	for k, v := range f {
+		res[k] = v
+	}
+	return res
+}
+
+// CloneDefaultFactory returns a copy of DefaultFactory that can be extended with Register
+func CloneDefaultFactory() MapFactory {
+	return DefaultFactory.Clone()
+}
+
 type cacheKey struct {
 	enc Encoding
 	rid uint8
 }
 
 // CacheFactory implements a RecordFactory that will only create a type once
+// It is not safe for concurrent use, so create one per decoder.
 type CacheFactory struct {
 	factory RecordFactory
 	cache   map[cacheKey]Record
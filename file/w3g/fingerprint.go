@@ -0,0 +1,123 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g
+
+import (
+	"crypto/sha256"
+	"sort"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// FingerprintVersion is incremented whenever the fingerprint input changes
+const FingerprintVersion = 1
+
+// Fingerprint returns a content hash of the game, for example to find duplicates in a replay collection.
+// Values are stable across library versions with the same FingerprintVersion.
+//
+// The fingerprint is the SHA-256 of the following little-endian data:
+//
+//    size/type | Description
+//   -----------+-----------------------------------------------------------
+//     string   | "w3g-fingerprint" (zero terminated)
+//     1 dword  | FingerprintVersion
+//     1 dword  | GameVersion.Product
+//     1 dword  | GameVersion.Version
+//     string   | GameName
+//     1 dword  | GameSettings.GameSettingFlags
+//     1 word   | GameSettings.MapWidth
+//     1 word   | GameSettings.MapHeight
+//     1 dword  | GameSettings.MapXoro
+//     string   | GameSettings.MapPath
+//     string   | GameSettings.HostName
+//    20 bytes  | GameSettings.MapSha1
+//     1 dword  | GameFlags
+//     1 byte   | number of players
+//              | for each player (sorted by ID):
+//              |   1 byte  | ID
+//              |   string  | Name
+//     1 byte   | number of slots
+//              | for each slot:
+//              |   1 byte  | PlayerID
+//              |   1 byte  | SlotStatus
+//              |   1 byte  | Computer
+//              |   1 byte  | Team
+//              |   1 byte  | Color
+//              |   1 byte  | Race
+//              |   1 byte  | ComputerType
+//              |   1 byte  | Handicap
+//     1 dword  | RandomSeed
+//     1 byte   | SlotLayout
+//     1 byte   | NumPlayers
+//              | for each TimeSlot (in order):
+//              |   1 word  | TimeIncrementMS
+//              |   1 word  | number of actions
+//              |           | for each action:
+//              |           |   1 byte  | PlayerID
+//              |           |   1 word  | size of data
+//              |           |   n bytes | data
+//
+// Strings are zero terminated. The header (duration, build number), download status,
+// PlayerExtra, chat, leave, checksum (TimeSlotAck, Desync) and timer records are excluded.
+func (r *Replay) Fingerprint() [32]byte {
+	var buf protocol.Buffer
+	buf.WriteCString("w3g-fingerprint")
+	buf.WriteUInt32(FingerprintVersion)
+
+	buf.WriteUInt32(uint32(r.GameVersion.Product))
+	buf.WriteUInt32(r.GameVersion.Version)
+	buf.WriteCString(r.GameName)
+
+	var gs = &r.GameSettings
+	buf.WriteUInt32(uint32(gs.GameSettingFlags))
+	buf.WriteUInt16(gs.MapWidth)
+	buf.WriteUInt16(gs.MapHeight)
+	buf.WriteUInt32(gs.MapXoro)
+	buf.WriteCString(gs.MapPath)
+	buf.WriteCString(gs.HostName)
+	buf.WriteBlob(gs.MapSha1[:])
+	buf.WriteUInt32(uint32(r.GameFlags))
+
+	var players = append([]*PlayerInfo(nil), r.PlayerInfo...)
+	sort.SliceStable(players, func(i, j int) bool { return players[i].ID < players[j].ID })
+
+	buf.WriteUInt8(uint8(len(players)))
+	for _, p := range players {
+		buf.WriteUInt8(p.ID)
+		buf.WriteCString(p.Name)
+	}
+
+	buf.WriteUInt8(uint8(len(r.Slots)))
+	for i := range r.Slots {
+		var s = &r.Slots[i]
+		buf.WriteUInt8(s.PlayerID)
+		buf.WriteUInt8(uint8(s.SlotStatus))
+		buf.WriteBool8(s.Computer)
+		buf.WriteUInt8(s.Team)
+		buf.WriteUInt8(s.Color)
+		buf.WriteUInt8(uint8(s.Race))
+		buf.WriteUInt8(uint8(s.ComputerType))
+		buf.WriteUInt8(s.Handicap)
+	}
+	buf.WriteUInt32(r.RandomSeed)
+	buf.WriteUInt8(uint8(r.SlotLayout))
+	buf.WriteUInt8(r.NumPlayers)
+
+	for _, rec := range r.Records {
+		ts, ok := rec.(*TimeSlot)
+		if !ok {
+			continue
+		}
+		buf.WriteUInt16(ts.TimeIncrementMS)
+		buf.WriteUInt16(uint16(len(ts.Actions)))
+		for _, a := range ts.Actions {
+			buf.WriteUInt8(a.PlayerID)
+			buf.WriteUInt16(uint16(len(a.Data)))
+			buf.WriteBlob(a.Data)
+		}
+	}
+
+	return sha256.Sum256(buf.Bytes)
+}
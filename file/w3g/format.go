@@ -0,0 +1,87 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Format of a replay file
+type Format uint8
+
+// Replay formats
+const (
+	FormatUnknown Format = iota
+	FormatW3G            // Plain w3g file
+	FormatNWG            // w3g file with a (Netease) header prepended
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatUnknown:
+		return "Unknown"
+	case FormatW3G:
+		return "W3G"
+	case FormatNWG:
+		return "NWG"
+	default:
+		return fmt.Sprintf("Format(%d)", uint8(f))
+	}
+}
+
+// DetectFormat peeks into r to determine the replay format, without advancing r.
+// The w3g header must start within the buffer size of r, or FormatUnknown is returned.
+func DetectFormat(r *bufio.Reader) (Format, error) {
+	b, err := r.Peek(r.Size())
+	if len(b) == 0 {
+		return FormatUnknown, err
+	}
+
+	switch bytes.Index(b, []byte(Signature)) {
+	case -1:
+		if err == io.EOF || err == bufio.ErrBufferFull {
+			err = nil
+		}
+		return FormatUnknown, err
+	case 0:
+		return FormatW3G, nil
+	default:
+		return FormatNWG, nil
+	}
+}
+
+// ReadNWGHeader reads the data prepended to the w3g header and advances r to the w3g header.
+// The layout of this data is undocumented, so it is returned verbatim (nil for plain w3g files).
+func ReadNWGHeader(r *bufio.Reader) ([]byte, error) {
+	var p = recordPeeker{Reader: r}
+	if _, err := FindHeader(&p); err != nil {
+		if err == io.EOF {
+			err = ErrBadFormat
+		}
+		return p.res, err
+	}
+	return p.res, nil
+}
+
+// recordPeeker keeps a copy of all discarded data
+type recordPeeker struct {
+	*bufio.Reader
+	res []byte
+}
+
+func (p *recordPeeker) Discard(n int) (int, error) {
+	b, _ := p.Peek(n)
+	p.res = append(p.res, b...)
+	return p.Reader.Discard(n)
+}
+
+// StripNWG advances r past the nwg header (if any), so that r points to the w3g header
+func StripNWG(r *bufio.Reader) error {
+	_, err := ReadNWGHeader(r)
+	return err
+}
@@ -0,0 +1,31 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+//go:build go1.18
+// +build go1.18
+
+package w3g_test
+
+import (
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+)
+
+func FuzzW3GRecord(f *testing.F) {
+	var versions = []uint32{0, 2, 26, 10030}
+	for _, rec := range records {
+		if b, err := w3g.SerializeRecord(rec, w3g.Encoding{}); err == nil {
+			f.Add(b)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		for _, gv := range versions {
+			var enc = w3g.Encoding{}
+			enc.GameVersion = gv
+			w3g.DeserializeRecord(b, enc)
+		}
+	})
+}
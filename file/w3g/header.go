@@ -6,6 +6,7 @@ package w3g
 
 import (
 	"bytes"
+	"fmt"
 	"hash/crc32"
 	"io"
 	"io/ioutil"
@@ -22,6 +23,28 @@ type Header struct {
 	SinglePlayer bool
 }
 
+// ReforgedVersionOffset is added to the patch number in the game version of Reforged replays
+const ReforgedVersionOffset = w3gs.ReforgedVersionOffset
+
+// IsReforged returns true if the replay was recorded with the Reforged client (1.30 and up)
+func (h *Header) IsReforged() bool {
+	return h.GameVersion.Version >= ReforgedVersionOffset
+}
+
+// ReleaseString returns a human readable description of the client release, i.e. "Classic 1.26 build 6059"
+func (h *Header) ReleaseString() string {
+	if h.IsReforged() {
+		return fmt.Sprintf("Reforged 1.%02d build %d", h.GameVersion.Version-ReforgedVersionOffset, h.BuildNumber)
+	}
+	return fmt.Sprintf("Classic 1.%02d build %d", h.GameVersion.Version, h.BuildNumber)
+}
+
+// Product returns the game product the replay was recorded with (w3gs.ProductROC or w3gs.ProductTFT).
+// Headers older than 1.07 do not store the product and always report ROC.
+func (h *Header) Product() protocol.DWordString {
+	return h.GameVersion.Product
+}
+
 // FindHeader in r
 func FindHeader(r Peeker) (int, error) {
 	var n = 0
@@ -48,8 +71,39 @@ func FindHeader(r Peeker) (int, error) {
 
 // DecodeHeader a w3g file, returns header and a Decompressor to read compressed records
 func DecodeHeader(r io.Reader, f RecordFactory) (*Header, *Decompressor, int, error) {
-	var buf [68]byte
+	hdr, s, n, err := decodeHeader(r)
+	if err != nil {
+		return nil, nil, n, err
+	}
+
+	// Skip to start of data section
+	nn, err := io.CopyN(ioutil.Discard, r, int64(s.sizeHeader-uint32(n)))
+	n += int(nn)
+	if err != nil {
+		return nil, nil, n, err
+	}
+
+	var d = NewDecompressor(r, hdr.Encoding(), f, s.numBlocks, s.sizeBlocks)
+	d.sizeFile = int64(s.sizeFile - s.sizeHeader)
+
+	return hdr, d, n, err
+}
+
+// maxHeaderSize is the number of bytes read by decodeHeader
+const maxHeaderSize = 68
+
+type headerSizes struct {
+	sizeHeader uint32
+	sizeFile   uint32
+	sizeBlocks uint32
+	numBlocks  uint32
+}
+
+// decodeHeader reads and validates the fixed size part of the header
+func decodeHeader(r io.Reader) (*Header, *headerSizes, int, error) {
+	var buf [maxHeaderSize]byte
 	var hdr Header
+	var s headerSizes
 
 	n, err := io.ReadFull(r, buf[:64])
 	if err != nil {
@@ -57,20 +111,20 @@ func DecodeHeader(r io.Reader, f RecordFactory) (*Header, *Decompressor, int, er
 	}
 
 	var pbuf = protocol.Buffer{Bytes: buf[:]}
-	if s, err := pbuf.ReadCString(); err != nil {
+	if sig, err := pbuf.ReadCString(); err != nil {
 		return nil, nil, n, err
-	} else if s != Signature {
+	} else if sig != Signature {
 		return nil, nil, n, ErrBadFormat
 	}
 
-	var sizeHeader = pbuf.ReadUInt32()
-	var sizeFile = pbuf.ReadUInt32()
+	s.sizeHeader = pbuf.ReadUInt32()
+	s.sizeFile = pbuf.ReadUInt32()
 
 	var headerVersion = pbuf.ReadUInt32()
 	switch headerVersion {
 	case 0:
 	case 1:
-		nn, err := io.ReadFull(r, buf[64:68])
+		nn, err := io.ReadFull(r, buf[64:maxHeaderSize])
 		n += nn
 		if err != nil {
 			return nil, nil, n, err
@@ -80,8 +134,8 @@ func DecodeHeader(r io.Reader, f RecordFactory) (*Header, *Decompressor, int, er
 		return nil, nil, n, ErrUnexpectedConst
 	}
 
-	var sizeBlocks = pbuf.ReadUInt32()
-	var numBlocks = pbuf.ReadUInt32()
+	s.sizeBlocks = pbuf.ReadUInt32()
+	s.numBlocks = pbuf.ReadUInt32()
 
 	switch headerVersion {
 	case 0:
@@ -104,18 +158,11 @@ func DecodeHeader(r io.Reader, f RecordFactory) (*Header, *Decompressor, int, er
 		return nil, nil, n, ErrInvalidChecksum
 	}
 
-	if uint32(n) > sizeHeader || uint32(n) > sizeFile {
+	if uint32(n) > s.sizeHeader || uint32(n) > s.sizeFile {
 		return nil, nil, n, ErrBadFormat
 	}
 
-	// Skip to start of data section
-	nn, err := io.CopyN(ioutil.Discard, r, int64(sizeHeader-uint32(n)))
-	n += int(nn)
-	if err != nil {
-		return nil, nil, n, err
-	}
-
-	return &hdr, NewDecompressor(r, hdr.Encoding(), f, numBlocks, sizeBlocks), n, err
+	return &hdr, &s, n, nil
 }
 
 // Encoding for (de)serialization
@@ -134,36 +181,200 @@ type Encoder struct {
 
 	b protocol.Buffer
 	w io.Writer
+
+	numRecords int
+	checkpoint *checkpoint
+}
+
+// checkpoint keeps track of the padded block written by Flush()
+type checkpoint struct {
+	data []byte
+	size uint32
 }
 
 // NewEncoder for replay file
 func NewEncoder(w io.Writer, e Encoding) (*Encoder, error) {
+	return NewEncoderOptions(w, e, nil)
+}
+
+// NewEncoderOptions for replay file with specified compression options (defaults if o is nil)
+func NewEncoderOptions(w io.Writer, e Encoding, o *EncoderOptions) (*Encoder, error) {
 	var res = Encoder{
 		w: w,
 	}
 
+	var err error
 	if _, ok := w.(io.Seeker); ok {
+		res.Compressor, err = NewCompressorOptions(w, e, o)
+		if err != nil {
+			return nil, err
+		}
+
 		// Write placeholder for header
 		var h [68]byte
 		if _, err := w.Write(h[:]); err != nil {
 			return nil, err
 		}
-
-		res.Compressor = NewCompressor(w, e)
 	} else {
-		res.Compressor = NewCompressor(&res.b, e)
+		res.Compressor, err = NewCompressorOptions(&res.b, e, o)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return &res, nil
 }
 
+// Records returns the number of records written
+func (e *Encoder) Records() int {
+	return e.numRecords
+}
+
+// Write implements the io.Writer interface.
+func (e *Encoder) Write(p []byte) (int, error) {
+	if err := e.resume(); err != nil {
+		return 0, err
+	}
+	return e.Compressor.Write(p)
+}
+
+// WriteRecord serializes r and writes it to e
+func (e *Encoder) WriteRecord(r Record) (int, error) {
+	if err := e.resume(); err != nil {
+		return 0, err
+	}
+	n, err := e.Compressor.WriteRecord(r)
+	if err == nil {
+		e.numRecords++
+	}
+	return n, err
+}
+
+// WriteRawRecord writes the already serialized record b to e (see RecordDecoder.Raw)
+func (e *Encoder) WriteRawRecord(b []byte) (int, error) {
+	if err := e.resume(); err != nil {
+		return 0, err
+	}
+	n, err := e.Compressor.Write(b)
+	if err == nil {
+		e.numRecords++
+	}
+	return n, err
+}
+
+// WriteRecords serializes r and writes to e
+func (e *Encoder) WriteRecords(r ...Record) (int, error) {
+	var n = 0
+	for _, v := range r {
+		nn, err := e.WriteRecord(v)
+		n += nn
+
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// EncodeRecords validates recs (see ValidateRecords) and encodes them as a replay with header h to w.
+// If h.DurationMS is not set, it is derived from the TimeSlot records.
+func EncodeRecords(w io.Writer, h Header, recs []Record, e Encoding) error {
+	if err := ValidateRecords(recs); err != nil {
+		return err
+	}
+
+	enc, err := NewEncoder(w, e)
+	if err != nil {
+		return err
+	}
+	if _, err := enc.WriteRecords(recs...); err != nil {
+		return err
+	}
+
+	enc.Header = h
+	if enc.DurationMS == 0 {
+		enc.DurationMS = duration(recs)
+	}
+
+	return enc.Close()
+}
+
+// Flush finalizes the current (padded) block and updates the header, so that the underlying
+// writer contains a valid replay up to this point. The padded block is rewritten on the next write.
+// No-op if the underlying writer is not an io.Seeker (data is only written on Close).
+func (e *Encoder) Flush() error {
+	if _, ok := e.w.(io.Seeker); !ok {
+		return nil
+	}
+
+	var pending = e.Compressor.Buffered()
+	if pending > 0 && e.checkpoint == nil {
+		var size = e.SizeWritten
+
+		e.keep = true
+		var err = e.Compressor.Close()
+		e.keep = false
+
+		if err != nil {
+			return err
+		}
+
+		e.checkpoint = &checkpoint{
+			data: append([]byte(nil), e.last[:pending]...),
+			size: e.SizeWritten - size,
+		}
+	}
+
+	return e.writeHeader()
+}
+
+// resume removes the padded block written by Flush and restores its data in the write buffer
+func (e *Encoder) resume() error {
+	if e.checkpoint == nil {
+		return nil
+	}
+
+	var c = e.checkpoint
+	e.checkpoint = nil
+
+	var s = e.w.(io.Seeker)
+	pos, err := s.Seek(-int64(c.size), io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if t, ok := e.w.(interface{ Truncate(size int64) error }); ok {
+		if err := t.Truncate(pos); err != nil {
+			return err
+		}
+	}
+
+	e.SizeWritten -= c.size
+	e.SizeTotal -= uint32(len(c.data))
+	e.NumBlocks--
+
+	_, err = e.Compressor.Write(c.data)
+	return err
+}
+
 // Close writer, flush data, and update header.
 // Does not close underlying writer.
 func (e *Encoder) Close() error {
 	if err := e.Compressor.Close(); err != nil {
 		return err
 	}
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
 
+	if _, seeker := e.w.(io.Seeker); seeker {
+		return nil
+	}
+
+	_, err := e.w.Write(e.b.Bytes)
+	return err
+}
+
+func (e *Encoder) writeHeader() error {
 	var buf [68]byte
 	var pbuf = protocol.Buffer{Bytes: buf[:0]}
 	pbuf.WriteCString(Signature)
@@ -184,29 +395,23 @@ func (e *Encoder) Close() error {
 	pbuf.WriteUInt32At(64, crc32.ChecksumIEEE(pbuf.Bytes))
 
 	s, seeker := e.w.(io.Seeker)
-	if seeker {
-		// Seek to beginning
-		if _, err := s.Seek(-int64(e.Compressor.SizeWritten+68), io.SeekCurrent); err != nil {
-			return err
-		}
-		// Overwrite header
-		if n, err := e.w.Write(pbuf.Bytes); err != nil {
-			s.Seek(-int64(e.Compressor.SizeWritten+68-uint32(n)), io.SeekCurrent)
-			return err
-		}
-		// Seek to end
-		if _, err := s.Seek(int64(e.Compressor.SizeWritten), io.SeekCurrent); err != nil {
-			return err
-		}
-		return nil
+	if !seeker {
+		_, err := e.w.Write(pbuf.Bytes)
+		return err
 	}
 
-	if _, err := e.w.Write(pbuf.Bytes); err != nil {
+	// Seek to beginning
+	if _, err := s.Seek(-int64(e.Compressor.SizeWritten+68), io.SeekCurrent); err != nil {
 		return err
 	}
-	if _, err := e.w.Write(e.b.Bytes); err != nil {
+	// Overwrite header
+	if n, err := e.w.Write(pbuf.Bytes); err != nil {
+		s.Seek(-int64(e.Compressor.SizeWritten+68-uint32(n)), io.SeekCurrent)
+		return err
+	}
+	// Seek to end
+	if _, err := s.Seek(int64(e.Compressor.SizeWritten), io.SeekCurrent); err != nil {
 		return err
 	}
-
 	return nil
 }
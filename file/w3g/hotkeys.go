@@ -0,0 +1,47 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g
+
+import (
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// HotkeyEvent is a single control group assignment or recall
+type HotkeyEvent struct {
+	GameTime time.Duration
+	Group    uint8 // Control group (0-9), group 0 is bound to key 1 and group 9 to key 0
+	Assign   bool  // Assigned (true) or selected (false)
+	Units    int   // Number of assigned units
+}
+
+// HotkeyStats counts control group usage per group
+type HotkeyStats struct {
+	Assign [10]int
+	Select [10]int
+	Events []HotkeyEvent
+}
+
+// HotkeyUsage collects the control group assignments and recalls of playerID
+func (r *Replay) HotkeyUsage(playerID uint8) HotkeyStats {
+	var res HotkeyStats
+	r.playerActions(playerID, func(t time.Duration, act w3gs.Action) bool {
+		switch v := act.(type) {
+		case *w3gs.AssignGroupHotkey:
+			if v.Group < 10 {
+				res.Assign[v.Group]++
+			}
+			res.Events = append(res.Events, HotkeyEvent{GameTime: t, Group: v.Group, Assign: true, Units: len(v.Units)})
+		case *w3gs.SelectGroupHotkey:
+			if v.Group < 10 {
+				res.Select[v.Group]++
+			}
+			res.Events = append(res.Events, HotkeyEvent{GameTime: t, Group: v.Group})
+		}
+		return true
+	})
+	return res
+}
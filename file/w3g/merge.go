@@ -0,0 +1,201 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// Merge combines two recordings of the same game (i.e. from the host and a client perspective).
+//
+// TimeSlot records are aligned by game time and their actions are combined, so that actions missing
+// from one recording are recovered from the other. Other game records (chat, leavers, etc.) that are
+// missing from a are inserted from b. If one of the recordings ends early, the remainder is taken
+// from the other. The lobby records and recording player of a are kept.
+//
+// Returned errors wrap ErrReplayMismatch if the recordings are not of the same game.
+func Merge(a, b *Replay) (*Replay, error) {
+	if err := matchReplays(a, b); err != nil {
+		return nil, err
+	}
+
+	var res = *a
+	res.PlayerInfo = append([]*PlayerInfo(nil), a.PlayerInfo...)
+	res.PlayerExtra = append([]*PlayerExtra(nil), a.PlayerExtra...)
+	res.Records = nil
+
+outer:
+	for _, y := range b.PlayerExtra {
+		for _, x := range a.PlayerExtra {
+			if reflect.DeepEqual(x, y) {
+				continue outer
+			}
+		}
+		res.PlayerExtra = append(res.PlayerExtra, y)
+	}
+
+	var left = map[uint8]bool{}
+	var emit = func(rec Record) {
+		if l, ok := rec.(*PlayerLeft); ok {
+			if left[l.PlayerID] {
+				return
+			}
+			left[l.PlayerID] = true
+		}
+		res.Records = append(res.Records, rec)
+	}
+
+	var i, j int
+	var ms uint32
+	for {
+		// Records between time slots
+		var gap = len(res.Records)
+		for ; i < len(a.Records); i++ {
+			if _, ok := a.Records[i].(*TimeSlot); ok {
+				break
+			}
+			emit(a.Records[i])
+		}
+		for ; j < len(b.Records); j++ {
+			if _, ok := b.Records[j].(*TimeSlot); ok {
+				break
+			}
+			if !containsRecord(res.Records[gap:], b.Records[j]) {
+				emit(b.Records[j])
+			}
+		}
+
+		var sa, sb *TimeSlot
+		if i < len(a.Records) {
+			sa = a.Records[i].(*TimeSlot)
+		}
+		if j < len(b.Records) {
+			sb = b.Records[j].(*TimeSlot)
+		}
+
+		switch {
+		case sa == nil && sb == nil:
+			// Keep the duration of the longest recording
+			if ms > duration(a.Records) {
+				res.Header.DurationMS = b.DurationMS
+			}
+			return &res, nil
+		case sb == nil:
+			emit(sa)
+			ms += uint32(sa.TimeIncrementMS)
+			i++
+		case sa == nil:
+			emit(sb)
+			ms += uint32(sb.TimeIncrementMS)
+			j++
+		default:
+			if sa.TimeIncrementMS != sb.TimeIncrementMS || sa.Fragment != sb.Fragment {
+				return nil, fmt.Errorf("%w: time slots not aligned at %dms", ErrReplayMismatch, ms)
+			}
+			emit(mergeTimeSlot(sa, sb))
+			ms += uint32(sa.TimeIncrementMS)
+			i++
+			j++
+		}
+	}
+}
+
+func matchReplays(a, b *Replay) error {
+	if a.GameVersion != b.GameVersion {
+		return fmt.Errorf("%w: game version %v != %v", ErrReplayMismatch, &a.GameVersion, &b.GameVersion)
+	}
+	if a.GameName != b.GameName {
+		return fmt.Errorf("%w: game name %q != %q", ErrReplayMismatch, a.GameName, b.GameName)
+	}
+	if a.GameSettings.MapPath != b.GameSettings.MapPath || a.GameSettings.MapSha1 != b.GameSettings.MapSha1 {
+		return fmt.Errorf("%w: map %q != %q", ErrReplayMismatch, a.GameSettings.MapPath, b.GameSettings.MapPath)
+	}
+
+	var players = map[uint8]string{}
+	for _, p := range a.PlayerInfo {
+		players[p.ID] = p.Name
+	}
+	if len(players) != len(b.PlayerInfo) {
+		return fmt.Errorf("%w: %d players != %d players", ErrReplayMismatch, len(players), len(b.PlayerInfo))
+	}
+	for _, p := range b.PlayerInfo {
+		if name, ok := players[p.ID]; !ok || name != p.Name {
+			return fmt.Errorf("%w: player %d (%s) not found", ErrReplayMismatch, p.ID, p.Name)
+		}
+	}
+
+	return nil
+}
+
+// mergeTimeSlot returns the union of actions in a and b (a is returned as-is if b has no additional actions)
+//
+// Actions are aligned on their longest common subsequence, so actions recovered from b are inserted at
+// the same position relative to the actions both have in common.
+func mergeTimeSlot(a, b *TimeSlot) *TimeSlot {
+	var n, m = len(a.Actions), len(b.Actions)
+	var eq = func(i, j int) bool {
+		return a.Actions[i].PlayerID == b.Actions[j].PlayerID && bytes.Equal(a.Actions[i].Data, b.Actions[j].Data)
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a.Actions[i:] and b.Actions[j:]
+	var lcs = make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case eq(i, j):
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	if lcs[0][0] == m {
+		return a
+	}
+
+	var res = &TimeSlot{}
+	res.Fragment = a.Fragment
+	res.TimeIncrementMS = a.TimeIncrementMS
+	res.Actions = make([]w3gs.PlayerAction, 0, n+m-lcs[0][0])
+
+	var i, j int
+	for i < n && j < m {
+		switch {
+		case eq(i, j):
+			res.Actions = append(res.Actions, a.Actions[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			res.Actions = append(res.Actions, a.Actions[i])
+			i++
+		default:
+			res.Actions = append(res.Actions, b.Actions[j])
+			j++
+		}
+	}
+	res.Actions = append(res.Actions, a.Actions[i:]...)
+	res.Actions = append(res.Actions, b.Actions[j:]...)
+
+	return res
+}
+
+func containsRecord(recs []Record, rec Record) bool {
+	for _, r := range recs {
+		if reflect.DeepEqual(r, rec) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g
+
+import (
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// TimedPacket is a packet that a host sends to its clients during replay playback
+type TimedPacket struct {
+	Delay    time.Duration // Time to wait before sending Packet (at normal game speed)
+	GameTime time.Duration // Game time after sending Packet
+	Packet   w3gs.Packet
+	Record   Record // Source record
+}
+
+// PlaybackPacket returns the packet that replays rec to game clients, and the time to wait
+// before sending it. Records without in-game representation (i.e. lobby records) return false.
+//
+//   PlayerLeft  -> w3gs.PlayerLeft
+//   TimeSlot    -> w3gs.TimeSlot (delayed by TimeIncrementMS)
+//   ChatMessage -> w3gs.MessageRelay
+//   Desync      -> w3gs.Desync
+//
+// The returned packet may share memory with rec.
+func PlaybackPacket(rec Record) (w3gs.Packet, time.Duration, bool) {
+	switch v := rec.(type) {
+	case *PlayerLeft:
+		return &w3gs.PlayerLeft{
+			PlayerID: v.PlayerID,
+			Reason:   v.Reason,
+		}, 0, true
+	case *TimeSlot:
+		return &v.TimeSlot, time.Duration(v.TimeIncrementMS) * time.Millisecond, true
+	case *Desync:
+		return &v.Desync, 0, true
+	case *ChatMessage:
+		return &w3gs.MessageRelay{Message: v.Message}, 0, true
+	default:
+		return nil, 0, false
+	}
+}
+
+// Playback returns the sequence of packets (see PlaybackPacket) that replays r to game clients
+func (r *Replay) Playback() []TimedPacket {
+	var res []TimedPacket
+	var t time.Duration
+	for _, rec := range r.Records {
+		pkt, delay, ok := PlaybackPacket(rec)
+		if !ok {
+			continue
+		}
+		t += delay
+		res = append(res, TimedPacket{
+			Delay:    delay,
+			GameTime: t,
+			Packet:   pkt,
+			Record:   rec,
+		})
+	}
+	return res
+}
@@ -0,0 +1,262 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// ResolvedPlayer combines the PlayerInfo, SlotData and PlayerExtra records of a single player
+type ResolvedPlayer struct {
+	ID        uint8
+	Name      string
+	Color     uint8
+	Team      uint8
+	Race      w3gs.RacePref
+	Handicap  uint8
+	Observer  bool
+	Computer  bool
+	AI        w3gs.AI // Only set for computer players
+	BattleTag string
+	Clan      string
+	SlotIndex int
+}
+
+// MaxPlayers returns the number of playable (non-observer) slots for the replay version
+func (r *Replay) MaxPlayers() uint8 {
+	return r.Encoding().MaxPlayers()
+}
+
+func (r *Replay) resolveSlot(idx int) *ResolvedPlayer {
+	var slot = &r.Slots[idx]
+	var res = ResolvedPlayer{
+		Color:     slot.Color,
+		Team:      slot.Team,
+		Race:      slot.Race &^ w3gs.RaceSelectable,
+		Handicap:  slot.Handicap,
+		Observer:  slot.Team >= r.MaxPlayers(),
+		Computer:  slot.Computer,
+		SlotIndex: idx,
+	}
+	if slot.Computer {
+		res.AI = slot.ComputerType
+		return &res
+	}
+
+	res.ID = slot.PlayerID
+	for _, p := range r.PlayerInfo {
+		if p.ID == slot.PlayerID {
+			res.Name = p.Name
+			break
+		}
+	}
+	for _, e := range r.PlayerExtra {
+		for _, p := range e.Profiles {
+			if p.PlayerID == uint32(slot.PlayerID) {
+				res.BattleTag = p.BattleTag
+				res.Clan = p.Clan
+			}
+		}
+	}
+
+	return &res
+}
+
+// PlayerByID resolves the (human) player with the given ID
+func (r *Replay) PlayerByID(id uint8) (*ResolvedPlayer, bool) {
+	if id == 0 {
+		return nil, false
+	}
+	for i := range r.Slots {
+		if r.Slots[i].PlayerID == id && !r.Slots[i].Computer && r.Slots[i].SlotStatus == w3gs.SlotOccupied {
+			return r.resolveSlot(i), true
+		}
+	}
+	return nil, false
+}
+
+// Players resolves all occupied slots (including computers and observers) in slot order
+func (r *Replay) Players() []*ResolvedPlayer {
+	var res []*ResolvedPlayer
+	for i := range r.Slots {
+		if r.Slots[i].SlotStatus != w3gs.SlotOccupied {
+			continue
+		}
+		res = append(res, r.resolveSlot(i))
+	}
+	return res
+}
+
+// Observers returns all observers and referees (players in the team after the last playable team), in slot order
+func (r *Replay) Observers() []*ResolvedPlayer {
+	var res []*ResolvedPlayer
+	for _, p := range r.Players() {
+		if p.Observer && !p.Computer {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// Teams groups all occupied slots (including computers) by team, observers are omitted
+func (r *Replay) Teams() map[uint8][]*ResolvedPlayer {
+	var res = make(map[uint8][]*ResolvedPlayer)
+	for _, p := range r.Players() {
+		if p.Observer {
+			continue
+		}
+		res[p.Team] = append(res[p.Team], p)
+	}
+	return res
+}
+
+// TeamSizes returns the number of occupied slots (including computers) per team, observers are omitted
+func (r *Replay) TeamSizes() map[uint8]int {
+	var res = make(map[uint8]int)
+	for t, p := range r.Teams() {
+		res[t] = len(p)
+	}
+	return res
+}
+
+// IsFFA returns true for free-for-all games (more than two teams with a single player each)
+func (r *Replay) IsFFA() bool {
+	var sizes = r.TeamSizes()
+	if len(sizes) <= 2 {
+		return false
+	}
+	for _, n := range sizes {
+		if n != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSavedGame returns true for games that were loaded from a saved game.
+// Records from before the game was saved are not part of the replay, so action
+// based statistics (APM, build order, etc.) only cover the game after loading.
+func (r *Replay) IsSavedGame() bool {
+	return r.GameFlags.Has(w3gs.GameFlagSavedGame) || r.SavedGamePath() != ""
+}
+
+// SavedGamePath returns the path of the saved game file (*.w3z) the game was loaded from, if any
+func (r *Replay) SavedGamePath() string {
+	if !strings.HasSuffix(strings.ToLower(r.GameSettings.MapPath), ".w3z") {
+		return ""
+	}
+	return r.GameSettings.MapPath
+}
+
+// MapPath returns GameSettings.MapPath with forward slashes as path separator
+func (r *Replay) MapPath() string {
+	return strings.Replace(r.GameSettings.MapPath, "\\", "/", -1)
+}
+
+// MapName returns the file name of the map without directory, i.e. "(2)EchoIsles.w3x"
+func (r *Replay) MapName() string {
+	if r.GameSettings.MapPath == "" {
+		return ""
+	}
+	return path.Base(r.MapPath())
+}
+
+// Matchup describes the team configuration, i.e. "1v1", "2v2", "3v2" (ordered by team) or "FFA"
+func (r *Replay) Matchup() string {
+	if r.IsFFA() {
+		return "FFA"
+	}
+
+	var sizes = r.TeamSizes()
+	var teams = make([]int, 0, len(sizes))
+	for t := range sizes {
+		teams = append(teams, int(t))
+	}
+	sort.Ints(teams)
+
+	var res = make([]string, len(teams))
+	for i, t := range teams {
+		res[i] = strconv.Itoa(sizes[uint8(t)])
+	}
+	return strings.Join(res, "v")
+}
+
+// Departure describes when and why a player left the game
+type Departure struct {
+	PlayerID uint8
+	Name     string
+	GameTime time.Duration
+	Reason   w3gs.LeaveReason
+	Local    bool
+	Remained bool // Player was still present when the replay ended
+}
+
+func (r *Replay) playerName(id uint8) string {
+	for _, p := range r.PlayerInfo {
+		if p.ID == id {
+			return p.Name
+		}
+	}
+	return ""
+}
+
+// Leavers returns all players that left the game, in order of departure
+func (r *Replay) Leavers() []Departure {
+	var res []Departure
+	var ms uint32
+	r.Accept(Visitor{
+		OnTimeSlot: func(v *TimeSlot) {
+			ms += uint32(v.TimeIncrementMS)
+		},
+		OnPlayerLeft: func(v *PlayerLeft) {
+			res = append(res, Departure{
+				PlayerID: v.PlayerID,
+				Name:     r.playerName(v.PlayerID),
+				GameTime: time.Duration(ms) * time.Millisecond,
+				Reason:   v.Reason,
+				Local:    v.Local,
+			})
+		},
+	})
+	return res
+}
+
+// Departures returns Leavers(), followed by the players that never left the game
+// (Remained set, GameTime set to the end of the replay)
+func (r *Replay) Departures() []Departure {
+	var res = r.Leavers()
+
+	var left = make(map[uint8]bool, len(res))
+	for _, d := range res {
+		left[d.PlayerID] = true
+	}
+
+	var end time.Duration
+	for _, rec := range r.Records {
+		if ts, ok := rec.(*TimeSlot); ok {
+			end += time.Duration(ts.TimeIncrementMS) * time.Millisecond
+		}
+	}
+
+	for _, p := range r.PlayerInfo {
+		if left[p.ID] {
+			continue
+		}
+		res = append(res, Departure{
+			PlayerID: p.ID,
+			Name:     p.Name,
+			GameTime: end,
+			Remained: true,
+		})
+	}
+
+	return res
+}
@@ -0,0 +1,139 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g
+
+import (
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// LegitActions is the default set of action IDs that are expected in multiplayer games.
+// Cheats and action IDs that cannot be decoded are not included.
+var LegitActions = map[uint8]bool{
+	w3gs.AidPause:              true,
+	w3gs.AidResume:             true,
+	w3gs.AidSetGameSpeed:       true,
+	w3gs.AidIncreaseGameSpeed:  true,
+	w3gs.AidDecreaseGameSpeed:  true,
+	w3gs.AidSaveGame:           true,
+	w3gs.AidSaveGameFinished:   true,
+	w3gs.AidAbility:            true,
+	w3gs.AidAbilityTargetPos:   true,
+	w3gs.AidAbilityTargetObj:   true,
+	w3gs.AidGiveItem:           true,
+	w3gs.AidAbilityTwoTargets:  true,
+	w3gs.AidChangeSelection:    true,
+	w3gs.AidAssignGroupHotkey:  true,
+	w3gs.AidSelectGroupHotkey:  true,
+	w3gs.AidSelectSubgroup:     true,
+	w3gs.AidPreSubselection:    true,
+	w3gs.AidUnknown1B:          true,
+	w3gs.AidSelectGroundItem:   true,
+	w3gs.AidCancelHeroRevival:  true,
+	w3gs.AidRemoveFromQueue:    true,
+	w3gs.AidUnknown21:          true,
+	w3gs.AidChangeAllyOptions:  true,
+	w3gs.AidTransferResources:  true,
+	w3gs.AidTriggerChatCommand: true,
+	w3gs.AidEscPressed:         true,
+	w3gs.AidScenarioTrigger:    true,
+	w3gs.AidHeroSkillSubmenu:   true,
+	w3gs.AidBuildSubmenu:       true,
+	w3gs.AidMinimapPing:        true,
+	w3gs.AidContinueGameB:      true,
+	w3gs.AidContinueGameA:      true,
+	w3gs.AidSyncStoredInteger:  true,
+	w3gs.AidUnknown75:          true,
+	w3gs.AidUnknown7B:          true,
+}
+
+// pauseActions are sent by the game itself while paused (i.e. the continue game handshake before
+// resuming), they are not counted as actions issued while paused
+var pauseActions = map[uint8]bool{
+	w3gs.AidPause:             true,
+	w3gs.AidResume:            true,
+	w3gs.AidSaveGame:          true,
+	w3gs.AidSaveGameFinished:  true,
+	w3gs.AidContinueGameB:     true,
+	w3gs.AidContinueGameA:     true,
+	w3gs.AidSyncStoredInteger: true,
+}
+
+// ActionProfile summarizes the action data issued by a single player
+type ActionProfile struct {
+	Actions int           // Number of actions
+	Bytes   int           // Size of action data
+	IDs     map[uint8]int // Number of actions by action ID
+
+	// Number of actions by action ID for IDs outside the legit set (see LegitActions).
+	// Action data that cannot be decoded is counted by its first unknown (wire) ID.
+	Unusual map[uint8]int
+
+	// Number of actions issued while the game was paused, not counting pause, resume,
+	// and system actions the game sends by itself (see pauseActions)
+	Paused int
+}
+
+// ActionProfile returns the ActionProfile of every player that issued actions, checked against LegitActions
+func (r *Replay) ActionProfile() map[uint8]ActionProfile {
+	return r.ActionProfileWith(LegitActions)
+}
+
+// ActionProfileWith is like ActionProfile, but checks action IDs against legit instead of LegitActions
+func (r *Replay) ActionProfileWith(legit map[uint8]bool) map[uint8]ActionProfile {
+	var enc = r.Encoding().Encoding
+	var res = map[uint8]ActionProfile{}
+	var paused = false
+
+	for _, rec := range r.Records {
+		ts, ok := rec.(*TimeSlot)
+		if !ok {
+			continue
+		}
+
+		for i := range ts.Actions {
+			var pa = &ts.Actions[i]
+			var p, ok = res[pa.PlayerID]
+			if !ok {
+				p.IDs = map[uint8]int{}
+				p.Unusual = map[uint8]int{}
+			}
+			p.Bytes += len(pa.Data)
+
+			acts, err := pa.Actions(enc)
+			for _, a := range acts {
+				var id = w3gs.ActionID(a)
+				switch id {
+				case w3gs.AidPause:
+					paused = true
+				case w3gs.AidResume:
+					paused = false
+				default:
+					if paused && !pauseActions[id] {
+						p.Paused++
+					}
+				}
+
+				p.Actions++
+				p.IDs[id]++
+				if !legit[id] {
+					p.Unusual[id]++
+				}
+			}
+
+			if err != nil {
+				// Find the ID that could not be decoded
+				if b, serr := w3gs.SerializeActions(acts, enc); serr == nil && len(b) < len(pa.Data) {
+					var id = pa.Data[len(b)]
+					p.IDs[id]++
+					p.Unusual[id]++
+				}
+			}
+
+			res[pa.PlayerID] = p
+		}
+	}
+
+	return res
+}
@@ -5,6 +5,7 @@
 package w3g
 
 import (
+	"fmt"
 	"io"
 	"strings"
 	"unicode"
@@ -22,9 +23,52 @@ type Record interface {
 // Encoding options for (de)serialization
 type Encoding struct {
 	w3gs.Encoding
+
+	// Ignore unexpected constant values where the rest of the record can still be decoded,
+	// Deserialize then returns a ConstWarning after decoding the record completely.
+	Lenient bool
+}
+
+// ConstWarning lists the unexpected constant values that were ignored while decoding a record in
+// Lenient mode (i.e. "GameInfo.Unknown=0x2"). It wraps ErrUnexpectedConst.
+type ConstWarning []string
+
+func (w ConstWarning) Error() string {
+	return fmt.Sprintf("%v (%s)", ErrUnexpectedConst, strings.Join(w, ", "))
+}
+
+// Unwrap returns ErrUnexpectedConst
+func (w ConstWarning) Unwrap() error {
+	return ErrUnexpectedConst
+}
+
+// unexpectedConst returns ErrUnexpectedConst, or adds field to w and returns nil in Lenient mode
+func (w *ConstWarning) unexpectedConst(enc *Encoding, field string, val uint32) error {
+	if !enc.Lenient {
+		return ErrUnexpectedConst
+	}
+	*w = append(*w, fmt.Sprintf("%s=0x%X", field, val))
+	return nil
+}
+
+// merge adds the fields of err to w if it is a ConstWarning, other errors are returned as-is
+func (w *ConstWarning) merge(err error) error {
+	if c, ok := err.(ConstWarning); ok {
+		*w = append(*w, c...)
+		return nil
+	}
+	return err
+}
+
+// err returns w as error (nil if empty)
+func (w ConstWarning) err() error {
+	if len(w) == 0 {
+		return nil
+	}
+	return w
 }
 
-// DefaultFactory maps record ID to matching type
+// DefaultFactory maps record ID to matching type (returns new instances on every call)
 var DefaultFactory = MapFactory{
 	RidGameInfo:       func(_ *Encoding) Record { return &GameInfo{} },
 	RidPlayerInfo:     func(_ *Encoding) Record { return &PlayerInfo{} },
@@ -64,6 +108,9 @@ var DefaultFactory = MapFactory{
 //       4 byte | GameType
 //       4 byte | LanguageID
 //
+// LanguageID may hold a locale identifier (see w3gs.LanguageName), but replays
+// often store zero or seemingly random values instead.
+//
 type GameInfo struct {
 	HostPlayer   PlayerInfo
 	GameName     string
@@ -100,11 +147,14 @@ func (rec *GameInfo) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 	// Skip record ID
 	buf.Skip(1)
 
-	if buf.ReadUInt32() != 1 {
-		return ErrUnexpectedConst
+	var warn ConstWarning
+	if v := buf.ReadUInt32(); v != 1 {
+		if err := warn.unexpectedConst(enc, "GameInfo.NumHosts", v); err != nil {
+			return err
+		}
 	}
 
-	if err := rec.HostPlayer.DeserializeContent(buf, enc); err != nil {
+	if err := warn.merge(rec.HostPlayer.DeserializeContent(buf, enc)); err != nil {
 		return err
 	}
 
@@ -120,8 +170,10 @@ func (rec *GameInfo) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 	if buf.Size() < 14 {
 		return io.ErrShortBuffer
 	}
-	if buf.ReadUInt8() != 0 {
-		return ErrUnexpectedConst
+	if v := buf.ReadUInt8(); v != 0 {
+		if err := warn.unexpectedConst(enc, "GameInfo.Nullbyte", uint32(v)); err != nil {
+			return err
+		}
 	}
 
 	if err := rec.GameSettings.DeserializeContent(buf, &enc.Encoding); err != nil {
@@ -136,7 +188,7 @@ func (rec *GameInfo) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 	rec.GameFlags = w3gs.GameFlags(buf.ReadUInt32())
 	rec.LanguageID = buf.ReadUInt32()
 
-	return nil
+	return warn.err()
 }
 
 // PlayerInfo record [0x16]
@@ -188,7 +240,8 @@ func (rec *PlayerInfo) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 	// Skip record ID
 	buf.Skip(1)
 
-	if err := rec.DeserializeContent(buf, enc); err != nil {
+	var warn ConstWarning
+	if err := warn.merge(rec.DeserializeContent(buf, enc)); err != nil {
 		return err
 	}
 
@@ -198,7 +251,7 @@ func (rec *PlayerInfo) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 	}
 	buf.Skip(4)
 
-	return nil
+	return warn.err()
 }
 
 // SerializeContent encodes the struct into its binary form without record ID.
@@ -238,21 +291,23 @@ func (rec *PlayerInfo) DeserializeContent(buf *protocol.Buffer, enc *Encoding) e
 		return io.ErrShortBuffer
 	}
 
+	var warn ConstWarning
 	switch len {
-	case 0x01, 0x02:
-		buf.Skip(int(len))
-		fallthrough
-	case 0x00:
-		rec.JoinCounter = 0
-		rec.Race = 0
 	case 0x08:
 		rec.JoinCounter = buf.ReadUInt32()
 		rec.Race = w3gs.RacePref(buf.ReadUInt32())
 	default:
-		return ErrUnexpectedConst
+		if len > 0x02 {
+			if err := warn.unexpectedConst(enc, "PlayerInfo.Size", uint32(len)); err != nil {
+				return err
+			}
+		}
+		buf.Skip(int(len))
+		rec.JoinCounter = 0
+		rec.Race = 0
 	}
 
-	return nil
+	return warn.err()
 }
 
 // PlayerLeft record [0x17]
@@ -299,20 +354,24 @@ func (rec *PlayerLeft) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 	// Skip record ID
 	buf.Skip(1)
 
-	switch buf.ReadUInt32() {
+	var warn ConstWarning
+	switch v := buf.ReadUInt32(); v {
 	case 0x01, 0x0E:
 		rec.Local = false
 	case 0x0C:
 		rec.Local = true
 	default:
-		return ErrUnexpectedConst
+		if err := warn.unexpectedConst(enc, "PlayerLeft.Local", v); err != nil {
+			return err
+		}
+		rec.Local = false
 	}
 
 	rec.PlayerID = buf.ReadUInt8()
 	rec.Reason = w3gs.LeaveReason(buf.ReadUInt32())
 	rec.Counter = buf.ReadUInt32()
 
-	return nil
+	return warn.err()
 }
 
 // SlotInfo record [0x19]
@@ -418,11 +477,14 @@ func (rec *GameStart) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 	// Skip record ID
 	buf.Skip(1)
 
-	if buf.ReadUInt32() != 0x01 {
-		return ErrUnexpectedConst
+	var warn ConstWarning
+	if v := buf.ReadUInt32(); v != 0x01 {
+		if err := warn.unexpectedConst(enc, "GameStart.Unknown", v); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return warn.err()
 }
 
 // CountDownStart record [0x1A]
@@ -534,12 +596,17 @@ func (rec *TimeSlot) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 		rec.Actions[i].PlayerID = buf.ReadUInt8()
 
 		var subsize = int(buf.ReadUInt16())
-		if size < subsize {
+		if size < 3+subsize {
 			return ErrBadFormat
 		}
 		size -= 3 + subsize
 
-		rec.Actions[i].Data = append(rec.Actions[i].Data[:0], buf.ReadBlob(subsize)...)
+		if enc.LazyActions {
+			var data = buf.ReadBlob(subsize)
+			rec.Actions[i].Data = data[:subsize:subsize]
+		} else {
+			rec.Actions[i].Data = append(rec.Actions[i].Data[:0], buf.ReadBlob(subsize)...)
+		}
 		i++
 	}
 
@@ -568,23 +635,47 @@ func (rec *TimeSlot) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 //              |   0x03+N for messages to specific player N (with N = slotnumber)
 //     n bytes  | zero terminated string containing the text message
 //
+// Unlike the live protocol, where RecipientIDs lists every player that receives the message,
+// replays only store the slot of the recipient of directed chat (see MessageScope.Slot).
+// RecipientIDs is not stored; it is nil after Deserialize and ignored by Serialize. Decode
+// resolves the recipient of directed chat through SlotInfo and fills RecipientIDs with it.
 type ChatMessage struct {
 	w3gs.Message
+
+	// Offsets in Content where the text was split into multiple
+	// null terminated strings (nwg quirk), kept to preserve layout
+	Split []int
 }
 
 // Serialize encodes the struct into its binary form.
 func (rec *ChatMessage) Serialize(buf *protocol.Buffer, enc *Encoding) error {
-	buf.WriteUInt8(RidChatMessage)
-	buf.WriteUInt8(rec.SenderID)
+	var last = 0
+	for _, s := range rec.Split {
+		if s < last || s > len(rec.Content) {
+			return ErrBadFormat
+		}
+		last = s
+	}
+
+	// Null bytes would be read back as string terminators
+	if (rec.Type == w3gs.MsgChat || rec.Type == w3gs.MsgChatExtra) && strings.IndexByte(rec.Content, 0) != -1 {
+		return ErrBadFormat
+	}
 
+	var size = 2
 	switch rec.Type {
 	case w3gs.MsgChatExtra:
-		buf.WriteUInt16(uint16(6 + len(rec.Content)))
+		size += 4 + len(rec.Content) + len(rec.Split)
 	case w3gs.MsgChat:
-		buf.WriteUInt16(uint16(2 + len(rec.Content)))
-	default:
-		buf.WriteUInt16(2)
+		size += len(rec.Content) + len(rec.Split)
 	}
+	if size > 0xFFFF {
+		return protocol.ErrSizeOverflow
+	}
+
+	buf.WriteUInt8(RidChatMessage)
+	buf.WriteUInt8(rec.SenderID)
+	buf.WriteUInt16(uint16(size))
 
 	buf.WriteUInt8(uint8(rec.Type))
 
@@ -593,7 +684,12 @@ func (rec *ChatMessage) Serialize(buf *protocol.Buffer, enc *Encoding) error {
 		buf.WriteUInt32(uint32(rec.Scope))
 		fallthrough
 	case w3gs.MsgChat:
-		buf.WriteCString(rec.Content)
+		var last = 0
+		for _, s := range rec.Split {
+			buf.WriteCString(rec.Content[last:s])
+			last = s
+		}
+		buf.WriteCString(rec.Content[last:])
 	default:
 		buf.WriteUInt8(rec.NewVal)
 	}
@@ -603,7 +699,7 @@ func (rec *ChatMessage) Serialize(buf *protocol.Buffer, enc *Encoding) error {
 
 // Deserialize decodes the binary data generated by Serialize.
 func (rec *ChatMessage) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
-	if buf.Size() < 3 {
+	if buf.Size() < 4 {
 		return io.ErrShortBuffer
 	}
 
@@ -615,6 +711,7 @@ func (rec *ChatMessage) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 	rec.Scope = w3gs.ScopeAll
 	rec.NewVal = 0
 	rec.Content = ""
+	rec.Split = nil
 
 	var size = int(buf.ReadUInt16())
 	if size < 2 || buf.Size() < size {
@@ -645,10 +742,13 @@ func (rec *ChatMessage) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 				return err
 			}
 
-			if strings.IndexFunc(buf, func(r rune) bool { return !unicode.IsPrint(r) }) != -1 {
+			// Allow any visible text, including non-ASCII spaces (formatting codes such as
+			// "|cffff0000" and "|r" are plain text and pass through untouched)
+			if strings.IndexFunc(buf, func(r rune) bool { return !unicode.IsGraphic(r) }) != -1 {
 				return ErrBadFormat
 			}
 
+			rec.Split = append(rec.Split, len(rec.Content))
 			rec.Content += buf
 			size -= len(buf) + 1
 		}
@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"io"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/nielsAD/gowarcraft3/file/w3g"
@@ -29,170 +30,202 @@ var ts = w3g.TimeSlot{TimeSlot: w3gs.TimeSlot{
 	}(),
 }}
 
-func TestRecords(t *testing.T) {
-	var types = []w3g.Record{
-		&w3g.GameInfo{},
-		&w3g.GameInfo{
-			HostPlayer: w3g.PlayerInfo{
-				ID:          1,
-				Name:        "Niels",
-				Race:        w3gs.RaceHuman,
-				JoinCounter: 666,
-			},
-			GameName: "niels (Local Game)",
-			GameSettings: w3gs.GameSettings{
-				GameSettingFlags: w3gs.SettingSpeedNormal,
-				MapWidth:         1,
-				MapHeight:        2,
-				MapXoro:          3,
-				MapPath:          "4",
-				HostName:         "5",
-			},
-			GameFlags:  w3gs.GameFlagCustomGame,
-			NumSlots:   12,
-			LanguageID: 0x0012F824,
-		},
-		&w3g.PlayerInfo{},
-		&w3g.PlayerInfo{
-			ID:          2,
-			Name:        "Moon",
-			Race:        w3gs.RaceNightElf,
-			JoinCounter: 456,
+var records = []w3g.Record{
+	&w3g.GameInfo{},
+	&w3g.GameInfo{
+		HostPlayer: w3g.PlayerInfo{
+			ID:          1,
+			Name:        "Niels",
+			Race:        w3gs.RaceHuman,
+			JoinCounter: 666,
 		},
-		&w3g.PlayerLeft{},
-		&w3g.PlayerLeft{
-			Local:    true,
-			PlayerID: 3,
-			Reason:   w3gs.LeaveLost,
-			Counter:  777,
+		GameName: "niels (Local Game)",
+		GameSettings: w3gs.GameSettings{
+			GameSettingFlags: w3gs.SettingSpeedNormal,
+			MapWidth:         1,
+			MapHeight:        2,
+			MapXoro:          3,
+			MapPath:          "4",
+			HostName:         "5",
 		},
-		&w3g.SlotInfo{},
-		&w3g.SlotInfo{
-			SlotInfo: w3gs.SlotInfo{
-				Slots: []w3gs.SlotData{
-					w3gs.SlotData{
-						PlayerID:       1,
-						DownloadStatus: 2,
-						SlotStatus:     3,
-						Computer:       true,
-						Team:           5,
-						Color:          6,
-						Race:           7,
-						ComputerType:   8,
-						Handicap:       9,
-					},
-					w3gs.SlotData{
-						PlayerID:       9,
-						DownloadStatus: 8,
-						SlotStatus:     7,
-						Computer:       false,
-						Team:           5,
-						Color:          4,
-						Race:           3,
-						ComputerType:   2,
-						Handicap:       1,
-					},
+		GameFlags:  w3gs.GameFlagCustomGame,
+		NumSlots:   12,
+		LanguageID: 0x0012F824,
+	},
+	&w3g.PlayerInfo{},
+	&w3g.PlayerInfo{
+		ID:          2,
+		Name:        "Moon",
+		Race:        w3gs.RaceNightElf,
+		JoinCounter: 456,
+	},
+	&w3g.PlayerLeft{},
+	&w3g.PlayerLeft{
+		Local:    true,
+		PlayerID: 3,
+		Reason:   w3gs.LeaveLost,
+		Counter:  777,
+	},
+	&w3g.SlotInfo{},
+	&w3g.SlotInfo{
+		SlotInfo: w3gs.SlotInfo{
+			Slots: []w3gs.SlotData{
+				w3gs.SlotData{
+					PlayerID:       1,
+					DownloadStatus: 2,
+					SlotStatus:     3,
+					Computer:       true,
+					Team:           5,
+					Color:          6,
+					Race:           7,
+					ComputerType:   8,
+					Handicap:       9,
+				},
+				w3gs.SlotData{
+					PlayerID:       9,
+					DownloadStatus: 8,
+					SlotStatus:     7,
+					Computer:       false,
+					Team:           5,
+					Color:          4,
+					Race:           3,
+					ComputerType:   2,
+					Handicap:       1,
 				},
-				RandomSeed: 10,
-				SlotLayout: w3gs.LayoutMelee,
-				NumPlayers: 12,
 			},
+			RandomSeed: 10,
+			SlotLayout: w3gs.LayoutMelee,
+			NumPlayers: 12,
 		},
-		&w3g.CountDownStart{},
-		&w3g.CountDownEnd{},
-		&w3g.GameStart{},
-		&w3g.TimeSlot{},
-		&ts,
-		&w3g.ChatMessage{},
-		&w3g.ChatMessage{
-			Message: w3gs.Message{
-				SenderID: 4,
-				Type:     w3gs.MsgChatExtra,
-				Scope:    w3gs.ScopeAllies,
-				Content:  "Pitiful",
-			},
+	},
+	&w3g.CountDownStart{},
+	&w3g.CountDownEnd{},
+	&w3g.GameStart{},
+	&w3g.TimeSlot{},
+	&ts,
+	&w3g.ChatMessage{},
+	&w3g.ChatMessage{
+		Message: w3gs.Message{
+			SenderID: 4,
+			Type:     w3gs.MsgChatExtra,
+			Scope:    w3gs.ScopeAllies,
+			Content:  "Pitiful",
 		},
-		&w3g.TimeSlotAck{},
-		&w3g.TimeSlotAck{
-			Checksum: []byte{4, 5, 6},
+	},
+	&w3g.ChatMessage{
+		Message: w3gs.Message{
+			SenderID: 4,
+			Type:     w3gs.MsgChatExtra,
+			Scope:    w3gs.ScopeToSlot(1),
+			Content:  "psst",
 		},
-		&w3g.Desync{},
-		&w3g.Desync{
-			Desync: w3gs.Desync{
-				Unknown1:       234,
-				Checksum:       567,
-				PlayersInState: []uint8{1, 2, 3},
-			},
+	},
+	&w3g.ChatMessage{
+		Message: w3gs.Message{
+			SenderID: 5,
+			Type:     w3gs.MsgChat,
+			Content:  "glhf",
 		},
-		&w3g.EndTimer{},
-		&w3g.EndTimer{
-			GameOver:     true,
-			CountDownSec: 5,
+		Split: []int{2, 3, 4},
+	},
+	&w3g.ChatMessage{
+		Message: w3gs.Message{
+			SenderID: 3,
+			Type:     w3gs.MsgChatExtra,
+			Scope:    w3gs.ScopeAll,
+			Content:  "|cffff0000red|r |CFF00FF00green|R|n",
 		},
-		&w3g.PlayerExtra{},
-		&w3g.PlayerExtra{
-			PlayerExtra: w3gs.PlayerExtra{
-				Type: w3gs.PlayerProfile,
-				Profiles: []w3gs.PlayerDataProfile{
-					w3gs.PlayerDataProfile{
-						PlayerID:  1,
-						BattleTag: "niels#1234",
-						Clan:      "clan",
-						Portrait:  "p051",
-						Realm:     w3gs.RealmAmericas,
-						Unknown1:  "",
-					},
-					w3gs.PlayerDataProfile{
-						PlayerID:  2,
-						BattleTag: "moon#56789",
-						Clan:      "clan",
-						Portrait:  "p055",
-						Realm:     w3gs.RealmAsia,
-						Unknown1:  "",
-					},
+	},
+	&w3g.ChatMessage{
+		Message: w3gs.Message{
+			SenderID: 3,
+			Type:     w3gs.MsgChat,
+			Content:  "|cffffcc00gl\u3000hf|r",
+		},
+		Split: []int{10, 12},
+	},
+	&w3g.TimeSlotAck{},
+	&w3g.TimeSlotAck{
+		Checksum: []byte{4, 5, 6},
+	},
+	&w3g.Desync{},
+	&w3g.Desync{
+		Desync: w3gs.Desync{
+			Unknown1:       234,
+			Checksum:       567,
+			PlayersInState: []uint8{1, 2, 3},
+		},
+	},
+	&w3g.EndTimer{},
+	&w3g.EndTimer{
+		GameOver:     true,
+		CountDownSec: 5,
+	},
+	&w3g.PlayerExtra{},
+	&w3g.PlayerExtra{
+		PlayerExtra: w3gs.PlayerExtra{
+			Type: w3gs.PlayerProfile,
+			Profiles: []w3gs.PlayerDataProfile{
+				w3gs.PlayerDataProfile{
+					PlayerID:  1,
+					BattleTag: "niels#1234",
+					Clan:      "clan",
+					Portrait:  "p051",
+					Realm:     w3gs.RealmAmericas,
+					Unknown1:  "",
+				},
+				w3gs.PlayerDataProfile{
+					PlayerID:  2,
+					BattleTag: "moon#56789",
+					Clan:      "clan",
+					Portrait:  "p055",
+					Realm:     w3gs.RealmAsia,
+					Unknown1:  "",
 				},
 			},
 		},
-		&w3g.PlayerExtra{
-			PlayerExtra: w3gs.PlayerExtra{
-				Type: w3gs.PlayerSkins,
-				Skins: []w3gs.PlayerDataSkins{
-					w3gs.PlayerDataSkins{
-						PlayerID: 3,
-						Skins: []w3gs.PlayerDataSkin{
-							w3gs.PlayerDataSkin{
-								Unit:       1164207469,
-								Skin:       1164207462,
-								Collection: "w3-standard",
-							},
-							w3gs.PlayerDataSkin{
-								Unit:       1164666213,
-								Skin:       1164665701,
-								Collection: "w3-sow-skins",
-							},
+	},
+	&w3g.PlayerExtra{
+		PlayerExtra: w3gs.PlayerExtra{
+			Type: w3gs.PlayerSkins,
+			Skins: []w3gs.PlayerDataSkins{
+				w3gs.PlayerDataSkins{
+					PlayerID: 3,
+					Skins: []w3gs.PlayerDataSkin{
+						w3gs.PlayerDataSkin{
+							Unit:       1164207469,
+							Skin:       1164207462,
+							Collection: "w3-standard",
+						},
+						w3gs.PlayerDataSkin{
+							Unit:       1164666213,
+							Skin:       1164665701,
+							Collection: "w3-sow-skins",
 						},
 					},
-					w3gs.PlayerDataSkins{
-						PlayerID: 4,
-						Skins: []w3gs.PlayerDataSkin{
-							w3gs.PlayerDataSkin{
-								Unit:       1432642913,
-								Skin:       1432642918,
-								Collection: "w3-standard",
-							},
-							w3gs.PlayerDataSkin{
-								Unit:       1332109682,
-								Skin:       1332114536,
-								Collection: "w3-sow-skins",
-							},
+				},
+				w3gs.PlayerDataSkins{
+					PlayerID: 4,
+					Skins: []w3gs.PlayerDataSkin{
+						w3gs.PlayerDataSkin{
+							Unit:       1432642913,
+							Skin:       1432642918,
+							Collection: "w3-standard",
+						},
+						w3gs.PlayerDataSkin{
+							Unit:       1332109682,
+							Skin:       1332114536,
+							Collection: "w3-sow-skins",
 						},
 					},
 				},
 			},
 		},
-	}
+	},
+}
 
-	for _, rec := range types {
+func TestRecords(t *testing.T) {
+	for _, rec := range records {
 		var err error
 		var buf = protocol.Buffer{}
 		var enc = w3g.Encoding{}
@@ -235,3 +268,108 @@ func TestRecords(t *testing.T) {
 		}
 	}
 }
+
+func TestChatRecipients(t *testing.T) {
+	var roundTrip = func(msg *w3gs.Message) *w3g.ChatMessage {
+		var buf = protocol.Buffer{}
+		if err := (&w3g.ChatMessage{Message: *msg}).Serialize(&buf, &w3g.Encoding{}); err != nil {
+			t.Fatal(err)
+		}
+		var res w3g.ChatMessage
+		if err := res.Deserialize(&buf, &w3g.Encoding{}); err != nil {
+			t.Fatal(err)
+		}
+		return &res
+	}
+
+	// Recipient list of live protocol is not stored
+	var all = w3gs.NewChatToAll(1, "gg")
+	all.RecipientIDs = []uint8{2, 3}
+	if res := roundTrip(all); res.Scope != w3gs.ScopeAll || res.RecipientIDs != nil {
+		t.Fatal("Unexpected recipients", res.Scope, res.RecipientIDs)
+	}
+
+	// Directed scope holds the slot of the recipient, not its player ID
+	var whisper = w3gs.NewChatToPlayer(1, 2, 0, "hi")
+	if res := roundTrip(whisper); res.Scope != w3gs.ScopeToSlot(0) || res.RecipientIDs != nil {
+		t.Fatal("Unexpected recipients", res.Scope, res.RecipientIDs)
+	}
+
+	// Decode resolves the recipient through the slot table (player 2 sits in slot 0)
+	replay, err := w3g.Open("./test_132.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.Slots[0].PlayerID != 2 {
+		t.Fatal("Expected player 2 in slot 0", replay.Slots[0].PlayerID)
+	}
+
+	var found = false
+	for _, r := range replay.Records {
+		var msg, ok = r.(*w3g.ChatMessage)
+		if !ok || msg.Scope != w3gs.ScopeToSlot(0) {
+			continue
+		}
+		if !reflect.DeepEqual(msg.RecipientIDs, []uint8{2}) {
+			t.Fatal("Unexpected recipients", msg.SenderID, msg.RecipientIDs)
+		}
+		found = true
+	}
+	if !found {
+		t.Fatal("Expected directed chat in test_132.w3g")
+	}
+}
+
+func TestChatFormatting(t *testing.T) {
+	var content = "|cffff0000gg|r|n"
+	var rec = w3g.ChatMessage{Message: *w3gs.NewChatToAll(2, content)}
+
+	var buf = protocol.Buffer{}
+	if err := rec.Serialize(&buf, &w3g.Encoding{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var expected = append([]byte{w3g.RidChatMessage, 2, byte(6 + len(content)), 0, byte(w3gs.MsgChatExtra), 0, 0, 0, 0}, content...)
+	expected = append(expected, 0)
+	if !bytes.Equal(buf.Bytes, expected) {
+		t.Fatalf("Unexpected serialization % X", buf.Bytes)
+	}
+
+	var res w3g.ChatMessage
+	if err := res.Deserialize(&buf, &w3g.Encoding{}); err != nil {
+		t.Fatal(err)
+	}
+	if res.Content != content {
+		t.Fatalf("Content mismatch: %q", res.Content)
+	}
+}
+
+func TestChatContent(t *testing.T) {
+	for _, typ := range []w3gs.MessageType{w3gs.MsgChat, w3gs.MsgChatExtra} {
+		var rec = w3g.ChatMessage{Message: w3gs.Message{SenderID: 1, Type: typ}}
+
+		var buf = protocol.Buffer{}
+		if err := rec.Serialize(&buf, &w3g.Encoding{}); err != nil {
+			t.Fatal(typ, err)
+		}
+		var res w3g.ChatMessage
+		if err := res.Deserialize(&buf, &w3g.Encoding{}); err != nil {
+			t.Fatal(typ, err)
+		}
+		if res.Content != "" || res.Type != typ || buf.Size() != 0 {
+			t.Fatal(typ, "Unexpected round-trip of empty content", res)
+		}
+
+		rec.Content = "gl\x00hf"
+		buf.Truncate()
+		if err := rec.Serialize(&buf, &w3g.Encoding{}); err != w3g.ErrBadFormat {
+			t.Fatal(typ, "Expected ErrBadFormat for content with null byte", err)
+		}
+
+		rec.Content = strings.Repeat("x", 0xFFFF)
+		buf.Truncate()
+		if err := rec.Serialize(&buf, &w3g.Encoding{}); err != protocol.ErrSizeOverflow || buf.Size() != 0 {
+			t.Fatal(typ, "Expected ErrSizeOverflow for oversized content", err)
+		}
+	}
+}
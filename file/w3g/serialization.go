@@ -5,6 +5,8 @@
 package w3g
 
 import (
+	"encoding/binary"
+	"errors"
 	"io"
 
 	"github.com/nielsAD/gowarcraft3/protocol"
@@ -48,7 +50,22 @@ func (enc *RecordEncoder) Write(w io.Writer, r Record) (int, error) {
 type RecordDecoder struct {
 	Encoding
 	RecordFactory
+
+	// Only return records with these IDs from Read (if not nil), others are skipped
+	// without being deserialized where their size can be determined from a fixed prefix.
+	DecodeOnly []uint8
+
+	// Keep a copy of the raw bytes of the last decoded record (see Raw)
+	KeepRaw bool
+
+	// Return a *protocol.TrailingDataError if a record with a length prefix is not fully parsed
+	Strict bool
+
+	// Unexpected constant values ignored in Lenient mode (*protocol.PacketError wrapping ConstWarning)
+	Warnings []error
+
 	buf protocol.Buffer
+	raw []byte
 }
 
 // NewRecordDecoder initialization
@@ -81,13 +98,35 @@ func (dec *RecordDecoder) Deserialize(b []byte) (Record, int, error) {
 	var err = rec.Deserialize(&dec.buf, &dec.Encoding)
 
 	var n = size - dec.buf.Size()
+	if w, ok := err.(ConstWarning); ok {
+		dec.Warnings = append(dec.Warnings, &protocol.PacketError{ID: b[0], Offset: n, Underlying: w})
+		err = nil
+	}
 	if err != nil {
-		return nil, n, err
+		return nil, n, &protocol.PacketError{ID: b[0], Offset: n, Underlying: err}
+	}
+	if dec.Strict {
+		if s := recordSize(b, &dec.Encoding); s > n {
+			return nil, n, &protocol.PacketError{ID: b[0], Offset: n, Underlying: &protocol.TrailingDataError{N: s - n}}
+		}
+	}
+
+	if dec.KeepRaw {
+		dec.raw = append(dec.raw[:0], b[:n]...)
 	}
 
 	return rec, n, nil
 }
 
+// Raw returns the raw bytes of the last record returned by Deserialize or Read (nil if KeepRaw is not set)
+// The result is only valid until the next call to Deserialize or Read.
+func (dec *RecordDecoder) Raw() []byte {
+	if !dec.KeepRaw {
+		return nil
+	}
+	return dec.raw
+}
+
 // Peeker is the interface that wraps basic Peek and Discard methods.
 //
 // There is no way to determine record size without reading the full record,
@@ -130,12 +169,35 @@ func (dec *RecordDecoder) Read(r Peeker) (Record, int, error) {
 			continue
 		}
 
+		var filter = dec.DecodeOnly != nil && !dec.decode(bytes[0])
+		if filter {
+			if n := recordSize(bytes, &dec.Encoding); n > 0 {
+				d, err := r.Discard(n)
+				skip += d
+
+				if err != nil {
+					if err == io.EOF {
+						err = io.ErrUnexpectedEOF
+					}
+					return nil, skip, err
+				}
+
+				peek = 1024
+				continue
+			}
+		}
+
 		rec, n, err := dec.Deserialize(bytes)
-		switch err {
-		case nil:
+		switch {
+		case err == nil:
 			d, err := r.Discard(n)
+			if filter && err == nil {
+				skip += d
+				peek = 1024
+				continue
+			}
 			return rec, skip + d, err
-		case io.ErrShortBuffer:
+		case errors.Is(err, io.ErrShortBuffer):
 			if peekErr != nil && peekErr != io.EOF {
 				return nil, skip, peekErr
 			}
@@ -144,11 +206,70 @@ func (dec *RecordDecoder) Read(r Peeker) (Record, int, error) {
 			}
 			peek *= 2
 		default:
+			// Stream errors take precedence, the record may have failed on incomplete data
+			if peekErr != nil && peekErr != io.EOF {
+				return nil, skip, peekErr
+			}
 			return nil, skip, err
 		}
 	}
 }
 
+func (dec *RecordDecoder) decode(rid uint8) bool {
+	for _, id := range dec.DecodeOnly {
+		if id == rid {
+			return true
+		}
+	}
+	return false
+}
+
+// recordSize returns the size of the record in b without deserializing it,
+// or -1 if it cannot be determined from a fixed size prefix.
+func recordSize(b []byte, enc *Encoding) int {
+	var rid = b[0]
+	if rid == RidChatMessage && enc.GameVersion != 0 && enc.GameVersion <= 2 {
+		rid = RidTimeSlotAck
+	}
+
+	var l = len(b)
+	switch rid {
+	case RidGameStart, RidCountDownStart, RidCountDownEnd:
+		return 5
+	case RidEndTimer:
+		return 9
+	case RidPlayerLeft:
+		return 14
+	case RidSlotInfo, RidTimeSlot, RidTimeSlot2:
+		if l < 3 {
+			return -1
+		}
+		return 3 + int(binary.LittleEndian.Uint16(b[1:]))
+	case RidChatMessage:
+		if l < 4 {
+			return -1
+		}
+		return 4 + int(binary.LittleEndian.Uint16(b[2:]))
+	case RidTimeSlotAck:
+		if l < 2 {
+			return -1
+		}
+		return 2 + int(b[1])
+	case RidDesync:
+		if l < 11 {
+			return -1
+		}
+		return 11 + int(b[10])
+	case RidPlayerExtra:
+		if l < 6 {
+			return -1
+		}
+		return 6 + int(binary.LittleEndian.Uint32(b[2:]))
+	default:
+		return -1
+	}
+}
+
 // SerializeRecord serializes r and returns its byte representation.
 func SerializeRecord(r Record, e Encoding) ([]byte, error) {
 	return NewRecordEncoder(e).Serialize(r)
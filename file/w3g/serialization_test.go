@@ -5,11 +5,17 @@
 package w3g_test
 
 import (
+	"bufio"
+	"errors"
 	"io"
+	"os"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/nielsAD/gowarcraft3/file/w3g"
 	"github.com/nielsAD/gowarcraft3/protocol"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
 )
 
 func TestDeserializeRecord(t *testing.T) {
@@ -19,11 +25,202 @@ func TestDeserializeRecord(t *testing.T) {
 	if _, _, e := w3g.DeserializeRecord([]byte{255, 0, 0, 0}, w3g.Encoding{}); e != w3g.ErrUnknownRecord {
 		t.Fatal("ErrUnknownRecord expected if invalid record ID")
 	}
-	if _, _, e := w3g.DeserializeRecord([]byte{w3g.RidCountDownEnd, 0, 0}, w3g.Encoding{}); e != io.ErrShortBuffer {
+	if _, _, e := w3g.DeserializeRecord([]byte{w3g.RidCountDownEnd, 0, 0}, w3g.Encoding{}); !errors.Is(e, io.ErrShortBuffer) {
 		t.Fatal("ErrShortBuffer expected if buffer too short")
 	}
 }
 
+func TestLenient(t *testing.T) {
+	var left = []byte{w3g.RidPlayerLeft, 0x05, 0, 0, 0, 3, 0x08, 0, 0, 0, 1, 0, 0, 0}
+	var start = []byte{w3g.RidGameStart, 0x02, 0, 0, 0}
+
+	for _, b := range [][]byte{left, start} {
+		if _, _, e := w3g.DeserializeRecord(b, w3g.Encoding{}); !errors.Is(e, w3g.ErrUnexpectedConst) {
+			t.Fatal("ErrUnexpectedConst expected if not lenient", e)
+		}
+	}
+
+	var d = w3g.NewRecordDecoder(w3g.Encoding{Lenient: true}, nil)
+	rec, n, err := d.Deserialize(left)
+	if err != nil || n != len(left) {
+		t.Fatal(err, n)
+	}
+	if l, ok := rec.(*w3g.PlayerLeft); !ok || l.PlayerID != 3 || l.Local {
+		t.Fatal("Unexpected record", rec)
+	}
+	if _, _, err := d.Deserialize(start); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.Warnings) != 2 {
+		t.Fatal("Expected 2 warnings", d.Warnings)
+	}
+	var w w3g.ConstWarning
+	if !errors.As(d.Warnings[0], &w) || !errors.Is(d.Warnings[0], w3g.ErrUnexpectedConst) || len(w) != 1 || w[0] != "PlayerLeft.Local=0x5" {
+		t.Fatal("Unexpected warning", d.Warnings[0])
+	}
+}
+
+func TestHandicap(t *testing.T) {
+	var rec = w3g.SlotInfo{SlotInfo: w3gs.SlotInfo{
+		Slots: []w3gs.SlotData{
+			w3gs.SlotData{PlayerID: 1, SlotStatus: w3gs.SlotOccupied, Handicap: 100},
+			w3gs.SlotData{PlayerID: 2, SlotStatus: w3gs.SlotOccupied, Handicap: 42},
+			w3gs.SlotData{SlotStatus: w3gs.SlotOpen, Handicap: 0},
+		},
+	}}
+	b, err := w3g.SerializeRecord(&rec, w3g.Encoding{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, _, err := w3g.DeserializeRecord(b, w3g.Encoding{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := res.(*w3g.SlotInfo).Slots[1]; s.Handicap != 42 {
+		t.Fatal("Expected handicap to be kept", s.Handicap)
+	}
+
+	var replay = w3g.Replay{SlotInfo: *res.(*w3g.SlotInfo)}
+	var found = 0
+	for _, i := range replay.Validate() {
+		if strings.Contains(i.Description, "handicap") {
+			found++
+		}
+	}
+	if found != 1 {
+		t.Fatal("Expected one invalid handicap issue", replay.Validate())
+	}
+}
+
+type customRecord struct {
+	Value uint16
+}
+
+func (r *customRecord) Serialize(buf *protocol.Buffer, enc *w3g.Encoding) error {
+	buf.WriteUInt8(0x99)
+	buf.WriteUInt16(r.Value)
+	return nil
+}
+
+func (r *customRecord) Deserialize(buf *protocol.Buffer, enc *w3g.Encoding) error {
+	if buf.Size() < 3 {
+		return io.ErrShortBuffer
+	}
+	buf.Skip(1)
+	r.Value = buf.ReadUInt16()
+	return nil
+}
+
+func TestFactoryRegister(t *testing.T) {
+	var fac = w3g.CloneDefaultFactory()
+	fac.Register(0x99, func(_ *w3g.Encoding) w3g.Record { return &customRecord{} })
+
+	if w3g.DefaultFactory.NewRecord(0x99, &w3g.Encoding{}) != nil {
+		t.Fatal("DefaultFactory modified by Register on clone")
+	}
+
+	var d = w3g.NewRecordDecoder(w3g.Encoding{}, w3g.NewFactoryCache(fac))
+	rec, n, err := d.Deserialize([]byte{0x99, 0x34, 0x12})
+	if err != nil || n != 3 {
+		t.Fatal(err, n)
+	}
+	if c, ok := rec.(*customRecord); !ok || c.Value != 0x1234 {
+		t.Fatal("Unexpected record", rec)
+	}
+
+	// Overwrite existing record type
+	fac.Register(w3g.RidCountDownEnd, func(_ *w3g.Encoding) w3g.Record { return &customRecord{} })
+	if _, ok := fac.NewRecord(w3g.RidCountDownEnd, &w3g.Encoding{}).(*customRecord); !ok {
+		t.Fatal("Expected registered constructor to take precedence")
+	}
+	if _, ok := w3g.DefaultFactory.NewRecord(w3g.RidCountDownEnd, &w3g.Encoding{}).(*w3g.CountDownEnd); !ok {
+		t.Fatal("DefaultFactory modified by Register on clone")
+	}
+}
+
+func TestStrict(t *testing.T) {
+	// Custom record that ignores the length prefix of TimeSlotAck
+	var fac = w3g.CloneDefaultFactory()
+	fac.Register(w3g.RidTimeSlotAck, func(_ *w3g.Encoding) w3g.Record { return &customRecord{} })
+
+	var b = []byte{w3g.RidTimeSlotAck, 4, 0xAA, 0xBB, 0xCC, 0xDD}
+	var d = w3g.NewRecordDecoder(w3g.Encoding{}, fac)
+	if _, n, err := d.Deserialize(b); err != nil || n != 3 {
+		t.Fatal("Expected trailing data to be accepted if not strict", err, n)
+	}
+
+	d.Strict = true
+	_, _, err := d.Deserialize(b)
+	var terr *protocol.TrailingDataError
+	if !errors.Is(err, protocol.ErrTrailingData) || !errors.As(err, &terr) || terr.N != 3 {
+		t.Fatal("Expected TrailingDataError", err)
+	}
+
+	// Next record in buffer is not trailing data
+	d = w3g.NewRecordDecoder(w3g.Encoding{}, nil)
+	d.Strict = true
+	if _, n, err := d.Deserialize([]byte{w3g.RidTimeSlotAck, 1, 0xAA, w3g.RidGameStart, 1, 0, 0, 0}); err != nil || n != 3 {
+		t.Fatal("Expected strict decode to accept full record", err, n)
+	}
+}
+
+func decodeFile(t *testing.T, name string, only []uint8) (*w3g.Header, []w3g.Record) {
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var b = bufio.NewReader(f)
+	if _, err := w3g.FindHeader(b); err != nil {
+		t.Fatal(err)
+	}
+	hdr, data, _, err := w3g.DecodeHeader(b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data.DecodeOnly = only
+
+	var res []w3g.Record
+	if err := data.ForEach(func(r w3g.Record) error {
+		res = append(res, r)
+		return nil
+	}); err != nil {
+		t.Fatal(name, err)
+	}
+	return hdr, res
+}
+
+func TestDecodeOnly(t *testing.T) {
+	var files = []string{"test_102.w3g", "test_126.w3g", "test_130.w3g", "test_132.w3g"}
+	var only = []uint8{w3g.RidChatMessage, w3g.RidPlayerLeft, w3g.RidPlayerInfo}
+
+	for _, f := range files {
+		var hdr, all = decodeFile(t, f, nil)
+
+		var expected []w3g.Record
+		for _, r := range all {
+			b, err := w3g.SerializeRecord(r, hdr.Encoding())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b[0] == only[0] || b[0] == only[1] || b[0] == only[2] {
+				expected = append(expected, r)
+			}
+		}
+
+		var _, filtered = decodeFile(t, f, only)
+		if len(filtered) == 0 {
+			t.Fatal(f, "Expected records")
+		}
+		if !reflect.DeepEqual(expected, filtered) {
+			t.Fatal(f, "Filtered records mismatch")
+		}
+	}
+}
+
 func BenchmarkEncoder(b *testing.B) {
 	var e = w3g.NewRecordEncoder(w3g.Encoding{})
 	var w = &protocol.Buffer{}
@@ -0,0 +1,160 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g
+
+import (
+	"fmt"
+
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// Severity of a ValidationIssue
+type Severity uint8
+
+// Severity levels
+const (
+	SeverityWarning Severity = iota // Replay is odd, but probably playable
+	SeverityError                   // Replay is inconsistent and likely unplayable
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "Warning"
+	case SeverityError:
+		return "Error"
+	default:
+		return fmt.Sprintf("Severity(%d)", uint8(s))
+	}
+}
+
+// ValidationIssue is a non-fatal problem found by Replay.Validate
+type ValidationIssue struct {
+	Severity    Severity
+	Description string
+}
+
+func (v ValidationIssue) String() string {
+	return v.Severity.String() + ": " + v.Description
+}
+
+// Validate performs structural sanity checks on the replay, such as whether all
+// referenced players exist. Issues are reported once per player per check.
+func (r *Replay) Validate() []ValidationIssue {
+	var res []ValidationIssue
+	var issue = func(s Severity, format string, a ...interface{}) {
+		res = append(res, ValidationIssue{Severity: s, Description: fmt.Sprintf(format, a...)})
+	}
+
+	if w3gs.ProductName(r.Product()) == "" {
+		issue(SeverityError, "Unknown game product %v", r.Product())
+	}
+
+	if r.HostPlayer.ID == 0 || r.HostPlayer.Name == "" {
+		issue(SeverityError, "Host player missing")
+	}
+
+	var players = make(map[uint8]bool, len(r.PlayerInfo))
+	for _, p := range r.PlayerInfo {
+		if players[p.ID] {
+			issue(SeverityError, "Duplicate player ID %d (%s)", p.ID, p.Name)
+		}
+		players[p.ID] = true
+	}
+
+	var occupied = 0
+	var slotted = make(map[uint8]bool, len(r.Slots))
+	for i, s := range r.Slots {
+		if s.SlotStatus != w3gs.SlotOccupied {
+			continue
+		}
+		occupied++
+		if !w3gs.ValidHandicap(s.Handicap) {
+			issue(SeverityWarning, "Slot %d has invalid handicap %d", i, s.Handicap)
+		}
+		if s.Computer {
+			continue
+		}
+		slotted[s.PlayerID] = true
+		if !players[s.PlayerID] {
+			issue(SeverityError, "Slot %d references unknown player ID %d", i, s.PlayerID)
+		}
+	}
+
+	// NumSlots is not always equal to len(Slots), but should fit all occupied slots
+	if r.NumSlots < uint32(occupied) {
+		issue(SeverityWarning, "NumSlots (%d) is less than number of occupied slots (%d)", r.NumSlots, occupied)
+	}
+
+	for _, p := range r.PlayerInfo {
+		if !slotted[p.ID] {
+			issue(SeverityWarning, "Player %d (%s) does not occupy a slot", p.ID, p.Name)
+		}
+	}
+
+	if r.IsSavedGame() {
+		issue(SeverityWarning, "Game was loaded from a saved game, records before loading are missing")
+	}
+
+	var reported = map[string]bool{}
+	var unknown = func(s Severity, what string, id uint8) {
+		var key = fmt.Sprintf("%s/%d", what, id)
+		if players[id] || reported[key] {
+			return
+		}
+		reported[key] = true
+		issue(s, "%s from unknown player ID %d", what, id)
+	}
+
+	for _, rec := range r.Records {
+		switch v := rec.(type) {
+		case *TimeSlot:
+			for _, a := range v.Actions {
+				unknown(SeverityError, "Action", a.PlayerID)
+			}
+		case *ChatMessage:
+			unknown(SeverityWarning, "Chat message", v.SenderID)
+		case *PlayerLeft:
+			unknown(SeverityWarning, "Leave", v.PlayerID)
+		}
+	}
+
+	return res
+}
+
+// ValidateRecords checks if recs is a well-formed record sequence that can be encoded as a replay:
+// GameInfo first, lobby records (PlayerInfo, SlotInfo, etc.) before GameStart, and game records
+// (TimeSlot, PlayerLeft, etc.) after GameStart. Returned errors wrap ErrInvalidSequence.
+func ValidateRecords(recs []Record) error {
+	if len(recs) == 0 {
+		return fmt.Errorf("%w: no records", ErrInvalidSequence)
+	}
+	if _, ok := recs[0].(*GameInfo); !ok {
+		return fmt.Errorf("%w: expected GameInfo as first record, found %T", ErrInvalidSequence, recs[0])
+	}
+
+	var started = false
+	for i := 1; i < len(recs); i++ {
+		switch recs[i].(type) {
+		case *GameInfo:
+			return fmt.Errorf("%w: duplicate GameInfo at index %d", ErrInvalidSequence, i)
+		case *PlayerInfo, *PlayerExtra, *SlotInfo, *CountDownStart, *CountDownEnd:
+			if started {
+				return fmt.Errorf("%w: %T after GameStart at index %d", ErrInvalidSequence, recs[i], i)
+			}
+		case *GameStart:
+			if started {
+				return fmt.Errorf("%w: duplicate GameStart at index %d", ErrInvalidSequence, i)
+			}
+			started = true
+		default:
+			if !started {
+				return fmt.Errorf("%w: %T before GameStart at index %d", ErrInvalidSequence, recs[i], i)
+			}
+		}
+	}
+
+	return nil
+}
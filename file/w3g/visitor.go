@@ -0,0 +1,143 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g
+
+// Visitor dispatches records to typed callbacks, nil callbacks are skipped
+// Records without a matching callback are passed to OnOther (if set)
+type Visitor struct {
+	OnGameInfo       func(*GameInfo)
+	OnPlayerInfo     func(*PlayerInfo)
+	OnPlayerLeft     func(*PlayerLeft)
+	OnSlotInfo       func(*SlotInfo)
+	OnCountDownStart func(*CountDownStart)
+	OnCountDownEnd   func(*CountDownEnd)
+	OnGameStart      func(*GameStart)
+	OnTimeSlot       func(*TimeSlot)
+	OnChat           func(*ChatMessage)
+	OnTimeSlotAck    func(*TimeSlotAck)
+	OnDesync         func(*Desync)
+	OnEndTimer       func(*EndTimer)
+	OnPlayerExtra    func(*PlayerExtra)
+	OnOther          func(Record)
+}
+
+// Visit dispatches r to the matching callback
+// Always returns nil, signature matches ForEach() callbacks
+func (v *Visitor) Visit(r Record) error {
+	switch rec := r.(type) {
+	case *GameInfo:
+		if v.OnGameInfo != nil {
+			v.OnGameInfo(rec)
+			return nil
+		}
+	case *PlayerInfo:
+		if v.OnPlayerInfo != nil {
+			v.OnPlayerInfo(rec)
+			return nil
+		}
+	case *PlayerLeft:
+		if v.OnPlayerLeft != nil {
+			v.OnPlayerLeft(rec)
+			return nil
+		}
+	case *SlotInfo:
+		if v.OnSlotInfo != nil {
+			v.OnSlotInfo(rec)
+			return nil
+		}
+	case *CountDownStart:
+		if v.OnCountDownStart != nil {
+			v.OnCountDownStart(rec)
+			return nil
+		}
+	case *CountDownEnd:
+		if v.OnCountDownEnd != nil {
+			v.OnCountDownEnd(rec)
+			return nil
+		}
+	case *GameStart:
+		if v.OnGameStart != nil {
+			v.OnGameStart(rec)
+			return nil
+		}
+	case *TimeSlot:
+		if v.OnTimeSlot != nil {
+			v.OnTimeSlot(rec)
+			return nil
+		}
+	case *ChatMessage:
+		if v.OnChat != nil {
+			v.OnChat(rec)
+			return nil
+		}
+	case *TimeSlotAck:
+		if v.OnTimeSlotAck != nil {
+			v.OnTimeSlotAck(rec)
+			return nil
+		}
+	case *Desync:
+		if v.OnDesync != nil {
+			v.OnDesync(rec)
+			return nil
+		}
+	case *EndTimer:
+		if v.OnEndTimer != nil {
+			v.OnEndTimer(rec)
+			return nil
+		}
+	case *PlayerExtra:
+		if v.OnPlayerExtra != nil {
+			v.OnPlayerExtra(rec)
+			return nil
+		}
+	}
+
+	if v.OnOther != nil {
+		v.OnOther(r)
+	}
+	return nil
+}
+
+// ForEach record in the replay (including game setup records) call f, in the order they are encoded
+func (r *Replay) ForEach(f func(r Record) error) error {
+	if err := f(&r.GameInfo); err != nil {
+		return err
+	}
+	for _, p := range r.PlayerInfo {
+		if p.ID == r.HostPlayer.ID {
+			// Skip host
+			continue
+		}
+		if err := f(p); err != nil {
+			return err
+		}
+	}
+	for _, p := range r.PlayerExtra {
+		if err := f(p); err != nil {
+			return err
+		}
+	}
+	for _, rec := range []Record{&r.SlotInfo, &CountDownStart{}, &CountDownEnd{}, &GameStart{}} {
+		if err := f(rec); err != nil {
+			return err
+		}
+	}
+	for _, rec := range r.Records {
+		if err := f(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Accept dispatches every record in the replay (see ForEach) to v
+func (r *Replay) Accept(v Visitor) error {
+	return r.ForEach(v.Visit)
+}
+
+// Accept dispatches every decoded record to v
+func (d *Decompressor) Accept(v Visitor) error {
+	return d.ForEach(v.Visit)
+}
@@ -51,7 +51,10 @@ package w3g
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"io"
+	"io/ioutil"
 	"os"
 
 	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
@@ -68,6 +71,7 @@ type Replay struct {
 }
 
 // Open a w3g file
+// Truncated replays are returned along with a *TruncatedError (see Decode)
 func Open(name string) (*Replay, error) {
 	f, err := os.Open(name)
 	if err != nil {
@@ -75,8 +79,13 @@ func Open(name string) (*Replay, error) {
 	}
 	defer f.Close()
 
-	var b = bufio.NewReaderSize(f, 8192)
-	if _, err := FindHeader(b); err != nil {
+	return OpenReader(f)
+}
+
+// OpenReader decodes a w3g file from r, skipping any data prepended to the header (such as in nwg files)
+func OpenReader(r io.Reader) (*Replay, error) {
+	var b = bufio.NewReaderSize(r, 8192)
+	if err := StripNWG(b); err != nil {
 		return nil, ErrBadFormat
 	}
 
@@ -84,6 +93,63 @@ func Open(name string) (*Replay, error) {
 	return rep, err
 }
 
+// OpenBytes decodes a w3g file stored in b
+func OpenBytes(b []byte) (*Replay, error) {
+	return OpenReader(bytes.NewReader(b))
+}
+
+// OpenAll decodes consecutive w3g files from r, i.e. a capture that holds multiple replays back-to-back.
+// Data before, in between and after the replays is skipped, decoding stops at the end of r.
+// Replays that fail to decode are skipped as well (truncated replays are kept), an error is only
+// returned if r does not contain any replay.
+func OpenAll(r io.Reader) ([]*Replay, error) {
+	var b = bufio.NewReaderSize(r, 8192)
+
+	var res []*Replay
+	var last error = ErrBadFormat
+	for {
+		if _, err := FindHeader(b); err != nil {
+			break
+		}
+
+		// Validate the header before consuming it, so that the search can resume
+		// right after the signature if it turns out to be invalid
+		p, _ := b.Peek(maxHeaderSize)
+		if _, _, _, err := decodeHeader(bytes.NewReader(p)); err != nil {
+			last = err
+			b.Discard(1)
+			continue
+		}
+
+		hdr, data, _, err := DecodeHeader(b, nil)
+		if err != nil {
+			last = err
+			continue
+		}
+
+		rep, err := decodeRecords(hdr, data)
+		if rep != nil {
+			res = append(res, rep)
+		}
+		if err != nil {
+			last = err
+			continue
+		}
+
+		// Skip to the end of the file as stated in the header
+		if n := data.sizeFile - int64(data.SizeRead); n > 0 {
+			if _, err := io.CopyN(ioutil.Discard, b, n); err != nil {
+				break
+			}
+		}
+	}
+
+	if len(res) == 0 {
+		return nil, last
+	}
+	return res, nil
+}
+
 // Save a w3g file
 func (r *Replay) Save(name string) error {
 	f, err := os.Create(name)
@@ -102,44 +168,76 @@ func (r *Replay) Encode(w io.Writer) error {
 		return err
 	}
 
-	if _, err := e.WriteRecord(&r.GameInfo); err != nil {
+	if err := r.ForEach(func(rec Record) error {
+		_, err := e.WriteRecord(rec)
 		return err
-	}
-	for _, p := range r.PlayerInfo {
-		if p.ID == r.HostPlayer.ID {
-			// Skip host
-			continue
-		}
-		if _, err := e.WriteRecord(p); err != nil {
-			return err
-		}
-	}
-	for _, p := range r.PlayerExtra {
-		if _, err := e.WriteRecord(p); err != nil {
-			return err
-		}
-	}
-	if _, err := e.WriteRecords(&r.SlotInfo, &CountDownStart{}, &CountDownEnd{}, &GameStart{}); err != nil {
+	}); err != nil {
 		return err
 	}
 
-	if _, err := e.WriteRecords(r.Records...); err != nil {
-		return err
+	e.Header = r.Header
+	if e.DurationMS == 0 {
+		e.DurationMS = duration(r.Records)
 	}
 
-	e.Header = r.Header
 	return e.Close()
 }
 
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// WriteTo encodes the replay to w and returns the number of bytes written.
+// If Header.DurationMS is not set, it is derived from the TimeSlot records.
+func (r *Replay) WriteTo(w io.Writer) (int64, error) {
+	var c = countWriter{w: w}
+	err := r.Encode(&c)
+	return c.n, err
+}
+
+func duration(recs []Record) uint32 {
+	var ms uint32
+	for _, rec := range recs {
+		if ts, ok := rec.(*TimeSlot); ok {
+			ms += uint32(ts.TimeIncrementMS)
+		}
+	}
+	return ms
+}
+
 // Decode a w3g file
+// If data ends unexpectedly, the partially decoded replay is returned with a *TruncatedError
 func Decode(r io.Reader) (*Replay, error) {
+	rep, _, err := DecodeWithStats(r)
+	return rep, err
+}
+
+// DecodeWithStats decodes a w3g file (see Decode) and returns statistics of the replay data
+func DecodeWithStats(r io.Reader) (*Replay, *ReplayStats, error) {
 	hdr, data, _, err := DecodeHeader(r, nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	rep, err := decodeRecords(hdr, data)
+	if rep == nil {
+		return nil, nil, err
 	}
 
+	var stats = data.Stats()
+	return rep, &stats, err
+}
+
+func decodeRecords(hdr *Header, data *Decompressor) (*Replay, error) {
 	var res = Replay{Header: *hdr}
-	if err := data.ForEach(func(r Record) error {
+	var err = data.ForEach(func(r Record) error {
 		switch v := r.(type) {
 		case *GameInfo:
 			res.GameInfo = *v
@@ -160,7 +258,8 @@ func Decode(r io.Reader) (*Replay, error) {
 			res.Records = append(res.Records, v)
 		}
 		return nil
-	}); err != nil {
+	})
+	if err != nil && !errors.Is(err, ErrTruncated) {
 		return nil, err
 	}
 
@@ -181,5 +280,20 @@ func Decode(r io.Reader) (*Replay, error) {
 		}
 	}
 
-	return &res, nil
+	// Directed chat is stored with the slot of the recipient
+	for _, r := range res.Records {
+		if msg, ok := r.(*ChatMessage); ok {
+			msg.RecipientIDs = chatRecipients(&res.SlotInfo.SlotInfo, msg.Scope)
+		}
+	}
+
+	return &res, err
+}
+
+func chatRecipients(info *w3gs.SlotInfo, scope w3gs.MessageScope) []uint8 {
+	s, ok := scope.Slot()
+	if !ok || int(s) >= len(info.Slots) || !info.Slots[s].IsHuman() {
+		return nil
+	}
+	return []uint8{info.Slots[s].PlayerID}
 }
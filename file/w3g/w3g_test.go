@@ -5,10 +5,21 @@
 package w3g_test
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/base64"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/nielsAD/gowarcraft3/file/w3g"
 	"github.com/nielsAD/gowarcraft3/protocol"
@@ -332,3 +343,1695 @@ func TestFiles(t *testing.T) {
 		}
 	}
 }
+
+func TestOpenBytes(t *testing.T) {
+	rep, err := w3g.Open("./test_130.w3g")
+	if err != nil {
+		t.Fatal("Open", err)
+	}
+
+	b, err := ioutil.ReadFile("./test_130.w3g")
+	if err != nil {
+		t.Fatal("ReadFile", err)
+	}
+
+	// Prepend garbage to verify the header is searched for
+	rep2, err := w3g.OpenBytes(append([]byte("nwg"), b...))
+	if err != nil {
+		t.Fatal("OpenBytes", err)
+	}
+
+	if !reflect.DeepEqual(rep, rep2) {
+		t.Fatal("Replays not deep equal after OpenBytes")
+	}
+
+	if _, err := w3g.OpenBytes(b[:100]); err == nil {
+		t.Fatal("Expected error for truncated replay")
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	b, err := ioutil.ReadFile("./test_130.w3g")
+	if err != nil {
+		t.Fatal("ReadFile", err)
+	}
+
+	rep, err := w3g.OpenBytes(b[:len(b)/2])
+	if !errors.Is(err, w3g.ErrTruncated) {
+		t.Fatal("Expected ErrTruncated", err)
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatal("Expected underlying ErrUnexpectedEOF", err)
+	}
+
+	var trunc *w3g.TruncatedError
+	if !errors.As(err, &trunc) || trunc.Records == 0 {
+		t.Fatal("Expected TruncatedError", err)
+	}
+	if rep == nil || rep.GameName == "" || len(rep.PlayerInfo) == 0 || len(rep.Records) == 0 {
+		t.Fatal("Expected partial replay")
+	}
+
+	full, err := w3g.OpenBytes(b)
+	if err != nil {
+		t.Fatal("OpenBytes", err)
+	}
+	if len(rep.Records) >= len(full.Records) || !reflect.DeepEqual(rep.Records, full.Records[:len(rep.Records)]) {
+		t.Fatal("Expected records to match prefix of full replay")
+	}
+}
+
+func TestLazyActions(t *testing.T) {
+	for _, file := range []string{"./test_102.w3g", "./test_126.w3g", "./test_130.w3g", "./test_132.w3g"} {
+		full, err := w3g.Open(file)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		_, data, _, err := w3g.DecodeHeader(f, nil)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+		data.LazyActions = true
+
+		var timeSlots []*w3g.TimeSlot
+		if err := data.ForEach(func(r w3g.Record) error {
+			if ts, ok := r.(*w3g.TimeSlot); ok {
+				ts.Materialize()
+				timeSlots = append(timeSlots, ts)
+			}
+			return nil
+		}); err != nil {
+			t.Fatal(file, err)
+		}
+		f.Close()
+
+		var i int
+		for _, r := range full.Records {
+			ts, ok := r.(*w3g.TimeSlot)
+			if !ok {
+				continue
+			}
+			if i >= len(timeSlots) || !reflect.DeepEqual(ts, timeSlots[i]) {
+				t.Fatalf("%s: TimeSlot %d not equal after lazy decoding", file, i)
+			}
+			i++
+		}
+		if i != len(timeSlots) {
+			t.Fatalf("%s: Unexpected number of time slots %d, expected %d", file, len(timeSlots), i)
+		}
+	}
+}
+
+func TestEachBlock(t *testing.T) {
+	for _, file := range []string{"./test_102.w3g", "./test_126.w3g", "./test_130.w3g", "./test_132.w3g"} {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		var data []byte
+		var blocks []w3g.BlockInfo
+		if err := w3g.EachBlock(bytes.NewReader(b), func(info w3g.BlockInfo) error {
+			if !info.OK() {
+				t.Fatal(file, "Unexpected damaged block", info.Index, info.Err)
+			}
+			data = append(data, info.Decompressed...)
+			blocks = append(blocks, info)
+			return nil
+		}); err != nil {
+			t.Fatal(file, err)
+		}
+		if len(blocks) < 2 {
+			t.Fatal(file, "Expected multiple blocks")
+		}
+
+		hdr, d, _, err := w3g.DecodeHeader(bytes.NewReader(b), nil)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+		var size = d.SizeTotal
+		dec, err := ioutil.ReadAll(d)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+		if uint32(len(dec)) != size || !bytes.HasPrefix(data, dec) {
+			t.Fatal(file, "Decompressed blocks mismatch", hdr.GameVersion)
+		}
+
+		// Corrupt compressed data of the second block
+		var dmg = append([]byte(nil), b...)
+		var off = blocks[1].Offset + 20
+		dmg[off] ^= 0xFF
+
+		var bad []int
+		if err := w3g.EachBlock(bytes.NewReader(dmg), func(info w3g.BlockInfo) error {
+			if !info.OK() {
+				bad = append(bad, info.Index)
+			}
+			return nil
+		}); err != nil {
+			t.Fatal(file, err)
+		}
+		if len(bad) != 1 || bad[0] != 1 {
+			t.Fatal(file, "Expected second block to be damaged", bad)
+		}
+
+		// Truncated in block header
+		var n = 0
+		if err := w3g.EachBlock(bytes.NewReader(b[:blocks[2].Offset+2]), func(info w3g.BlockInfo) error {
+			n++
+			return nil
+		}); err != io.ErrUnexpectedEOF || n != 2 {
+			t.Fatal(file, "Expected ErrUnexpectedEOF after 2 blocks", err, n)
+		}
+
+		// Truncated in block data
+		var last w3g.BlockInfo
+		if err := w3g.EachBlock(bytes.NewReader(b[:blocks[2].Offset+40]), func(info w3g.BlockInfo) error {
+			last = info
+			return nil
+		}); err != io.ErrUnexpectedEOF || last.Index != 2 || last.Err != io.ErrUnexpectedEOF || last.OK() {
+			t.Fatal(file, "Expected incomplete third block", err, last.Index, last.Err)
+		}
+	}
+}
+
+func TestCompressionHeader(t *testing.T) {
+	for _, file := range []string{"./test_126.w3g", "./test_132.w3g"} {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		var blocks []w3g.BlockInfo
+		if err := w3g.EachBlock(bytes.NewReader(b), func(info w3g.BlockInfo) error {
+			info.Data = append([]byte(nil), info.Data...)
+			blocks = append(blocks, info)
+			return nil
+		}); err != nil {
+			t.Fatal(file, err)
+		}
+
+		// Replace zlib header of second block (and fix data checksum)
+		var damage = func(cmf byte, flg byte) []byte {
+			var dmg = append([]byte(nil), b...)
+			var off = blocks[2].Offset - int64(len(blocks[1].Data))
+			dmg[off], dmg[off+1] = cmf, flg
+
+			var crc = crc32.ChecksumIEEE(dmg[off : off+int64(len(blocks[1].Data))])
+			binary.LittleEndian.PutUint16(dmg[off-2:], uint16(crc^crc>>16))
+			return dmg
+		}
+
+		var flg = byte(0)
+		for (0x79<<8|uint16(flg))%31 != 0 {
+			flg++
+		}
+
+		for _, dmg := range [][]byte{
+			damage(0x79, flg),  // Compression method 9
+			damage(0x78, 0x20), // Preset dictionary
+		} {
+			if _, err := w3g.Decode(bytes.NewReader(dmg)); !errors.Is(err, w3g.ErrCompression) {
+				t.Fatal(file, "Expected ErrCompression", err)
+			}
+
+			var errs = map[int]error{}
+			if err := w3g.EachBlock(bytes.NewReader(dmg), func(info w3g.BlockInfo) error {
+				errs[info.Index] = info.Err
+				return nil
+			}); err != nil {
+				t.Fatal(file, err)
+			}
+			if errs[0] != nil || errs[1] != w3g.ErrCompression || errs[2] != nil {
+				t.Fatal(file, "Expected ErrCompression in second block", errs)
+			}
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	for _, file := range []string{"./test_102.w3g", "./test_126.w3g", "./test_130.w3g", "./test_132.w3g"} {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		rep, stats, err := w3g.DecodeWithStats(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		var blocks, size int
+		if err := w3g.EachBlock(bytes.NewReader(b), func(info w3g.BlockInfo) error {
+			blocks++
+			size += info.SizeActual
+			return nil
+		}); err != nil {
+			t.Fatal(file, err)
+		}
+		if int(stats.NumBlocks) != blocks || int(stats.SizeDecompressed) > size || int(stats.SizeCompressed) >= len(b) {
+			t.Fatal(file, "Unexpected block stats", stats)
+		}
+
+		var timeSlots, actionBytes int
+		for _, r := range rep.Records {
+			if ts, ok := r.(*w3g.TimeSlot); ok {
+				timeSlots++
+				for _, a := range ts.Actions {
+					actionBytes += len(a.Data)
+				}
+			}
+		}
+		if stats.Records["TimeSlot"] != timeSlots || stats.ActionBytes != actionBytes || stats.Records["GameInfo"] != 1 {
+			t.Fatal(file, "Unexpected record stats", stats)
+		}
+
+		// Sizes are known up front
+		_, data, _, err := w3g.DecodeHeader(bytes.NewReader(b), nil)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+		data.Limit.Records = 1
+		if err := data.ForEach(func(w3g.Record) error { return nil }); err != nil {
+			t.Fatal(file, err)
+		}
+		var part = data.Stats()
+		if len(part.Records) != 1 || part.Records["GameInfo"] != 1 || part.NumBlocks != stats.NumBlocks || part.SizeCompressed != stats.SizeCompressed {
+			t.Fatal(file, "Unexpected partial stats", part)
+		}
+	}
+}
+
+func TestKeepRaw(t *testing.T) {
+	for _, file := range []string{"./test_102.w3g", "./test_126.w3g", "./test_130.w3g", "./test_132.w3g"} {
+		full, err := w3g.Open(file)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		hdr, data, _, err := w3g.DecodeHeader(f, nil)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+		data.KeepRaw = true
+
+		var b protocol.Buffer
+		e, err := w3g.NewEncoder(&b, hdr.Encoding())
+		if err != nil {
+			t.Fatal(file, err)
+		}
+		e.Header = *hdr
+
+		var raw []byte
+		if err := data.ForEach(func(_ w3g.Record) error {
+			raw = append(raw, data.Raw()...)
+			_, err := e.WriteRawRecord(data.Raw())
+			return err
+		}); err != nil {
+			t.Fatal(file, err)
+		}
+		f.Close()
+
+		if err := e.Close(); err != nil {
+			t.Fatal(file, err)
+		}
+
+		rep, err := w3g.Decode(&b)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+		if !reflect.DeepEqual(full.Records, rep.Records) {
+			t.Fatal(file, "Records not deep equal after WriteRawRecord")
+		}
+
+		// Raw bytes must match the decompressed stream (up to padding)
+		f, err = os.Open(file)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+		_, data, _, err = w3g.DecodeHeader(f, nil)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+		stream, err := ioutil.ReadAll(data)
+		f.Close()
+		if err != nil {
+			t.Fatal(file, err)
+		}
+		if !bytes.HasPrefix(stream, raw) || len(bytes.Trim(stream[len(raw):], "\x00")) != 0 {
+			t.Fatal(file, "Raw bytes do not match decompressed stream")
+		}
+	}
+
+	var dec = w3g.NewRecordDecoder(w3g.Encoding{}, nil)
+	if _, _, err := dec.Deserialize([]byte{w3g.RidGameStart, 1, 0, 0, 0}); err != nil || dec.Raw() != nil {
+		t.Fatal("Expected no raw bytes if KeepRaw is not set", err)
+	}
+}
+
+func TestDecodeLimit(t *testing.T) {
+	file, err := ioutil.ReadFile("./test_130.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decode = func(lim w3g.DecodeLimit, only []uint8) (*w3g.Decompressor, []w3g.Record, time.Duration) {
+		_, data, _, err := w3g.DecodeHeader(bytes.NewReader(file), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data.Limit = lim
+		data.DecodeOnly = only
+
+		var recs []w3g.Record
+		var ms time.Duration
+		if err := data.ForEach(func(r w3g.Record) error {
+			recs = append(recs, r)
+			if ts, ok := r.(*w3g.TimeSlot); ok {
+				ms += time.Duration(ts.TimeIncrementMS) * time.Millisecond
+			}
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return data, recs, ms
+	}
+
+	data, recs, _ := decode(w3g.DecodeLimit{}, nil)
+	if data.Limited() {
+		t.Fatal("Expected no limit")
+	}
+	var total = len(recs)
+
+	data, recs, _ = decode(w3g.DecodeLimit{Records: 100}, nil)
+	if len(recs) != 100 || !data.Limited() {
+		t.Fatal("Expected 100 records", len(recs))
+	}
+
+	// Continue after raising limit
+	data.Limit.Records = 0
+	var n = 0
+	if err := data.ForEach(func(r w3g.Record) error { n++; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if n+100 != total || data.Limited() {
+		t.Fatal("Expected remaining records after raising limit", n)
+	}
+
+	data, recs, ms := decode(w3g.DecodeLimit{Time: 30 * time.Second}, nil)
+	if !data.Limited() || ms < 30*time.Second || ms > 31*time.Second || len(recs) >= total {
+		t.Fatal("Expected 30 seconds of records", ms, len(recs))
+	}
+	if _, ok := recs[len(recs)-1].(*w3g.TimeSlot); !ok {
+		t.Fatal("Expected to stop after time slot")
+	}
+
+	// Time slots are used to track time, but filtered from the result
+	data, recs, _ = decode(w3g.DecodeLimit{Time: 30 * time.Second}, []uint8{w3g.RidChatMessage, w3g.RidPlayerLeft})
+	if !data.Limited() || data.DecodeOnly[len(data.DecodeOnly)-1] != w3g.RidPlayerLeft {
+		t.Fatal("Expected limited decode with filter restored")
+	}
+	for _, r := range recs {
+		if _, ok := r.(*w3g.TimeSlot); ok {
+			t.Fatal("Expected time slots to be filtered")
+		}
+	}
+}
+
+func TestProgress(t *testing.T) {
+	for _, file := range []string{"./test_102.w3g", "./test_126.w3g", "./test_130.w3g", "./test_132.w3g"} {
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		_, data, _, err := w3g.DecodeHeader(f, nil)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		var calls = 0
+		var numBlocks = int(data.NumBlocks)
+		var read, total int64
+		data.Progress = func(r, n int64) {
+			calls++
+			if r < read {
+				t.Errorf("%s: bytesRead decreased from %d to %d", file, read, r)
+			}
+			read, total = r, n
+		}
+		if err := data.ForEach(func(w3g.Record) error { return nil }); err != nil {
+			t.Fatal(file, err)
+		}
+		f.Close()
+
+		if calls != numBlocks || read != total || total <= 0 {
+			t.Fatalf("%s: Unexpected progress (calls: %d/%d, read: %d/%d)", file, calls, numBlocks, read, total)
+		}
+	}
+
+	f, err := os.Open("./test_130.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	hdr, data, _, err := w3g.DecodeHeader(f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var total int64
+	var stream = w3g.NewDecompressor(f, hdr.Encoding(), nil, data.NumBlocks, data.SizeTotal)
+	stream.Progress = func(_, n int64) { total = n }
+	if err := stream.ForEach(func(w3g.Record) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if total != -1 {
+		t.Fatal("Expected unknown total for streaming decode", total)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	b, err := ioutil.ReadFile("./test_130.w3g")
+	if err != nil {
+		t.Fatal("ReadFile", err)
+	}
+
+	var pre = bytes.Repeat([]byte("nwg"), 1000)
+	var nwg = append(append([]byte(nil), pre...), b...)
+
+	var tests = []struct {
+		data   []byte
+		format w3g.Format
+		header []byte
+	}{
+		{b, w3g.FormatW3G, nil},
+		{nwg, w3g.FormatNWG, pre},
+		{pre, w3g.FormatUnknown, nil},
+		{nil, w3g.FormatUnknown, nil},
+	}
+
+	for _, tc := range tests {
+		var r = bufio.NewReaderSize(bytes.NewReader(tc.data), 8192)
+		f, err := w3g.DetectFormat(r)
+		if f != tc.format || (err != nil && len(tc.data) > 0) {
+			t.Fatalf("DetectFormat: expected %v, got %v (%v)", tc.format, f, err)
+		}
+
+		hdr, err := w3g.ReadNWGHeader(r)
+		if tc.format == w3g.FormatUnknown {
+			if err == nil {
+				t.Fatal("ReadNWGHeader: expected error for unknown format")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal("ReadNWGHeader", err)
+		}
+		if !bytes.Equal(hdr, tc.header) {
+			t.Fatalf("ReadNWGHeader: header mismatch (%d bytes)", len(hdr))
+		}
+		if f, err := w3g.DetectFormat(r); f != w3g.FormatW3G || err != nil {
+			t.Fatal("Expected w3g format after ReadNWGHeader", f, err)
+		}
+	}
+}
+
+func TestOpenConcurrent(t *testing.T) {
+	var files = []string{"./test_102.w3g", "./test_126.w3g", "./test_130.w3g", "./test_132.w3g"}
+
+	var ref = make([]*w3g.Replay, len(files))
+	for i, f := range files {
+		rep, err := w3g.Open(f)
+		if err != nil {
+			t.Fatal(f, err)
+		}
+		ref[i] = rep
+	}
+
+	var wg sync.WaitGroup
+	var res = make([]*w3g.Replay, len(files)*4)
+	var errs = make([]error, len(res))
+	for i := range res {
+		wg.Add(1)
+		go func(i int) {
+			res[i], errs[i] = w3g.Open(files[i%len(files)])
+			wg.Done()
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range res {
+		if errs[i] != nil {
+			t.Fatal(files[i%len(files)], errs[i])
+		}
+		if !reflect.DeepEqual(res[i], ref[i%len(files)]) {
+			t.Fatalf("Replay %s not deep equal when decoded concurrently", files[i%len(files)])
+		}
+	}
+}
+
+func TestActions(t *testing.T) {
+	var files = []string{"./test_102.w3g", "./test_126.w3g", "./test_130.w3g", "./test_132.w3g"}
+	for _, f := range files {
+		rep, err := w3g.Open(f)
+		if err != nil {
+			t.Fatal(f, err)
+		}
+
+		var enc = rep.Encoding().Encoding
+		var targets = 0
+		for _, r := range rep.Records {
+			ts, ok := r.(*w3g.TimeSlot)
+			if !ok {
+				continue
+			}
+			for _, a := range ts.Actions {
+				acts, err := a.Actions(enc)
+				if err != nil {
+					t.Fatal(f, err)
+				}
+				for _, act := range acts {
+					if p, ok := act.(*w3gs.AbilityTargetPos); ok && p.HasTarget {
+						targets++
+					}
+				}
+
+				b, err := w3gs.SerializeActions(acts, enc)
+				if err != nil {
+					t.Fatal(f, err)
+				}
+				if !bytes.Equal(b, a.Data) {
+					t.Fatalf("%s: Action data not equal after encoding", f)
+				}
+			}
+		}
+
+		if targets == 0 {
+			t.Fatalf("%s: Expected targeted abilities", f)
+		}
+	}
+}
+
+func TestBuildOrder(t *testing.T) {
+	rep, err := w3g.Open("./test_130.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected = []struct {
+		kind w3g.BuildKind
+		name string
+	}{
+		{w3g.TrainUnit, "Peasant"},
+		{w3g.TrainUnit, "Peasant"},
+		{w3g.BuildStructure, "Farm"},
+		{w3g.BuildStructure, "Altar of Kings"},
+		{w3g.BuildStructure, "Farm"},
+		{w3g.BuildStructure, "Barracks"},
+	}
+
+	var bo = rep.BuildOrder(rep.HostPlayer.ID, len(expected))
+	if len(bo) != len(expected) {
+		t.Fatalf("Expected %d events, got %d", len(expected), len(bo))
+	}
+	for i, e := range expected {
+		if bo[i].Kind != e.kind || bo[i].Name() != e.name {
+			t.Fatalf("Unexpected build event %d: %v %v", i, bo[i].Kind, bo[i].Name())
+		}
+		if i > 0 && bo[i].GameTime < bo[i-1].GameTime {
+			t.Fatal("Build events out of order")
+		}
+	}
+
+	var all = rep.BuildOrder(rep.HostPlayer.ID, 0)
+	if len(all) <= len(bo) {
+		t.Fatal("Expected more events without limit")
+	}
+	for _, e := range all {
+		if e.ItemID.Type() == w3gs.ObjectAbility || !e.ItemID.IsObject() {
+			t.Fatal("Unexpected build event", e.ItemID)
+		}
+		if (e.ItemID.Type() == w3gs.ObjectUnknown) != (e.Kind == w3g.BuildOther) {
+			t.Fatal("Unexpected build kind", e.Kind, e.ItemID)
+		}
+	}
+}
+
+func TestHotkeyUsage(t *testing.T) {
+	rep, err := w3g.Open("./test_130.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var h = rep.HotkeyUsage(rep.HostPlayer.ID)
+	if h.Assign[0] != 20 || h.Select[0] != 138 || len(h.Events) != 429 {
+		t.Fatal("Unexpected hotkey usage", h.Assign, h.Select, len(h.Events))
+	}
+
+	var assign, sel [10]int
+	for i, e := range h.Events {
+		if e.Assign {
+			assign[e.Group]++
+		} else {
+			sel[e.Group]++
+		}
+		if i > 0 && e.GameTime < h.Events[i-1].GameTime {
+			t.Fatal("Hotkey events out of order")
+		}
+	}
+	if assign != h.Assign || sel != h.Select {
+		t.Fatal("Hotkey counts do not match events")
+	}
+
+	if h := rep.HotkeyUsage(100); len(h.Events) != 0 {
+		t.Fatal("Expected no events for unknown player")
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	rep, err := w3g.Open("./test_132.w3g")
+	if err != nil {
+		t.Fatal("Open", err)
+	}
+
+	var b protocol.Buffer
+	n, err := rep.WriteTo(&b)
+	if err != nil {
+		t.Fatal("WriteTo", err)
+	}
+	if n != int64(b.Size()) {
+		t.Fatalf("WriteTo returned %d, but wrote %d bytes", n, b.Size())
+	}
+
+	rep2, err := w3g.Decode(&b)
+	if err != nil {
+		t.Fatal("Decode", err)
+	}
+	if !reflect.DeepEqual(rep, rep2) {
+		t.Fatal("Replays not deep equal after WriteTo/Decode")
+	}
+
+	rep.DurationMS = 0
+	rep.Records = []w3g.Record{
+		&w3g.TimeSlot{TimeSlot: w3gs.TimeSlot{TimeIncrementMS: 100}},
+		&w3g.TimeSlot{TimeSlot: w3gs.TimeSlot{TimeIncrementMS: 50}},
+	}
+
+	b.Truncate()
+	if _, err := rep.WriteTo(&b); err != nil {
+		t.Fatal("WriteTo", err)
+	}
+	rep2, err = w3g.Decode(&b)
+	if err != nil {
+		t.Fatal("Decode", err)
+	}
+	if rep2.DurationMS != 150 {
+		t.Fatalf("Expected duration to be derived from time slots, got %d", rep2.DurationMS)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	var seen = map[[32]byte]string{}
+	for _, file := range []string{"./test_102.w3g", "./test_126.w3g", "./test_130.w3g", "./test_132.w3g"} {
+		rep, err := w3g.Open(file)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+		var fp = rep.Fingerprint()
+		if f, ok := seen[fp]; ok {
+			t.Fatalf("%s: Same fingerprint as %s", file, f)
+		}
+		seen[fp] = file
+	}
+
+	rep, err := w3g.Open("./test_132.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Pinned to detect accidental changes, increment FingerprintVersion if intended
+	var fp = rep.Fingerprint()
+	if s := fmt.Sprintf("%x", fp); s != "320f9f4890067549fa5141bd3035bcb6ce816f3c000a845f98b4494f5dd48fde" {
+		t.Fatal("Unexpected fingerprint", s)
+	}
+
+	var b protocol.Buffer
+	if _, err := rep.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	rep2, err := w3g.Decode(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rep2.DurationMS++
+	rep2.BuildNumber++
+	rep2.Records = append(rep2.Records,
+		&w3g.ChatMessage{Message: *w3gs.NewChatToAll(1, "gg")},
+		&w3g.PlayerLeft{PlayerID: 1, Reason: w3gs.LeaveLost},
+	)
+	if rep2.Fingerprint() != fp {
+		t.Fatal("Fingerprint changed by excluded fields")
+	}
+
+	rep2.Records = append(rep2.Records, &w3g.TimeSlot{TimeSlot: w3gs.TimeSlot{TimeIncrementMS: 100}})
+	if rep2.Fingerprint() == fp {
+		t.Fatal("Expected fingerprint to change by TimeSlot")
+	}
+}
+
+func TestPlayback(t *testing.T) {
+	rep, err := w3g.Open("./test_132.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	var ms time.Duration
+	for _, r := range rep.Records {
+		switch v := r.(type) {
+		case *w3g.TimeSlot:
+			ms += time.Duration(v.TimeIncrementMS) * time.Millisecond
+		case *w3g.PlayerLeft, *w3g.ChatMessage, *w3g.Desync:
+		default:
+			continue
+		}
+		n++
+	}
+
+	var pb = rep.Playback()
+	if len(pb) != n || n == 0 {
+		t.Fatalf("Expected %d packets, got %d", n, len(pb))
+	}
+	if pb[len(pb)-1].GameTime != ms {
+		t.Fatalf("Expected game time %v, got %v", ms, pb[len(pb)-1].GameTime)
+	}
+
+	for _, tp := range pb {
+		switch v := tp.Record.(type) {
+		case *w3g.TimeSlot:
+			if tp.Packet != &v.TimeSlot || tp.Delay != time.Duration(v.TimeIncrementMS)*time.Millisecond {
+				t.Fatal("Unexpected packet for TimeSlot", tp)
+			}
+		case *w3g.ChatMessage:
+			if p, ok := tp.Packet.(*w3gs.MessageRelay); !ok || p.Content != v.Content || tp.Delay != 0 {
+				t.Fatal("Unexpected packet for ChatMessage", tp)
+			}
+		case *w3g.PlayerLeft:
+			if p, ok := tp.Packet.(*w3gs.PlayerLeft); !ok || p.PlayerID != v.PlayerID || p.Reason != v.Reason {
+				t.Fatal("Unexpected packet for PlayerLeft", tp)
+			}
+		}
+	}
+
+	if _, _, ok := w3g.PlaybackPacket(&w3g.SlotInfo{}); ok {
+		t.Fatal("Expected no packet for SlotInfo")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	rep, err := w3g.Open("./test_132.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m, err := w3g.Merge(rep, rep); err != nil || !reflect.DeepEqual(m.Records, rep.Records) || m.DurationMS != rep.DurationMS {
+		t.Fatal("Expected merge with self to be identical", err)
+	}
+
+	// a: recording that ends halfway and misses every other action in the first half, b: complete recording
+	var half = len(rep.Records) / 2
+	var a = *rep
+	a.Records = append([]w3g.Record(nil), rep.Records[:half]...)
+
+	var missing = 0
+	for i := range a.Records {
+		ts, ok := a.Records[i].(*w3g.TimeSlot)
+		if !ok {
+			continue
+		}
+		var cpy = w3g.TimeSlot{}
+		cpy.Fragment = ts.Fragment
+		cpy.TimeIncrementMS = ts.TimeIncrementMS
+		for k, act := range ts.Actions {
+			if k%2 == 1 {
+				cpy.Actions = append(cpy.Actions, act)
+			} else if k+1 < len(ts.Actions) {
+				missing++
+			}
+		}
+		a.Records[i] = &cpy
+	}
+	if missing == 0 {
+		t.Fatal("Expected actions to be removed before others")
+	}
+
+	var b = *rep
+
+	m, err := w3g.Merge(&a, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m.Records, rep.Records) || m.DurationMS != rep.DurationMS {
+		t.Fatal("Expected merged records to match original")
+	}
+
+	var buf bytes.Buffer
+	if err := m.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	dec, err := w3g.OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dec.Records, rep.Records) {
+		t.Fatal("Expected merged replay to decode")
+	}
+
+	var other = *rep
+	other.GameInfo.GameName += "x"
+	if _, err := w3g.Merge(rep, &other); !errors.Is(err, w3g.ErrReplayMismatch) {
+		t.Fatal("Expected ErrReplayMismatch for different game name", err)
+	}
+
+	other = *rep
+	other.PlayerInfo = rep.PlayerInfo[1:]
+	if _, err := w3g.Merge(rep, &other); !errors.Is(err, w3g.ErrReplayMismatch) {
+		t.Fatal("Expected ErrReplayMismatch for different players", err)
+	}
+}
+
+func TestEncodeRecords(t *testing.T) {
+	for _, file := range []string{"./test_102.w3g", "./test_126.w3g", "./test_130.w3g", "./test_132.w3g"} {
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatal("Open", err)
+		}
+
+		hdr, data, _, err := w3g.DecodeHeader(f, nil)
+		if err != nil {
+			t.Fatal("DecodeHeader", err)
+		}
+
+		var recs []w3g.Record
+		if err := data.ForEach(func(r w3g.Record) error {
+			recs = append(recs, r)
+			return nil
+		}); err != nil {
+			t.Fatal("ForEach", err)
+		}
+		f.Close()
+
+		if err := w3g.ValidateRecords(recs); err != nil {
+			t.Fatal(file, err)
+		}
+
+		var b protocol.Buffer
+		if err := w3g.EncodeRecords(&b, *hdr, recs, hdr.Encoding()); err != nil {
+			t.Fatal("EncodeRecords", err)
+		}
+
+		rep, err := w3g.Open(file)
+		if err != nil {
+			t.Fatal("Open", err)
+		}
+		rep2, err := w3g.Decode(&b)
+		if err != nil {
+			t.Fatal("Decode", err)
+		}
+		if !reflect.DeepEqual(rep, rep2) {
+			t.Fatal(file, "Replays not deep equal after EncodeRecords/Decode")
+		}
+	}
+
+	var gi w3g.GameInfo
+	var ts w3g.TimeSlot
+	for _, recs := range [][]w3g.Record{
+		nil,
+		{&ts},
+		{&gi, &gi},
+		{&gi, &ts},
+		{&gi, &w3g.GameStart{}, &w3g.SlotInfo{}},
+		{&gi, &w3g.GameStart{}, &w3g.GameStart{}},
+	} {
+		if err := w3g.EncodeRecords(&protocol.Buffer{}, w3g.Header{}, recs, w3g.Encoding{}); !errors.Is(err, w3g.ErrInvalidSequence) {
+			t.Fatal("Expected ErrInvalidSequence", err)
+		}
+	}
+}
+
+func TestAccept(t *testing.T) {
+	replay, err := w3g.Open("./test_130.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var all []w3g.Record
+	replay.ForEach(func(r w3g.Record) error {
+		all = append(all, r)
+		return nil
+	})
+	if len(all) != len(replay.Records)+len(replay.PlayerInfo)-1+len(replay.PlayerExtra)+5 {
+		t.Fatalf("Unexpected number of records in ForEach: %d", len(all))
+	}
+
+	var slots, timeSlots, left, other int
+	var visited []w3g.Record
+	if err := replay.Accept(w3g.Visitor{
+		OnSlotInfo:   func(r *w3g.SlotInfo) { slots++; visited = append(visited, r) },
+		OnTimeSlot:   func(r *w3g.TimeSlot) { timeSlots++; visited = append(visited, r) },
+		OnPlayerLeft: func(r *w3g.PlayerLeft) { left++; visited = append(visited, r) },
+		OnOther:      func(r w3g.Record) { other++; visited = append(visited, r) },
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if slots != 1 || timeSlots == 0 || left == 0 {
+		t.Fatalf("Unexpected visit counts (slots: %d, timeslots: %d, left: %d)", slots, timeSlots, left)
+	}
+	if !reflect.DeepEqual(all, visited) {
+		t.Fatal("Accept did not visit records in order")
+	}
+
+	var n int
+	if err := replay.Accept(w3g.Visitor{OnChat: func(*w3g.ChatMessage) { n++ }}); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatalf("Unexpected number of chat messages: %d", n)
+	}
+}
+
+func TestPlayers(t *testing.T) {
+	replay, err := w3g.Open("./test_132.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, ok := replay.PlayerByID(3)
+	if !ok {
+		t.Fatal("Expected player 3 to be resolved")
+	}
+	if p.Name != "Серник#26" || p.BattleTag != "Серник#2653" || p.Clan != "clan" || p.Race != w3gs.RaceUndead || p.Team != 1 || p.Color != 1 || p.Observer {
+		t.Fatalf("Unexpected resolved player: %+v", p)
+	}
+	if p, ok := replay.PlayerByID(1); !ok || !p.Observer {
+		t.Fatal("Expected player 1 to be an observer")
+	}
+	if _, ok := replay.PlayerByID(4); ok {
+		t.Fatal("Expected player 4 to not exist")
+	}
+
+	if obs := replay.Observers(); len(obs) != 1 || obs[0].ID != 1 || obs[0].Name != "Blizzard" {
+		t.Fatalf("Unexpected observers: %v", obs)
+	}
+
+	var teams = replay.Teams()
+	if len(teams) != 2 || len(teams[0]) != 1 || len(teams[1]) != 1 || teams[0][0].ID != 2 {
+		t.Fatalf("Unexpected teams: %v", teams)
+	}
+
+	replay, err = w3g.Open("./test_130.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obs := replay.Observers(); len(obs) != 0 {
+		t.Fatalf("Unexpected observers: %v", obs)
+	}
+
+	teams = replay.Teams()
+	if len(teams[1]) != 1 || !teams[1][0].Computer || teams[1][0].AI != w3gs.ComputerNormal {
+		t.Fatalf("Expected computer player in team 1: %v", teams)
+	}
+}
+
+func TestRandomSeed(t *testing.T) {
+	for _, file := range []string{"./test_102.w3g", "./test_126.w3g", "./test_130.w3g", "./test_132.w3g"} {
+		replay, err := w3g.Open(file)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		var seed = replay.RandomSeed
+		if seed == 0 {
+			t.Fatal(file, "Expected random seed")
+		}
+
+		// Sanitize color normalization must not clobber the seed
+		for i := range replay.Slots {
+			replay.Slots[i].Color = uint8(len(replay.Slots) - i)
+		}
+		replay.SlotInfo.NormalizeColors(replay.MaxPlayers())
+
+		var b bytes.Buffer
+		if err := replay.Encode(&b); err != nil {
+			t.Fatal(file, err)
+		}
+		res, err := w3g.Decode(&b)
+		if err != nil {
+			t.Fatal(file, err)
+		}
+		if res.RandomSeed != seed {
+			t.Fatal(file, "Random seed changed after sanitize", seed, res.RandomSeed)
+		}
+	}
+}
+
+func TestMatchup(t *testing.T) {
+	var files = map[string]string{
+		"./test_102.w3g": "1v1",
+		"./test_126.w3g": "1v1",
+		"./test_130.w3g": "1v1",
+		"./test_132.w3g": "1v1",
+	}
+	for file, matchup := range files {
+		replay, err := w3g.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m := replay.Matchup(); m != matchup || replay.IsFFA() {
+			t.Fatalf("Unexpected matchup for %s: %s (FFA: %v)", file, m, replay.IsFFA())
+		}
+	}
+
+	var replay w3g.Replay
+	replay.Slots = []w3gs.SlotData{
+		{PlayerID: 1, SlotStatus: w3gs.SlotOccupied, Team: 0},
+		{PlayerID: 2, SlotStatus: w3gs.SlotOccupied, Team: 1},
+		{SlotStatus: w3gs.SlotOccupied, Computer: true, Team: 2},
+		{SlotStatus: w3gs.SlotOpen, Team: 3},
+		{SlotStatus: w3gs.SlotClosed, Team: 4},
+		{PlayerID: 3, SlotStatus: w3gs.SlotOccupied, Team: 24},
+	}
+	if !replay.IsFFA() || replay.Matchup() != "FFA" {
+		t.Fatal("Expected FFA", replay.TeamSizes())
+	}
+
+	replay.Slots[2].Team = 0
+	if replay.IsFFA() || replay.Matchup() != "2v1" {
+		t.Fatal("Expected 2v1", replay.TeamSizes())
+	}
+}
+
+func TestSavedGame(t *testing.T) {
+	replay, err := w3g.Open("./test_132.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.IsSavedGame() || replay.SavedGamePath() != "" {
+		t.Fatal("Expected regular game")
+	}
+
+	// No recording of a loaded game is available; saved_game.w3g is generated with Builder
+	// (saved game flag, .w3z map path), so this only checks how saved games are detected.
+	loaded, err := w3g.Open("./testdata/saved_game.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.IsSavedGame() || loaded.GameFlags.Type() != w3gs.GameFlagSavedGame || loaded.SavedGamePath() != "Save\\Multiplayer\\Test.w3z" {
+		t.Fatal("Expected saved game", loaded.GameFlags, loaded.SavedGamePath())
+	}
+	if issues := loaded.Validate(); len(issues) != 1 || issues[0].Severity != w3g.SeverityWarning {
+		t.Fatal("Expected saved game warning", issues)
+	}
+
+	loaded.GameSettings.MapPath = "Maps\\Test.w3x"
+	if !loaded.IsSavedGame() || loaded.SavedGamePath() != "" {
+		t.Fatal("Expected saved game from flags")
+	}
+	loaded.GameFlags = loaded.GameFlags.Without(w3gs.GameFlagSavedGame)
+	if loaded.IsSavedGame() {
+		t.Fatal("Expected regular game")
+	}
+}
+
+func TestPlayerExtraRoundTrip(t *testing.T) {
+	b, err := ioutil.ReadFile("./test_132.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hdr, data, _, err := w3g.DecodeHeader(bytes.NewReader(b), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := ioutil.ReadAll(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replay, err := w3g.OpenBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replay.PlayerExtra) == 0 {
+		t.Fatal("Expected PlayerExtra records")
+	}
+
+	var enc = w3g.Encoding{GameVersion: hdr.GameVersion.Version}
+	for _, e := range replay.PlayerExtra {
+		var buf protocol.Buffer
+		if err := e.Serialize(&buf, &enc); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(raw, buf.Bytes) {
+			t.Fatalf("Re-encoded PlayerExtra (%v) not found in original data: %x", e.Type, buf.Bytes)
+		}
+	}
+}
+
+func TestLeavers(t *testing.T) {
+	replay, err := w3g.Open("./test_126.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var leavers = replay.Leavers()
+	if len(leavers) != 2 {
+		t.Fatalf("Expected 2 leavers, got %d", len(leavers))
+	}
+	if leavers[0].PlayerID != 2 || leavers[0].Name != "Fighting-" || leavers[0].Reason != w3gs.LeaveLost || leavers[0].Local || leavers[0].GameTime != 3*time.Minute+54396*time.Millisecond {
+		t.Fatalf("Unexpected first leaver: %+v", leavers[0])
+	}
+	if !reflect.DeepEqual(leavers, replay.Departures()) {
+		t.Fatal("Expected Departures() to equal Leavers() when everyone left")
+	}
+
+	// Drop the first PlayerLeft record, player should remain until the end
+	for i, r := range replay.Records {
+		if _, ok := r.(*w3g.PlayerLeft); ok {
+			replay.Records = append(replay.Records[:i], replay.Records[i+1:]...)
+			break
+		}
+	}
+
+	var dep = replay.Departures()
+	if len(dep) != 2 || dep[0].PlayerID != 1 || dep[1].PlayerID != 2 || !dep[1].Remained || dep[1].GameTime < dep[0].GameTime {
+		t.Fatalf("Unexpected departures: %+v", dep)
+	}
+}
+
+func TestDesyncs(t *testing.T) {
+	replay, err := w3g.Open("./test_130.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replay.Desyncs()) != 0 {
+		t.Fatal("Expected no desyncs")
+	}
+
+	replay.Records = append(replay.Records,
+		&w3g.TimeSlot{TimeSlot: w3gs.TimeSlot{TimeIncrementMS: 100}},
+		&w3g.Desync{Desync: w3gs.Desync{Checksum: 123, PlayersInState: []uint8{1}}},
+	)
+
+	var ds = replay.Desyncs()
+	if len(ds) != 1 || ds[0].Checksum != 123 || ds[0].GameTime < 100*time.Millisecond {
+		t.Fatalf("Unexpected desyncs: %+v", ds)
+	}
+
+	b, err := ioutil.ReadFile("./test_130.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, data, _, err := w3g.DecodeHeader(bytes.NewReader(b), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sums, err := w3g.Checksums(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sums) == 0 || sums[0].Tick != 0 || len(sums[0].Checksum) != 4 {
+		t.Fatalf("Unexpected checksums: %d", len(sums))
+	}
+
+	var other = append([]w3g.Checksum(nil), sums...)
+	if ev := w3g.ChecksumMismatches(sums, other); len(ev) != 0 {
+		t.Fatalf("Expected no mismatches, got %d", len(ev))
+	}
+
+	other[42].Checksum = []byte{1, 2, 3, 4}
+	var ev = w3g.ChecksumMismatches(sums, other[:100])
+	if len(ev) != 1 || ev[0].Tick != 42 || ev[0].GameTime != sums[42].GameTime || !bytes.Equal(ev[0].Checksums[1], other[42].Checksum) {
+		t.Fatalf("Unexpected mismatches: %+v", ev)
+	}
+}
+
+func TestEncoderFlush(t *testing.T) {
+	replay, err := w3g.Open("./test_130.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var records []w3g.Record
+	replay.ForEach(func(r w3g.Record) error {
+		records = append(records, r)
+		return nil
+	})
+
+	ref, err := ioutil.TempFile("", "w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(ref.Name())
+	defer ref.Close()
+
+	f, err := ioutil.TempFile("", "w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	e1, err := w3g.NewEncoder(ref, replay.Encoding())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e2, err := w3g.NewEncoder(f, replay.Encoding())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e1.Header = replay.Header
+	e2.Header = replay.Header
+
+	var half = len(records) / 2
+	for i, r := range records {
+		if _, err := e1.WriteRecord(r); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := e2.WriteRecord(r); err != nil {
+			t.Fatal(err)
+		}
+		if i != half {
+			continue
+		}
+
+		if err := e2.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if err := e2.Flush(); err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := ioutil.ReadFile(f.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		partial, err := w3g.OpenBytes(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var n int
+		partial.ForEach(func(r w3g.Record) error {
+			n++
+			return nil
+		})
+		if n != half+1 {
+			t.Fatalf("Expected %d records after Flush, got %d", half+1, n)
+		}
+	}
+
+	if e2.Records() != len(records) {
+		t.Fatalf("Expected %d records written, got %d", len(records), e2.Records())
+	}
+	if err := e1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e2.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b1, err := ioutil.ReadFile(ref.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b2, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b1, b2) {
+		t.Fatal("Expected output to be equal to encoding without Flush")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, f := range []string{"./test_102.w3g", "./test_126.w3g", "./test_130.w3g", "./test_132.w3g"} {
+		replay, err := w3g.Open(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if issues := replay.Validate(); len(issues) != 0 {
+			t.Fatalf("%s: unexpected issues: %v", f, issues)
+		}
+	}
+
+	replay, err := w3g.Open("./test_126.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replay.PlayerInfo = replay.PlayerInfo[:1]
+	replay.NumSlots = 1
+	replay.Records = append(replay.Records, &w3g.ChatMessage{Message: w3gs.Message{SenderID: 9}})
+
+	var errors, warnings int
+	for _, v := range replay.Validate() {
+		switch v.Severity {
+		case w3g.SeverityError:
+			errors++
+		case w3g.SeverityWarning:
+			warnings++
+		}
+	}
+
+	// Errors: slot and actions of removed player
+	// Warnings: NumSlots, chat and leave of removed player, chat of unknown player
+	if errors != 2 || warnings != 4 {
+		t.Fatalf("Unexpected number of issues (errors: %d, warnings: %d): %v", errors, warnings, replay.Validate())
+	}
+}
+
+func TestRelease(t *testing.T) {
+	var releases = map[string]string{
+		"./test_102.w3g": "Classic 1.02 build 4531",
+		"./test_126.w3g": "Classic 1.26 build 6059",
+		"./test_130.w3g": "Reforged 1.30 build 6061",
+		"./test_132.w3g": "Reforged 1.32 build 6105",
+	}
+	for f, r := range releases {
+		replay, err := w3g.Open(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if replay.ReleaseString() != r || replay.IsReforged() != strings.HasPrefix(r, "Reforged") {
+			t.Fatal(f, "Unexpected release", replay.ReleaseString())
+		}
+	}
+}
+
+func TestProduct(t *testing.T) {
+	var products = map[string]protocol.DWordString{
+		"./test_102.w3g": w3gs.ProductROC,
+		"./test_126.w3g": w3gs.ProductTFT,
+		"./test_132.w3g": w3gs.ProductTFT,
+	}
+	for f, p := range products {
+		replay, err := w3g.Open(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if replay.Product() != p {
+			t.Fatal(f, "Unexpected product", replay.Product())
+		}
+		for _, i := range replay.Validate() {
+			if strings.Contains(i.Description, "product") {
+				t.Fatal(f, "Unexpected issue", i)
+			}
+		}
+
+		var b protocol.Buffer
+		if _, err := replay.WriteTo(&b); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := w3g.Decode(&b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if loaded.Product() != p {
+			t.Fatal(f, "Product not preserved", loaded.Product())
+		}
+	}
+
+	var replay = w3g.Replay{}
+	replay.GameVersion.Product = protocol.DString("ABCD")
+	var found = false
+	for _, i := range replay.Validate() {
+		found = found || (i.Severity == w3g.SeverityError && strings.Contains(i.Description, "product"))
+	}
+	if !found {
+		t.Fatal("Expected unknown product issue")
+	}
+}
+
+func TestClone(t *testing.T) {
+	replay, err := w3g.Open("./test_132.w3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var clone = replay.Clone()
+	if !reflect.DeepEqual(replay, clone) {
+		t.Fatal("Expected clone to be equal")
+	}
+	if clone.PlayerInfo[0] != &clone.HostPlayer {
+		t.Fatal("Expected clone to keep host player reference")
+	}
+
+	var orig = replay.Fingerprint()
+
+	clone.HostPlayer.Name = "anonymous"
+	clone.PlayerInfo[1].Name = "anonymous"
+	clone.Slots[0].Team = 5
+	clone.GameSettings.MapSha1[0] ^= 0xFF
+	for i := range clone.PlayerExtra {
+		for j := range clone.PlayerExtra[i].Profiles {
+			clone.PlayerExtra[i].Profiles[j].BattleTag = "anonymous"
+		}
+	}
+	for _, rec := range clone.Records {
+		switch v := rec.(type) {
+		case *w3g.TimeSlot:
+			for _, a := range v.Actions {
+				for i := range a.Data {
+					a.Data[i] = 0
+				}
+			}
+		case *w3g.ChatMessage:
+			v.Content = "***"
+		}
+	}
+	clone.Records = clone.Records[:1]
+
+	if replay.HostPlayer.Name == "anonymous" || replay.PlayerInfo[1].Name == "anonymous" || replay.Slots[0].Team == 5 {
+		t.Fatal("Original modified through clone")
+	}
+	for i := range replay.PlayerExtra {
+		for j := range replay.PlayerExtra[i].Profiles {
+			if replay.PlayerExtra[i].Profiles[j].BattleTag == "anonymous" {
+				t.Fatal("Original profile modified through clone")
+			}
+		}
+	}
+	if replay.Fingerprint() != orig {
+		t.Fatal("Original records modified through clone")
+	}
+
+	var rec = w3g.CloneRecord(replay.Records[0])
+	if !reflect.DeepEqual(rec, replay.Records[0]) || rec == replay.Records[0] {
+		t.Fatal("Expected CloneRecord to return an equal copy")
+	}
+	if w3g.CloneRecord(nil) != nil {
+		t.Fatal("Expected nil clone of nil record")
+	}
+}
+
+func TestMapName(t *testing.T) {
+	var replay = w3g.Replay{}
+	if replay.MapName() != "" || replay.MapPath() != "" {
+		t.Fatal("Expected empty map name", replay.MapName(), replay.MapPath())
+	}
+
+	replay.GameSettings.MapPath = "Maps\\FrozenThrone\\(2)EchoIsles.w3x"
+	if p := replay.MapPath(); p != "Maps/FrozenThrone/(2)EchoIsles.w3x" {
+		t.Fatal("Unexpected map path", p)
+	}
+	if n := replay.MapName(); n != "(2)EchoIsles.w3x" {
+		t.Fatal("Unexpected map name", n)
+	}
+
+	replay.GameSettings.MapPath = "EchoIsles.w3x"
+	if n := replay.MapName(); n != "EchoIsles.w3x" {
+		t.Fatal("Unexpected map name", n)
+	}
+}
+
+func TestOpenAll(t *testing.T) {
+	var files = []string{"./test_126.w3g", "./test_132.w3g"}
+
+	// A stray signature right in front of a replay must not hide it
+	var prefix = []string{"garbage", w3g.Signature}
+
+	var buf bytes.Buffer
+	var expected []*w3g.Replay
+	for i, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rep, err := w3g.OpenBytes(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected = append(expected, rep)
+
+		buf.WriteString(prefix[i])
+		buf.Write(b)
+	}
+	buf.WriteString(w3g.Signature + "trailing garbage")
+
+	replays, err := w3g.OpenAll(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replays) != len(expected) {
+		t.Fatalf("Expected %d replays, got %d", len(expected), len(replays))
+	}
+	for i := range replays {
+		if !reflect.DeepEqual(replays[i], expected[i]) {
+			t.Fatal(files[i], "Replay mismatch")
+		}
+	}
+
+	if _, err := w3g.OpenAll(strings.NewReader("garbage")); err != w3g.ErrBadFormat {
+		t.Fatal("Expected ErrBadFormat", err)
+	}
+}
+
+func TestActionProfile(t *testing.T) {
+	for _, file := range []string{"./test_102.w3g", "./test_126.w3g", "./test_130.w3g", "./test_132.w3g"} {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		replay, stats, err := w3g.DecodeWithStats(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		var n = 0
+		for id, p := range replay.ActionProfile() {
+			if len(p.Unusual) != 0 {
+				t.Fatal(file, "Unexpected unusual actions", id, p.Unusual)
+			}
+			if p.Actions == 0 || len(p.IDs) == 0 {
+				t.Fatal(file, "Expected actions", id)
+			}
+			if p.Paused != 0 {
+				t.Fatal(file, "Unexpected actions while paused", id, p.Paused)
+			}
+			n += p.Bytes
+		}
+		if n != stats.ActionBytes {
+			t.Fatal(file, "Action bytes mismatch", n, stats.ActionBytes)
+		}
+	}
+
+	data, err := w3gs.SerializeActions([]w3gs.Action{
+		&w3gs.Pause{},
+		&w3gs.EscPressed{},
+		&w3gs.UnknownAction{ID: w3gs.AidContinueGameA, Data: make([]byte, 16)},
+		&w3gs.Resume{},
+		&w3gs.Cheat{ID: 0x2D, Data: []byte{1, 2, 3, 4, 5}},
+	}, w3gs.Encoding{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var replay = w3g.Replay{Records: []w3g.Record{
+		&w3g.TimeSlot{TimeSlot: w3gs.TimeSlot{Actions: []w3gs.PlayerAction{
+			{PlayerID: 1, Data: data},
+			{PlayerID: 2, Data: []byte{w3gs.AidEscPressed, 0xFF, 0x00}},
+		}}},
+	}}
+
+	var prof = replay.ActionProfile()
+	if p := prof[1]; p.Actions != 5 || p.Bytes != len(data) || p.Paused != 1 || p.IDs[w3gs.AidPause] != 1 || !reflect.DeepEqual(p.Unusual, map[uint8]int{0x2D: 1}) {
+		t.Fatal("Unexpected profile", p)
+	}
+	if p := prof[2]; p.Actions != 1 || p.Bytes != 3 || !reflect.DeepEqual(p.Unusual, map[uint8]int{0xFF: 1}) {
+		t.Fatal("Unexpected profile for unknown action", p)
+	}
+
+	var legit = map[uint8]bool{0x2D: true}
+	if p := replay.ActionProfileWith(legit)[1]; len(p.Unusual) != 4 || p.Unusual[0x2D] != 0 {
+		t.Fatal("Expected custom legit set", p.Unusual)
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	for _, v := range []uint32{26, 10032} {
+		var b = w3g.NewBuilder(w3gs.GameVersion{Product: w3gs.ProductTFT, Version: v}).
+			GameName("builder").
+			Player("foo", w3gs.RaceHuman, 0).
+			Player("bar", w3gs.RaceOrc, 1).
+			Computer(w3gs.RaceUndead, 1, w3gs.ComputerInsane).
+			Observer("baz").
+			TimeSlot(100).
+			Chat(1, "gl hf").
+			TimeSlot(250, w3gs.PlayerAction{PlayerID: 2, Data: []byte{w3gs.AidEscPressed}}).
+			Chat(2, "gg").
+			Leave(2, w3gs.LeaveLost)
+
+		var rep = b.Build()
+		if issues := rep.Validate(); len(issues) != 0 {
+			t.Fatalf("%d: unexpected issues: %v", v, issues)
+		}
+
+		var buf bytes.Buffer
+		if _, err := rep.WriteTo(&buf); err != nil {
+			t.Fatal(v, err)
+		}
+
+		rep2, err := w3g.Decode(&buf)
+		if err != nil {
+			t.Fatal(v, err)
+		}
+
+		if rep2.GameName != "builder" || rep2.HostPlayer.Name != "foo" || rep2.DurationMS != 350 || rep2.NumPlayers != 3 || rep2.RandomSeed != rep.RandomSeed {
+			t.Fatalf("%d: unexpected game info: %+v", v, rep2.GameInfo)
+		}
+		if !reflect.DeepEqual(rep.PlayerInfo, rep2.PlayerInfo) || !reflect.DeepEqual(rep.Slots, rep2.Slots) {
+			t.Fatalf("%d: players not equal after round trip: %v", v, rep2.PlayerInfo)
+		}
+		if m := rep2.Matchup(); m != "1v2" {
+			t.Fatalf("%d: unexpected matchup %q", v, m)
+		}
+		if obs := rep2.Observers(); len(obs) != 1 || obs[0].ID != 3 || obs[0].Name != "baz" {
+			t.Fatalf("%d: unexpected observers: %v", v, obs)
+		}
+
+		var chat []string
+		for _, r := range rep2.Records {
+			if m, ok := r.(*w3g.ChatMessage); ok {
+				chat = append(chat, fmt.Sprintf("%d:%s", m.SenderID, m.Content))
+			}
+		}
+		if !reflect.DeepEqual(chat, []string{"1:gl hf", "2:gg"}) {
+			t.Fatalf("%d: unexpected chat: %v", v, chat)
+		}
+		if l := rep2.Leavers(); len(l) != 1 || l[0].PlayerID != 2 || l[0].Name != "bar" || l[0].GameTime != 350*time.Millisecond {
+			t.Fatalf("%d: unexpected leavers: %v", v, l)
+		}
+
+		// Builder can continue after Build
+		if rep3 := b.Chat(1, "again").Build(); len(rep3.Records) != len(rep.Records)+1 {
+			t.Fatal("Expected builder to continue after Build")
+		}
+	}
+}
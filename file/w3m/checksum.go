@@ -8,6 +8,7 @@ import (
 	"crypto/sha1"
 	"encoding/base64"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"math/bits"
 	"os"
@@ -138,3 +139,41 @@ func (m *Map) Checksum(stor *fs.Storage) (*Hash, error) {
 
 	return &h, nil
 }
+
+// FileChecksum returns the size and CRC32 of the raw map file (as used in w3gs.MapCheck)
+func FileChecksum(r io.Reader) (uint32, uint32, error) {
+	var crc = crc32.NewIEEE()
+	size, err := io.Copy(crc, r)
+	if err != nil {
+		return 0, 0, err
+	}
+	return uint32(size), crc.Sum32(), nil
+}
+
+// Hashes computes all the values required to identify a map file in w3gs.MapCheck:
+// file size, file CRC32 and content hash. stor is used to look up common.j and blizzard.j
+// if the map does not contain them (can be nil).
+func Hashes(fileName string, stor *fs.Storage) (size uint32, crc uint32, hash *Hash, err error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	size, crc, err = FileChecksum(f)
+	f.Close()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	m, err := Open(fileName)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	defer m.Close()
+
+	hash, err = m.Checksum(stor)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	return size, crc, hash, nil
+}
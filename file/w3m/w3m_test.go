@@ -206,3 +206,28 @@ func TestFiles(t *testing.T) {
 		}
 	}
 }
+
+func TestHashes(t *testing.T) {
+	var files = []struct {
+		file     string
+		size     uint32
+		crc      uint32
+		checksum string
+	}{
+		{"test_roc.w3m", 15802, 0x648A2F17, "0xDD4E3EBE|P5c/izfa1qstJu5zYYVyc2FD2gE"},
+		{"test_tft.w3x", 15148, 0x7B626511, "0x7F321A74|/1ndO+WvBCWiQutD9VyCefo3GYM"},
+	}
+
+	for _, f := range files {
+		size, crc, hash, err := w3m.Hashes("./"+f.file, nil)
+		if err != nil {
+			t.Fatal(f.file, err)
+		}
+		if size != f.size || crc != f.crc {
+			t.Fatalf("%v file checksum mismatch %v/0x%08X != %v/0x%08X\n", f.file, size, crc, f.size, f.crc)
+		}
+		if hash.String() != f.checksum {
+			t.Fatalf("%v checksum mismatch %v != %v\n", f.file, hash, f.checksum)
+		}
+	}
+}
@@ -40,6 +40,21 @@ type Config struct {
 	CDKeyOwner        string
 	CDKeys            []string
 	GamePort          uint16
+	WardenHandler     WardenHandler
+}
+
+// WardenHandler responds to encrypted SID_WARDEN challenges
+// Returning a nil response will not send a reply to the server
+type WardenHandler interface {
+	HandleWarden(data []byte) ([]byte, error)
+}
+
+// WardenHandlerFunc is an adapter to allow the use of ordinary functions as WardenHandler
+type WardenHandlerFunc func(data []byte) ([]byte, error)
+
+// HandleWarden calls f(data)
+func (f WardenHandlerFunc) HandleWarden(data []byte) ([]byte, error) {
+	return f(data)
 }
 
 // Client represents a mocked BNCS client
@@ -66,8 +81,8 @@ var DefaultConfig = Config{
 		GameVersion:         w3gs.GameVersion{Product: w3gs.ProductROC, Version: w3gs.CurrentGameVersion},
 		LanguageCode:        protocol.DString("enUS"),
 		TimeZoneBias:        4294967176,
-		MpqLocaleID:         1033,
-		UserLanguageID:      1033,
+		MpqLocaleID:         w3gs.LocaleEnglishUS,
+		UserLanguageID:      w3gs.LocaleEnglishUS,
 		CountryAbbreviation: "USA",
 		Country:             "United States",
 	},
@@ -90,6 +105,8 @@ func NewClient(conf *Config) (*Client, error) {
 		return nil, err
 	}
 
+	c.SetAutoPing(true)
+
 	if conf.Platform.GameVersion.Version == 0 {
 		if c.ExeVersion != 0 {
 			c.Platform.GameVersion.Version = (c.ExeVersion >> 16) & 0xFF
@@ -239,20 +256,12 @@ func (b *Client) Dial() (*network.BNCSConn, error) {
 		b.ServerAddr += ":6112"
 	}
 
-	addr, err := net.ResolveTCPAddr("tcp", b.ServerAddr)
+	var d = network.Dialer{GameVersion: b.Platform.GameVersion}
+	conn, err := d.Dial(b.ServerAddr)
 	if err != nil {
 		return nil, err
 	}
 
-	conn, err := net.DialTCP("tcp", nil, addr)
-	if err != nil {
-		return nil, err
-	}
-
-	conn.SetKeepAlive(false)
-	conn.SetNoDelay(true)
-	conn.SetLinger(3)
-
 	return b.DialWithConn(conn)
 }
 
@@ -337,6 +346,8 @@ func (b *Client) Logon() error {
 	}
 
 	b.UniqueName = chat.UniqueName
+	// Apply current config, SetKeepAlive may still override it after Logon
+	b.SetKeepAlive(b.KeepAliveInterval)
 	b.SetConn(bncsconn.Conn(), bncs.NewFactoryCache(bncs.DefaultFactory), b.Encoding())
 	return nil
 }
@@ -705,39 +716,9 @@ func (b *Client) sendEnterChat(conn *network.BNCSConn) (*bncs.EnterChatResp, err
 	}
 }
 
-func (b *Client) runKeepAlive() func() {
-	var stop = make(chan struct{})
-
-	go func() {
-		var ticker = time.NewTicker(b.KeepAliveInterval)
-
-		var pkt bncs.KeepAlive
-		for {
-			select {
-			case <-stop:
-				ticker.Stop()
-				return
-			case <-ticker.C:
-				if _, err := b.Send(&pkt); err != nil && !network.IsCloseError(err) {
-					b.Fire(&network.AsyncError{Src: "runKeepAlive[Send]", Err: err})
-				}
-			}
-		}
-	}()
-
-	return func() {
-		stop <- struct{}{}
-	}
-}
-
 // Run reads packets and emits an event for each received packet
 // Not safe for concurrent invocation
 func (b *Client) Run() error {
-	if b.KeepAliveInterval != 0 {
-		var stop = b.runKeepAlive()
-		defer stop()
-	}
-
 	return b.BNCSConn.Run(&b.EventEmitter, 30*time.Second)
 }
 
@@ -785,15 +766,28 @@ func (b *Client) Say(s string) error {
 
 // InitDefaultHandlers adds the default callbacks for relevant packets
 func (b *Client) InitDefaultHandlers() {
-	b.On(&bncs.Ping{}, b.onPing)
 	b.On(&bncs.ChatEvent{}, b.onChatEvent)
+	b.On(&bncs.Warden{}, b.onWarden)
 }
 
-func (b *Client) onPing(ev *network.Event) {
-	var pkt = ev.Arg.(*bncs.Ping)
+func (b *Client) onWarden(ev *network.Event) {
+	if b.WardenHandler == nil {
+		return
+	}
+
+	var pkt = ev.Arg.(*bncs.Warden)
+
+	resp, err := b.WardenHandler.HandleWarden(pkt.Data)
+	if err != nil {
+		b.Fire(&network.AsyncError{Src: "onWarden[HandleWarden]", Err: err})
+		return
+	}
+	if resp == nil {
+		return
+	}
 
-	if _, err := b.Send(pkt); err != nil {
-		b.Fire(&network.AsyncError{Src: "onPing[Send]", Err: err})
+	if _, err := b.Send(&bncs.Warden{Data: resp}); err != nil {
+		b.Fire(&network.AsyncError{Src: "onWarden[Send]", Err: err})
 	}
 }
 
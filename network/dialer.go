@@ -0,0 +1,80 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network
+
+import (
+	"net"
+	"strings"
+
+	"github.com/nielsAD/gowarcraft3/protocol/bncs"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// Dialer opens TCP connections to W3GS hosts and BNCS servers, and wraps them with the
+// encoding and packet factory that match GameVersion.
+//
+// CD key authentication is not part of the connection handshake, see bnet.Client for that.
+type Dialer struct {
+	net.Dialer
+
+	// Product (w3gs.ProductROC, w3gs.ProductTFT) and version (0 for latest)
+	GameVersion w3gs.GameVersion
+}
+
+// Encoding for W3GS connections
+func (d *Dialer) Encoding() w3gs.Encoding {
+	return w3gs.Encoding{GameVersion: d.GameVersion.Version}
+}
+
+// BNCSEncoding for BNCS connections (client side)
+func (d *Dialer) BNCSEncoding() bncs.Encoding {
+	return bncs.Encoding{
+		Encoding: d.Encoding(),
+
+		// Assume response when deserializing ambiguous packet IDs
+		Request: false,
+	}
+}
+
+// Dial opens a TCP connection to addr (default port 6112) with the socket options used by the game
+func (d *Dialer) Dial(addr string) (*net.TCPConn, error) {
+	if !strings.ContainsRune(addr, ':') {
+		addr += ":6112"
+	}
+
+	conn, err := d.Dialer.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var tcp = conn.(*net.TCPConn)
+	tcp.SetKeepAlive(false)
+	tcp.SetNoDelay(true)
+	tcp.SetLinger(3)
+
+	return tcp, nil
+}
+
+// DialW3GS opens a W3GS connection to addr
+func (d *Dialer) DialW3GS(addr string) (*W3GSConn, error) {
+	conn, err := d.Dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewW3GSConn(conn, w3gs.NewFactoryCache(w3gs.DefaultFactory), d.Encoding()), nil
+}
+
+// DialBNCS opens a BNCS connection to addr and sends the protocol greeting
+func (d *Dialer) DialBNCS(addr string) (*BNCSConn, error) {
+	conn, err := d.Dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write([]byte{bncs.ProtocolGreeting}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return NewBNCSConn(conn, bncs.NewFactoryCache(bncs.DefaultFactory), d.BNCSEncoding()), nil
+}
@@ -0,0 +1,82 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol/bncs"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+func TestDialer(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	var d = network.Dialer{GameVersion: w3gs.GameVersion{Product: w3gs.ProductTFT, Version: 26}}
+	if d.Encoding().GameVersion != 26 || d.BNCSEncoding().GameVersion != 26 {
+		t.Fatal("Encoding mismatch")
+	}
+
+	w3, err := d.DialW3GS(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w3.Close()
+
+	c1, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c1.Close()
+
+	if _, err := w3.Send(&w3gs.Ping{Payload: 123}); err != nil {
+		t.Fatal(err)
+	}
+	pkt, _, err := w3gs.Read(c1, d.Encoding())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, ok := pkt.(*w3gs.Ping); !ok || p.Payload != 123 {
+		t.Fatal("Unexpected packet", pkt)
+	}
+
+	bn, err := d.DialBNCS(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bn.Close()
+
+	c2, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+
+	var greet [1]byte
+	if _, err := c2.Read(greet[:]); err != nil || greet[0] != bncs.ProtocolGreeting {
+		t.Fatal("Expected protocol greeting", greet, err)
+	}
+
+	if _, err := bn.Send(&bncs.KeepAlive{}); err != nil {
+		t.Fatal(err)
+	}
+	pkt2, _, err := bncs.Read(c2, bncs.Encoding{Request: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pkt2.(*bncs.KeepAlive); !ok {
+		t.Fatal("Unexpected packet", pkt2)
+	}
+
+	if _, err := d.DialW3GS("127.0.0.1:1"); err == nil {
+		t.Fatal("Expected error")
+	}
+}
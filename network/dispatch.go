@@ -0,0 +1,236 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// DispatchMode determines on which goroutine event handlers are called
+type DispatchMode uint32
+
+// Dispatch modes
+//
+// DispatchSync calls handlers on the goroutine that fires the event, before Fire returns.
+// Events are handled in the order they are fired. This is the default.
+//
+// DispatchAsync calls handlers on a new goroutine for every event. There are no ordering
+// guarantees, not even between events of the same type.
+//
+// DispatchPool calls handlers on a bounded number of worker goroutines (see SetPoolSize).
+// Events of the same type are handled one at a time, in the order they were fired.
+// Events of different types are handled concurrently, so a slow handler only delays
+// events of its own type (as long as there are idle workers).
+const (
+	DispatchSync DispatchMode = iota
+	DispatchAsync
+	DispatchPool
+)
+
+func (m DispatchMode) String() string {
+	switch m {
+	case DispatchSync:
+		return "Sync"
+	case DispatchAsync:
+		return "Async"
+	case DispatchPool:
+		return "Pool"
+	default:
+		return fmt.Sprintf("DispatchMode(%d)", uint32(m))
+	}
+}
+
+// DefaultPoolSize is the maximum number of workers for DispatchPool, unless changed with SetPoolSize
+const DefaultPoolSize = 4
+
+type dispatchTask struct {
+	ht   string
+	arr1 []eventHandler
+	arr2 []eventHandler
+	ev   *Event
+}
+
+// dispatcher keeps track of events that are handled asynchronously
+type dispatcher struct {
+	mut     sync.Mutex
+	cond    sync.Cond
+	pending int // Events fired, but not yet handled
+	size    int // Maximum number of workers
+	workers int // Number of running workers
+	ready   []string
+	busy    map[string]bool
+	queue   map[string][]*dispatchTask
+}
+
+func (d *dispatcher) init() {
+	if d.cond.L == nil {
+		d.cond.L = &d.mut
+		d.busy = make(map[string]bool)
+		d.queue = make(map[string][]*dispatchTask)
+	}
+}
+
+func (d *dispatcher) done(n int) {
+	d.mut.Lock()
+	d.pending -= n
+	if d.pending == 0 {
+		d.cond.Broadcast()
+	}
+	d.mut.Unlock()
+}
+
+func (d *dispatcher) async(t *dispatchTask, run func(*dispatchTask)) {
+	d.mut.Lock()
+	d.init()
+	d.pending++
+	d.mut.Unlock()
+
+	go func() {
+		run(t)
+		d.done(1)
+	}()
+}
+
+func (d *dispatcher) pool(t *dispatchTask, run func(*dispatchTask)) {
+	d.mut.Lock()
+	d.init()
+	d.pending++
+	d.queue[t.ht] = append(d.queue[t.ht], t)
+
+	// Topic is already scheduled or being handled by a worker
+	if d.busy[t.ht] {
+		d.mut.Unlock()
+		return
+	}
+
+	d.busy[t.ht] = true
+	d.ready = append(d.ready, t.ht)
+
+	var size = d.size
+	if size <= 0 {
+		size = DefaultPoolSize
+	}
+	if d.workers < size {
+		d.workers++
+		go d.work(run)
+	}
+	d.mut.Unlock()
+}
+
+// work handles queued events, one topic at a time, and exits once there is nothing left to do
+func (d *dispatcher) work(run func(*dispatchTask)) {
+	d.mut.Lock()
+	for len(d.ready) > 0 {
+		var ht = d.ready[0]
+		d.ready = d.ready[1:]
+
+		var tasks = d.queue[ht]
+		delete(d.queue, ht)
+		d.mut.Unlock()
+
+		for _, t := range tasks {
+			run(t)
+		}
+
+		d.mut.Lock()
+		if len(d.queue[ht]) > 0 {
+			// Reschedule instead of continuing, so that other topics get a turn
+			d.ready = append(d.ready, ht)
+		} else {
+			delete(d.busy, ht)
+		}
+
+		d.pending -= len(tasks)
+		if d.pending == 0 {
+			d.cond.Broadcast()
+		}
+	}
+	d.workers--
+	d.mut.Unlock()
+}
+
+func (d *dispatcher) wait() {
+	d.mut.Lock()
+	d.init()
+	for d.pending > 0 {
+		d.cond.Wait()
+	}
+	d.mut.Unlock()
+}
+
+// asyncEmitter returns true if f calls its handlers after Fire returns
+func asyncEmitter(f Emitter) bool {
+	d, ok := f.(interface{ DispatchMode() DispatchMode })
+	return ok && d.DispatchMode() != DispatchSync
+}
+
+// detach returns a deep copy of v, so that it remains valid when the original is reused (i.e. by a cached packet factory)
+func detach(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	return deepCopy(reflect.ValueOf(v)).Interface()
+}
+
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		var res = reflect.New(v.Type().Elem())
+		res.Elem().Set(deepCopy(v.Elem()))
+		return res
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		var res = reflect.New(v.Type()).Elem()
+		res.Set(deepCopy(v.Elem()))
+		return res
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		var res = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			reflect.Copy(res, v)
+			return res
+		}
+		for i := 0; i < v.Len(); i++ {
+			res.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return res
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		var res = reflect.MakeMapWithSize(v.Type(), v.Len())
+		for _, k := range v.MapKeys() {
+			res.SetMapIndex(k, deepCopy(v.MapIndex(k)))
+		}
+		return res
+	case reflect.Array, reflect.Struct:
+		// Copy by value first, unexported fields are kept as is
+		var res = reflect.New(v.Type()).Elem()
+		res.Set(v)
+		if v.Kind() == reflect.Array {
+			for i := 0; i < v.Len(); i++ {
+				res.Index(i).Set(deepCopy(v.Index(i)))
+			}
+			return res
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if res.Field(i).CanSet() {
+				res.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+		return res
+	default:
+		return v
+	}
+}
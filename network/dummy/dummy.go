@@ -140,20 +140,12 @@ func (p *Player) JoinWithConn(conn net.Conn) error {
 // Join opens a new connection to host
 // Not safe for concurrent invocation
 func (p *Player) Join() error {
-	addr, err := net.ResolveTCPAddr("tcp", p.HostAddr)
+	var d = network.Dialer{}
+	conn, err := d.Dial(p.HostAddr)
 	if err != nil {
 		return err
 	}
 
-	conn, err := net.DialTCP("tcp", nil, addr)
-	if err != nil {
-		return err
-	}
-
-	conn.SetKeepAlive(false)
-	conn.SetNoDelay(true)
-	conn.SetLinger(3)
-
 	return p.JoinWithConn(conn)
 }
 
@@ -183,10 +175,10 @@ func (p *Player) Run() error {
 	return err
 }
 
-// Say sends a chat message
+// Say sends a chat message, color codes (i.e. "|cffff0000red|r") are sent as-is
 func (p *Player) Say(s string) error {
 	s = strings.Map(func(r rune) rune {
-		if !unicode.IsPrint(r) {
+		if !unicode.IsGraphic(r) {
 			return -1
 		}
 		return r
@@ -5,14 +5,75 @@
 package network
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"net"
 	"os"
 	"syscall"
 
 	"github.com/gorilla/websocket"
+
+	"github.com/nielsAD/gowarcraft3/protocol/bncs"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// Errors
+var (
+	ErrShortRead        = errors.New("network: Connection closed mid-packet")
+	ErrUnexpectedPacket = errors.New("network: Unexpected packet")
+	ErrMapUnavailable   = errors.New("network: Map unavailable")
 )
 
+// ShortReadError is returned by NextPacket when the connection was closed after receiving
+// only part of a packet. A clean close at a packet boundary is reported as io.EOF instead.
+type ShortReadError struct {
+	N int // Number of bytes received of the incomplete packet
+}
+
+func (e *ShortReadError) Error() string {
+	return fmt.Sprintf("%v (%d bytes received)", ErrShortRead, e.N)
+}
+
+// Is implements errors.Is, matches ErrShortRead and io.ErrUnexpectedEOF
+func (e *ShortReadError) Is(target error) bool {
+	return target == ErrShortRead || target == io.ErrUnexpectedEOF
+}
+
+// packetReader keeps track of whether the first byte of a packet matches the protocol signature
+type packetReader struct {
+	io.Reader
+	sig     byte
+	n       int
+	invalid bool
+}
+
+func (r *packetReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if r.n == 0 && n > 0 {
+		r.invalid = p[0] != r.sig
+	}
+	r.n += n
+	return n, err
+}
+
+// readError converts the result of a packet decoder into an error that distinguishes
+// between a clean close (io.EOF) and a close in the middle of a packet (*ShortReadError)
+func readError(r *packetReader, n int, err error, errNoSig error) error {
+	if n == 0 || err == nil {
+		return err
+	}
+	// Malformed data is reported as such, even if the connection was closed afterwards
+	if r.invalid {
+		return err
+	}
+	// Decoders report an incomplete header as a missing protocol signature
+	if err == io.EOF || err == io.ErrUnexpectedEOF || (err == errNoSig && n < 4) {
+		return &ShortReadError{N: n}
+	}
+	return err
+}
+
 // AsyncError keeps track of where a non-fatal asynchronous error orignated
 type AsyncError struct {
 	Src string
@@ -36,6 +97,36 @@ func (e *AsyncError) Timeout() bool {
 	return IsTimeout(e.Err)
 }
 
+// Unwrap returns the underlying error
+func (e *AsyncError) Unwrap() error {
+	return e.Err
+}
+
+// ConnError adds the remote address of a connection to an error
+type ConnError struct {
+	Addr net.Addr
+	Err  error
+}
+
+func (e *ConnError) Error() string {
+	if e.Addr == nil {
+		return e.Err.Error()
+	}
+	return e.Addr.String() + ":" + e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *ConnError) Unwrap() error {
+	return e.Err
+}
+
+func connError(addr net.Addr, err error) error {
+	if addr == nil {
+		return err
+	}
+	return &ConnError{Addr: addr, Err: err}
+}
+
 // UnnestError retrieves the innermost error
 func UnnestError(err error) error {
 	switch e := err.(type) {
@@ -49,6 +140,11 @@ func UnnestError(err error) error {
 		return UnnestError(e.Err)
 	case *os.LinkError:
 		return UnnestError(e.Err)
+	case interface{ Unwrap() error }:
+		if u := e.Unwrap(); u != nil {
+			return UnnestError(u)
+		}
+		return err
 	default:
 		return err
 	}
@@ -75,13 +171,31 @@ func IsSysCallError(err error, errno ...syscall.Errno) bool {
 	return false
 }
 
+// IsDecodeError checks if err indicates a failed packet deserialization (connection is still valid)
+func IsDecodeError(err error) bool {
+	for _, e := range []error{
+		w3gs.ErrInvalidPacketSize, w3gs.ErrInvalidChecksum, w3gs.ErrUnexpectedConst,
+		bncs.ErrInvalidPacketSize, bncs.ErrInvalidChecksum, bncs.ErrUnexpectedConst,
+	} {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
+
 // IsUseClosedNetworkError checks if net.error is poll.ErrNetClosed
 func IsUseClosedNetworkError(err error) bool {
 	return err != nil && err.Error() == "use of closed network connection"
 }
 
 // IsCloseError checks if err indicates a (cleanly) closed connection
+// Connections that were closed in the middle of a packet (see ShortReadError) are included.
 func IsCloseError(err error) bool {
+	if errors.Is(err, ErrShortRead) {
+		return true
+	}
+
 	err = UnnestError(err)
 	if err == io.EOF || IsUseClosedNetworkError(err) {
 		return true
@@ -37,23 +37,33 @@ type EventHandler func(*Event)
 
 // Internal eventHandler struct
 type eventHandler = struct {
-	id   uint32
-	once bool
-	fun  EventHandler
+	id    uint32
+	once  bool
+	fired *uint32 // Claimed by the first call of a once handler
+	fun   EventHandler
 }
 
 // EventEmitter is an event emitter based on argument types
 // For every type, a listener can register callbacks. Callbacks will be fired in reverse order of registration.
 // The structure is thread-safe and functions can be called from multiple goroutines at the same time.
+//
+// By default, callbacks are called synchronously by Fire (see SetDispatchMode for alternatives).
 type EventEmitter struct {
 	id       uint32
 	hanmutex sync.RWMutex
 	handlers map[string][]eventHandler
 	emask    uint32
 	epool    [16]Event
+	mode     uint32
+	disp     dispatcher
 }
 
 // Emitter is the interface that wraps the basic Fire method
+//
+// Fire returns true if a handler called PreventNext. Handlers that are called asynchronously
+// (see EventEmitter.SetDispatchMode) run after Fire returns, so Fire cannot report this and
+// always returns false. Callers that use the result as a veto (i.e. to skip default behavior)
+// require synchronous dispatch.
 type Emitter interface {
 	Fire(a EventArg, o ...EventArg) bool
 }
@@ -92,11 +102,16 @@ func (e *EventEmitter) addHandler(a EventArg, h EventHandler, once bool) EventID
 	if e.handlers == nil {
 		e.handlers = make(map[string][]eventHandler)
 	}
-	e.handlers[ht] = append([]eventHandler{eventHandler{
+	var eh = eventHandler{
 		id:   id,
 		once: once,
 		fun:  h,
-	}}, e.handlers[ht]...)
+	}
+	if once {
+		eh.fired = new(uint32)
+	}
+
+	e.handlers[ht] = append([]eventHandler{eh}, e.handlers[ht]...)
 	e.hanmutex.Unlock()
 
 	return EventID{
@@ -196,12 +211,16 @@ func (e *EventEmitter) fire(ht string, arr []eventHandler, ev *Event) bool {
 
 	for i := 0; i < len(arr); i++ {
 		var eh = arr[i]
-		eh.fun(ev)
-
-		minID = eh.id
 		if eh.once {
+			// Handler list may be outdated when events are fired concurrently
+			if !atomic.CompareAndSwapUint32(eh.fired, 0, 1) {
+				continue
+			}
 			once = true
 		}
+
+		eh.fun(ev)
+		minID = eh.id
 		if ev.preventNext {
 			prevent = true
 			break
@@ -215,10 +234,51 @@ func (e *EventEmitter) fire(ht string, arr []eventHandler, ev *Event) bool {
 	return prevent
 }
 
+func (e *EventEmitter) run(ht string, arr1 []eventHandler, arr2 []eventHandler, ev *Event) bool {
+	var prevent = e.fire(ca, arr1, ev)
+	if !prevent && ht != ca {
+		prevent = e.fire(ht, arr2, ev)
+	}
+	return prevent
+}
+
+func (e *EventEmitter) runAsync(t *dispatchTask) {
+	e.run(t.ht, t.arr1, t.arr2, t.ev)
+}
+
+// SetDispatchMode changes how handlers are called for events fired from now on.
+// Events that are still pending are not affected (see Wait).
+//
+// In DispatchAsync and DispatchPool mode, Fire always returns false (see Emitter).
+// Packets emitted by the connection types in this package (W3GSConn.Run, BNCSConn.Run, etc.)
+// are decoded into reused objects, they are copied before being queued.
+func (e *EventEmitter) SetDispatchMode(m DispatchMode) {
+	atomic.StoreUint32(&e.mode, uint32(m))
+}
+
+// DispatchMode returns the current dispatch mode
+func (e *EventEmitter) DispatchMode() DispatchMode {
+	return DispatchMode(atomic.LoadUint32(&e.mode))
+}
+
+// SetPoolSize sets the maximum number of workers for DispatchPool (DefaultPoolSize if n <= 0)
+func (e *EventEmitter) SetPoolSize(n int) {
+	e.disp.mut.Lock()
+	e.disp.size = n
+	e.disp.mut.Unlock()
+}
+
+// Wait until all events fired in DispatchAsync or DispatchPool mode are handled.
+// Must not be called from an event handler.
+func (e *EventEmitter) Wait() {
+	e.disp.wait()
+}
+
 // CatchAll
 var ca = topic(nil)
 
 // Fire new event of type a
+// Returns true if a handler called PreventNext, always false if the event is not handled synchronously (see Emitter).
 func (e *EventEmitter) Fire(a EventArg, o ...EventArg) bool {
 	var ht = topic(a)
 
@@ -231,14 +291,20 @@ func (e *EventEmitter) Fire(a EventArg, o ...EventArg) bool {
 		return false
 	}
 
+	switch e.DispatchMode() {
+	case DispatchAsync:
+		e.disp.async(&dispatchTask{ht: ht, arr1: arr1, arr2: arr2, ev: &Event{Arg: a, Opt: o}}, e.runAsync)
+		return false
+	case DispatchPool:
+		e.disp.pool(&dispatchTask{ht: ht, arr1: arr1, arr2: arr2, ev: &Event{Arg: a, Opt: o}}, e.runAsync)
+		return false
+	}
+
 	var ev, eid = e.newEvent()
 	ev.Arg = a
 	ev.Opt = o
 
-	var prevent = e.fire(ca, arr1, ev)
-	if !prevent && ht != ca {
-		prevent = e.fire(ht, arr2, ev)
-	}
+	var prevent = e.run(ht, arr1, arr2, ev)
 
 	e.freeEvent(eid)
 
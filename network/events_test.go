@@ -8,8 +8,10 @@ import (
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
 )
 
 func TestEmitterSingle(t *testing.T) {
@@ -218,6 +220,162 @@ func TestGoroutines(t *testing.T) {
 	}
 }
 
+func TestDispatchAsync(t *testing.T) {
+	var c uint32
+
+	var e network.EventEmitter
+	e.SetDispatchMode(network.DispatchAsync)
+	e.On(uint32(0), func(ev *network.Event) {
+		atomic.AddUint32(&c, ev.Arg.(uint32))
+		ev.PreventNext()
+	})
+
+	for i := 0; i < 1000; i++ {
+		if e.Fire(uint32(1)) {
+			t.Fatal("Expected Fire to return false for asynchronous event")
+		}
+	}
+
+	e.Wait()
+	if atomic.LoadUint32(&c) != 1000 {
+		t.Fatal("Result invalid")
+	}
+}
+
+func TestDispatchPool(t *testing.T) {
+	var e network.EventEmitter
+	e.SetDispatchMode(network.DispatchPool)
+	e.SetPoolSize(2)
+
+	var block = make(chan struct{})
+	var order []int
+	e.On("", func(ev *network.Event) {
+		<-block
+		order = append(order, ev.Opt[0].(int))
+	})
+
+	var fired = make(chan struct{})
+	e.On(0, func(ev *network.Event) {
+		fired <- struct{}{}
+	})
+
+	for i := 0; i < 100; i++ {
+		e.Fire("", i)
+	}
+
+	// Slow handler should not stall events of other types
+	e.Fire(0)
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Fatal("Event blocked by slow handler of other type")
+	}
+
+	close(block)
+	e.Wait()
+
+	if len(order) != 100 {
+		t.Fatal("Expected 100 events, got", len(order))
+	}
+	for i, v := range order {
+		if v != i {
+			t.Fatal("Events of same type not handled in order", order)
+		}
+	}
+
+	e.SetDispatchMode(network.DispatchSync)
+	if e.DispatchMode() != network.DispatchSync {
+		t.Fatal("Expected sync mode")
+	}
+}
+
+func TestDispatchOnce(t *testing.T) {
+	for _, m := range []network.DispatchMode{network.DispatchSync, network.DispatchAsync, network.DispatchPool} {
+		var c uint32
+
+		var e network.EventEmitter
+		e.SetDispatchMode(m)
+		e.Once(nil, func(ev *network.Event) {
+			atomic.AddUint32(&c, 1)
+		})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				for j := 0; j < 100; j++ {
+					e.Fire(i)
+				}
+				wg.Done()
+			}(i)
+		}
+		wg.Wait()
+		e.Wait()
+
+		if atomic.LoadUint32(&c) != 1 {
+			t.Fatalf("%v: expected once handler to be called once, got %d", m, c)
+		}
+	}
+}
+
+func TestDispatchDetach(t *testing.T) {
+	var e network.EventEmitter
+	e.SetDispatchMode(network.DispatchPool)
+
+	var block = make(chan struct{})
+	var res = make(chan string, 1)
+	e.On(&w3gs.MessageRelay{}, func(ev *network.Event) {
+		<-block
+		var msg = ev.Arg.(*w3gs.MessageRelay)
+		if msg.RecipientIDs[0] != 2 {
+			res <- "modified recipients"
+			return
+		}
+		res <- msg.Content
+	})
+
+	// Mimic a cached packet factory that reuses the packet object
+	var pkt = w3gs.MessageRelay{Message: *w3gs.NewChatToAll(1, "first")}
+	pkt.RecipientIDs = []uint8{2, 3}
+	network.FireW3GS(&e, &pkt)
+	pkt.Content = "second"
+	pkt.RecipientIDs[0] = 9
+
+	close(block)
+	e.Wait()
+	if c := <-res; c != "first" {
+		t.Fatal("Packet not detached from original, got", c)
+	}
+}
+
+// Fire events of four different types, handlers block for d
+func benchmarkDispatch(b *testing.B, m network.DispatchMode, d time.Duration) {
+	var e network.EventEmitter
+	e.SetDispatchMode(m)
+	for _, a := range []network.EventArg{0, "", false, 0.0} {
+		e.On(a, func(ev *network.Event) {
+			if d > 0 {
+				time.Sleep(d)
+			}
+		})
+	}
+
+	var args = []network.EventArg{0, "", false, 0.0}
+	for n := 0; n < b.N; n++ {
+		e.Fire(args[n%len(args)])
+	}
+	e.Wait()
+}
+
+const slow = time.Millisecond
+
+func BenchmarkDispatchSync(b *testing.B)      { benchmarkDispatch(b, network.DispatchSync, 0) }
+func BenchmarkDispatchAsync(b *testing.B)     { benchmarkDispatch(b, network.DispatchAsync, 0) }
+func BenchmarkDispatchPool(b *testing.B)      { benchmarkDispatch(b, network.DispatchPool, 0) }
+func BenchmarkDispatchSyncSlow(b *testing.B)  { benchmarkDispatch(b, network.DispatchSync, slow) }
+func BenchmarkDispatchAsyncSlow(b *testing.B) { benchmarkDispatch(b, network.DispatchAsync, slow) }
+func BenchmarkDispatchPoolSlow(b *testing.B)  { benchmarkDispatch(b, network.DispatchPool, slow) }
+
 func benchmarkEmitter(b *testing.B, numListeners int) {
 	var e network.EventEmitter
 	for i := 0; i < numListeners; i++ {
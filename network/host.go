@@ -0,0 +1,49 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network
+
+import (
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// CheckMap sends m to a joining player and waits until it reports its map state (host side of the lobby handshake).
+// PlayerExtra packets received in the meantime are skipped.
+// Returns ErrMapUnavailable if the player does not have the map.
+// Not safe for concurrent invocation with NextPacket or Run
+func (c *W3GSConn) CheckMap(m *w3gs.MapCheck, timeout time.Duration) error {
+	if _, err := c.Send(m); err != nil {
+		return err
+	}
+
+	for {
+		pkt, err := c.NextPacket(timeout)
+		if err != nil {
+			return err
+		}
+
+		switch v := pkt.(type) {
+		case *w3gs.PlayerExtra:
+			continue
+		case *w3gs.MapState:
+			if !v.Ready {
+				return ErrMapUnavailable
+			}
+			return nil
+		default:
+			return ErrUnexpectedPacket
+		}
+	}
+}
+
+// CountDown sends the countdown to a player, after which it starts loading the game
+func (c *W3GSConn) CountDown() error {
+	if _, err := c.Send(&w3gs.CountDownStart{}); err != nil {
+		return err
+	}
+	_, err := c.Send(&w3gs.CountDownEnd{})
+	return err
+}
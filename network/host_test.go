@@ -0,0 +1,75 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+func TestHostHandshake(t *testing.T) {
+	var enc = w3gs.Encoding{GameVersion: 10032}
+	var srv, cli = net.Pipe()
+	defer cli.Close()
+
+	var conn = network.NewW3GSConn(srv, nil, enc)
+	defer conn.Close()
+
+	var done = make(chan error, 1)
+	go func() {
+		done <- conn.CheckMap(&w3gs.MapCheck{FilePath: "Maps\\test.w3x", FileSize: 123}, time.Second)
+	}()
+
+	pkt, _, err := w3gs.Read(cli, enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := pkt.(*w3gs.MapCheck); !ok || m.FileSize != 123 {
+		t.Fatal("Expected MapCheck", pkt)
+	}
+	if _, err := w3gs.Write(cli, &w3gs.PlayerExtra{Type: w3gs.PlayerSkins}, enc); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w3gs.Write(cli, &w3gs.MapState{Ready: true, FileSize: 123}, enc); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		done <- conn.CountDown()
+	}()
+	if pkt, _, err := w3gs.Read(cli, enc); err != nil {
+		t.Fatal(err)
+	} else if _, ok := pkt.(*w3gs.CountDownStart); !ok {
+		t.Fatal("Expected CountDownStart", pkt)
+	}
+	if pkt, _, err := w3gs.Read(cli, enc); err != nil {
+		t.Fatal(err)
+	} else if _, ok := pkt.(*w3gs.CountDownEnd); !ok {
+		t.Fatal("Expected CountDownEnd", pkt)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		done <- conn.CheckMap(&w3gs.MapCheck{}, time.Second)
+	}()
+	if _, _, err := w3gs.Read(cli, enc); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w3gs.Write(cli, &w3gs.MapState{Ready: false}, enc); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != network.ErrMapUnavailable {
+		t.Fatal("Expected ErrMapUnavailable, got", err)
+	}
+}
@@ -33,10 +33,20 @@ type MDNSAdvertiser struct {
 	info w3gs.GameInfo
 	msgc int32
 
+	bmut  sync.Mutex
+	conn4 *ipv4.PacketConn
+
 	created time.Time
 
-	// Set once before Run(), read-only after that
+	ivch chan struct{}
+
+	// Set before Run(), use SetInterval() to change while running
 	BroadcastInterval time.Duration
+
+	// Network interfaces to advertise on, defaults to BroadcastInterfaces()
+	// The system default multicast interface is used when empty
+	// Set once before Run(), read-only after that
+	Interfaces []net.Interface
 }
 
 // NewMDNSAdvertiser initializes MDNSAdvertiser struct
@@ -53,7 +63,10 @@ func NewMDNSAdvertiser(info *w3gs.GameInfo) (*MDNSAdvertiser, error) {
 	var a = MDNSAdvertiser{
 		info:              *info,
 		created:           time.Now().Add(time.Duration(info.UptimeSec) * -time.Second),
+		ivch:              make(chan struct{}, 1),
+		conn4:             conn4,
 		BroadcastInterval: 3 * time.Minute,
+		Interfaces:        BroadcastInterfaces(),
 	}
 
 	a.InitDefaultHandlers()
@@ -76,8 +89,11 @@ func (a *MDNSAdvertiser) mdnsName() string {
 	a.imut.Lock()
 	var name = a.info.GameName
 	a.imut.Unlock()
+	return mdnsName(name)
+}
 
-	name = illegalChars.ReplaceAllStringFunc(name, func(s string) string {
+func mdnsName(gameName string) string {
+	var name = illegalChars.ReplaceAllStringFunc(gameName, func(s string) string {
 		return "\\" + s
 	})
 
@@ -104,15 +120,23 @@ func newMsg(id uint16) *dns.Msg {
 }
 
 func (a *MDNSAdvertiser) addPtr(msg *dns.Msg) {
-	msg.Answer = append(msg.Answer, &dns.PTR{
+	a.imut.Lock()
+	var ptr = newPtr(&a.info)
+	a.imut.Unlock()
+
+	msg.Answer = append(msg.Answer, ptr)
+}
+
+func newPtr(info *w3gs.GameInfo) *dns.PTR {
+	return &dns.PTR{
 		Hdr: dns.RR_Header{
-			Name:   a.mdnsService(),
+			Name:   mdnsService(&info.GameVersion),
 			Rrtype: dns.TypePTR,
 			Class:  dns.ClassINET | TypeCacheFlush,
 			Ttl:    4500,
 		},
-		Ptr: a.mdnsName(),
-	})
+		Ptr: mdnsName(info.GameName),
+	}
 }
 
 func (a *MDNSAdvertiser) addTxt(msg *dns.Msg) {
@@ -183,13 +207,7 @@ func (a *MDNSAdvertiser) addIP(msg *dns.Msg) {
 func (a *MDNSAdvertiser) addGameInfo(msg *dns.Msg) {
 	a.imut.Lock()
 
-	var data = gameData{
-		GameFlags:    a.info.GameFlags,
-		GameSettings: a.info.GameSettings,
-		SlotsTotal:   a.info.SlotsTotal,
-		GameName:     a.info.GameName,
-		GamePort:     a.info.GamePort,
-	}
+	var data = newGameData(&a.info)
 
 	var buf = protocol.Buffer{}
 	if err := data.SerializeContent(&buf, &w3gs.Encoding{GameVersion: a.info.GameVersion.Version}); err != nil {
@@ -237,7 +255,38 @@ func (a *MDNSAdvertiser) Create() error {
 	a.addSrv(msg)
 	a.addGameInfo(msg)
 
-	_, err := a.Broadcast(msg)
+	return a.broadcast(msg)
+}
+
+// broadcast msg on all interfaces, only fails if none of the interfaces could be reached
+func (a *MDNSAdvertiser) broadcast(msg *dns.Msg) error {
+	if len(a.Interfaces) == 0 {
+		_, err := a.Broadcast(msg)
+		return err
+	}
+
+	a.bmut.Lock()
+	defer a.bmut.Unlock()
+
+	var err error
+	var sent = false
+	for i := range a.Interfaces {
+		var e = a.conn4.SetMulticastInterface(&a.Interfaces[i])
+		if e == nil {
+			_, e = a.Broadcast(msg)
+		}
+		if e != nil {
+			err = e
+			if !network.IsCloseError(e) {
+				a.Fire(&BroadcastError{Iface: a.Interfaces[i].Name, Addr: &MulticastGroup, Err: e})
+			}
+		} else {
+			sent = true
+		}
+	}
+	if sent {
+		return nil
+	}
 	return err
 }
 
@@ -245,11 +294,10 @@ func (a *MDNSAdvertiser) refresh() error {
 	var msg = newMsg(0)
 	a.addGameInfo(msg)
 
-	_, err := a.Broadcast(msg)
-	return err
+	return a.broadcast(msg)
 }
 
-// Refresh game info
+// Refresh slot counts and broadcast them immediately
 func (a *MDNSAdvertiser) Refresh(slotsUsed uint32, slotsAvailable uint32) error {
 	a.imut.Lock()
 	a.info.SlotsUsed = slotsUsed
@@ -259,28 +307,90 @@ func (a *MDNSAdvertiser) Refresh(slotsUsed uint32, slotsAvailable uint32) error
 	return a.refresh()
 }
 
+// Update replaces the advertised game info and broadcasts the changes immediately
+// Returns ErrVersionMismatch if the new game version is discovered differently (UDP before 1.30, mDNS after)
+func (a *MDNSAdvertiser) Update(info *w3gs.GameInfo) error {
+	if w3gs.ProductName(info.GameVersion.Product) == "" {
+		return ErrUnknownProduct
+	}
+
+	a.imut.Lock()
+	var old = a.info
+	if udpVersion(&old.GameVersion) != udpVersion(&info.GameVersion) {
+		// Clients of the new version discover games using UDP broadcast instead of mDNS
+		a.imut.Unlock()
+		return ErrVersionMismatch
+	}
+	a.info = *info
+
+	var recreate = old.HostCounter != info.HostCounter || old.GameVersion != info.GameVersion ||
+		old.GameName != info.GameName || old.GamePort != info.GamePort
+	if recreate {
+		a.created = time.Now().Add(time.Duration(info.UptimeSec) * -time.Second)
+	}
+	a.imut.Unlock()
+
+	if !recreate {
+		return a.refresh()
+	}
+
+	// Service name changes, remove old entry
+	if err := a.decreate(newPtr(&old)); err != nil {
+		return err
+	}
+	return a.Create()
+}
+
 // Decreate game
 func (a *MDNSAdvertiser) Decreate() error {
-	var msg = newMsg(0)
-	a.addPtr(msg)
+	a.imut.Lock()
+	var ptr = newPtr(&a.info)
+	a.imut.Unlock()
 
-	msg.Answer[0].(*dns.PTR).Hdr.Ttl = 0
+	return a.decreate(ptr)
+}
 
-	_, err := a.Broadcast(msg)
-	return err
+func (a *MDNSAdvertiser) decreate(ptr *dns.PTR) error {
+	var msg = newMsg(0)
+	ptr.Hdr.Ttl = 0
+	msg.Answer = append(msg.Answer, ptr)
+
+	return a.broadcast(msg)
 }
 
 func (a *MDNSAdvertiser) runBroadcast() func() {
 	var stop = make(chan struct{})
 
 	go func() {
-		var ticker = time.NewTicker(a.BroadcastInterval)
+		var ticker *time.Ticker
+		var tick <-chan time.Time
+		var reset = func() {
+			if ticker != nil {
+				ticker.Stop()
+				ticker, tick = nil, nil
+			}
+
+			a.imut.Lock()
+			var interval = a.BroadcastInterval
+			a.imut.Unlock()
+
+			if interval > 0 {
+				ticker = time.NewTicker(interval)
+				tick = ticker.C
+			}
+		}
+
+		reset()
 		for {
 			select {
 			case <-stop:
-				ticker.Stop()
+				if ticker != nil {
+					ticker.Stop()
+				}
 				return
-			case <-ticker.C:
+			case <-a.ivch:
+				reset()
+			case <-tick:
 				if err := a.refresh(); err != nil && !network.IsCloseError(err) {
 					a.Fire(&network.AsyncError{Src: "runBroadcast[refresh]", Err: err})
 				}
@@ -293,17 +403,36 @@ func (a *MDNSAdvertiser) runBroadcast() func() {
 	}
 }
 
+// SetInterval changes the interval between game info broadcasts (no periodic broadcast if d <= 0)
+func (a *MDNSAdvertiser) SetInterval(d time.Duration) {
+	a.imut.Lock()
+	a.BroadcastInterval = d
+	a.imut.Unlock()
+
+	select {
+	case a.ivch <- struct{}{}:
+	default:
+	}
+}
+
 // Run broadcasts gameinfo in Local Area Network
 func (a *MDNSAdvertiser) Run() error {
+	for i := range a.Interfaces {
+		// Fails for the interface that joined in NewMDNSAdvertiser, ignore
+		a.conn4.JoinGroup(&a.Interfaces[i], &net.UDPAddr{IP: MulticastGroup.IP})
+	}
+
 	if err := a.Create(); err != nil {
-		return err
+		if network.IsCloseError(err) {
+			return err
+		}
+		// Interfaces may come up later, keep running
+		a.Fire(&network.AsyncError{Src: "Run[Create]", Err: err})
 	}
 	defer a.Decreate()
 
-	if a.BroadcastInterval > 0 {
-		var stop = a.runBroadcast()
-		defer stop()
-	}
+	var stop = a.runBroadcast()
+	defer stop()
 
 	return a.DNSPacketConn.Run(&a.EventEmitter, network.NoTimeout)
 }
@@ -376,7 +505,11 @@ func (a *MDNSAdvertiser) onDNS(ev *network.Event) {
 		if addInfo {
 			a.addGameInfo(ans)
 		}
-		if _, err := a.Send(addr, ans); err != nil && !network.IsCloseError(err) {
+		if addr == &MulticastGroup {
+			if err := a.broadcast(ans); err != nil && !network.IsCloseError(err) {
+				a.Fire(&network.AsyncError{Src: "onDNS[broadcast]", Err: err})
+			}
+		} else if _, err := a.Send(addr, ans); err != nil && !network.IsCloseError(err) {
 			a.Fire(&network.AsyncError{Src: "onDNS[Send]", Err: err})
 		}
 	}
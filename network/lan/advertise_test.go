@@ -0,0 +1,344 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package lan_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/network/lan"
+	"github.com/nielsAD/gowarcraft3/protocol"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+func TestBroadcastInterfaces(t *testing.T) {
+	for _, iface := range lan.BroadcastInterfaces() {
+		if iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagBroadcast == 0 {
+			t.Fatalf("Unexpected interface %v", iface)
+		}
+	}
+}
+
+func TestAdvertiserAddrs(t *testing.T) {
+	a, err := lan.NewUDPAdvertiser(&gameInfo, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	a.Interfaces = nil
+	if addrs := a.Addrs(); len(addrs) != 1 || addrs[0].String() != network.W3GSBroadcastAddr.String() {
+		t.Fatalf("Expected global broadcast address, got %v", addrs)
+	}
+
+	var custom = net.UDPAddr{IP: net.IPv4(10, 0, 0, 255), Port: 6112}
+	a.BroadcastAddrs = append(a.BroadcastAddrs, &custom)
+	if addrs := a.Addrs(); len(addrs) != 1 || addrs[0] != &custom {
+		t.Fatalf("Expected custom broadcast address, got %v", addrs)
+	}
+
+	if lo, err := net.InterfaceByName("lo"); err == nil {
+		a.Interfaces = []net.Interface{*lo}
+		if addrs := a.Addrs(); len(addrs) < 2 || !addrs[0].IP.Equal(net.IPv4(127, 255, 255, 255)) {
+			t.Fatalf("Expected loopback broadcast address, got %v", addrs)
+		}
+	}
+}
+
+func TestAdvertiserUpdate(t *testing.T) {
+	var info = gameInfo
+	info.GameVersion = w3gs.GameVersion{
+		Product: w3gs.ProductTFT,
+		Version: 26,
+	}
+
+	g, err := lan.NewUDPGameList(info.GameVersion, 6112)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	a, err := lan.NewUDPAdvertiser(&info, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	a.SetInterval(0)
+
+	go g.Run()
+	go a.Run()
+	time.Sleep(wait)
+
+	var game = func() *w3gs.GameInfo {
+		for _, v := range g.Games() {
+			return &v
+		}
+		return nil
+	}
+
+	if gi := game(); gi == nil || gi.GameName != info.GameName {
+		t.Fatal("Game not found after create")
+	}
+
+	info.SlotsUsed++
+	if err := a.Update(&info); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(wait)
+
+	if gi := game(); gi == nil || gi.SlotsUsed != info.SlotsUsed {
+		t.Fatal("Slots not updated")
+	}
+
+	info.GameName += " (updated)"
+	if err := a.Update(&info); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(wait)
+
+	if gi := game(); gi == nil || gi.GameName != info.GameName {
+		t.Fatal("Game name not updated")
+	}
+
+	info.HostCounter++
+	if err := a.Update(&info); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(wait)
+
+	if games := g.Games(); len(games) != 1 {
+		t.Fatalf("Expected 1 game after host counter change, found %d", len(games))
+	}
+	if gi := game(); gi == nil || gi.HostCounter != info.HostCounter {
+		t.Fatal("Host counter not updated")
+	}
+}
+
+func TestAdvertiserSearchGame(t *testing.T) {
+	var info = gameInfo
+	info.GameVersion = w3gs.GameVersion{
+		Product: w3gs.ProductTFT,
+		Version: 26,
+	}
+
+	a, err := lan.NewUDPAdvertiser(&info, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	a.SetInterval(0)
+	go a.Run()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c = network.NewW3GSPacketConn(conn, nil, w3gs.Encoding{GameVersion: info.GameVersion.Version})
+	defer c.Close()
+
+	var addr = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: a.Conn().LocalAddr().(*net.UDPAddr).Port}
+
+	// Different version, no reply expected
+	if _, err := c.Send(addr, &w3gs.SearchGame{GameVersion: w3gs.GameVersion{Product: w3gs.ProductROC, Version: 26}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Send(addr, &w3gs.SearchGame{GameVersion: w3gs.GameVersion{Product: w3gs.ProductTFT, Version: 29}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Send(addr, &w3gs.SearchGame{GameVersion: info.GameVersion, HostCounter: 7}); err != nil {
+		t.Fatal(err)
+	}
+
+	pkt, src, err := c.NextPacket(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.(*net.UDPAddr).Port != addr.Port {
+		t.Fatal("Unexpected source address", src)
+	}
+	if gi, ok := pkt.(*w3gs.GameInfo); !ok || gi.GameName != info.GameName || gi.HostCounter != info.HostCounter {
+		t.Fatal("Expected GameInfo reply", pkt)
+	}
+
+	if pkt, _, err := c.NextPacket(wait); err == nil {
+		t.Fatal("Unexpected second reply", pkt)
+	}
+
+	// Version change while running
+	info.GameVersion.Version = 29
+	if err := a.Update(&info); err != nil {
+		t.Fatal(err)
+	}
+	c.SetEncoding(w3gs.Encoding{GameVersion: info.GameVersion.Version})
+	if _, err := c.Send(addr, &w3gs.SearchGame{GameVersion: info.GameVersion}); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		pkt, _, err := c.NextPacket(time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gi, ok := pkt.(*w3gs.GameInfo); ok && gi.GameVersion == info.GameVersion {
+			break
+		}
+	}
+}
+
+func TestAdvertiserBroadcastError(t *testing.T) {
+	a, err := lan.NewUDPAdvertiser(&gameInfo, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	var errs = make(chan *lan.BroadcastError, 8)
+	a.On(&lan.BroadcastError{}, func(ev *network.Event) {
+		errs <- ev.Arg.(*lan.BroadcastError)
+	})
+
+	// Partial failure is not an error
+	var bad = net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0}
+	var ok = net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 6112}
+	a.Interfaces = nil
+	a.BroadcastAddrs = []*net.UDPAddr{&bad, &ok}
+	if err := a.Create(); err != nil {
+		t.Fatal(err)
+	}
+	if e := <-errs; e.Addr != &bad || e.Iface != "" || e.Err == nil {
+		t.Fatal("Unexpected broadcast error", e)
+	}
+
+	// Run keeps going if no address could be reached
+	a.BroadcastAddrs = a.BroadcastAddrs[:1]
+	a.SetInterval(0)
+
+	var done = make(chan error)
+	go func() {
+		done <- a.Run()
+	}()
+
+	select {
+	case e := <-errs:
+		if e.Addr != &bad {
+			t.Fatal("Unexpected broadcast error", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected broadcast error")
+	}
+
+	select {
+	case err := <-done:
+		t.Fatal("Run returned early", err)
+	case <-time.After(wait):
+	}
+
+	a.Close()
+	if err := <-done; !network.IsCloseError(err) {
+		t.Fatal("Expected close error", err)
+	}
+}
+
+func TestAdvertiserProduct(t *testing.T) {
+	var info = gameInfo
+	for _, p := range []protocol.DWordString{w3gs.ProductROC, w3gs.ProductTFT} {
+		info.GameVersion = w3gs.GameVersion{Product: p, Version: 26}
+
+		a, err := lan.NewAdvertiser(&info)
+		if err != nil {
+			t.Fatal(p, err)
+		}
+		a.Close()
+	}
+
+	info.GameVersion = w3gs.GameVersion{Product: protocol.DString("ABCD"), Version: 26}
+	if _, err := lan.NewAdvertiser(&info); err != lan.ErrUnknownProduct {
+		t.Fatal("Expected ErrUnknownProduct, got", err)
+	}
+}
+
+func TestGameInfoEncoding(t *testing.T) {
+	var versions = []w3gs.GameVersion{
+		{Product: w3gs.ProductTFT, Version: 26},
+		{Product: w3gs.ProductTFT, Version: 30},
+		{Product: w3gs.ProductTFT, Version: w3gs.ReforgedVersionOffset + 32},
+	}
+
+	for _, gv := range versions {
+		var info = gameInfo
+		info.GameVersion = gv
+
+		b, err := lan.EncodeGameInfo(&info)
+		if err != nil {
+			t.Fatal(gv, err)
+		}
+
+		res, err := lan.DecodeGameInfo(b, gv)
+		if err != nil {
+			t.Fatal(gv, err)
+		}
+		if res.GameVersion != gv || res.GameName != info.GameName || res.GameFlags != info.GameFlags ||
+			res.GameSettings != info.GameSettings || res.SlotsTotal != info.SlotsTotal || res.GamePort != info.GamePort {
+			t.Fatalf("%v: game info differs after decode: %+v", gv, res)
+		}
+
+		for _, other := range versions {
+			if other == gv {
+				continue
+			}
+			if _, err := lan.DecodeGameInfo(b, other); err == nil {
+				t.Fatalf("%v: expected decode with encoding of %v to fail", gv, other)
+			}
+		}
+	}
+
+	var info = gameInfo
+	info.GameVersion = w3gs.GameVersion{Product: protocol.DString("ABCD"), Version: 26}
+	if _, err := lan.EncodeGameInfo(&info); err != lan.ErrUnknownProduct {
+		t.Fatal("Expected ErrUnknownProduct, got", err)
+	}
+}
+
+func TestAdvertiserEncoding(t *testing.T) {
+	var info = gameInfo
+	info.GameVersion = w3gs.GameVersion{Product: w3gs.ProductTFT, Version: 26}
+
+	a, err := lan.NewUDPAdvertiser(&info, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	a.Interfaces = nil
+	a.BroadcastAddrs = []*net.UDPAddr{{IP: net.IPv4(127, 0, 0, 1), Port: 6112}}
+
+	if enc := a.Encoding(); enc.GameVersion != 26 {
+		t.Fatal("Unexpected encoding", enc)
+	}
+
+	info.GameVersion.Version = 28
+	if err := a.Update(&info); err != nil {
+		t.Fatal(err)
+	}
+	if enc := a.Encoding(); enc.GameVersion != 28 {
+		t.Fatal("Encoding not updated with game version", enc)
+	}
+
+	info.GameVersion.Version = 30
+	if err := a.Update(&info); err != lan.ErrVersionMismatch {
+		t.Fatal("Expected ErrVersionMismatch, got", err)
+	}
+	if enc := a.Encoding(); enc.GameVersion != 28 {
+		t.Fatal("Encoding changed after failed update", enc)
+	}
+
+	info.GameVersion.Product = protocol.DString("ABCD")
+	if err := a.Update(&info); err != lan.ErrUnknownProduct {
+		t.Fatal("Expected ErrUnknownProduct, got", err)
+	}
+}
@@ -23,8 +23,17 @@ type UDPAdvertiser struct {
 
 	created time.Time
 
-	// Set once before Run(), read-only after that
+	ivch chan struct{}
+
+	// Set before Run(), use SetInterval() to change while running
 	BroadcastInterval time.Duration
+
+	// Network interfaces to advertise on (directed broadcast), defaults to BroadcastInterfaces()
+	// Additional broadcast addresses can be added in BroadcastAddrs
+	// The global broadcast address is used when both are empty
+	// Set once before Run(), read-only after that
+	Interfaces     []net.Interface
+	BroadcastAddrs []*net.UDPAddr
 }
 
 // NewUDPAdvertiser initializes UDPAdvertiser struct
@@ -37,7 +46,9 @@ func NewUDPAdvertiser(info *w3gs.GameInfo, port int) (*UDPAdvertiser, error) {
 	var a = UDPAdvertiser{
 		info:              *info,
 		created:           time.Now().Add(time.Duration(info.UptimeSec) * -time.Second),
+		ivch:              make(chan struct{}, 1),
 		BroadcastInterval: 3 * time.Second,
+		Interfaces:        BroadcastInterfaces(),
 	}
 
 	a.InitDefaultHandlers()
@@ -56,7 +67,57 @@ func (a *UDPAdvertiser) Create() error {
 	}
 	a.imut.Unlock()
 
-	_, err := a.Broadcast(&pkt)
+	return a.broadcast(&pkt)
+}
+
+type broadcastTarget struct {
+	iface string
+	addr  *net.UDPAddr
+}
+
+func (a *UDPAdvertiser) targets() []broadcastTarget {
+	var res []broadcastTarget
+	for i := range a.Interfaces {
+		for _, addr := range interfaceBroadcastAddrs(a.Interfaces[i:i+1], network.W3GSBroadcastAddr.Port) {
+			res = append(res, broadcastTarget{iface: a.Interfaces[i].Name, addr: addr})
+		}
+	}
+	for _, addr := range a.BroadcastAddrs {
+		res = append(res, broadcastTarget{addr: addr})
+	}
+	if len(res) == 0 {
+		res = append(res, broadcastTarget{addr: &network.W3GSBroadcastAddr})
+	}
+	return res
+}
+
+// Addrs returns the addresses that will be used to advertise the game
+func (a *UDPAdvertiser) Addrs() []*net.UDPAddr {
+	var targets = a.targets()
+	var addrs = make([]*net.UDPAddr, len(targets))
+	for i, t := range targets {
+		addrs[i] = t.addr
+	}
+	return addrs
+}
+
+// broadcast pkt to all addresses, only fails if none of the addresses could be reached
+func (a *UDPAdvertiser) broadcast(pkt w3gs.Packet) error {
+	var err error
+	var sent = false
+	for _, t := range a.targets() {
+		if _, e := a.Send(t.addr, pkt); e != nil {
+			err = e
+			if !network.IsCloseError(e) {
+				a.Fire(&BroadcastError{Iface: t.iface, Addr: t.addr, Err: e})
+			}
+		} else {
+			sent = true
+		}
+	}
+	if sent {
+		return nil
+	}
 	return err
 }
 
@@ -69,11 +130,10 @@ func (a *UDPAdvertiser) refresh() error {
 	}
 	a.imut.Unlock()
 
-	_, err := a.Broadcast(&pkt)
-	return err
+	return a.broadcast(&pkt)
 }
 
-// Refresh game info
+// Refresh slot counts and broadcast them immediately
 func (a *UDPAdvertiser) Refresh(slotsUsed uint32, slotsAvailable uint32) error {
 	a.imut.Lock()
 	a.info.SlotsUsed = slotsUsed
@@ -83,6 +143,50 @@ func (a *UDPAdvertiser) Refresh(slotsUsed uint32, slotsAvailable uint32) error {
 	return a.refresh()
 }
 
+// Update replaces the advertised game info and broadcasts the changes immediately
+// Returns ErrVersionMismatch if the new game version is discovered differently (UDP before 1.30, mDNS after)
+func (a *UDPAdvertiser) Update(info *w3gs.GameInfo) error {
+	if w3gs.ProductName(info.GameVersion.Product) == "" {
+		return ErrUnknownProduct
+	}
+
+	a.imut.Lock()
+	var old = a.info
+	if udpVersion(&old.GameVersion) != udpVersion(&info.GameVersion) {
+		// Clients of the new version do not discover games using UDP broadcast
+		a.imut.Unlock()
+		return ErrVersionMismatch
+	}
+	a.info = *info
+	a.imut.Unlock()
+
+	var slots = old
+	slots.SlotsUsed = info.SlotsUsed
+	slots.SlotsAvailable = info.SlotsAvailable
+	slots.UptimeSec = info.UptimeSec
+
+	switch {
+	case old.HostCounter != info.HostCounter || old.GameVersion != info.GameVersion:
+		var err = a.broadcast(&w3gs.DecreateGame{HostCounter: old.HostCounter})
+
+		// Packets for the new game must match its version, old game was removed with the old encoding
+		a.SetEncoding(w3gs.Encoding{GameVersion: info.GameVersion.Version})
+		if err != nil {
+			return err
+		}
+
+		a.imut.Lock()
+		a.created = time.Now().Add(time.Duration(info.UptimeSec) * -time.Second)
+		a.imut.Unlock()
+		return a.Create()
+	case slots == *info:
+		return a.refresh()
+	default:
+		// Game lists query game info after create
+		return a.Create()
+	}
+}
+
 // Decreate game
 func (a *UDPAdvertiser) Decreate() error {
 	a.imut.Lock()
@@ -91,21 +195,42 @@ func (a *UDPAdvertiser) Decreate() error {
 	}
 	a.imut.Unlock()
 
-	_, err := a.Broadcast(&pkt)
-	return err
+	return a.broadcast(&pkt)
 }
 
 func (a *UDPAdvertiser) runBroadcast() func() {
 	var stop = make(chan struct{})
 
 	go func() {
-		var ticker = time.NewTicker(a.BroadcastInterval)
+		var ticker *time.Ticker
+		var tick <-chan time.Time
+		var reset = func() {
+			if ticker != nil {
+				ticker.Stop()
+				ticker, tick = nil, nil
+			}
+
+			a.imut.Lock()
+			var interval = a.BroadcastInterval
+			a.imut.Unlock()
+
+			if interval > 0 {
+				ticker = time.NewTicker(interval)
+				tick = ticker.C
+			}
+		}
+
+		reset()
 		for {
 			select {
 			case <-stop:
-				ticker.Stop()
+				if ticker != nil {
+					ticker.Stop()
+				}
 				return
-			case <-ticker.C:
+			case <-a.ivch:
+				reset()
+			case <-tick:
 				if err := a.refresh(); err != nil && !network.IsCloseError(err) {
 					a.Fire(&network.AsyncError{Src: "runBroadcast[refresh]", Err: err})
 				}
@@ -118,17 +243,31 @@ func (a *UDPAdvertiser) runBroadcast() func() {
 	}
 }
 
+// SetInterval changes the interval between game info broadcasts (no periodic broadcast if d <= 0)
+func (a *UDPAdvertiser) SetInterval(d time.Duration) {
+	a.imut.Lock()
+	a.BroadcastInterval = d
+	a.imut.Unlock()
+
+	select {
+	case a.ivch <- struct{}{}:
+	default:
+	}
+}
+
 // Run broadcasts gameinfo in Local Area Network
 func (a *UDPAdvertiser) Run() error {
 	if err := a.Create(); err != nil {
-		return err
+		if network.IsCloseError(err) {
+			return err
+		}
+		// Interfaces may come up later, keep running
+		a.Fire(&network.AsyncError{Src: "Run[Create]", Err: err})
 	}
 	defer a.Decreate()
 
-	if a.BroadcastInterval > 0 {
-		var stop = a.runBroadcast()
-		defer stop()
-	}
+	var stop = a.runBroadcast()
+	defer stop()
 
 	return a.W3GSPacketConn.Run(&a.EventEmitter, network.NoTimeout)
 }
@@ -146,11 +285,13 @@ func (a *UDPAdvertiser) InitDefaultHandlers() {
 	a.On(&w3gs.SearchGame{}, a.onSearchGame)
 }
 
+// onSearchGame replies with the current game info directly to the searching client,
+// so that it does not have to wait for the next broadcast
 func (a *UDPAdvertiser) onSearchGame(ev *network.Event) {
 	var pkt = ev.Arg.(*w3gs.SearchGame)
 
 	a.imut.Lock()
-	if pkt.Product != a.info.Product {
+	if pkt.GameVersion != a.info.GameVersion {
 		a.imut.Unlock()
 		return
 	}
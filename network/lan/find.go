@@ -0,0 +1,140 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package lan
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// SearchGames broadcasts w3gs.SearchGame on every network interface and collects the responses
+// until timeout. Games are de-duplicated by host counter. Map key is the remote address.
+func SearchGames(gv w3gs.GameVersion, timeout time.Duration) (map[string]w3gs.GameInfo, error) {
+	if gv.Version >= 30 || gv.Version == 0 {
+		return searchGamesMDNS(gv, timeout)
+	}
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, err
+	}
+
+	var c = network.NewW3GSPacketConn(conn, w3gs.NewFactoryCache(w3gs.DefaultFactory), w3gs.Encoding{GameVersion: gv.Version})
+	defer c.Close()
+
+	var sg = w3gs.SearchGame{GameVersion: gv}
+	var addrs = append(interfaceBroadcastAddrs(BroadcastInterfaces(), network.W3GSBroadcastAddr.Port), &network.W3GSBroadcastAddr)
+
+	var sent = 0
+	for _, a := range addrs {
+		if _, err = c.Send(a, &sg); err == nil {
+			sent++
+		}
+	}
+	if sent == 0 {
+		return nil, err
+	}
+
+	type gameID struct {
+		hostCounter uint32
+		entryKey    uint32
+	}
+
+	var res = make(map[string]w3gs.GameInfo)
+	var ids = make(map[gameID]bool)
+	var deadline = time.Now().Add(timeout)
+
+	for {
+		var left = time.Until(deadline)
+		if left <= 0 {
+			break
+		}
+
+		pkt, adr, err := c.NextPacket(left)
+		if err != nil {
+			if network.IsTimeout(err) {
+				break
+			}
+			// Connection is still valid after decode errors, only deserialization failed
+			if network.IsDecodeError(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		info, ok := pkt.(*w3gs.GameInfo)
+		if !ok || info.GameVersion != gv || info.GamePort == 0 {
+			continue
+		}
+
+		var id = gameID{info.HostCounter, info.EntryKey}
+		if ids[id] {
+			continue
+		}
+		ids[id] = true
+
+		var host = adr.String()
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = fmt.Sprintf("%s:%d", h, info.GamePort)
+		}
+		res[host] = *info
+	}
+
+	return res, nil
+}
+
+func searchGamesMDNS(gv w3gs.GameVersion, timeout time.Duration) (map[string]w3gs.GameInfo, error) {
+	g, err := NewMDNSGameList(gv)
+	if err != nil {
+		return nil, err
+	}
+
+	var stop = make(chan error, 1)
+	go func() {
+		stop <- g.Run()
+	}()
+
+	select {
+	case err = <-stop:
+		if network.IsCloseError(err) {
+			err = nil
+		}
+	case <-time.After(timeout):
+	}
+
+	var res = g.Games()
+	g.Close()
+
+	return res, err
+}
+
+// FindGames broadcasts w3gs.SearchGame on every network interface and returns all
+// (unique) games that responded within timeout, sorted by game name.
+func FindGames(gv w3gs.GameVersion, timeout time.Duration) ([]*w3gs.GameInfo, error) {
+	games, err := SearchGames(gv, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	var res = make([]*w3gs.GameInfo, 0, len(games))
+	for _, g := range games {
+		var info = g
+		res = append(res, &info)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].GameName != res[j].GameName {
+			return res[i].GameName < res[j].GameName
+		}
+		return res[i].HostCounter < res[j].HostCounter
+	})
+
+	return res, nil
+}
@@ -0,0 +1,58 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package lan_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/network/lan"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+func TestFindGames(t *testing.T) {
+	var info = gameInfo
+	info.GameVersion = w3gs.GameVersion{
+		Product: w3gs.ProductTFT,
+		Version: 26,
+	}
+
+	// Advertiser needs to bind port 6112 to receive broadcasts
+	a, err := lan.NewUDPAdvertiser(&info, 6112)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.On(&network.AsyncError{}, func(ev *network.Event) {
+		t.Fatal(ev.Arg.(*network.AsyncError))
+	})
+
+	go a.Run()
+	time.Sleep(wait)
+
+	games, err := lan.FindGames(info.GameVersion, 4*wait)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 1 {
+		t.Fatalf("Expected 1 game, found %d", len(games))
+	}
+	if games[0].GameName != info.GameName || games[0].HostCounter != info.HostCounter {
+		t.Fatalf("Unexpected game %v", games[0])
+	}
+
+	var other = info.GameVersion
+	other.Version++
+	if games, err = lan.FindGames(other, 4*wait); err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 0 {
+		t.Fatal("Game found with different game version")
+	}
+
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -7,14 +7,42 @@ package lan
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
 
 	"github.com/nielsAD/gowarcraft3/network"
 	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
 )
 
+// Errors
+var (
+	ErrUnknownProduct  = errors.New("lan: Unknown game product")
+	ErrVersionMismatch = errors.New("lan: Game version mismatch")
+	ErrNotGameInfo     = errors.New("lan: Packet is not a game advertisement")
+)
+
 // Update event for GameList changes
 type Update struct{}
 
+// BroadcastError event, emitted by Advertiser for every interface (or address) that could not be reached.
+// Broadcasting continues on the other interfaces.
+type BroadcastError struct {
+	Iface string // Empty for addresses not bound to an interface
+	Addr  net.Addr
+	Err   error
+}
+
+func (e *BroadcastError) Error() string {
+	return fmt.Sprintf("%s[%v]:%v", e.Iface, e.Addr, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *BroadcastError) Unwrap() error {
+	return e.Err
+}
+
 // GameList keeps track of all the hosted games in the Local Area Network
 // Emits events for every received packet and Update{} when the output of Games() changes
 type GameList interface {
@@ -24,9 +52,14 @@ type GameList interface {
 	Close() error
 }
 
+// udpVersion returns true if clients of version gv discover games using UDP broadcast (before 1.30), false for mDNS
+func udpVersion(gv *w3gs.GameVersion) bool {
+	return gv.Version > 0 && gv.Version < 30
+}
+
 // NewGameList initializes proper GameList type for game version
 func NewGameList(gv w3gs.GameVersion) (GameList, error) {
-	if gv.Version > 0 && gv.Version < 30 {
+	if udpVersion(&gv) {
 		// Use random port to not occupy port 6112 by default
 		return NewUDPGameList(gv, 0)
 	}
@@ -35,21 +68,80 @@ func NewGameList(gv w3gs.GameVersion) (GameList, error) {
 }
 
 // Advertiser broadcasts available game information to the Local Area Network
-// Emits events for every received packet, responds to search queries
+// Emits events for every received packet and BroadcastError for unreachable interfaces, responds to search queries
+// Run only fails if the underlying connection fails, broadcast errors are emitted as events
 type Advertiser interface {
 	network.Listener
 
 	Create() error
 	Refresh(slotsUsed uint32, slotsAvailable uint32) error
+	Update(info *w3gs.GameInfo) error
 	Decreate() error
 
+	SetInterval(d time.Duration)
+
 	Run() error
 	Close() error
 }
 
+// BroadcastInterfaces returns all network interfaces that are up, broadcast-capable and not a loopback
+func BroadcastInterfaces() []net.Interface {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+
+	var res []net.Interface
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagBroadcast == 0 {
+			continue
+		}
+		res = append(res, iface)
+	}
+
+	return res
+}
+
+// interfaceBroadcastAddrs returns the directed IPv4 broadcast address (at port) of every address assigned to ifaces
+func interfaceBroadcastAddrs(ifaces []net.Interface, port int) []*net.UDPAddr {
+	var res []*net.UDPAddr
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+
+		for _, a := range addrs {
+			ipnet, ok := a.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			var ip = ipnet.IP.To4()
+			if ip == nil || len(ipnet.Mask) != net.IPv4len {
+				continue
+			}
+
+			var bc = make(net.IP, net.IPv4len)
+			for i := range ip {
+				bc[i] = ip[i] | ^ipnet.Mask[i]
+			}
+			res = append(res, &net.UDPAddr{IP: bc, Port: port})
+		}
+	}
+
+	return res
+}
+
 // NewAdvertiser initializes proper Advertiser type for game version
+//
+// Clients only see games of their own product, so a ROC game is never listed by TFT clients (and vice versa).
+// Returns ErrUnknownProduct if info.GameVersion.Product is not a Warcraft III product.
 func NewAdvertiser(info *w3gs.GameInfo) (Advertiser, error) {
-	if info.GameVersion.Version > 0 && info.GameVersion.Version < 30 {
+	if w3gs.ProductName(info.GameVersion.Product) == "" {
+		return nil, ErrUnknownProduct
+	}
+
+	if udpVersion(&info.GameVersion) {
 		// Use random port to not occupy port 6112 by default
 		return NewUDPAdvertiser(info, 0)
 	}
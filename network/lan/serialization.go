@@ -38,6 +38,16 @@ type gameData struct {
 	GamePort     uint16
 }
 
+func newGameData(info *w3gs.GameInfo) gameData {
+	return gameData{
+		GameFlags:    info.GameFlags,
+		GameSettings: info.GameSettings,
+		SlotsTotal:   info.SlotsTotal,
+		GameName:     info.GameName,
+		GamePort:     info.GamePort,
+	}
+}
+
 // Serialize encodes the struct into its binary form.
 func (pkt *gameData) SerializeContent(buf *protocol.Buffer, enc *w3gs.Encoding) error {
 	// Reforged swapped game name and game flags records
@@ -118,3 +128,58 @@ func (pkt *gameData) DeserializeContent(buf *protocol.Buffer, enc *w3gs.Encoding
 
 	return nil
 }
+
+// EncodeGameInfo serializes info in the LAN advertisement format of info.GameVersion, that is
+// a W3GS GameInfo packet for versions before 1.30 (UDP) or the game data record for later versions (mDNS).
+func EncodeGameInfo(info *w3gs.GameInfo) ([]byte, error) {
+	if w3gs.ProductName(info.GameVersion.Product) == "" {
+		return nil, ErrUnknownProduct
+	}
+
+	var enc = w3gs.Encoding{GameVersion: info.GameVersion.Version}
+	if udpVersion(&info.GameVersion) {
+		return w3gs.Serialize(info, enc)
+	}
+
+	var buf protocol.Buffer
+	var data = newGameData(info)
+	if err := data.SerializeContent(&buf, &enc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes, nil
+}
+
+// DecodeGameInfo decodes a LAN advertisement for game version gv (see EncodeGameInfo).
+// Game data records (mDNS) only contain the game name, flags, settings, total slots and port.
+func DecodeGameInfo(b []byte, gv w3gs.GameVersion) (*w3gs.GameInfo, error) {
+	var enc = w3gs.Encoding{GameVersion: gv.Version}
+	if !udpVersion(&gv) {
+		var data gameData
+		if err := data.DeserializeContent(&protocol.Buffer{Bytes: b}, &enc); err != nil {
+			return nil, err
+		}
+		return &w3gs.GameInfo{
+			GameVersion:  gv,
+			GameName:     data.GameName,
+			GameSettings: data.GameSettings,
+			SlotsTotal:   data.SlotsTotal,
+			GameFlags:    data.GameFlags,
+			GamePort:     data.GamePort,
+		}, nil
+	}
+
+	pkt, _, err := w3gs.Deserialize(b, enc)
+	if err != nil {
+		return nil, err
+	}
+
+	info, ok := pkt.(*w3gs.GameInfo)
+	if !ok {
+		return nil, ErrNotGameInfo
+	}
+	if info.GameVersion != gv {
+		return nil, ErrVersionMismatch
+	}
+
+	return info, nil
+}
@@ -0,0 +1,215 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/protocol/bncs"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// TCPListener accepts TCP connections with the socket options used by the game
+type TCPListener struct {
+	l *net.TCPListener
+}
+
+// Listen on addr (default port 6112, any port if addr is empty)
+func Listen(addr string) (*TCPListener, error) {
+	if addr != "" && !strings.ContainsRune(addr, ':') {
+		addr += ":6112"
+	}
+
+	tcpAddr, err := net.ResolveTCPAddr("tcp4", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := net.ListenTCP("tcp4", tcpAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TCPListener{l: l}, nil
+}
+
+// Addr returns the listener's network address
+func (l *TCPListener) Addr() net.Addr {
+	return l.l.Addr()
+}
+
+// SetDeadline sets the deadline for Accept calls
+func (l *TCPListener) SetDeadline(t time.Time) error {
+	return l.l.SetDeadline(t)
+}
+
+// Close stops listening, blocked Accept calls will return an error
+func (l *TCPListener) Close() error {
+	return l.l.Close()
+}
+
+// AcceptTCP waits for the next incoming connection
+func (l *TCPListener) AcceptTCP() (*net.TCPConn, error) {
+	conn, err := l.l.AcceptTCP()
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetNoDelay(true)
+	return conn, nil
+}
+
+// W3GSListener accepts W3GS connections
+type W3GSListener struct {
+	*TCPListener
+
+	// Factory shared by all accepted connections, must be safe for concurrent use
+	// (new w3gs.FactoryCache per connection if nil, since FactoryCache is not)
+	Factory  w3gs.PacketFactory
+	Encoding w3gs.Encoding
+}
+
+// ListenW3GS listens for W3GS connections on addr (see Listen)
+func ListenW3GS(addr string, fact w3gs.PacketFactory, enc w3gs.Encoding) (*W3GSListener, error) {
+	l, err := Listen(addr)
+	if err != nil {
+		return nil, err
+	}
+	return &W3GSListener{TCPListener: l, Factory: fact, Encoding: enc}, nil
+}
+
+// Accept waits for the next incoming connection and wraps it in W3GSConn
+func (l *W3GSListener) Accept() (*W3GSConn, error) {
+	conn, err := l.AcceptTCP()
+	if err != nil {
+		return nil, err
+	}
+
+	var fact = l.Factory
+	if fact == nil {
+		fact = w3gs.NewFactoryCache(w3gs.DefaultFactory)
+	}
+
+	return NewW3GSConn(conn, fact, l.Encoding), nil
+}
+
+// BNCSListener accepts BNCS connections (server side)
+type BNCSListener struct {
+	*TCPListener
+
+	// Factory shared by all accepted connections, must be safe for concurrent use
+	// (new bncs.FactoryCache per connection if nil, since FactoryCache is not)
+	Factory  bncs.PacketFactory
+	Encoding bncs.Encoding
+
+	// Time to wait for the protocol greeting of a new connection
+	GreetingTimeout time.Duration
+
+	once  sync.Once
+	conn  chan *BNCSConn
+	err   chan error
+	stop  chan struct{}
+	serr  error
+	cmut  sync.Mutex
+	done  chan struct{}
+	close bool
+}
+
+// ListenBNCS listens for BNCS connections on addr (see Listen)
+func ListenBNCS(addr string, fact bncs.PacketFactory, enc bncs.Encoding) (*BNCSListener, error) {
+	l, err := Listen(addr)
+	if err != nil {
+		return nil, err
+	}
+	return &BNCSListener{TCPListener: l, Factory: fact, Encoding: enc, GreetingTimeout: 5 * time.Second}, nil
+}
+
+func (l *BNCSListener) init() {
+	l.once.Do(func() {
+		l.conn = make(chan *BNCSConn)
+		l.err = make(chan error)
+		l.stop = make(chan struct{})
+		l.done = make(chan struct{})
+		go l.accept()
+	})
+}
+
+// accept connections in the background, so that a slow greeting does not hold up other connections
+func (l *BNCSListener) accept() {
+	for {
+		conn, err := l.AcceptTCP()
+		if err != nil {
+			if !IsTimeout(err) {
+				l.serr = err
+				close(l.stop)
+				return
+			}
+			select {
+			case l.err <- err:
+				continue
+			case <-l.done:
+				return
+			}
+		}
+
+		go l.greet(conn)
+	}
+}
+
+// greet waits for the protocol greeting of conn and hands it over to Accept
+func (l *BNCSListener) greet(conn *net.TCPConn) {
+	var greet [1]byte
+	conn.SetReadDeadline(Deadline(l.GreetingTimeout))
+	if _, err := conn.Read(greet[:]); err != nil || greet[0] != bncs.ProtocolGreeting {
+		conn.Close()
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	var fact = l.Factory
+	if fact == nil {
+		fact = bncs.NewFactoryCache(bncs.DefaultFactory)
+	}
+
+	select {
+	case l.conn <- NewBNCSConn(conn, fact, l.Encoding):
+	case <-l.done:
+		conn.Close()
+	case <-l.stop:
+		conn.Close()
+	}
+}
+
+// Accept waits for the next incoming connection that sends the protocol greeting and wraps it in BNCSConn.
+// Greetings are read concurrently, connections that do not send one within GreetingTimeout are closed and skipped.
+func (l *BNCSListener) Accept() (*BNCSConn, error) {
+	l.init()
+
+	select {
+	case c := <-l.conn:
+		return c, nil
+	case err := <-l.err:
+		return nil, err
+	case <-l.stop:
+		return nil, l.serr
+	}
+}
+
+// Close stops listening, blocked Accept calls will return an error
+func (l *BNCSListener) Close() error {
+	l.init()
+
+	l.cmut.Lock()
+	if !l.close {
+		l.close = true
+		close(l.done)
+	}
+	l.cmut.Unlock()
+
+	return l.TCPListener.Close()
+}
@@ -0,0 +1,99 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol/bncs"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+func TestListener(t *testing.T) {
+	var enc = w3gs.Encoding{GameVersion: 26}
+
+	l, err := network.ListenW3GS("127.0.0.1:0", nil, enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	var d = network.Dialer{GameVersion: w3gs.GameVersion{Product: w3gs.ProductTFT, Version: 26}}
+	w3, err := d.DialW3GS(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w3.Close()
+
+	c1, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c1.Close()
+
+	if _, err := w3.Send(&w3gs.Ping{Payload: 123}); err != nil {
+		t.Fatal(err)
+	}
+	pkt, err := c1.NextPacket(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, ok := pkt.(*w3gs.Ping); !ok || p.Payload != 123 {
+		t.Fatal("Unexpected packet", pkt)
+	}
+
+	b, err := network.ListenBNCS("127.0.0.1:0", nil, bncs.Encoding{Request: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	b.GreetingTimeout = time.Minute
+
+	// No greeting, must not hold up other connections
+	silent, err := net.Dial("tcp4", b.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+
+	bn, err := d.DialBNCS(b.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bn.Close()
+
+	var start = time.Now()
+	c2, err := b.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+	if time.Since(start) > 10*time.Second {
+		t.Fatal("Accept blocked by silent connection")
+	}
+
+	if _, err := bn.Send(&bncs.KeepAlive{}); err != nil {
+		t.Fatal(err)
+	}
+	pkt2, err := c2.NextPacket(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pkt2.(*bncs.KeepAlive); !ok {
+		t.Fatal("Unexpected packet", pkt2)
+	}
+
+	l.Close()
+	if _, err := l.Accept(); err == nil {
+		t.Fatal("Expected error after Close")
+	}
+	b.Close()
+	if _, err := b.Accept(); err == nil {
+		t.Fatal("Expected error after Close")
+	}
+}
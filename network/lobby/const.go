@@ -17,6 +17,7 @@ var (
 	ErrInvalidArgument = errors.New("lobby: Invalid argument")
 	ErrInvalidSlot     = errors.New("lobby: Invalid slot")
 	ErrInvalidPacket   = errors.New("lobby: Invalid packet")
+	ErrInvalidJoin     = errors.New("lobby: Invalid join request")
 	ErrMapUnavailable  = errors.New("lobby: Map unavailable")
 	ErrNotReady        = errors.New("lobby: Player was not ready")
 	ErrPlayersOccupied = errors.New("lobby: No player slots left")
@@ -34,6 +34,7 @@ type Lobby struct {
 	// Set once before Run(), read-only after that
 	w3gs.Encoder
 	w3gs.MapCheck
+	GameInfo     *w3gs.GameInfo // Validate HostCounter/EntryKey of join requests (if not nil)
 	ObsTeam      uint8
 	ColorSet     protocol.BitSet32
 	ReadyTimeout time.Duration
@@ -222,6 +223,13 @@ func (l *Lobby) join(conn net.Conn, join *w3gs.Join) (*Player, error) {
 	})
 	p.SetConn(conn, w3gs.NewFactoryCache(w3gs.DefaultFactory), l.Encoding)
 
+	if l.GameInfo != nil {
+		if reason, ok := l.GameInfo.ValidateJoin(join); !ok {
+			p.Send(&w3gs.RejectJoin{Reason: reason})
+			return nil, ErrInvalidJoin
+		}
+	}
+
 	if l.locked {
 		p.Send(&w3gs.RejectJoin{Reason: w3gs.RejectJoinStarted})
 		return nil, ErrLocked
@@ -319,7 +327,7 @@ func (l *Lobby) swapSlots(slotA int, slotB int, swapTeams bool) {
 		l.slots[slotA].Handicap, l.slots[slotB].Handicap = l.slots[slotB].Handicap, l.slots[slotA].Handicap
 	}
 	// Swap back races if either slot did not have selectable race
-	if l.slots[slotA].Race&w3gs.RaceSelectable == 0 || l.slots[slotB].Race&w3gs.RaceSelectable == 0 {
+	if l.slots[slotA].Race.IsFixed() || l.slots[slotB].Race.IsFixed() {
 		l.slots[slotA].Race, l.slots[slotB].Race = l.slots[slotB].Race, l.slots[slotA].Race
 	}
 }
@@ -352,7 +360,7 @@ func (l *Lobby) changeSlotStatus(slot int, status w3gs.SlotStatus, kick bool) er
 
 // slotmut should be locked
 func (l *Lobby) changeRace(slot int, r w3gs.RacePref) error {
-	if l.slots[slot].Race&w3gs.RaceSelectable == 0 || bits.OnesCount(uint(r&w3gs.RaceMask)) != 1 || r&w3gs.RaceDemon != 0 {
+	if l.slots[slot].Race.IsFixed() || bits.OnesCount(uint(r&w3gs.RaceMask)) != 1 || r&w3gs.RaceDemon != 0 {
 		return ErrInvalidArgument
 	}
 
@@ -813,6 +821,30 @@ func (l *Lobby) Accept(conn net.Conn) (*Player, error) {
 	return l.JoinAndServe(conn, join)
 }
 
+// Serve accepts incoming player connections on listener until it is closed
+func (l *Lobby) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if network.IsCloseError(err) {
+				return nil
+			}
+			return err
+		}
+
+		if tcp, ok := conn.(*net.TCPConn); ok {
+			tcp.SetNoDelay(true)
+		}
+
+		go func() {
+			if _, err := l.Accept(conn); err != nil {
+				l.Fire(&network.AsyncError{Src: "Lobby.Serve[Accept]", Err: err})
+				conn.Close()
+			}
+		}()
+	}
+}
+
 func (l *Lobby) onLeave(p *Player) {
 	l.slotmut.Lock()
 
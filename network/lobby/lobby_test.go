@@ -303,6 +303,24 @@ func TestComputer(t *testing.T) {
 	}
 }
 
+func TestEntryKey(t *testing.T) {
+	var g = makeGame(t, 2)
+	defer g.Close()
+
+	g.GameInfo = &w3gs.GameInfo{HostCounter: 1, EntryKey: 0xDEADBEEF}
+	if _, err := joinDummy(t, g, "DUMMY1"); err != lobby.ErrInvalidJoin {
+		t.Fatalf("Expected ErrInvalidJoin when joining with wrong key, got %v\n", err)
+	}
+
+	g.GameInfo = &w3gs.GameInfo{}
+	if _, err := joinDummy(t, g, "DUMMY2"); err != nil {
+		t.Fatal(err)
+	}
+	if g.SlotsUsed() != 1 {
+		t.Fatal("Expected 1 slot to be used")
+	}
+}
+
 func TestJoin24(t *testing.T) {
 	var g = makeGame(t, 24)
 
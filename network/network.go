@@ -6,6 +6,7 @@
 package network
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"io/ioutil"
@@ -29,6 +30,122 @@ type RunStart struct{}
 // RunStop event
 type RunStop struct{}
 
+// Timeout event, emitted by Run before closing a connection that did not receive a packet within the idle timeout
+type Timeout struct {
+	Idle time.Duration
+}
+
+// FireW3GS emits pkt through f exactly like W3GSConn.Run does for a received packet.
+// Useful to drive handlers without a real connection (i.e. for testing or playback).
+//
+// Unlike f.Fire(pkt), pkt is copied if f handles events asynchronously (see EventEmitter.SetDispatchMode),
+// because decoders reuse their packets and the next decode would otherwise overwrite it before it is handled.
+func FireW3GS(f Emitter, pkt w3gs.Packet) bool {
+	if asyncEmitter(f) {
+		pkt = detach(pkt).(w3gs.Packet)
+	}
+	return f.Fire(pkt)
+}
+
+// FireW3GSFrom emits pkt through f exactly like W3GSPacketConn.Run does for a packet received from addr.
+// Like FireW3GS, pkt is copied if f handles events asynchronously.
+func FireW3GSFrom(f Emitter, pkt w3gs.Packet, addr net.Addr) bool {
+	if asyncEmitter(f) {
+		pkt = detach(pkt).(w3gs.Packet)
+	}
+	return f.Fire(pkt, addr)
+}
+
+// FireBNCS emits pkt through f exactly like BNCSConn.Run does for a received packet.
+// Like FireW3GS, pkt is copied if f handles events asynchronously.
+// Note that Run may also reply to Ping packets (see SetAutoPing), which is not done for injected packets.
+func FireBNCS(f Emitter, pkt bncs.Packet) bool {
+	if asyncEmitter(f) {
+		pkt = detach(pkt).(bncs.Packet)
+	}
+	return f.Fire(pkt)
+}
+
+// idleTimeout returns the read timeout for Run, and whether it is the idle timeout
+func idleTimeout(timeout time.Duration, idle time.Duration) (time.Duration, bool) {
+	if idle > 0 && (timeout < 0 || idle <= timeout) {
+		return idle, true
+	}
+	return timeout, false
+}
+
+// readCanceler moves the read deadline of a connection to the past when ctx is done,
+// which unblocks a pending read without closing the connection
+type readCanceler struct {
+	mut  sync.Mutex
+	ctx  context.Context
+	set  func(time.Time) error
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newReadCanceler(ctx context.Context, set func(time.Time) error) *readCanceler {
+	var r = readCanceler{
+		ctx:  ctx,
+		set:  set,
+		stop: make(chan struct{}),
+	}
+
+	if ctx.Done() != nil {
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			select {
+			case <-ctx.Done():
+				r.mut.Lock()
+				r.set(time.Unix(1, 0))
+				r.mut.Unlock()
+			case <-r.stop:
+			}
+		}()
+	}
+
+	return &r
+}
+
+// readDeadlineFunc returns conn.SetReadDeadline (or a func that returns io.EOF if conn is nil)
+func readDeadlineFunc(conn net.Conn) func(time.Time) error {
+	if conn == nil {
+		return func(time.Time) error { return io.EOF }
+	}
+	return conn.SetReadDeadline
+}
+
+// setTimeout sets the deadline for the next read, returns ctx.Err() if ctx is done
+func (r *readCanceler) setTimeout(timeout time.Duration) error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if err := r.ctx.Err(); err != nil {
+		return err
+	}
+	if timeout >= 0 {
+		return r.set(Deadline(timeout))
+	}
+	return nil
+}
+
+// cancelled returns ctx.Err() if err was caused by cancellation of ctx
+func (r *readCanceler) cancelled(err error) error {
+	if cerr := r.ctx.Err(); cerr != nil && (err == cerr || IsTimeout(err)) {
+		// Clear the past deadline so that the connection can be read again
+		r.set(time.Time{})
+		return cerr
+	}
+	return nil
+}
+
+// close stops watching ctx
+func (r *readCanceler) close() {
+	close(r.stop)
+	r.wg.Wait()
+}
+
 // W3GSBroadcastAddr is used to broadcast W3GS packets to LAN
 var W3GSBroadcastAddr = net.UDPAddr{IP: net.IPv4bcast, Port: 6112}
 
@@ -50,8 +167,9 @@ type W3GSPacketConn struct {
 	smut sync.Mutex
 	enc  w3gs.Encoder
 
-	dec w3gs.Decoder
-	buf [2048]byte
+	dmut sync.Mutex
+	dec  w3gs.Decoder
+	buf  [2048]byte
 }
 
 // NewW3GSPacketConn returns conn wrapped in W3GSPacketConn
@@ -76,12 +194,35 @@ func (c *W3GSPacketConn) SetConn(conn net.PacketConn, fact w3gs.PacketFactory, e
 	c.Close()
 	c.cmut.Lock()
 	c.conn = conn
+	c.dmut.Lock()
 	c.dec.PacketFactory = fact
 	c.dec.Encoding = enc
+	c.dmut.Unlock()
+	c.smut.Lock()
 	c.enc.Encoding = enc
+	c.smut.Unlock()
 	c.cmut.Unlock()
 }
 
+// Encoding returns the encoding used to (de)serialize packets
+func (c *W3GSPacketConn) Encoding() w3gs.Encoding {
+	c.smut.Lock()
+	var enc = c.enc.Encoding
+	c.smut.Unlock()
+	return enc
+}
+
+// SetEncoding changes the encoding used to (de)serialize packets without replacing the connection
+// (i.e. after a game version change), safe to call while Run() is active
+func (c *W3GSPacketConn) SetEncoding(enc w3gs.Encoding) {
+	c.smut.Lock()
+	c.enc.Encoding = enc
+	c.smut.Unlock()
+	c.dmut.Lock()
+	c.dec.Encoding = enc
+	c.dmut.Unlock()
+}
+
 // SetWriteTimeout for Send() calls
 func (c *W3GSPacketConn) SetWriteTimeout(wto time.Duration) {
 	c.smut.Lock()
@@ -156,11 +297,13 @@ func (c *W3GSPacketConn) NextPacket(timeout time.Duration) (w3gs.Packet, net.Add
 		return nil, nil, err
 	}
 
+	c.dmut.Lock()
 	pkt, _, err := c.dec.Deserialize(c.buf[:size])
+	c.dmut.Unlock()
 	c.cmut.RUnlock()
 
 	if err != nil {
-		return nil, nil, err
+		return nil, addr, err
 	}
 
 	return pkt, addr, err
@@ -175,19 +318,18 @@ func (c *W3GSPacketConn) Run(f Emitter, timeout time.Duration) error {
 		pkt, addr, err := c.NextPacket(timeout)
 
 		if err != nil {
-			switch err {
-			// Connection is still valid after these errors, only deserialization failed
-			case w3gs.ErrInvalidPacketSize, w3gs.ErrInvalidChecksum, w3gs.ErrUnexpectedConst:
-				f.Fire(&AsyncError{Src: "Run[NextPacket]", Err: err})
+			// Connection is still valid after decode errors, only deserialization failed
+			if IsDecodeError(err) {
+				f.Fire(&AsyncError{Src: "Run[NextPacket]", Err: connError(addr, err)})
 				continue
-			default:
-				f.Fire(RunStop{})
-				c.cmut.RUnlock()
-				return err
 			}
+
+			f.Fire(RunStop{})
+			c.cmut.RUnlock()
+			return err
 		}
 
-		f.Fire(pkt, addr)
+		FireW3GSFrom(f, pkt, addr)
 	}
 }
 
@@ -201,6 +343,9 @@ type W3GSConn struct {
 	smut sync.Mutex
 	enc  w3gs.Encoder
 	dec  w3gs.Decoder
+
+	imut sync.Mutex
+	idle time.Duration
 }
 
 // NewW3GSConn returns conn wrapped in W3GSConn
@@ -238,6 +383,38 @@ func (c *W3GSConn) SetWriteTimeout(wto time.Duration) {
 	c.smut.Unlock()
 }
 
+// SetReadDeadline of the underlying connection, overridden by NextPacket and Run unless their timeout is NoTimeout
+func (c *W3GSConn) SetReadDeadline(t time.Time) error {
+	c.cmut.RLock()
+	defer c.cmut.RUnlock()
+
+	if c.conn == nil {
+		return io.EOF
+	}
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline of the underlying connection, overridden by Send and Write unless the write timeout is NoTimeout
+func (c *W3GSConn) SetWriteDeadline(t time.Time) error {
+	c.cmut.RLock()
+	defer c.cmut.RUnlock()
+
+	if c.conn == nil {
+		return io.EOF
+	}
+
+	// Do not wait for smut, this can be used to unblock a pending write
+	return c.conn.SetWriteDeadline(t)
+}
+
+// SetIdleTimeout makes Run close the connection if no packet is received within d (disabled if d <= 0)
+// A Timeout event is emitted before closing. Takes effect on the next call to Run
+func (c *W3GSConn) SetIdleTimeout(d time.Duration) {
+	c.imut.Lock()
+	c.idle = d
+	c.imut.Unlock()
+}
+
 // Close the connection
 func (c *W3GSConn) Close() error {
 	c.cmut.RLock()
@@ -303,6 +480,7 @@ func (c *W3GSConn) Send(pkt w3gs.Packet) (int, error) {
 }
 
 // NextPacket waits for the next packet (with given timeout) and returns its deserialized representation
+// Returns io.EOF if the connection was closed cleanly, or a *ShortReadError if it was closed mid-packet
 // Not safe for concurrent invocation
 func (c *W3GSConn) NextPacket(timeout time.Duration) (w3gs.Packet, error) {
 	c.cmut.RLock()
@@ -319,34 +497,68 @@ func (c *W3GSConn) NextPacket(timeout time.Duration) (w3gs.Packet, error) {
 		}
 	}
 
-	pkt, _, err := c.dec.Read(c.conn)
+	var r = packetReader{Reader: c.conn, sig: w3gs.ProtocolSig}
+	pkt, n, err := c.dec.Read(&r)
 	c.cmut.RUnlock()
 
-	return pkt, err
+	return pkt, readError(&r, n, err, w3gs.ErrNoProtocolSig)
 }
 
 // Run reads packets (with given max time between packets) from Conn and fires an event through f for each received packet
+// The connection is closed if no packet is received within the idle timeout (see SetIdleTimeout)
 // Not safe for concurrent invocation
 func (c *W3GSConn) Run(f Emitter, timeout time.Duration) error {
+	return c.RunContext(context.Background(), f, timeout)
+}
+
+// RunContext is like Run, but returns ctx.Err() as soon as ctx is done. The connection is left open,
+// so pending and future writes are unaffected. A packet that was only partially received when ctx
+// got cancelled is lost, after which the connection should no longer be read from.
+// Not safe for concurrent invocation
+func (c *W3GSConn) RunContext(ctx context.Context, f Emitter, timeout time.Duration) error {
+	c.imut.Lock()
+	var idle = c.idle
+	c.imut.Unlock()
+
+	timeout, isIdle := idleTimeout(timeout, idle)
+
 	c.cmut.RLock()
+	var rc = newReadCanceler(ctx, readDeadlineFunc(c.conn))
+	defer rc.close()
+
 	f.Fire(RunStart{})
 	for {
-		pkt, err := c.NextPacket(timeout)
+		var err = rc.setTimeout(timeout)
+
+		var pkt w3gs.Packet
+		if err == nil {
+			pkt, err = c.NextPacket(NoTimeout)
+		}
 
 		if err != nil {
-			switch err {
-			// Connection is still valid after these errors, only deserialization failed
-			case w3gs.ErrInvalidPacketSize, w3gs.ErrInvalidChecksum, w3gs.ErrUnexpectedConst:
-				f.Fire(&AsyncError{Src: "Run[NextPacket]", Err: err})
-				continue
-			default:
+			if cerr := rc.cancelled(err); cerr != nil {
 				f.Fire(RunStop{})
 				c.cmut.RUnlock()
-				return err
+				return cerr
+			}
+
+			// Connection is still valid after decode errors, only deserialization failed
+			if IsDecodeError(err) {
+				f.Fire(&AsyncError{Src: "Run[NextPacket]", Err: connError(c.conn.RemoteAddr(), err)})
+				continue
+			}
+
+			if isIdle && IsTimeout(err) {
+				f.Fire(&Timeout{Idle: idle})
+				c.conn.Close()
 			}
+
+			f.Fire(RunStop{})
+			c.cmut.RUnlock()
+			return err
 		}
 
-		f.Fire(pkt)
+		FireW3GS(f, pkt)
 	}
 }
 
@@ -363,6 +575,13 @@ type BNCSConn struct {
 
 	lmut sync.Mutex
 	lnxt time.Time
+
+	kmut sync.Mutex
+	kint time.Duration
+	ping bool
+
+	imut sync.Mutex
+	idle time.Duration
 }
 
 // NewBNCSConn returns conn wrapped in BNCSConn
@@ -400,6 +619,38 @@ func (c *BNCSConn) SetWriteTimeout(wto time.Duration) {
 	c.smut.Unlock()
 }
 
+// SetReadDeadline of the underlying connection, overridden by NextPacket and Run unless their timeout is NoTimeout
+func (c *BNCSConn) SetReadDeadline(t time.Time) error {
+	c.cmut.RLock()
+	defer c.cmut.RUnlock()
+
+	if c.conn == nil {
+		return io.EOF
+	}
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline of the underlying connection, overridden by Send and Write unless the write timeout is NoTimeout
+func (c *BNCSConn) SetWriteDeadline(t time.Time) error {
+	c.cmut.RLock()
+	defer c.cmut.RUnlock()
+
+	if c.conn == nil {
+		return io.EOF
+	}
+
+	// Do not wait for smut, this can be used to unblock a pending write
+	return c.conn.SetWriteDeadline(t)
+}
+
+// SetIdleTimeout makes Run close the connection if no packet is received within d (disabled if d <= 0)
+// A Timeout event is emitted before closing. Takes effect on the next call to Run
+func (c *BNCSConn) SetIdleTimeout(d time.Duration) {
+	c.imut.Lock()
+	c.idle = d
+	c.imut.Unlock()
+}
+
 // Close the connection
 func (c *BNCSConn) Close() error {
 	c.cmut.RLock()
@@ -464,6 +715,52 @@ func (c *BNCSConn) Send(pkt bncs.Packet) (int, error) {
 	return n, err
 }
 
+// SetKeepAlive sends a KeepAlive packet every interval while Run is active (disabled if interval <= 0)
+// Takes effect on the next call to Run
+func (c *BNCSConn) SetKeepAlive(interval time.Duration) {
+	c.kmut.Lock()
+	c.kint = interval
+	c.kmut.Unlock()
+}
+
+// SetAutoPing makes Run automatically reply to Ping packets (disabled by default)
+// Takes effect on the next call to Run
+func (c *BNCSConn) SetAutoPing(enable bool) {
+	c.kmut.Lock()
+	c.ping = enable
+	c.kmut.Unlock()
+}
+
+// DisableAutoPing stops Run from automatically replying to Ping packets, same as SetAutoPing(false)
+func (c *BNCSConn) DisableAutoPing() {
+	c.SetAutoPing(false)
+}
+
+func (c *BNCSConn) runKeepAlive(f Emitter, interval time.Duration) func() {
+	var stop = make(chan struct{})
+
+	go func() {
+		var ticker = time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var pkt bncs.KeepAlive
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if _, err := c.Send(&pkt); err != nil && !IsCloseError(err) {
+					f.Fire(&AsyncError{Src: "Run[KeepAlive]", Err: err})
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+	}
+}
+
 // SendRL pkt to addr over net.Conn with rate limit
 func (c *BNCSConn) SendRL(pkt bncs.Packet) (int, error) {
 	c.lmut.Lock()
@@ -489,6 +786,7 @@ func (c *BNCSConn) SendRL(pkt bncs.Packet) (int, error) {
 }
 
 // NextPacket waits for the next packet (with given timeout) and returns its deserialized representation
+// Returns io.EOF if the connection was closed cleanly, or a *ShortReadError if it was closed mid-packet
 // Not safe for concurrent invocation
 func (c *BNCSConn) NextPacket(timeout time.Duration) (bncs.Packet, error) {
 	c.cmut.RLock()
@@ -504,35 +802,84 @@ func (c *BNCSConn) NextPacket(timeout time.Duration) (bncs.Packet, error) {
 		}
 	}
 
-	pkt, _, err := c.dec.Read(c.conn)
+	var r = packetReader{Reader: c.conn, sig: bncs.ProtocolSig}
+	pkt, n, err := c.dec.Read(&r)
 	c.cmut.RUnlock()
 
-	return pkt, err
+	return pkt, readError(&r, n, err, bncs.ErrNoProtocolSig)
 }
 
 // Run reads packets (with given max time between packets) from Conn and emits an event for each received packet
+// Ping packets are answered automatically if enabled with SetAutoPing, and KeepAlive packets are sent periodically
+// if enabled with SetKeepAlive. The connection is closed if no packet is received within the idle timeout (see SetIdleTimeout)
 // Not safe for concurrent invocation
 func (c *BNCSConn) Run(f Emitter, timeout time.Duration) error {
+	return c.RunContext(context.Background(), f, timeout)
+}
+
+// RunContext is like Run, but returns ctx.Err() as soon as ctx is done (see W3GSConn.RunContext)
+// Not safe for concurrent invocation
+func (c *BNCSConn) RunContext(ctx context.Context, f Emitter, timeout time.Duration) error {
+	c.kmut.Lock()
+	var kint = c.kint
+	var ping = c.ping
+	c.kmut.Unlock()
+
+	c.imut.Lock()
+	var idle = c.idle
+	c.imut.Unlock()
+
+	timeout, isIdle := idleTimeout(timeout, idle)
+
 	c.cmut.RLock()
+	var rc = newReadCanceler(ctx, readDeadlineFunc(c.conn))
+	defer rc.close()
+
 	f.Fire(RunStart{})
+
+	if kint > 0 {
+		var stop = c.runKeepAlive(f, kint)
+		defer stop()
+	}
+
 	for {
-		pkt, err := c.NextPacket(timeout)
+		var err = rc.setTimeout(timeout)
+
+		var pkt bncs.Packet
+		if err == nil {
+			pkt, err = c.NextPacket(NoTimeout)
+		}
 
 		if err != nil {
-			switch err {
-			// Connection is still valid after these errors, only deserialization failed
-			case bncs.ErrInvalidPacketSize, bncs.ErrInvalidChecksum, bncs.ErrUnexpectedConst,
-				w3gs.ErrInvalidPacketSize, w3gs.ErrInvalidChecksum, w3gs.ErrUnexpectedConst:
-				f.Fire(&AsyncError{Src: "Run[NextPacket]", Err: err})
-				continue
-			default:
+			if cerr := rc.cancelled(err); cerr != nil {
 				f.Fire(RunStop{})
 				c.cmut.RUnlock()
-				return err
+				return cerr
+			}
+
+			// Connection is still valid after decode errors, only deserialization failed
+			if IsDecodeError(err) {
+				f.Fire(&AsyncError{Src: "Run[NextPacket]", Err: connError(c.conn.RemoteAddr(), err)})
+				continue
+			}
+
+			if isIdle && IsTimeout(err) {
+				f.Fire(&Timeout{Idle: idle})
+				c.conn.Close()
+			}
+
+			f.Fire(RunStop{})
+			c.cmut.RUnlock()
+			return err
+		}
+
+		if p, ok := pkt.(*bncs.Ping); ok && ping {
+			if _, err := c.Send(&bncs.Ping{Payload: p.Payload}); err != nil && !IsCloseError(err) {
+				f.Fire(&AsyncError{Src: "Run[Ping]", Err: err})
 			}
 		}
 
-		f.Fire(pkt)
+		FireBNCS(f, pkt)
 	}
 }
 
@@ -0,0 +1,348 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol"
+	"github.com/nielsAD/gowarcraft3/protocol/bncs"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+func TestBNCSKeepAlive(t *testing.T) {
+	var srv, cli = net.Pipe()
+	defer srv.Close()
+
+	var conn = network.NewBNCSConn(cli, nil, bncs.Encoding{})
+	conn.SetKeepAlive(10 * time.Millisecond)
+	conn.SetAutoPing(true)
+
+	var e network.EventEmitter
+	var done = make(chan error)
+	go func() {
+		done <- conn.Run(&e, network.NoTimeout)
+	}()
+
+	var enc = bncs.Encoding{Request: true}
+	if _, err := bncs.Write(srv, &bncs.Ping{Payload: 0xDEADBEEF}, enc); err != nil {
+		t.Fatal(err)
+	}
+
+	var ping, keep bool
+	for !ping || !keep {
+		pkt, _, err := bncs.Read(srv, enc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch p := pkt.(type) {
+		case *bncs.Ping:
+			if p.Payload != 0xDEADBEEF {
+				t.Fatal("Unexpected ping payload", p.Payload)
+			}
+			ping = true
+		case *bncs.KeepAlive:
+			keep = true
+		default:
+			t.Fatal("Unexpected packet", pkt)
+		}
+	}
+
+	conn.Close()
+	<-done
+
+	// Auto ping disabled by default
+	srv, cli = net.Pipe()
+	defer srv.Close()
+
+	conn = network.NewBNCSConn(cli, nil, bncs.Encoding{})
+
+	var recv = make(chan uint32, 1)
+	e.On(&bncs.Ping{}, func(ev *network.Event) {
+		recv <- ev.Arg.(*bncs.Ping).Payload
+	})
+
+	go func() {
+		done <- conn.Run(&e, network.NoTimeout)
+	}()
+
+	if _, err := bncs.Write(srv, &bncs.Ping{Payload: 123}, enc); err != nil {
+		t.Fatal(err)
+	}
+	if p := <-recv; p != 123 {
+		t.Fatal("Unexpected ping payload", p)
+	}
+
+	srv.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if pkt, _, err := bncs.Read(srv, enc); err == nil {
+		t.Fatal("Expected no reply", pkt)
+	}
+
+	conn.Close()
+	<-done
+}
+
+func TestDecodeError(t *testing.T) {
+	var addr = &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 6112}
+	var err error = &network.AsyncError{Src: "Run[NextPacket]", Err: &network.ConnError{
+		Addr: addr,
+		Err:  &protocol.PacketError{ID: w3gs.PidPingFromHost, Offset: 4, Underlying: w3gs.ErrInvalidPacketSize},
+	}}
+
+	if !network.IsDecodeError(err) {
+		t.Fatal("Expected decode error")
+	}
+	if network.UnnestError(err) != w3gs.ErrInvalidPacketSize {
+		t.Fatal("Expected ErrInvalidPacketSize after unnesting")
+	}
+	if s := err.Error(); s != "Run[NextPacket]:127.0.0.1:6112:w3gs: Invalid packet size (id: 0x01, offset: 4)" {
+		t.Fatal("Unexpected error string", s)
+	}
+	if network.IsDecodeError(io.EOF) {
+		t.Fatal("Expected no decode error")
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	var srv, cli = net.Pipe()
+	defer srv.Close()
+
+	var conn = network.NewW3GSConn(cli, nil, w3gs.Encoding{})
+	conn.SetIdleTimeout(50 * time.Millisecond)
+
+	var e network.EventEmitter
+	var pings = make(chan struct{}, 8)
+	var idle = make(chan time.Duration, 1)
+	e.On(&w3gs.Ping{}, func(ev *network.Event) {
+		pings <- struct{}{}
+	})
+	e.On(&network.Timeout{}, func(ev *network.Event) {
+		idle <- ev.Arg.(*network.Timeout).Idle
+	})
+
+	var done = make(chan error)
+	go func() {
+		done <- conn.Run(&e, network.NoTimeout)
+	}()
+
+	// Idle timer resets on every packet
+	for i := 0; i < 4; i++ {
+		time.Sleep(20 * time.Millisecond)
+		if _, err := w3gs.Write(srv, &w3gs.Ping{Payload: uint32(i)}, w3gs.Encoding{}); err != nil {
+			t.Fatal(err)
+		}
+		<-pings
+	}
+
+	if err := <-done; !network.IsTimeout(err) {
+		t.Fatal("Expected timeout error", err)
+	}
+	if d := <-idle; d != 50*time.Millisecond {
+		t.Fatal("Unexpected idle timeout", d)
+	}
+	if _, err := srv.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatal("Expected connection to be closed", err)
+	}
+
+	// Write deadline
+	srv, cli = net.Pipe()
+	defer srv.Close()
+
+	conn = network.NewW3GSConn(cli, nil, w3gs.Encoding{})
+	conn.SetWriteTimeout(network.NoTimeout)
+	if err := conn.SetWriteDeadline(time.Now().Add(20 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Send(&w3gs.Ping{}); !network.IsTimeout(err) {
+		t.Fatal("Expected write timeout", err)
+	}
+	conn.Close()
+}
+
+func TestRunContext(t *testing.T) {
+	var srv, cli = net.Pipe()
+	defer srv.Close()
+
+	var conn = network.NewW3GSConn(cli, nil, w3gs.Encoding{})
+	defer conn.Close()
+
+	var e network.EventEmitter
+	var pings = make(chan struct{}, 8)
+	e.On(&w3gs.Ping{}, func(ev *network.Event) {
+		pings <- struct{}{}
+	})
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	var done = make(chan error)
+	go func() {
+		done <- conn.RunContext(ctx, &e, network.NoTimeout)
+	}()
+
+	if _, err := w3gs.Write(srv, &w3gs.Ping{}, w3gs.Encoding{}); err != nil {
+		t.Fatal(err)
+	}
+	<-pings
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Fatal("Expected context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected RunContext to return after cancel")
+	}
+
+	// Connection is still usable in both directions
+	go func() {
+		w3gs.Write(srv, &w3gs.Ping{Payload: 7}, w3gs.Encoding{})
+		w3gs.Read(srv, w3gs.Encoding{})
+	}()
+	if pkt, err := conn.NextPacket(time.Second); err != nil || pkt.(*w3gs.Ping).Payload != 7 {
+		t.Fatal("Expected ping after cancel", pkt, err)
+	}
+	if _, err := conn.Send(&w3gs.Pong{}); err != nil {
+		t.Fatal("Expected send after cancel to succeed", err)
+	}
+
+	// Cancelled context returns immediately
+	if err := conn.RunContext(ctx, &e, time.Second); err != context.Canceled {
+		t.Fatal("Expected context.Canceled", err)
+	}
+}
+
+func TestFire(t *testing.T) {
+	var e network.EventEmitter
+	var calls = make(chan string, 16)
+	e.On(nil, func(ev *network.Event) {
+		if _, ok := ev.Arg.(*w3gs.Ping); ok {
+			calls <- "all"
+		}
+	})
+	e.On(&w3gs.Ping{}, func(ev *network.Event) {
+		calls <- "first"
+	})
+	e.On(&w3gs.Ping{}, func(ev *network.Event) {
+		calls <- "second"
+		if ev.Arg.(*w3gs.Ping).Payload == 1 {
+			ev.PreventNext()
+		}
+	})
+
+	var collect = func(n int) []string {
+		var res []string
+		for i := 0; i < n; i++ {
+			res = append(res, <-calls)
+		}
+		return res
+	}
+
+	// Received packet
+	var srv, cli = net.Pipe()
+	defer srv.Close()
+
+	var conn = network.NewW3GSConn(cli, nil, w3gs.Encoding{})
+	defer conn.Close()
+	go conn.Run(&e, network.NoTimeout)
+
+	if _, err := w3gs.Write(srv, &w3gs.Ping{Payload: 2}, w3gs.Encoding{}); err != nil {
+		t.Fatal(err)
+	}
+	var recv = collect(3)
+
+	// Injected packet
+	if network.FireW3GS(&e, &w3gs.Ping{Payload: 2}) {
+		t.Fatal("Expected no prevent")
+	}
+	if inj := collect(3); !reflect.DeepEqual(inj, recv) || !reflect.DeepEqual(inj, []string{"all", "second", "first"}) {
+		t.Fatal("Unexpected handler order", inj, recv)
+	}
+
+	if !network.FireW3GS(&e, &w3gs.Ping{Payload: 1}) {
+		t.Fatal("Expected prevent")
+	}
+	if inj := collect(2); !reflect.DeepEqual(inj, []string{"all", "second"}) {
+		t.Fatal("Expected PreventNext to stop propagation", inj)
+	}
+
+	var addr = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 6112}
+	e.Once(&w3gs.SearchGame{}, func(ev *network.Event) {
+		if len(ev.Opt) != 1 || ev.Opt[0] != addr {
+			t.Error("Expected sender address in Opt", ev.Opt)
+		}
+		calls <- "udp"
+	})
+	network.FireW3GSFrom(&e, &w3gs.SearchGame{}, addr)
+
+	e.Once(&bncs.Ping{}, func(ev *network.Event) {
+		calls <- "bncs"
+	})
+	network.FireBNCS(&e, &bncs.Ping{})
+
+	if inj := collect(2); !reflect.DeepEqual(inj, []string{"udp", "bncs"}) {
+		t.Fatal("Unexpected events", inj)
+	}
+}
+
+func TestShortRead(t *testing.T) {
+	var ping, err = w3gs.Serialize(&w3gs.Ping{Payload: 1}, w3gs.Encoding{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, n := range []int{0, 2, len(ping) - 1} {
+		var srv, cli = net.Pipe()
+		var conn = network.NewW3GSConn(cli, nil, w3gs.Encoding{})
+
+		go func() {
+			srv.Write(ping)
+			srv.Write(ping[:n])
+			srv.Close()
+		}()
+
+		if _, err := conn.NextPacket(time.Second); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := conn.NextPacket(time.Second)
+		if n == 0 {
+			if err != io.EOF {
+				t.Fatal("Expected io.EOF on clean close", err)
+			}
+		} else {
+			var short *network.ShortReadError
+			if !errors.As(err, &short) || short.N != n || !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Fatal("Expected ShortReadError", n, err)
+			}
+		}
+		if !network.IsCloseError(err) {
+			t.Fatal("Expected close error", err)
+		}
+		conn.Close()
+	}
+
+	// Garbage is not a short read, not even if the connection is closed right after
+	for _, garbage := range [][]byte{{0x00, 0x25, 0x08, 0x00}, {0x00, 0x25}} {
+		var srv, cli = net.Pipe()
+		var conn = network.NewBNCSConn(cli, nil, bncs.Encoding{})
+
+		go func() {
+			srv.Write(garbage)
+			srv.Close()
+		}()
+
+		if _, err := conn.NextPacket(time.Second); err != bncs.ErrNoProtocolSig {
+			t.Fatal("Expected ErrNoProtocolSig", len(garbage), err)
+		}
+		conn.Close()
+	}
+}
@@ -0,0 +1,128 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network
+
+import (
+	"io"
+	"net"
+	"sync"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+// Direction of a proxied packet
+type Direction uint8
+
+// Directions
+const (
+	ClientToServer Direction = iota
+	ServerToClient
+)
+
+func (d Direction) String() string {
+	switch d {
+	case ClientToServer:
+		return "C>S"
+	case ServerToClient:
+		return "S>C"
+	default:
+		return "Direction(?)"
+	}
+}
+
+// ForwardedPacket event, fired right before Raw is forwarded
+type ForwardedPacket struct {
+	Direction Direction
+	Raw       []byte      // Original bytes, forwarded verbatim
+	Packet    w3gs.Packet // *w3gs.UnknownPacket if deserialization failed
+	Err       error       // Deserialization error (if any)
+}
+
+// W3GSProxy forwards W3GS packets between a client and a server without re-serializing them,
+// and fires a ForwardedPacket event for each packet.
+// Public methods/fields are thread-safe unless explicitly stated otherwise
+type W3GSProxy struct {
+	EventEmitter
+
+	// Set once before Run(), read-only after that
+	Client        net.Conn
+	Server        net.Conn
+	PacketFactory w3gs.PacketFactory
+	Encoding      w3gs.Encoding
+}
+
+// Proxy returns a W3GSProxy that relays packets between client and server once Run() is called
+func Proxy(client, server net.Conn, fact w3gs.PacketFactory, enc w3gs.Encoding) *W3GSProxy {
+	return &W3GSProxy{
+		Client:        client,
+		Server:        server,
+		PacketFactory: fact,
+		Encoding:      enc,
+	}
+}
+
+// Close both connections
+func (p *W3GSProxy) Close() error {
+	var err = p.Client.Close()
+	if err2 := p.Server.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+func (p *W3GSProxy) forward(dir Direction, src net.Conn, dst net.Conn) error {
+	var dec = w3gs.NewDecoder(p.Encoding, p.PacketFactory)
+	for {
+		raw, err := protocol.ReadFrame(src, 4, 2)
+		if err != nil {
+			return err
+		}
+
+		pkt, _, err := dec.Deserialize(raw)
+		if err != nil {
+			pkt = &w3gs.UnknownPacket{ID: raw[1], Blob: raw[4:]}
+		}
+
+		p.Fire(&ForwardedPacket{
+			Direction: dir,
+			Raw:       raw,
+			Packet:    pkt,
+			Err:       err,
+		})
+
+		if _, err := dst.Write(raw); err != nil {
+			return err
+		}
+	}
+}
+
+// Run forwards packets in both directions until either connection is closed, then closes both.
+// Returns nil if a connection was closed gracefully.
+func (p *W3GSProxy) Run() error {
+	var wg sync.WaitGroup
+	var errs = make(chan error, 2)
+
+	var run = func(dir Direction, src net.Conn, dst net.Conn) {
+		errs <- p.forward(dir, src, dst)
+		p.Close()
+		wg.Done()
+	}
+
+	p.Fire(RunStart{})
+
+	wg.Add(2)
+	go run(ClientToServer, p.Client, p.Server)
+	go run(ServerToClient, p.Server, p.Client)
+	wg.Wait()
+
+	p.Fire(RunStop{})
+
+	var err = <-errs
+	if err == io.ErrClosedPipe || IsCloseError(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,79 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package network_test
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/network"
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+func TestProxy(t *testing.T) {
+	var c1, c2 = net.Pipe()
+	var s1, s2 = net.Pipe()
+
+	var p = network.Proxy(c2, s1, w3gs.DefaultFactory, w3gs.Encoding{})
+
+	var mut sync.Mutex
+	var fwd []*network.ForwardedPacket
+	p.On(&network.ForwardedPacket{}, func(ev *network.Event) {
+		mut.Lock()
+		fwd = append(fwd, ev.Arg.(*network.ForwardedPacket))
+		mut.Unlock()
+	})
+
+	var done = make(chan error)
+	go func() { done <- p.Run() }()
+
+	ping, err := w3gs.Serialize(&w3gs.Ping{Payload: 123}, w3gs.Encoding{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ping2 = append([]byte(nil), ping...)
+
+	// Trailing bytes make deserialization fail, packet must still be forwarded verbatim
+	var bad = []byte{w3gs.ProtocolSig, w3gs.PidPingFromHost, 10, 0, 1, 2, 3, 4, 5, 6}
+
+	go func() {
+		c1.Write(ping)
+		c1.Write(bad)
+	}()
+
+	var buf = make([]byte, len(ping)+len(bad))
+	if _, err := io.ReadFull(s2, buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, append(ping2, bad...)) {
+		t.Fatal("Forwarded bytes mismatch", buf)
+	}
+
+	go s2.Write(ping)
+	if _, err := io.ReadFull(c1, buf[:len(ping)]); err != nil {
+		t.Fatal(err)
+	}
+
+	c1.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fwd) != 3 {
+		t.Fatalf("Expected 3 forwarded packets, got %d", len(fwd))
+	}
+	if fwd[0].Direction != network.ClientToServer || fwd[0].Err != nil || fwd[0].Packet.(*w3gs.Ping).Payload != 123 {
+		t.Fatal("Unexpected first packet", fwd[0])
+	}
+	if u, ok := fwd[1].Packet.(*w3gs.UnknownPacket); !ok || fwd[1].Err == nil || u.ID != w3gs.PidPingFromHost || !bytes.Equal(fwd[1].Raw, bad) {
+		t.Fatal("Unexpected second packet", fwd[1])
+	}
+	if fwd[2].Direction != network.ServerToClient || fwd[2].Err != nil {
+		t.Fatal("Unexpected third packet", fwd[2])
+	}
+}
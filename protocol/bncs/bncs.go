@@ -55,6 +55,7 @@ var DefaultFactory = MapFactory{
 	PidPing:          func(_ *Encoding) Packet { return &Ping{} },
 	PidNetGamePort:   func(_ *Encoding) Packet { return &NetGamePort{} },
 	PidSetEmail:      func(_ *Encoding) Packet { return &SetEmail{} },
+	PidWarden:        func(_ *Encoding) Packet { return &Warden{} },
 	PidClanInfo:      func(_ *Encoding) Packet { return &ClanInfo{} },
 
 	PidGetAdvListEx: ReqResp(
@@ -47,6 +47,7 @@ const (
 	PidAuthAccountChange      = 0x55 // C -> S | S -> C
 	PidAuthAccountChangeProof = 0x56 // C -> S | S -> C
 	PidSetEmail               = 0x59 // C -> S |
+	PidWarden                 = 0x5E // C -> S | S -> C
 	PidClanInfo               = 0x75 //        | S -> C
 )
 
@@ -0,0 +1,71 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package bncs_test
+
+import (
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/protocol/bncs"
+)
+
+func TestChatEventType(t *testing.T) {
+	var names = map[bncs.ChatEventType]string{
+		bncs.ChatShowUser:            "ShowUser",
+		bncs.ChatJoin:                "Join",
+		bncs.ChatLeave:               "Leave",
+		bncs.ChatWhisper:             "Whisper",
+		bncs.ChatTalk:                "Chat",
+		bncs.ChatBroadcast:           "Broadcast",
+		bncs.ChatChannelInfo:         "ChannelInfo",
+		bncs.ChatUserFlagsUpdate:     "UserFlagsUpdate",
+		bncs.ChatWhisperSent:         "WhisperSent",
+		bncs.ChatChannelFull:         "ChannelFull",
+		bncs.ChatChannelDoesNotExist: "ChannelDoesNotExist",
+		bncs.ChatChannelRestricted:   "ChannelRestricted",
+		bncs.ChatInfo:                "Info",
+		bncs.ChatError:               "Error",
+		bncs.ChatIgnore:              "Ignore",
+		bncs.ChatUnignore:            "Unignore",
+		bncs.ChatEmote:               "Emote",
+		bncs.ChatEventType(0x08):     "ChatEventType(0x08)",
+	}
+
+	for id, name := range names {
+		if id.String() != name {
+			t.Fatalf("%d.String() == %q, expected %q", uint32(id), id.String(), name)
+		}
+	}
+}
+
+func TestChatEventPredicates(t *testing.T) {
+	var msg = map[bncs.ChatEventType]bool{
+		bncs.ChatTalk:        true,
+		bncs.ChatEmote:       true,
+		bncs.ChatWhisper:     true,
+		bncs.ChatWhisperSent: true,
+		bncs.ChatBroadcast:   false,
+		bncs.ChatInfo:        false,
+		bncs.ChatJoin:        false,
+	}
+	for id, exp := range msg {
+		if (&bncs.ChatEvent{Type: id}).IsUserMessage() != exp {
+			t.Fatalf("IsUserMessage(%v) != %v", id, exp)
+		}
+	}
+
+	var usr = map[bncs.ChatEventType]bool{
+		bncs.ChatShowUser:        true,
+		bncs.ChatJoin:            true,
+		bncs.ChatLeave:           true,
+		bncs.ChatUserFlagsUpdate: true,
+		bncs.ChatTalk:            false,
+		bncs.ChatChannelInfo:     false,
+	}
+	for id, exp := range usr {
+		if (&bncs.ChatEvent{Type: id}).IsChannelUser() != exp {
+			t.Fatalf("IsChannelUser(%v) != %v", id, exp)
+		}
+	}
+}
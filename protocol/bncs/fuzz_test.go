@@ -0,0 +1,32 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+//go:build go1.18
+// +build go1.18
+
+package bncs_test
+
+import (
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/protocol/bncs"
+)
+
+func FuzzBNCSPacket(f *testing.F) {
+	for _, pkt := range clientPackets {
+		if b, err := bncs.Serialize(pkt, bncs.Encoding{Request: true}); err == nil {
+			f.Add(b)
+		}
+	}
+	for _, pkt := range serverPackets {
+		if b, err := bncs.Serialize(pkt, bncs.Encoding{}); err == nil {
+			f.Add(b)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		bncs.Deserialize(b, bncs.Encoding{Request: true})
+		bncs.Deserialize(b, bncs.Encoding{})
+	})
+}
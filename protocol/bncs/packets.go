@@ -476,6 +476,26 @@ func (pkt *ChatEvent) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 	return nil
 }
 
+// IsUserMessage returns true if the event carries a message typed by a user (chat, emote, or whisper).
+func (pkt *ChatEvent) IsUserMessage() bool {
+	switch pkt.Type {
+	case ChatTalk, ChatEmote, ChatWhisper, ChatWhisperSent:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsChannelUser returns true if the event describes a user in the current channel (show, join, leave, or flags update).
+func (pkt *ChatEvent) IsChannelUser() bool {
+	switch pkt.Type {
+	case ChatShowUser, ChatJoin, ChatLeave, ChatUserFlagsUpdate:
+		return true
+	default:
+		return false
+	}
+}
+
 // FloodDetected implements the [0x13] SID_FloodDetected packet (S -> C).
 //
 // Sent prior to a disconnect along with SID_MessageBox to indicate that the client has flooded off.
@@ -643,6 +663,10 @@ func (gs *GameSettings) SerializeContent(buf *protocol.Buffer, enc *Encoding) {
 
 // DeserializeContent GameSettings from StatString
 func (gs *GameSettings) DeserializeContent(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < 9 {
+		return ErrInvalidPacketSize
+	}
+
 	gs.SlotsFree = buf.ReadUInt8()
 	if gs.SlotsFree >= '1'+'0' {
 		gs.SlotsFree -= '1' + '0' - 10
@@ -807,6 +831,9 @@ func (pkt *GetAdvListResp) Deserialize(buf *protocol.Buffer, enc *Encoding) erro
 	}
 
 	var numGames = int(buf.ReadUInt32())
+	if numGames > (size-8)/35 {
+		return ErrInvalidPacketSize
+	}
 	if cap(pkt.Games) < numGames {
 		pkt.Games = make([]GetAdvListGame, 0, numGames)
 	}
@@ -1330,6 +1357,8 @@ func (pkt *AuthInfoResp) Deserialize(buf *protocol.Buffer, enc *Encoding) error
 //    (STRING) Country abbreviation
 //    (STRING) Country
 //
+// Locale IDs are Windows LCIDs, see w3gs.LanguageName.
+//
 type AuthInfoReq struct {
 	PlatformCode        protocol.DWordString
 	GameVersion         w3gs.GameVersion
@@ -2057,6 +2086,44 @@ func (pkt *SetEmail) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 	return nil
 }
 
+// Warden implements the [0x5E] SID_WARDEN packet (S -> C, C -> S).
+//
+// This packet is received after successfully logging onto Battle.net and usually after receiving the first
+// initial chat events. If the client does not respond to this packet within about 2 minutes, the server
+// will disconnect the client.
+//
+// The data in this packet is encrypted with a key derived from the CD key hash. Decrypting and responding
+// to its contents is left to the caller.
+//
+// Format:
+//
+//    (VOID) Encrypted Packet
+//
+type Warden struct {
+	Data []byte
+}
+
+// Serialize encodes the struct into its binary form.
+func (pkt *Warden) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	buf.WriteUInt8(ProtocolSig)
+	buf.WriteUInt8(PidWarden)
+	buf.WriteUInt16(uint16(4 + len(pkt.Data)))
+	buf.WriteBlob(pkt.Data)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (pkt *Warden) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	var size = readPacketSize(buf)
+	if size < 4 {
+		return ErrInvalidPacketSize
+	}
+
+	pkt.Data = append(pkt.Data[:0], buf.ReadBlob(size-4)...)
+
+	return nil
+}
+
 // ClanInfo implements the [0x75] SID_CLANINFO packet (S -> C).
 //
 // Received to declare that the client is a member of a clan.
@@ -14,119 +14,123 @@ import (
 	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
 )
 
-func TestClientPackets(t *testing.T) {
-	var types = []bncs.Packet{
-		&bncs.UnknownPacket{
-			ID:   255,
-			Blob: []byte{bncs.ProtocolSig, 255, 4, 0},
-		},
-		&bncs.KeepAlive{},
-		&bncs.Ping{},
-		&bncs.Ping{
-			Payload: 123,
-		},
-		&bncs.EnterChatReq{},
-		&bncs.JoinChannel{},
-		&bncs.JoinChannel{
-			Flag:    bncs.ChannelJoinFirst,
-			Channel: "The Void",
-		},
-		&bncs.ChatCommand{},
-		&bncs.ChatCommand{
-			Text: "I come from the darkness of the pit.",
-		},
-		&bncs.GetAdvListReq{},
-		&bncs.GetAdvListReq{
-			Filter:        w3gs.GameFlagMapTypeMelee,
-			FilterMask:    w3gs.GameFlagMapTypeMask,
-			NumberOfGames: 2,
-			GameName:      "345",
-		},
-		&bncs.StartAdvex3Req{},
-		&bncs.StartAdvex3Req{
-			GameStateFlags: bncs.GameStateFlagPrivate,
-			UptimeSec:      2,
-			GameFlags:      w3gs.GameFlagSizeLarge,
-			Ladder:         true,
-			GameName:       "Test",
-			GameSettings: bncs.GameSettings{
-				SlotsFree:   5,
-				HostCounter: 6,
-				GameSettings: w3gs.GameSettings{
-					GameSettingFlags: w3gs.SettingSpeedNormal,
-					MapWidth:         1,
-					MapHeight:        2,
-					MapXoro:          3,
-					MapPath:          "4",
-					HostName:         "5",
-				},
+var clientPackets = []bncs.Packet{
+	&bncs.UnknownPacket{
+		ID:   255,
+		Blob: []byte{bncs.ProtocolSig, 255, 4, 0},
+	},
+	&bncs.KeepAlive{},
+	&bncs.Ping{},
+	&bncs.Ping{
+		Payload: 123,
+	},
+	&bncs.EnterChatReq{},
+	&bncs.JoinChannel{},
+	&bncs.JoinChannel{
+		Flag:    bncs.ChannelJoinFirst,
+		Channel: "The Void",
+	},
+	&bncs.ChatCommand{},
+	&bncs.ChatCommand{
+		Text: "I come from the darkness of the pit.",
+	},
+	&bncs.GetAdvListReq{},
+	&bncs.GetAdvListReq{
+		Filter:        w3gs.GameFlagMapTypeMelee,
+		FilterMask:    w3gs.GameFlagMapTypeMask,
+		NumberOfGames: 2,
+		GameName:      "345",
+	},
+	&bncs.StartAdvex3Req{},
+	&bncs.StartAdvex3Req{
+		GameStateFlags: bncs.GameStateFlagPrivate,
+		UptimeSec:      2,
+		GameFlags:      w3gs.GameFlagSizeLarge,
+		Ladder:         true,
+		GameName:       "Test",
+		GameSettings: bncs.GameSettings{
+			SlotsFree:   5,
+			HostCounter: 6,
+			GameSettings: w3gs.GameSettings{
+				GameSettingFlags: w3gs.SettingSpeedNormal,
+				MapWidth:         1,
+				MapHeight:        2,
+				MapXoro:          3,
+				MapPath:          "4",
+				HostName:         "5",
 			},
 		},
-		&bncs.StopAdv{},
-		&bncs.NotifyJoin{},
-		&bncs.NotifyJoin{
-			GameName: "GameGameNameName",
-		},
-		&bncs.NetGamePort{},
-		&bncs.NetGamePort{
-			Port: 6112,
-		},
-		&bncs.AuthInfoReq{},
-		&bncs.AuthInfoReq{
-			PlatformCode: protocol.DString("ix86"),
-			GameVersion: w3gs.GameVersion{
-				Product: w3gs.ProductROC,
-				Version: 1,
+	},
+	&bncs.StopAdv{},
+	&bncs.NotifyJoin{},
+	&bncs.NotifyJoin{
+		GameName: "GameGameNameName",
+	},
+	&bncs.NetGamePort{},
+	&bncs.NetGamePort{
+		Port: 6112,
+	},
+	&bncs.AuthInfoReq{},
+	&bncs.AuthInfoReq{
+		PlatformCode: protocol.DString("ix86"),
+		GameVersion: w3gs.GameVersion{
+			Product: w3gs.ProductROC,
+			Version: 1,
+		},
+		LanguageCode:        protocol.DString("enUS"),
+		LocalIP:             net.IP{1, 1, 1, 1},
+		TimeZoneBias:        2,
+		MpqLocaleID:         3,
+		UserLanguageID:      4,
+		CountryAbbreviation: "NLD",
+		Country:             "The Netherlands",
+	},
+	&bncs.AuthCheckReq{},
+	&bncs.AuthCheckReq{
+		ClientToken: 555,
+		ExeVersion:  666,
+		ExeHash:     777,
+		CDKeys: []bncs.CDKey{
+			bncs.CDKey{
+				KeyLength:       1,
+				KeyProductValue: 2,
+				KeyPublicValue:  3,
 			},
-			LanguageCode:        protocol.DString("enUS"),
-			LocalIP:             net.IP{1, 1, 1, 1},
-			TimeZoneBias:        2,
-			MpqLocaleID:         3,
-			UserLanguageID:      4,
-			CountryAbbreviation: "NLD",
-			Country:             "The Netherlands",
-		},
-		&bncs.AuthCheckReq{},
-		&bncs.AuthCheckReq{
-			ClientToken: 555,
-			ExeVersion:  666,
-			ExeHash:     777,
-			CDKeys: []bncs.CDKey{
-				bncs.CDKey{
-					KeyLength:       1,
-					KeyProductValue: 2,
-					KeyPublicValue:  3,
-				},
-				bncs.CDKey{
-					KeyLength:       4,
-					KeyProductValue: 5,
-					KeyPublicValue:  6,
-				},
+			bncs.CDKey{
+				KeyLength:       4,
+				KeyProductValue: 5,
+				KeyPublicValue:  6,
 			},
-			ExeInformation: "Warcraft III.exe",
-			KeyOwnerName:   "Niels",
-		},
-		&bncs.AuthAccountCreateReq{},
-		&bncs.AuthAccountCreateReq{
-			Username: "Grubby",
-		},
-		&bncs.AuthAccountLogonReq{},
-		&bncs.AuthAccountLogonReq{
-			Username: "Moon",
-		},
-		&bncs.AuthAccountLogonProofReq{},
-		&bncs.AuthAccountChangePassReq{},
-		&bncs.AuthAccountChangePassReq{
-			AuthAccountLogonReq: bncs.AuthAccountLogonReq{Username: "Lyn"},
-		},
-		&bncs.AuthAccountChangePassProofReq{},
-		&bncs.SetEmail{},
-		&bncs.SetEmail{
-			EmailAddress: "test@test.com",
 		},
-	}
+		ExeInformation: "Warcraft III.exe",
+		KeyOwnerName:   "Niels",
+	},
+	&bncs.AuthAccountCreateReq{},
+	&bncs.AuthAccountCreateReq{
+		Username: "Grubby",
+	},
+	&bncs.AuthAccountLogonReq{},
+	&bncs.AuthAccountLogonReq{
+		Username: "Moon",
+	},
+	&bncs.AuthAccountLogonProofReq{},
+	&bncs.AuthAccountChangePassReq{},
+	&bncs.AuthAccountChangePassReq{
+		AuthAccountLogonReq: bncs.AuthAccountLogonReq{Username: "Lyn"},
+	},
+	&bncs.AuthAccountChangePassProofReq{},
+	&bncs.SetEmail{},
+	&bncs.SetEmail{
+		EmailAddress: "test@test.com",
+	},
+	&bncs.Warden{},
+	&bncs.Warden{
+		Data: []byte{1, 2, 3, 4},
+	},
+}
 
-	for _, pkt := range types {
+func TestClientPackets(t *testing.T) {
+	for _, pkt := range clientPackets {
 		var err error
 		var buf = protocol.Buffer{}
 		var enc = bncs.Encoding{
@@ -183,124 +187,128 @@ func TestClientPackets(t *testing.T) {
 	}
 }
 
-func TestServerPackets(t *testing.T) {
-	var types = []bncs.Packet{
-		&bncs.UnknownPacket{
-			ID:   255,
-			Blob: []byte{bncs.ProtocolSig, 255, 4, 0},
-		},
-		&bncs.KeepAlive{},
-		&bncs.Ping{},
-		&bncs.Ping{
-			Payload: 123,
-		},
-		&bncs.EnterChatResp{},
-		&bncs.EnterChatResp{
-			UniqueName:  "He",
-			StatString:  "lo wo",
-			AccountName: "rld",
-		},
-		&bncs.ChatEvent{},
-		&bncs.ChatEvent{
-			Type:      bncs.ChatTalk,
-			UserFlags: 2,
-			Ping:      3,
-			Username:  "Grubby",
-			Text:      "Oh hi, Mark!",
-		},
-		&bncs.ChatEvent{
-			Type:         bncs.ChatChannelInfo,
-			ChannelFlags: bncs.ChatChannelFlagSilent,
-			Ping:         3,
-			Username:     "Grubby",
-			Text:         "Oh hi, Mark!",
-		},
-		&bncs.FloodDetected{},
-		&bncs.MessageBox{},
-		&bncs.MessageBox{
-			Style:   1,
-			Text:    "They came from behind",
-			Caption: "Gyrocopter",
-		},
-		&bncs.GetAdvListResp{},
-		&bncs.GetAdvListResp{
-			Result: bncs.AdvListFull,
-		},
-		&bncs.GetAdvListResp{
-			Games: []bncs.GetAdvListGame{
-				bncs.GetAdvListGame{
-					GameFlags:  w3gs.GameFlagCreatorUser,
-					LanguageID: 1,
-					Addr: protocol.SockAddr{
-						Port: 6,
-						IP:   net.IP{7, 8, 9, 10},
-					},
-					GameStateFlags: bncs.GameStateFlagInProgress,
-					UptimeSec:      4,
-					GameName:       "ShortName",
-					GameSettings: bncs.GameSettings{
-						SlotsFree:   5,
-						HostCounter: 6,
-						GameSettings: w3gs.GameSettings{
-							GameSettingFlags: w3gs.SettingSpeedNormal,
-							MapWidth:         1,
-							MapHeight:        2,
-							MapXoro:          3,
-							MapPath:          "4",
-							HostName:         "5",
-						},
+var serverPackets = []bncs.Packet{
+	&bncs.UnknownPacket{
+		ID:   255,
+		Blob: []byte{bncs.ProtocolSig, 255, 4, 0},
+	},
+	&bncs.KeepAlive{},
+	&bncs.Ping{},
+	&bncs.Ping{
+		Payload: 123,
+	},
+	&bncs.EnterChatResp{},
+	&bncs.EnterChatResp{
+		UniqueName:  "He",
+		StatString:  "lo wo",
+		AccountName: "rld",
+	},
+	&bncs.ChatEvent{},
+	&bncs.ChatEvent{
+		Type:      bncs.ChatTalk,
+		UserFlags: 2,
+		Ping:      3,
+		Username:  "Grubby",
+		Text:      "Oh hi, Mark!",
+	},
+	&bncs.ChatEvent{
+		Type:         bncs.ChatChannelInfo,
+		ChannelFlags: bncs.ChatChannelFlagSilent,
+		Ping:         3,
+		Username:     "Grubby",
+		Text:         "Oh hi, Mark!",
+	},
+	&bncs.FloodDetected{},
+	&bncs.MessageBox{},
+	&bncs.MessageBox{
+		Style:   1,
+		Text:    "They came from behind",
+		Caption: "Gyrocopter",
+	},
+	&bncs.GetAdvListResp{},
+	&bncs.GetAdvListResp{
+		Result: bncs.AdvListFull,
+	},
+	&bncs.GetAdvListResp{
+		Games: []bncs.GetAdvListGame{
+			bncs.GetAdvListGame{
+				GameFlags:  w3gs.GameFlagCreatorUser,
+				LanguageID: 1,
+				Addr: protocol.SockAddr{
+					Port: 6,
+					IP:   net.IP{7, 8, 9, 10},
+				},
+				GameStateFlags: bncs.GameStateFlagInProgress,
+				UptimeSec:      4,
+				GameName:       "ShortName",
+				GameSettings: bncs.GameSettings{
+					SlotsFree:   5,
+					HostCounter: 6,
+					GameSettings: w3gs.GameSettings{
+						GameSettingFlags: w3gs.SettingSpeedNormal,
+						MapWidth:         1,
+						MapHeight:        2,
+						MapXoro:          3,
+						MapPath:          "4",
+						HostName:         "5",
 					},
 				},
 			},
 		},
-		&bncs.StartAdvex3Resp{},
-		&bncs.StartAdvex3Resp{
-			Failed: true,
-		},
-		&bncs.AuthInfoResp{},
-		&bncs.AuthInfoResp{
-			ServerToken: 2,
-			MpqFileTime: 3,
-			MpqFileName: "456",
-			ValueString: "789",
-		},
-		&bncs.AuthCheckResp{},
-		&bncs.AuthCheckResp{
-			Result:                111,
-			AdditionalInformation: "222",
-		},
-		&bncs.AuthAccountCreateResp{},
-		&bncs.AuthAccountCreateResp{
-			Result: bncs.AccountCreateNameExists,
-		},
-		&bncs.AuthAccountLogonResp{},
-		&bncs.AuthAccountLogonResp{
-			Result: bncs.LogonUpgradeRequired,
-		},
-		&bncs.AuthAccountLogonProofResp{},
-		&bncs.AuthAccountLogonProofResp{
-			Result: bncs.LogonProofPasswordIncorrect,
-		},
-		&bncs.AuthAccountLogonProofResp{
-			Result:                bncs.LogonProofCustomError,
-			AdditionalInformation: "Foo, bar.",
-		},
-		&bncs.AuthAccountChangePassResp{},
-		&bncs.AuthAccountChangePassResp{
-			AuthAccountLogonResp: bncs.AuthAccountLogonResp{Result: bncs.LogonUpgradeRequired},
-		},
-		&bncs.AuthAccountChangePassProofResp{},
-		&bncs.AuthAccountChangePassProofResp{
-			AuthAccountLogonProofResp: bncs.AuthAccountLogonProofResp{Result: bncs.LogonProofPasswordIncorrect},
-		},
-		&bncs.ClanInfo{},
-		&bncs.ClanInfo{
-			Tag:  protocol.DString("4K"),
-			Rank: bncs.ClanRankMember,
-		},
-	}
+	},
+	&bncs.StartAdvex3Resp{},
+	&bncs.StartAdvex3Resp{
+		Failed: true,
+	},
+	&bncs.AuthInfoResp{},
+	&bncs.AuthInfoResp{
+		ServerToken: 2,
+		MpqFileTime: 3,
+		MpqFileName: "456",
+		ValueString: "789",
+	},
+	&bncs.AuthCheckResp{},
+	&bncs.AuthCheckResp{
+		Result:                111,
+		AdditionalInformation: "222",
+	},
+	&bncs.AuthAccountCreateResp{},
+	&bncs.AuthAccountCreateResp{
+		Result: bncs.AccountCreateNameExists,
+	},
+	&bncs.AuthAccountLogonResp{},
+	&bncs.AuthAccountLogonResp{
+		Result: bncs.LogonUpgradeRequired,
+	},
+	&bncs.AuthAccountLogonProofResp{},
+	&bncs.AuthAccountLogonProofResp{
+		Result: bncs.LogonProofPasswordIncorrect,
+	},
+	&bncs.AuthAccountLogonProofResp{
+		Result:                bncs.LogonProofCustomError,
+		AdditionalInformation: "Foo, bar.",
+	},
+	&bncs.AuthAccountChangePassResp{},
+	&bncs.AuthAccountChangePassResp{
+		AuthAccountLogonResp: bncs.AuthAccountLogonResp{Result: bncs.LogonUpgradeRequired},
+	},
+	&bncs.AuthAccountChangePassProofResp{},
+	&bncs.AuthAccountChangePassProofResp{
+		AuthAccountLogonProofResp: bncs.AuthAccountLogonProofResp{Result: bncs.LogonProofPasswordIncorrect},
+	},
+	&bncs.ClanInfo{},
+	&bncs.ClanInfo{
+		Tag:  protocol.DString("4K"),
+		Rank: bncs.ClanRankMember,
+	},
+	&bncs.Warden{},
+	&bncs.Warden{
+		Data: []byte{5, 6, 7},
+	},
+}
 
-	for _, pkt := range types {
+func TestServerPackets(t *testing.T) {
+	for _, pkt := range serverPackets {
 		var err error
 		var buf = protocol.Buffer{}
 		var enc = bncs.Encoding{
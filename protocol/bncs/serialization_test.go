@@ -5,8 +5,10 @@
 package bncs_test
 
 import (
+	"errors"
 	"io"
 	"net"
+	"reflect"
 	"testing"
 
 	"github.com/nielsAD/gowarcraft3/protocol"
@@ -30,10 +32,43 @@ func TestDeserialize(t *testing.T) {
 	if _, _, e := bncs.Deserialize([]byte{bncs.ProtocolSig, 3, 0}, bncs.Encoding{}); e != bncs.ErrNoProtocolSig {
 		t.Fatal("ErrNoProtocolSig expected if size < 4")
 	}
-	if _, _, e := bncs.Deserialize([]byte{bncs.ProtocolSig, 255, 255, 0}, bncs.Encoding{}); e != bncs.ErrInvalidPacketSize {
+	if _, _, e := bncs.Deserialize([]byte{bncs.ProtocolSig, 255, 255, 0}, bncs.Encoding{}); !errors.Is(e, bncs.ErrInvalidPacketSize) {
 		t.Fatal("ErrUnexpectedEOF expected if bytes invalid size", e)
 	}
 	if _, _, e := bncs.Read(&protocol.Buffer{Bytes: []byte{bncs.ProtocolSig, 255, 255, 0}}, bncs.Encoding{}); e != io.ErrUnexpectedEOF {
 		t.Fatal("ErrUnexpectedEOF expected if reader invalid size", e)
 	}
 }
+
+func TestStrict(t *testing.T) {
+	var b = []byte{bncs.ProtocolSig, bncs.PidPing, 8, 0, 1, 2, 3, 4, 5, 6}
+
+	var dec = bncs.NewDecoder(bncs.Encoding{}, nil)
+	if _, n, e := dec.Deserialize(b); e != nil || n != 8 {
+		t.Fatal("Expected trailing data to be accepted if not strict", e, n)
+	}
+
+	dec.Strict = true
+	_, _, e := dec.Deserialize(b)
+	var terr *protocol.TrailingDataError
+	if !errors.Is(e, protocol.ErrTrailingData) || !errors.As(e, &terr) || terr.N != 2 {
+		t.Fatal("Expected TrailingDataError", e)
+	}
+	if _, n, e := dec.Deserialize(b[:8]); e != nil || n != 8 {
+		t.Fatal("Expected exact packet to be accepted", e, n)
+	}
+}
+
+func TestKnownPackets(t *testing.T) {
+	var resp = bncs.KnownPackets()
+	var req = bncs.KnownRequests()
+	if len(resp) != len(bncs.DefaultFactory) || len(req) != len(bncs.DefaultFactory) {
+		t.Fatal("KnownPackets size mismatch")
+	}
+	if resp[bncs.PidAuthInfo] != reflect.TypeOf(bncs.AuthInfoResp{}) || req[bncs.PidAuthInfo] != reflect.TypeOf(bncs.AuthInfoReq{}) {
+		t.Fatal("Unexpected type for PidAuthInfo", resp[bncs.PidAuthInfo], req[bncs.PidAuthInfo])
+	}
+	if resp[bncs.PidPing] != req[bncs.PidPing] {
+		t.Fatal("Expected identical types for PidPing")
+	}
+}
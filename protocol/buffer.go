@@ -9,7 +9,6 @@ import (
 	"errors"
 	"io"
 	"math"
-	"math/bits"
 	"net"
 )
 
@@ -18,6 +17,7 @@ var (
 	ErrInvalidIP4               = errors.New("pbuf: Invalid IP4 address")
 	ErrInvalidSockAddr          = errors.New("pbuf: Invalid SockAddr structure")
 	ErrNoCStringTerminatorFound = errors.New("pbuf: No null terminator for string found in buffer")
+	ErrInvalidFrameSize         = errors.New("pbuf: Invalid frame size")
 )
 
 // AF_INET
@@ -80,6 +80,16 @@ func (b *Buffer) WriteUInt32(v uint32) {
 	b.Reset(append(b.Bytes, byte(v), byte(v>>8), byte(v>>16), byte(v>>24)))
 }
 
+// WriteUInt16BE appends big-endian uint16 v to the buffer
+func (b *Buffer) WriteUInt16BE(v uint16) {
+	b.Reset(append(b.Bytes, byte(v>>8), byte(v)))
+}
+
+// WriteUInt32BE appends big-endian uint32 v to the buffer
+func (b *Buffer) WriteUInt32BE(v uint32) {
+	b.Reset(append(b.Bytes, byte(v>>24), byte(v>>16), byte(v>>8), byte(v)))
+}
+
 // WriteUInt64 appends uint64 v to the buffer
 func (b *Buffer) WriteUInt64(v uint64) {
 	b.Reset(append(b.Bytes, byte(v), byte(v>>8), byte(v>>16), byte(v>>24), byte(v>>32), byte(v>>40), byte(v>>48), byte(v>>56)))
@@ -128,7 +138,7 @@ func (b *Buffer) WriteSockAddr(v *SockAddr) error {
 		b.WriteUInt32(0)
 	} else {
 		b.WriteUInt16(connAddressFamily)
-		b.WriteUInt16(bits.ReverseBytes16(v.Port))
+		b.WriteUInt16BE(v.Port)
 		if err := b.WriteIP(v.IP); err != nil {
 			return err
 		}
@@ -152,7 +162,7 @@ func (b *Buffer) WriteLEDString(v DWordString) {
 
 // WriteBEDString appends big-endian dword string v to the buffer
 func (b *Buffer) WriteBEDString(v DWordString) {
-	b.WriteUInt32(bits.ReverseBytes32(uint32(v)))
+	b.WriteUInt32BE(uint32(v))
 }
 
 // WriteBlobAt overwrites position p in the buffer with blob v
@@ -175,6 +185,16 @@ func (b *Buffer) WriteUInt32At(p int, v uint32) {
 	b.Bytes[p+3], b.Bytes[p+2], b.Bytes[p+1], b.Bytes[p] = byte(v>>24), byte(v>>16), byte(v>>8), byte(v)
 }
 
+// WriteUInt16BEAt overwrites position p in the buffer with big-endian uint16 v
+func (b *Buffer) WriteUInt16BEAt(p int, v uint16) {
+	b.Bytes[p], b.Bytes[p+1] = byte(v>>8), byte(v)
+}
+
+// WriteUInt32BEAt overwrites position p in the buffer with big-endian uint32 v
+func (b *Buffer) WriteUInt32BEAt(p int, v uint32) {
+	b.Bytes[p], b.Bytes[p+1], b.Bytes[p+2], b.Bytes[p+3] = byte(v>>24), byte(v>>16), byte(v>>8), byte(v)
+}
+
 // WriteUInt64At overwrites position p in the buffer with uint64 v
 func (b *Buffer) WriteUInt64At(p int, v uint64) {
 	b.Bytes[p+7], b.Bytes[p+6], b.Bytes[p+5], b.Bytes[p+4], b.Bytes[p+3], b.Bytes[p+2], b.Bytes[p+1], b.Bytes[p] = byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32), byte(v>>24), byte(v>>16), byte(v>>8), byte(v)
@@ -223,7 +243,7 @@ func (b *Buffer) WriteSockAddrAt(p int, v *SockAddr) error {
 		b.WriteUInt32At(p+4, 0)
 	} else {
 		b.WriteUInt16At(p, connAddressFamily)
-		b.WriteUInt16At(p+2, bits.ReverseBytes16(v.Port))
+		b.WriteUInt16BEAt(p+2, v.Port)
 		if err := b.WriteIPAt(p+4, v.IP); err != nil {
 			return err
 		}
@@ -248,7 +268,7 @@ func (b *Buffer) WriteLEDStringAt(p int, v DWordString) {
 
 // WriteBEDStringAt overwrites position p in the buffer with big-endian dword string v
 func (b *Buffer) WriteBEDStringAt(p int, v DWordString) {
-	b.WriteUInt32At(p, bits.ReverseBytes32(uint32(v)))
+	b.WriteUInt32BEAt(p, uint32(v))
 }
 
 // WriteTo implements io.WriterTo interface
@@ -273,6 +293,31 @@ func (b *Buffer) ReadSizeFrom(r io.Reader, n int) (int, error) {
 	return nn, err
 }
 
+// ReadFrameFrom reads exactly one length-prefixed frame from r. The frame starts with a header of
+// headerLen bytes that contains the (little-endian) 16-bit size of the complete frame at lengthOffset.
+func (b *Buffer) ReadFrameFrom(r io.Reader, headerLen int, lengthOffset int) (int, error) {
+	if headerLen < 2 || lengthOffset < 0 || lengthOffset+2 > headerLen {
+		return 0, ErrInvalidFrameSize
+	}
+
+	var s = len(b.Bytes)
+	if n, err := b.ReadSizeFrom(r, headerLen); err != nil {
+		return n, err
+	}
+
+	var size = int(b.Bytes[s+lengthOffset+1])<<8 | int(b.Bytes[s+lengthOffset])
+	if size < headerLen {
+		return headerLen, ErrInvalidFrameSize
+	}
+
+	n, err := b.ReadSizeFrom(r, size-headerLen)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return n + headerLen, err
+}
+
 // ReadFrom implements io.ReaderFrom interface
 func (b *Buffer) ReadFrom(r io.Reader) (int64, error) {
 	var n = int64(0)
@@ -313,6 +358,9 @@ func (b *Buffer) ReadByte() (byte, error) {
 }
 
 // ReadBlob consumes a blob of size len and returns (a slice of) its value
+//
+// The result aliases the buffer and is overwritten when the buffer is reused,
+// use ReadBlobCopy (or copy/append) to retain the value.
 func (b *Buffer) ReadBlob(len int) []byte {
 	if len > 0 {
 		var res = b.Bytes[:len]
@@ -323,6 +371,38 @@ func (b *Buffer) ReadBlob(len int) []byte {
 	return nil
 }
 
+// ReadBlobCopy consumes a blob of size len and returns a copy of its value
+func (b *Buffer) ReadBlobCopy(len int) []byte {
+	if len > 0 {
+		var res = make([]byte, len)
+		copy(res, b.Bytes[:len])
+		b.Reset(b.Bytes[len:])
+		return res
+	}
+
+	return nil
+}
+
+// Remaining returns (a slice of) the unread bytes without consuming them
+//
+// The result is never nil (an empty slice if the buffer is exhausted) and aliases the buffer.
+func (b *Buffer) Remaining() []byte {
+	if b.Bytes == nil {
+		return []byte{}
+	}
+	return b.Bytes
+}
+
+// ReadRemaining consumes all unread bytes and returns (a slice of) their value
+//
+// The result is never nil (an empty slice if the buffer is exhausted) and aliases the buffer,
+// use ReadBlobCopy(b.Size()) (or copy/append) to retain the value.
+func (b *Buffer) ReadRemaining() []byte {
+	var res = b.Remaining()
+	b.Reset(res[len(res):])
+	return res
+}
+
 // ReadUInt8 consumes a uint8 and returns its value
 func (b *Buffer) ReadUInt8() byte {
 	var res = byte(b.Bytes[0])
@@ -344,6 +424,20 @@ func (b *Buffer) ReadUInt32() uint32 {
 	return res
 }
 
+// ReadUInt16BE consumes a big-endian uint16 and returns its value
+func (b *Buffer) ReadUInt16BE() uint16 {
+	var res = uint16(b.Bytes[0])<<8 | uint16(b.Bytes[1])
+	b.Reset(b.Bytes[2:])
+	return res
+}
+
+// ReadUInt32BE consumes a big-endian uint32 and returns its value
+func (b *Buffer) ReadUInt32BE() uint32 {
+	var res = uint32(b.Bytes[0])<<24 | uint32(b.Bytes[1])<<16 | uint32(b.Bytes[2])<<8 | uint32(b.Bytes[3])
+	b.Reset(b.Bytes[4:])
+	return res
+}
+
 // ReadUInt64 consumes a uint32 and returns its value
 func (b *Buffer) ReadUInt64() uint64 {
 	var res = uint64(b.Bytes[7])<<56 | uint64(b.Bytes[6])<<48 | uint64(b.Bytes[5])<<40 | uint64(b.Bytes[4])<<32 | uint64(b.Bytes[3])<<24 | uint64(b.Bytes[2])<<16 | uint64(b.Bytes[1])<<8 | uint64(b.Bytes[0])
@@ -389,7 +483,7 @@ func (b *Buffer) ReadSockAddr() (SockAddr, error) {
 		res.Port = 0
 		res.IP = nil
 	case connAddressFamily:
-		res.Port = bits.ReverseBytes16(b.ReadUInt16())
+		res.Port = b.ReadUInt16BE()
 		res.IP = b.ReadIP()
 	default:
 		return res, ErrInvalidSockAddr
@@ -422,5 +516,5 @@ func (b *Buffer) ReadLEDString() DWordString {
 
 // ReadBEDString consumes a big-endian dword string and returns its value
 func (b *Buffer) ReadBEDString() DWordString {
-	return DWordString(bits.ReverseBytes32(b.ReadUInt32()))
+	return DWordString(b.ReadUInt32BE())
 }
@@ -105,6 +105,57 @@ func TestBlob(t *testing.T) {
 	}
 }
 
+func TestBlobCopy(t *testing.T) {
+	var blob = []byte{1, 2, 3, 4, 5, 6}
+	var buf = protocol.Buffer{Bytes: append([]byte(nil), blob...)}
+	var raw = buf.Bytes
+
+	var alias = buf.ReadBlob(3)
+	var cpy = buf.ReadBlobCopy(3)
+	if !bytes.Equal(alias, blob[:3]) || !bytes.Equal(cpy, blob[3:]) {
+		t.Fatalf("Unexpected blobs: %v %v", alias, cpy)
+	}
+	if buf.Size() != 0 {
+		t.Fatalf("Leftover: %v != 0", buf.Size())
+	}
+
+	raw[0], raw[3] = 0xFF, 0xFF
+	if alias[0] != 0xFF {
+		t.Fatal("Expected ReadBlob to alias buffer")
+	}
+	if cpy[0] != 4 {
+		t.Fatal("Expected ReadBlobCopy not to alias buffer")
+	}
+
+	if buf.ReadBlobCopy(0) != nil {
+		t.Fatal("nil expected")
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	var buf = protocol.Buffer{Bytes: []byte{1, 2, 3, 4, 5}}
+
+	buf.Skip(2)
+	if rem := buf.Remaining(); !bytes.Equal(rem, []byte{3, 4, 5}) || buf.Size() != 3 {
+		t.Fatalf("Unexpected remaining: %v (size %v)", rem, buf.Size())
+	}
+	if rem := buf.ReadRemaining(); !bytes.Equal(rem, []byte{3, 4, 5}) || buf.Size() != 0 {
+		t.Fatalf("Unexpected remaining: %v (size %v)", rem, buf.Size())
+	}
+
+	if rem := buf.Remaining(); rem == nil || len(rem) != 0 {
+		t.Fatal("Empty non-nil slice expected")
+	}
+	if rem := buf.ReadRemaining(); rem == nil || len(rem) != 0 {
+		t.Fatal("Empty non-nil slice expected")
+	}
+
+	var empty protocol.Buffer
+	if rem := empty.ReadRemaining(); rem == nil || len(rem) != 0 {
+		t.Fatal("Empty non-nil slice expected")
+	}
+}
+
 func TestUInt8(t *testing.T) {
 	var val = uint8(127)
 	var buf = protocol.Buffer{Bytes: make([]byte, 0)}
@@ -204,6 +255,58 @@ func TestUInt32(t *testing.T) {
 	}
 }
 
+func TestUInt16BE(t *testing.T) {
+	var val = uint16(0x1234)
+	var buf = protocol.Buffer{Bytes: make([]byte, 0)}
+
+	buf.WriteUInt16BE(val)
+	buf.WriteUInt16(val)
+	if !bytes.Equal(buf.Bytes, []byte{0x12, 0x34, 0x34, 0x12}) {
+		t.Fatalf("Unexpected byte order: % x", buf.Bytes)
+	}
+
+	buf.WriteUInt16BEAt(2, ^val)
+	if !bytes.Equal(buf.Bytes, []byte{0x12, 0x34, 0xED, 0xCB}) {
+		t.Fatalf("Unexpected byte order (WriteAt): % x", buf.Bytes)
+	}
+
+	if read := buf.ReadUInt16BE(); read != val {
+		t.Fatalf("read: %v != %v", read, val)
+	}
+	if read := buf.ReadUInt16BE(); read != ^val {
+		t.Fatalf("read (WriteAt): %v != %v", read, ^val)
+	}
+	if buf.Size() != 0 {
+		t.Fatalf("Leftover: %v != 0", buf.Size())
+	}
+}
+
+func TestUInt32BE(t *testing.T) {
+	var val = uint32(0x12345678)
+	var buf = protocol.Buffer{Bytes: make([]byte, 0)}
+
+	buf.WriteUInt32BE(val)
+	buf.WriteUInt32(val)
+	if !bytes.Equal(buf.Bytes, []byte{0x12, 0x34, 0x56, 0x78, 0x78, 0x56, 0x34, 0x12}) {
+		t.Fatalf("Unexpected byte order: % x", buf.Bytes)
+	}
+
+	buf.WriteUInt32BEAt(4, ^val)
+	if !bytes.Equal(buf.Bytes, []byte{0x12, 0x34, 0x56, 0x78, 0xED, 0xCB, 0xA9, 0x87}) {
+		t.Fatalf("Unexpected byte order (WriteAt): % x", buf.Bytes)
+	}
+
+	if read := buf.ReadUInt32BE(); read != val {
+		t.Fatalf("read: %v != %v", read, val)
+	}
+	if read := buf.ReadUInt32BE(); read != ^val {
+		t.Fatalf("read (WriteAt): %v != %v", read, ^val)
+	}
+	if buf.Size() != 0 {
+		t.Fatalf("Leftover: %v != 0", buf.Size())
+	}
+}
+
 func TestUInt64(t *testing.T) {
 	var val = uint64(18446744073709551614)
 	var buf = protocol.Buffer{Bytes: make([]byte, 0)}
@@ -403,6 +506,9 @@ func TestSockAddr(t *testing.T) {
 			t.Fatalf("Write(%v): %v != %v", i, buf.Size(), i*4)
 		}
 	}
+	if !bytes.Equal(buf.Bytes[2:8], []byte{0x17, 0xE0, 192, 168, 1, 101}) {
+		t.Fatalf("Unexpected byte order: % x", buf.Bytes[:8])
+	}
 
 	var rev = protocol.SockAddr{
 		Port: ^addr.Port,
@@ -603,3 +709,42 @@ func BenchmarkReadUInt32(b *testing.B) {
 		buf.ReadUInt32()
 	}
 }
+
+func TestReadFrame(t *testing.T) {
+	var stream = []byte{
+		0xF7, 0x01, 0x06, 0x00, 0xAA, 0xBB,
+		0xFF, 0x02, 0x04, 0x00,
+		0xF7, 0x03, 0x08, 0x00, 0x01,
+	}
+	var r = bytes.NewReader(stream)
+
+	f, err := protocol.ReadFrame(r, 4, 2)
+	if err != nil || !bytes.Equal(f, stream[:6]) {
+		t.Fatal("Frame 1 mismatch", f, err)
+	}
+	f, err = protocol.ReadFrame(r, 4, 2)
+	if err != nil || !bytes.Equal(f, stream[6:10]) {
+		t.Fatal("Frame 2 mismatch", f, err)
+	}
+	if _, err := protocol.ReadFrame(r, 4, 2); err != io.ErrUnexpectedEOF {
+		t.Fatal("Expected ErrUnexpectedEOF, got", err)
+	}
+	if _, err := protocol.ReadFrame(r, 4, 2); err != io.EOF {
+		t.Fatal("Expected EOF, got", err)
+	}
+
+	if _, err := protocol.ReadFrame(bytes.NewReader([]byte{0xF7, 0x01, 0x03, 0x00}), 4, 2); err != protocol.ErrInvalidFrameSize {
+		t.Fatal("Expected ErrInvalidFrameSize, got", err)
+	}
+	if _, err := protocol.ReadFrame(bytes.NewReader(stream), 4, 3); err != protocol.ErrInvalidFrameSize {
+		t.Fatal("Expected ErrInvalidFrameSize for invalid offset, got", err)
+	}
+
+	var buf = protocol.Buffer{Bytes: []byte{0x01}}
+	if n, err := buf.ReadFrameFrom(bytes.NewReader(stream), 4, -1); n != 0 || err != protocol.ErrInvalidFrameSize || buf.Size() != 1 {
+		t.Fatal("Expected ErrInvalidFrameSize for negative offset, got", n, err)
+	}
+	if n, err := buf.ReadFrameFrom(bytes.NewReader(stream), 4, 2); err != nil || n != 6 || !bytes.Equal(buf.Bytes[1:], stream[:6]) {
+		t.Fatal("ReadFrameFrom mismatch", n, err)
+	}
+}
@@ -4,3 +4,59 @@
 
 // Package protocol implements common utilities for Warcraft III network protocols.
 package protocol
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Errors
+var (
+	ErrTrailingData = errors.New("protocol: Trailing data after packet")
+	ErrSizeOverflow = errors.New("protocol: Size exceeds length field")
+)
+
+// MaxPacketSize is the size limit of BNCS and W3GS packets (including header), their length field is 16-bit
+const MaxPacketSize = 0xFFFF
+
+// ReadFrame reads exactly one length-prefixed frame (i.e. a BNCS or W3GS packet) from r and
+// returns its raw bytes, header included. See Buffer.ReadFrameFrom.
+func ReadFrame(r io.Reader, headerLen int, lengthOffset int) ([]byte, error) {
+	var buf Buffer
+	if _, err := buf.ReadFrameFrom(r, headerLen, lengthOffset); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes, nil
+}
+
+// PacketError adds packet context to a deserialization error
+type PacketError struct {
+	ID         uint8 // Packet or record ID
+	Offset     int   // Number of bytes consumed when the error occurred
+	Underlying error
+}
+
+func (e *PacketError) Error() string {
+	return fmt.Sprintf("%v (id: 0x%02X, offset: %d)", e.Underlying, e.ID, e.Offset)
+}
+
+// Unwrap returns the underlying error
+func (e *PacketError) Unwrap() error {
+	return e.Underlying
+}
+
+// TrailingDataError is returned by strict decoders when bytes remain after a packet is fully parsed
+type TrailingDataError struct {
+	N int // Number of bytes left
+}
+
+func (e *TrailingDataError) Error() string {
+	return fmt.Sprintf("%v (%d bytes)", ErrTrailingData, e.N)
+}
+
+// Is implements errors.Is, matches ErrTrailingData
+func (e *TrailingDataError) Is(target error) bool {
+	return target == ErrTrailingData
+}
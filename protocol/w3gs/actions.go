@@ -0,0 +1,1381 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3gs
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// Action is a single command in the action data of a PlayerAction (or GameAction).
+//
+// Based on action documentation by Blue and Nagger (w3g_actions.txt).
+// Action IDs below refer to the latest version, some IDs are shifted by one
+// in older versions and are translated transparently.
+type Action interface {
+	Serialize(buf *protocol.Buffer, enc *Encoding) error
+	Deserialize(buf *protocol.Buffer, enc *Encoding) error
+}
+
+// W3GS action type identifiers
+const (
+	AidPause              = 0x01
+	AidResume             = 0x02
+	AidSetGameSpeed       = 0x03
+	AidIncreaseGameSpeed  = 0x04
+	AidDecreaseGameSpeed  = 0x05
+	AidSaveGame           = 0x06
+	AidSaveGameFinished   = 0x07
+	AidAbility            = 0x10
+	AidAbilityTargetPos   = 0x11
+	AidAbilityTargetObj   = 0x12
+	AidGiveItem           = 0x13
+	AidAbilityTwoTargets  = 0x14
+	AidChangeSelection    = 0x16
+	AidAssignGroupHotkey  = 0x17
+	AidSelectGroupHotkey  = 0x18
+	AidSelectSubgroup     = 0x19
+	AidPreSubselection    = 0x1A
+	AidUnknown1B          = 0x1B
+	AidSelectGroundItem   = 0x1C
+	AidCancelHeroRevival  = 0x1D
+	AidRemoveFromQueue    = 0x1E
+	AidCheatFirst         = 0x20
+	AidUnknown21          = 0x21
+	AidCheatLast          = 0x32
+	AidChangeAllyOptions  = 0x50
+	AidTransferResources  = 0x51
+	AidTriggerChatCommand = 0x60
+	AidEscPressed         = 0x61
+	AidScenarioTrigger    = 0x62
+	AidHeroSkillSubmenu   = 0x66
+	AidBuildSubmenu       = 0x67
+	AidMinimapPing        = 0x68
+	AidContinueGameB      = 0x69
+	AidContinueGameA      = 0x6A
+	AidSyncStoredInteger  = 0x6B
+	AidUnknown75          = 0x75
+	AidUnknown7B          = 0x7B
+)
+
+// Size of the data of UnknownAction types
+var unknownActionSize = map[uint8]int{
+	AidUnknown1B:     9,
+	AidUnknown21:     8,
+	AidContinueGameB: 16,
+	AidContinueGameA: 16,
+	AidUnknown75:     1,
+	AidUnknown7B:     16,
+}
+
+// Size of the data of Cheat types (0 if not listed)
+var cheatSize = map[uint8]int{
+	0x27: 5, // KeyserSoze
+	0x28: 5, // LeafitToMe
+	0x2D: 5, // GreedIsGood
+	0x2E: 4, // DayLightSavings
+}
+
+// actionID converts aid to its wire value for enc
+func actionID(aid uint8, enc *Encoding) uint8 {
+	if enc.GameVersion != 0 && enc.GameVersion < 14 && aid >= AidUnknown1B && aid <= AidRemoveFromQueue {
+		return aid - 1
+	}
+	if enc.GameVersion != 0 && enc.GameVersion < 7 && (aid == AidHeroSkillSubmenu || aid == AidBuildSubmenu) {
+		return aid - 1
+	}
+	return aid
+}
+
+// actionIDFromWire is the inverse of actionID
+func actionIDFromWire(id uint8, enc *Encoding) uint8 {
+	if enc.GameVersion != 0 && enc.GameVersion < 14 && id >= AidPreSubselection && id <= AidRemoveFromQueue-1 {
+		return id + 1
+	}
+	if enc.GameVersion != 0 && enc.GameVersion < 7 && (id == AidHeroSkillSubmenu-1 || id == AidBuildSubmenu-1) {
+		return id + 1
+	}
+	return id
+}
+
+func newAction(aid uint8) Action {
+	switch aid {
+	case AidPause:
+		return &Pause{}
+	case AidResume:
+		return &Resume{}
+	case AidSetGameSpeed:
+		return &SetGameSpeed{}
+	case AidIncreaseGameSpeed:
+		return &IncreaseGameSpeed{}
+	case AidDecreaseGameSpeed:
+		return &DecreaseGameSpeed{}
+	case AidSaveGame:
+		return &SaveGame{}
+	case AidSaveGameFinished:
+		return &SaveGameFinished{}
+	case AidAbility:
+		return &Ability{}
+	case AidAbilityTargetPos:
+		return &AbilityTargetPos{}
+	case AidAbilityTargetObj:
+		return &AbilityTargetObj{}
+	case AidGiveItem:
+		return &GiveItem{}
+	case AidAbilityTwoTargets:
+		return &AbilityTwoTargets{}
+	case AidChangeSelection:
+		return &ChangeSelection{}
+	case AidAssignGroupHotkey:
+		return &AssignGroupHotkey{}
+	case AidSelectGroupHotkey:
+		return &SelectGroupHotkey{}
+	case AidSelectSubgroup:
+		return &SelectSubgroup{}
+	case AidPreSubselection:
+		return &PreSubselection{}
+	case AidSelectGroundItem:
+		return &SelectGroundItem{}
+	case AidCancelHeroRevival:
+		return &CancelHeroRevival{}
+	case AidRemoveFromQueue:
+		return &RemoveFromQueue{}
+	case AidChangeAllyOptions:
+		return &ChangeAllyOptions{}
+	case AidTransferResources:
+		return &TransferResources{}
+	case AidTriggerChatCommand:
+		return &TriggerChatCommand{}
+	case AidEscPressed:
+		return &EscPressed{}
+	case AidScenarioTrigger:
+		return &ScenarioTrigger{}
+	case AidHeroSkillSubmenu:
+		return &HeroSkillSubmenu{}
+	case AidBuildSubmenu:
+		return &BuildSubmenu{}
+	case AidMinimapPing:
+		return &MinimapPing{}
+	case AidSyncStoredInteger:
+		return &SyncStoredInteger{}
+	}
+
+	if _, ok := unknownActionSize[aid]; ok {
+		return &UnknownAction{ID: aid}
+	}
+	if aid >= AidCheatFirst && aid <= AidCheatLast {
+		return &Cheat{ID: aid}
+	}
+
+	return nil
+}
+
+// ActionID returns the type identifier of act (as used by the latest version), 0 if unknown
+func ActionID(act Action) uint8 {
+	switch v := act.(type) {
+	case *Pause:
+		return AidPause
+	case *Resume:
+		return AidResume
+	case *SetGameSpeed:
+		return AidSetGameSpeed
+	case *IncreaseGameSpeed:
+		return AidIncreaseGameSpeed
+	case *DecreaseGameSpeed:
+		return AidDecreaseGameSpeed
+	case *SaveGame:
+		return AidSaveGame
+	case *SaveGameFinished:
+		return AidSaveGameFinished
+	case *Ability:
+		return AidAbility
+	case *AbilityTargetPos:
+		return AidAbilityTargetPos
+	case *AbilityTargetObj:
+		return AidAbilityTargetObj
+	case *GiveItem:
+		return AidGiveItem
+	case *AbilityTwoTargets:
+		return AidAbilityTwoTargets
+	case *ChangeSelection:
+		return AidChangeSelection
+	case *AssignGroupHotkey:
+		return AidAssignGroupHotkey
+	case *SelectGroupHotkey:
+		return AidSelectGroupHotkey
+	case *SelectSubgroup:
+		return AidSelectSubgroup
+	case *PreSubselection:
+		return AidPreSubselection
+	case *SelectGroundItem:
+		return AidSelectGroundItem
+	case *CancelHeroRevival:
+		return AidCancelHeroRevival
+	case *RemoveFromQueue:
+		return AidRemoveFromQueue
+	case *ChangeAllyOptions:
+		return AidChangeAllyOptions
+	case *TransferResources:
+		return AidTransferResources
+	case *TriggerChatCommand:
+		return AidTriggerChatCommand
+	case *EscPressed:
+		return AidEscPressed
+	case *ScenarioTrigger:
+		return AidScenarioTrigger
+	case *HeroSkillSubmenu:
+		return AidHeroSkillSubmenu
+	case *BuildSubmenu:
+		return AidBuildSubmenu
+	case *MinimapPing:
+		return AidMinimapPing
+	case *SyncStoredInteger:
+		return AidSyncStoredInteger
+	case *UnknownAction:
+		return v.ID
+	case *Cheat:
+		return v.ID
+	default:
+		return 0
+	}
+}
+
+// DeserializeActions decodes all actions in action data b.
+// Decoding stops at the first unknown action, since its size cannot be determined.
+func DeserializeActions(b []byte, e Encoding) ([]Action, error) {
+	var res []Action
+	var buf = protocol.Buffer{Bytes: b}
+	for buf.Size() > 0 {
+		var act = newAction(actionIDFromWire(buf.Bytes[0], &e))
+		if act == nil {
+			return res, ErrUnknownAction
+		}
+		if err := act.Deserialize(&buf, &e); err != nil {
+			return res, err
+		}
+		res = append(res, act)
+	}
+	return res, nil
+}
+
+// SerializeActions encodes actions into action data
+func SerializeActions(actions []Action, e Encoding) ([]byte, error) {
+	var buf protocol.Buffer
+	for _, a := range actions {
+		if err := a.Serialize(&buf, &e); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes, nil
+}
+
+// Actions decodes the action data of pkt
+func (pkt *PlayerAction) Actions(e Encoding) ([]Action, error) {
+	return DeserializeActions(pkt.Data, e)
+}
+
+// ItemID is either a four character object ID (such as "hpea") or a numeric order ID (such as 0x000D0003)
+type ItemID uint32
+
+// IsOrder returns true if id is a numeric order ID
+func (id ItemID) IsOrder() bool {
+	return id>>16 == 0x000D
+}
+
+// IsObject returns true if id is a four character object ID
+func (id ItemID) IsObject() bool {
+	for i := uint(0); i < 32; i += 8 {
+		var c = byte(id >> i)
+		if c < '0' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func (id ItemID) String() string {
+	if !id.IsObject() {
+		return fmt.Sprintf("0x%08X", uint32(id))
+	}
+	return FourCC(uint32(id))
+}
+
+// ObjectID is an in-game object handle (stored as two dwords)
+type ObjectID [2]uint32
+
+func (o *ObjectID) serialize(buf *protocol.Buffer) {
+	buf.WriteUInt32(o[0])
+	buf.WriteUInt32(o[1])
+}
+
+func (o *ObjectID) deserialize(buf *protocol.Buffer) {
+	o[0] = buf.ReadUInt32()
+	o[1] = buf.ReadUInt32()
+}
+
+// Target position of an action
+//
+// Format:
+//
+//    (FLOAT) Target X (0xFFFFFFFF if no target)
+//    (FLOAT) Target Y (0xFFFFFFFF if no target)
+//
+type Target struct {
+	TargetX   float32
+	TargetY   float32
+	HasTarget bool
+}
+
+const noTarget = 0xFFFFFFFF
+
+func (t *Target) serialize(buf *protocol.Buffer) {
+	if !t.HasTarget {
+		buf.WriteUInt32(noTarget)
+		buf.WriteUInt32(noTarget)
+		return
+	}
+	buf.WriteFloat32(t.TargetX)
+	buf.WriteFloat32(t.TargetY)
+}
+
+func (t *Target) deserialize(buf *protocol.Buffer) {
+	var x = buf.ReadUInt32()
+	var y = buf.ReadUInt32()
+	t.HasTarget = x != noTarget || y != noTarget
+	if t.HasTarget {
+		t.TargetX = math.Float32frombits(x)
+		t.TargetY = math.Float32frombits(y)
+	} else {
+		t.TargetX = 0
+		t.TargetY = 0
+	}
+}
+
+func readObjectIDs(buf *protocol.Buffer, ids []ObjectID) ([]ObjectID, error) {
+	var n = int(buf.ReadUInt16())
+	if buf.Size() < n*8 {
+		return ids, ErrInvalidPacketSize
+	}
+	ids = ids[:0]
+	for i := 0; i < n; i++ {
+		var o ObjectID
+		o.deserialize(buf)
+		ids = append(ids, o)
+	}
+	return ids, nil
+}
+
+func writeObjectIDs(buf *protocol.Buffer, ids []ObjectID) {
+	buf.WriteUInt16(uint16(len(ids)))
+	for i := range ids {
+		ids[i].serialize(buf)
+	}
+}
+
+// Pause implements the [0x01] pause game action.
+type Pause struct{}
+
+// Serialize encodes the struct into its binary form.
+func (act *Pause) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	buf.WriteUInt8(AidPause)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *Pause) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < 1 {
+		return ErrInvalidPacketSize
+	}
+	buf.Skip(1)
+	return nil
+}
+
+// Resume implements the [0x02] resume game action.
+type Resume struct{}
+
+// Serialize encodes the struct into its binary form.
+func (act *Resume) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	buf.WriteUInt8(AidResume)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *Resume) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < 1 {
+		return ErrInvalidPacketSize
+	}
+	buf.Skip(1)
+	return nil
+}
+
+// SetGameSpeed implements the [0x03] set game speed action.
+//
+// Format:
+//
+//    (UINT8) Game speed (0: slow, 1: normal, 2: fast)
+//
+type SetGameSpeed struct {
+	Speed uint8
+}
+
+// Serialize encodes the struct into its binary form.
+func (act *SetGameSpeed) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	buf.WriteUInt8(AidSetGameSpeed)
+	buf.WriteUInt8(act.Speed)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *SetGameSpeed) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < 2 {
+		return ErrInvalidPacketSize
+	}
+	buf.Skip(1)
+	act.Speed = buf.ReadUInt8()
+	return nil
+}
+
+// IncreaseGameSpeed implements the [0x04] increase game speed action.
+type IncreaseGameSpeed struct{}
+
+// Serialize encodes the struct into its binary form.
+func (act *IncreaseGameSpeed) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	buf.WriteUInt8(AidIncreaseGameSpeed)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *IncreaseGameSpeed) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < 1 {
+		return ErrInvalidPacketSize
+	}
+	buf.Skip(1)
+	return nil
+}
+
+// DecreaseGameSpeed implements the [0x05] decrease game speed action.
+type DecreaseGameSpeed struct{}
+
+// Serialize encodes the struct into its binary form.
+func (act *DecreaseGameSpeed) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	buf.WriteUInt8(AidDecreaseGameSpeed)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *DecreaseGameSpeed) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < 1 {
+		return ErrInvalidPacketSize
+	}
+	buf.Skip(1)
+	return nil
+}
+
+// SaveGame implements the [0x06] save game action.
+//
+// Format:
+//
+//    (STRING) Save game name
+//
+type SaveGame struct {
+	Name string
+}
+
+// Serialize encodes the struct into its binary form.
+func (act *SaveGame) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	buf.WriteUInt8(AidSaveGame)
+	buf.WriteCString(act.Name)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *SaveGame) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < 2 {
+		return ErrInvalidPacketSize
+	}
+	buf.Skip(1)
+
+	var err error
+	act.Name, err = buf.ReadCString()
+	return err
+}
+
+// SaveGameFinished implements the [0x07] save game finished action.
+//
+// Format:
+//
+//    (UINT32) Unknown
+//
+type SaveGameFinished struct {
+	Unknown uint32
+}
+
+// Serialize encodes the struct into its binary form.
+func (act *SaveGameFinished) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	buf.WriteUInt8(AidSaveGameFinished)
+	buf.WriteUInt32(act.Unknown)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *SaveGameFinished) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < 5 {
+		return ErrInvalidPacketSize
+	}
+	buf.Skip(1)
+	act.Unknown = buf.ReadUInt32()
+	return nil
+}
+
+// Ability implements the [0x10] unit/building ability action (no target).
+//
+// Format:
+//
+//    (UINT16) Ability flags (UINT8 for version < 1.13)
+//    (UINT32) Item ID / order ID
+//    (UINT32) Unknown (version >= 1.07)
+//    (UINT32) Unknown (version >= 1.07)
+//
+type Ability struct {
+	Flags   uint16
+	ItemID  ItemID
+	Unknown [2]uint32
+}
+
+func (act *Ability) size(enc *Encoding) int {
+	var n = 1 + 2 + 4 + 8
+	if enc.GameVersion != 0 && enc.GameVersion < 13 {
+		n--
+	}
+	if enc.GameVersion != 0 && enc.GameVersion < 7 {
+		n -= 8
+	}
+	return n
+}
+
+func (act *Ability) serialize(aid uint8, buf *protocol.Buffer, enc *Encoding) {
+	buf.WriteUInt8(aid)
+	if enc.GameVersion != 0 && enc.GameVersion < 13 {
+		buf.WriteUInt8(uint8(act.Flags))
+	} else {
+		buf.WriteUInt16(act.Flags)
+	}
+	buf.WriteUInt32(uint32(act.ItemID))
+	if enc.GameVersion == 0 || enc.GameVersion >= 7 {
+		buf.WriteUInt32(act.Unknown[0])
+		buf.WriteUInt32(act.Unknown[1])
+	}
+}
+
+func (act *Ability) deserialize(buf *protocol.Buffer, enc *Encoding) {
+	buf.Skip(1)
+	if enc.GameVersion != 0 && enc.GameVersion < 13 {
+		act.Flags = uint16(buf.ReadUInt8())
+	} else {
+		act.Flags = buf.ReadUInt16()
+	}
+	act.ItemID = ItemID(buf.ReadUInt32())
+	if enc.GameVersion == 0 || enc.GameVersion >= 7 {
+		act.Unknown[0] = buf.ReadUInt32()
+		act.Unknown[1] = buf.ReadUInt32()
+	} else {
+		act.Unknown = [2]uint32{}
+	}
+}
+
+// Serialize encodes the struct into its binary form.
+func (act *Ability) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	act.serialize(AidAbility, buf, enc)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *Ability) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < act.size(enc) {
+		return ErrInvalidPacketSize
+	}
+	act.deserialize(buf, enc)
+	return nil
+}
+
+// AbilityTargetPos implements the [0x11] unit/building ability action with target position.
+//
+// Format:
+//
+//    [Ability]
+//    [Target]
+//
+type AbilityTargetPos struct {
+	Ability
+	Target
+}
+
+// Serialize encodes the struct into its binary form.
+func (act *AbilityTargetPos) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	act.Ability.serialize(AidAbilityTargetPos, buf, enc)
+	act.Target.serialize(buf)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *AbilityTargetPos) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < act.size(enc)+8 {
+		return ErrInvalidPacketSize
+	}
+	act.Ability.deserialize(buf, enc)
+	act.Target.deserialize(buf)
+	return nil
+}
+
+// AbilityTargetObj implements the [0x12] unit/building ability action with target position and target object.
+//
+// Format:
+//
+//    [Ability]
+//    [Target]
+//    (UINT32) Target object ID 1
+//    (UINT32) Target object ID 2
+//
+type AbilityTargetObj struct {
+	Ability
+	Target
+	Object ObjectID
+}
+
+// Serialize encodes the struct into its binary form.
+func (act *AbilityTargetObj) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	act.Ability.serialize(AidAbilityTargetObj, buf, enc)
+	act.Target.serialize(buf)
+	act.Object.serialize(buf)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *AbilityTargetObj) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < act.size(enc)+16 {
+		return ErrInvalidPacketSize
+	}
+	act.Ability.deserialize(buf, enc)
+	act.Target.deserialize(buf)
+	act.Object.deserialize(buf)
+	return nil
+}
+
+// GiveItem implements the [0x13] give item to unit / drop item on ground action.
+//
+// Format:
+//
+//    [Ability]
+//    [Target]
+//    (UINT32) Target object ID 1
+//    (UINT32) Target object ID 2
+//    (UINT32) Item object ID 1
+//    (UINT32) Item object ID 2
+//
+type GiveItem struct {
+	Ability
+	Target
+	Object ObjectID
+	Item   ObjectID
+}
+
+// Serialize encodes the struct into its binary form.
+func (act *GiveItem) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	act.Ability.serialize(AidGiveItem, buf, enc)
+	act.Target.serialize(buf)
+	act.Object.serialize(buf)
+	act.Item.serialize(buf)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *GiveItem) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < act.size(enc)+24 {
+		return ErrInvalidPacketSize
+	}
+	act.Ability.deserialize(buf, enc)
+	act.Target.deserialize(buf)
+	act.Object.deserialize(buf)
+	act.Item.deserialize(buf)
+	return nil
+}
+
+// AbilityTwoTargets implements the [0x14] unit/building ability action with two target positions and two item IDs.
+//
+// Format:
+//
+//    [Ability]
+//    [Target]
+//    (UINT32) Item ID B
+//     (UINT8) Unknown[9]
+//    [Target B]
+//
+type AbilityTwoTargets struct {
+	Ability
+	Target
+	ItemIDB  ItemID
+	Unknown2 [9]byte
+	TargetB  Target
+}
+
+// Serialize encodes the struct into its binary form.
+func (act *AbilityTwoTargets) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	act.Ability.serialize(AidAbilityTwoTargets, buf, enc)
+	act.Target.serialize(buf)
+	buf.WriteUInt32(uint32(act.ItemIDB))
+	buf.WriteBlob(act.Unknown2[:])
+	act.TargetB.serialize(buf)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *AbilityTwoTargets) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < act.size(enc)+29 {
+		return ErrInvalidPacketSize
+	}
+	act.Ability.deserialize(buf, enc)
+	act.Target.deserialize(buf)
+	act.ItemIDB = ItemID(buf.ReadUInt32())
+	copy(act.Unknown2[:], buf.ReadBlob(9))
+	act.TargetB.deserialize(buf)
+	return nil
+}
+
+// SelectMode enum
+type SelectMode uint8
+
+// Selection modes
+const (
+	SelectAdd    SelectMode = 0x01
+	SelectRemove SelectMode = 0x02
+)
+
+func (m SelectMode) String() string {
+	switch m {
+	case SelectAdd:
+		return "Add"
+	case SelectRemove:
+		return "Remove"
+	default:
+		return fmt.Sprintf("SelectMode(0x%02X)", uint8(m))
+	}
+}
+
+// ChangeSelection implements the [0x16] change selection action.
+//
+// Format:
+//
+//     (UINT8) Select mode
+//    (UINT16) Number of units (n)
+//
+//    For each unit (n):
+//        (UINT32) Object ID 1
+//        (UINT32) Object ID 2
+//
+type ChangeSelection struct {
+	Mode  SelectMode
+	Units []ObjectID
+}
+
+// Serialize encodes the struct into its binary form.
+func (act *ChangeSelection) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	buf.WriteUInt8(AidChangeSelection)
+	buf.WriteUInt8(uint8(act.Mode))
+	writeObjectIDs(buf, act.Units)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *ChangeSelection) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < 4 {
+		return ErrInvalidPacketSize
+	}
+	buf.Skip(1)
+	act.Mode = SelectMode(buf.ReadUInt8())
+
+	var err error
+	act.Units, err = readObjectIDs(buf, act.Units)
+	return err
+}
+
+// AssignGroupHotkey implements the [0x17] assign group hotkey action.
+//
+// Format:
+//
+//     (UINT8) Group number (0-9)
+//    (UINT16) Number of units (n)
+//
+//    For each unit (n):
+//        (UINT32) Object ID 1
+//        (UINT32) Object ID 2
+//
+type AssignGroupHotkey struct {
+	Group uint8
+	Units []ObjectID
+}
+
+// Serialize encodes the struct into its binary form.
+func (act *AssignGroupHotkey) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	buf.WriteUInt8(AidAssignGroupHotkey)
+	buf.WriteUInt8(act.Group)
+	writeObjectIDs(buf, act.Units)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *AssignGroupHotkey) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < 4 {
+		return ErrInvalidPacketSize
+	}
+	buf.Skip(1)
+	act.Group = buf.ReadUInt8()
+
+	var err error
+	act.Units, err = readObjectIDs(buf, act.Units)
+	return err
+}
+
+// SelectGroupHotkey implements the [0x18] select group hotkey action.
+//
+// Format:
+//
+//    (UINT8) Group number (0-9)
+//    (UINT8) Unknown
+//
+type SelectGroupHotkey struct {
+	Group   uint8
+	Unknown uint8
+}
+
+// Serialize encodes the struct into its binary form.
+func (act *SelectGroupHotkey) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	buf.WriteUInt8(AidSelectGroupHotkey)
+	buf.WriteUInt8(act.Group)
+	buf.WriteUInt8(act.Unknown)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *SelectGroupHotkey) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < 3 {
+		return ErrInvalidPacketSize
+	}
+	buf.Skip(1)
+	act.Group = buf.ReadUInt8()
+	act.Unknown = buf.ReadUInt8()
+	return nil
+}
+
+// SelectSubgroup implements the [0x19] select subgroup action.
+//
+// Format:
+//
+//    if version < 1.14:
+//        (UINT8) Subgroup number
+//
+//    else:
+//        (UINT32) Item ID
+//        (UINT32) Object ID 1
+//        (UINT32) Object ID 2
+//
+type SelectSubgroup struct {
+	Subgroup uint8
+	ItemID   ItemID
+	Object   ObjectID
+}
+
+// Serialize encodes the struct into its binary form.
+func (act *SelectSubgroup) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	buf.WriteUInt8(AidSelectSubgroup)
+	if enc.GameVersion != 0 && enc.GameVersion < 14 {
+		buf.WriteUInt8(act.Subgroup)
+		return nil
+	}
+	buf.WriteUInt32(uint32(act.ItemID))
+	act.Object.serialize(buf)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *SelectSubgroup) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if enc.GameVersion != 0 && enc.GameVersion < 14 {
+		if buf.Size() < 2 {
+			return ErrInvalidPacketSize
+		}
+		buf.Skip(1)
+		act.Subgroup = buf.ReadUInt8()
+		act.ItemID = 0
+		act.Object = ObjectID{}
+		return nil
+	}
+
+	if buf.Size() < 13 {
+		return ErrInvalidPacketSize
+	}
+	buf.Skip(1)
+	act.Subgroup = 0
+	act.ItemID = ItemID(buf.ReadUInt32())
+	act.Object.deserialize(buf)
+	return nil
+}
+
+// PreSubselection implements the [0x1A] pre subselection action (version >= 1.14).
+type PreSubselection struct{}
+
+// Serialize encodes the struct into its binary form.
+func (act *PreSubselection) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	buf.WriteUInt8(AidPreSubselection)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *PreSubselection) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < 1 {
+		return ErrInvalidPacketSize
+	}
+	buf.Skip(1)
+	return nil
+}
+
+// SelectGroundItem implements the [0x1C] select ground item action.
+//
+// Format:
+//
+//     (UINT8) Unknown
+//    (UINT32) Item object ID 1
+//    (UINT32) Item object ID 2
+//
+type SelectGroundItem struct {
+	Unknown uint8
+	Item    ObjectID
+}
+
+// Serialize encodes the struct into its binary form.
+func (act *SelectGroundItem) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	buf.WriteUInt8(actionID(AidSelectGroundItem, enc))
+	buf.WriteUInt8(act.Unknown)
+	act.Item.serialize(buf)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *SelectGroundItem) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < 10 {
+		return ErrInvalidPacketSize
+	}
+	buf.Skip(1)
+	act.Unknown = buf.ReadUInt8()
+	act.Item.deserialize(buf)
+	return nil
+}
+
+// CancelHeroRevival implements the [0x1D] cancel hero revival action.
+//
+// Format:
+//
+//    (UINT32) Hero object ID 1
+//    (UINT32) Hero object ID 2
+//
+type CancelHeroRevival struct {
+	Hero ObjectID
+}
+
+// Serialize encodes the struct into its binary form.
+func (act *CancelHeroRevival) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	buf.WriteUInt8(actionID(AidCancelHeroRevival, enc))
+	act.Hero.serialize(buf)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *CancelHeroRevival) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < 9 {
+		return ErrInvalidPacketSize
+	}
+	buf.Skip(1)
+	act.Hero.deserialize(buf)
+	return nil
+}
+
+// RemoveFromQueue implements the [0x1E] remove unit from building queue action.
+//
+// Format:
+//
+//     (UINT8) Slot number (0 is the unit in training)
+//    (UINT32) Item ID
+//
+type RemoveFromQueue struct {
+	Slot   uint8
+	ItemID ItemID
+}
+
+// Serialize encodes the struct into its binary form.
+func (act *RemoveFromQueue) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	buf.WriteUInt8(actionID(AidRemoveFromQueue, enc))
+	buf.WriteUInt8(act.Slot)
+	buf.WriteUInt32(uint32(act.ItemID))
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *RemoveFromQueue) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < 6 {
+		return ErrInvalidPacketSize
+	}
+	buf.Skip(1)
+	act.Slot = buf.ReadUInt8()
+	act.ItemID = ItemID(buf.ReadUInt32())
+	return nil
+}
+
+// Cheat implements the [0x20-0x32] single player cheat actions.
+//
+// Format:
+//
+//    (VOID) Cheat arguments (size depends on ID)
+//
+type Cheat struct {
+	ID   uint8
+	Data []byte
+}
+
+// Serialize encodes the struct into its binary form.
+func (act *Cheat) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	if len(act.Data) != cheatSize[act.ID] {
+		return ErrInvalidPacketSize
+	}
+	buf.WriteUInt8(act.ID)
+	buf.WriteBlob(act.Data)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *Cheat) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < 1 {
+		return ErrInvalidPacketSize
+	}
+	var size = cheatSize[buf.Bytes[0]]
+	if buf.Size() < 1+size {
+		return ErrInvalidPacketSize
+	}
+	act.ID = buf.ReadUInt8()
+	act.Data = append(act.Data[:0], buf.ReadBlob(size)...)
+	return nil
+}
+
+// ChangeAllyOptions implements the [0x50] change ally options action.
+//
+// Format:
+//
+//     (UINT8) Player slot number
+//    (UINT32) Flags
+//
+type ChangeAllyOptions struct {
+	Slot  uint8
+	Flags uint32
+}
+
+// Serialize encodes the struct into its binary form.
+func (act *ChangeAllyOptions) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	buf.WriteUInt8(AidChangeAllyOptions)
+	buf.WriteUInt8(act.Slot)
+	buf.WriteUInt32(act.Flags)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *ChangeAllyOptions) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < 6 {
+		return ErrInvalidPacketSize
+	}
+	buf.Skip(1)
+	act.Slot = buf.ReadUInt8()
+	act.Flags = buf.ReadUInt32()
+	return nil
+}
+
+// TransferResources implements the [0x51] transfer resources action.
+//
+// Format:
+//
+//     (UINT8) Player slot number
+//    (UINT32) Gold
+//    (UINT32) Lumber
+//
+type TransferResources struct {
+	Slot   uint8
+	Gold   uint32
+	Lumber uint32
+}
+
+// Serialize encodes the struct into its binary form.
+func (act *TransferResources) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	buf.WriteUInt8(AidTransferResources)
+	buf.WriteUInt8(act.Slot)
+	buf.WriteUInt32(act.Gold)
+	buf.WriteUInt32(act.Lumber)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *TransferResources) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < 10 {
+		return ErrInvalidPacketSize
+	}
+	buf.Skip(1)
+	act.Slot = buf.ReadUInt8()
+	act.Gold = buf.ReadUInt32()
+	act.Lumber = buf.ReadUInt32()
+	return nil
+}
+
+// TriggerChatCommand implements the [0x60] map trigger chat command action.
+//
+// Format:
+//
+//    (UINT32) Unknown
+//    (UINT32) Unknown
+//    (STRING) Chat command or cheat
+//
+type TriggerChatCommand struct {
+	Unknown [2]uint32
+	Message string
+}
+
+// Serialize encodes the struct into its binary form.
+func (act *TriggerChatCommand) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	buf.WriteUInt8(AidTriggerChatCommand)
+	buf.WriteUInt32(act.Unknown[0])
+	buf.WriteUInt32(act.Unknown[1])
+	buf.WriteCString(act.Message)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *TriggerChatCommand) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < 10 {
+		return ErrInvalidPacketSize
+	}
+	buf.Skip(1)
+	act.Unknown[0] = buf.ReadUInt32()
+	act.Unknown[1] = buf.ReadUInt32()
+
+	var err error
+	act.Message, err = buf.ReadCString()
+	return err
+}
+
+// EscPressed implements the [0x61] ESC pressed action.
+type EscPressed struct{}
+
+// Serialize encodes the struct into its binary form.
+func (act *EscPressed) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	buf.WriteUInt8(AidEscPressed)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *EscPressed) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < 1 {
+		return ErrInvalidPacketSize
+	}
+	buf.Skip(1)
+	return nil
+}
+
+// ScenarioTrigger implements the [0x62] scenario trigger action.
+//
+// Format:
+//
+//    (UINT32) Unknown
+//    (UINT32) Unknown
+//    (UINT32) Unknown (version >= 1.07)
+//
+type ScenarioTrigger struct {
+	Unknown [3]uint32
+}
+
+// Serialize encodes the struct into its binary form.
+func (act *ScenarioTrigger) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	buf.WriteUInt8(AidScenarioTrigger)
+	buf.WriteUInt32(act.Unknown[0])
+	buf.WriteUInt32(act.Unknown[1])
+	if enc.GameVersion == 0 || enc.GameVersion >= 7 {
+		buf.WriteUInt32(act.Unknown[2])
+	}
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *ScenarioTrigger) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	var old = enc.GameVersion != 0 && enc.GameVersion < 7
+	if buf.Size() < 13 && (!old || buf.Size() < 9) {
+		return ErrInvalidPacketSize
+	}
+	buf.Skip(1)
+	act.Unknown[0] = buf.ReadUInt32()
+	act.Unknown[1] = buf.ReadUInt32()
+	if old {
+		act.Unknown[2] = 0
+	} else {
+		act.Unknown[2] = buf.ReadUInt32()
+	}
+	return nil
+}
+
+// HeroSkillSubmenu implements the [0x66] enter choose hero skill submenu action.
+type HeroSkillSubmenu struct{}
+
+// Serialize encodes the struct into its binary form.
+func (act *HeroSkillSubmenu) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	buf.WriteUInt8(actionID(AidHeroSkillSubmenu, enc))
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *HeroSkillSubmenu) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < 1 {
+		return ErrInvalidPacketSize
+	}
+	buf.Skip(1)
+	return nil
+}
+
+// BuildSubmenu implements the [0x67] enter choose building submenu action.
+type BuildSubmenu struct{}
+
+// Serialize encodes the struct into its binary form.
+func (act *BuildSubmenu) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	buf.WriteUInt8(actionID(AidBuildSubmenu, enc))
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *BuildSubmenu) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < 1 {
+		return ErrInvalidPacketSize
+	}
+	buf.Skip(1)
+	return nil
+}
+
+// MinimapPing implements the [0x68] minimap signal (ping) action.
+//
+// Format:
+//
+//    [Target]
+//    (UINT32) Unknown
+//
+type MinimapPing struct {
+	Target
+	Unknown uint32
+}
+
+// Serialize encodes the struct into its binary form.
+func (act *MinimapPing) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	buf.WriteUInt8(AidMinimapPing)
+	act.Target.serialize(buf)
+	buf.WriteUInt32(act.Unknown)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *MinimapPing) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < 13 {
+		return ErrInvalidPacketSize
+	}
+	buf.Skip(1)
+	act.Target.deserialize(buf)
+	act.Unknown = buf.ReadUInt32()
+	return nil
+}
+
+// SyncStoredInteger implements the [0x6B] game cache sync action (used by W3MMD).
+//
+// Format:
+//
+//    (STRING) Game cache file name
+//    (STRING) Mission key
+//    (STRING) Key
+//    (UINT32) Value
+//
+type SyncStoredInteger struct {
+	File       string
+	MissionKey string
+	Key        string
+	Value      uint32
+}
+
+// Serialize encodes the struct into its binary form.
+func (act *SyncStoredInteger) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	buf.WriteUInt8(AidSyncStoredInteger)
+	buf.WriteCString(act.File)
+	buf.WriteCString(act.MissionKey)
+	buf.WriteCString(act.Key)
+	buf.WriteUInt32(act.Value)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *SyncStoredInteger) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < 8 {
+		return ErrInvalidPacketSize
+	}
+	buf.Skip(1)
+
+	var err error
+	if act.File, err = buf.ReadCString(); err != nil {
+		return err
+	}
+	if act.MissionKey, err = buf.ReadCString(); err != nil {
+		return err
+	}
+	if act.Key, err = buf.ReadCString(); err != nil {
+		return err
+	}
+	if buf.Size() < 4 {
+		return ErrInvalidPacketSize
+	}
+	act.Value = buf.ReadUInt32()
+	return nil
+}
+
+// UnknownAction is used to store actions of known size but with undocumented content
+// (0x1B, 0x21, 0x69, 0x6A, 0x75, 0x7B).
+type UnknownAction struct {
+	ID   uint8
+	Data []byte
+}
+
+// Serialize encodes the struct into its binary form.
+func (act *UnknownAction) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	if size, ok := unknownActionSize[act.ID]; !ok || len(act.Data) != size {
+		return ErrInvalidPacketSize
+	}
+	buf.WriteUInt8(actionID(act.ID, enc))
+	buf.WriteBlob(act.Data)
+	return nil
+}
+
+// Deserialize decodes the binary data generated by Serialize.
+func (act *UnknownAction) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < 1 {
+		return ErrInvalidPacketSize
+	}
+	var id = actionIDFromWire(buf.Bytes[0], enc)
+	var size, ok = unknownActionSize[id]
+	if !ok {
+		return ErrUnknownAction
+	}
+	if buf.Size() < 1+size {
+		return ErrInvalidPacketSize
+	}
+	buf.Skip(1)
+	act.ID = id
+	act.Data = append(act.Data[:0], buf.ReadBlob(size)...)
+	return nil
+}
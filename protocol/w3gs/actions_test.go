@@ -0,0 +1,202 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+package w3gs_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+var ability = w3gs.Ability{
+	Flags:   0x42,
+	ItemID:  0x68706561,
+	Unknown: [2]uint32{0xFFFFFFFF, 0xFFFFFFFF},
+}
+
+var target = w3gs.Target{
+	TargetX:   -992,
+	TargetY:   6112,
+	HasTarget: true,
+}
+
+func TestActions(t *testing.T) {
+	var types = []w3gs.Action{
+		&w3gs.Pause{},
+		&w3gs.Resume{},
+		&w3gs.SetGameSpeed{Speed: 2},
+		&w3gs.IncreaseGameSpeed{},
+		&w3gs.DecreaseGameSpeed{},
+		&w3gs.SaveGame{Name: "Save"},
+		&w3gs.SaveGameFinished{Unknown: 1},
+		&w3gs.Ability{},
+		&ability,
+		&w3gs.AbilityTargetPos{},
+		&w3gs.AbilityTargetPos{Ability: ability, Target: target},
+		&w3gs.AbilityTargetObj{Ability: ability, Target: target, Object: w3gs.ObjectID{1, 2}},
+		&w3gs.GiveItem{Ability: ability, Target: target, Object: w3gs.ObjectID{1, 2}, Item: w3gs.ObjectID{3, 4}},
+		&w3gs.AbilityTwoTargets{Ability: ability, Target: target, ItemIDB: 5, Unknown2: [9]byte{6}, TargetB: target},
+		&w3gs.ChangeSelection{Mode: w3gs.SelectAdd},
+		&w3gs.ChangeSelection{Mode: w3gs.SelectRemove, Units: []w3gs.ObjectID{{1, 2}, {3, 4}}},
+		&w3gs.AssignGroupHotkey{Group: 1, Units: []w3gs.ObjectID{{1, 2}}},
+		&w3gs.SelectGroupHotkey{Group: 1, Unknown: 3},
+		&w3gs.SelectSubgroup{ItemID: 0x68706561, Object: w3gs.ObjectID{1, 2}},
+		&w3gs.PreSubselection{},
+		&w3gs.SelectGroundItem{Unknown: 4, Item: w3gs.ObjectID{1, 2}},
+		&w3gs.CancelHeroRevival{Hero: w3gs.ObjectID{1, 2}},
+		&w3gs.RemoveFromQueue{Slot: 1, ItemID: 0x68706561},
+		&w3gs.Cheat{ID: 0x20},
+		&w3gs.Cheat{ID: 0x2D, Data: []byte{1, 2, 3, 4, 5}},
+		&w3gs.ChangeAllyOptions{Slot: 1, Flags: 0x1F},
+		&w3gs.TransferResources{Slot: 1, Gold: 100, Lumber: 200},
+		&w3gs.TriggerChatCommand{Unknown: [2]uint32{1, 2}, Message: "-ap"},
+		&w3gs.EscPressed{},
+		&w3gs.ScenarioTrigger{Unknown: [3]uint32{1, 2, 3}},
+		&w3gs.HeroSkillSubmenu{},
+		&w3gs.BuildSubmenu{},
+		&w3gs.MinimapPing{Target: target, Unknown: 1},
+		&w3gs.SyncStoredInteger{File: "MMD.Dat", MissionKey: "val:0", Key: "init version 1", Value: 1},
+		&w3gs.UnknownAction{ID: w3gs.AidUnknown7B, Data: make([]byte, 16)},
+	}
+
+	for _, act := range types {
+		var b, err = w3gs.SerializeActions([]w3gs.Action{act, act}, w3gs.Encoding{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := w3gs.DeserializeActions(b, w3gs.Encoding{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res) != 2 || !reflect.DeepEqual(act, res[0]) || !reflect.DeepEqual(act, res[1]) {
+			t.Fatalf("Action type %v not equal after encoding", reflect.TypeOf(act))
+		}
+		if id := w3gs.ActionID(act); id != b[0] {
+			t.Fatalf("ActionID mismatch for %v: 0x%02X != 0x%02X", reflect.TypeOf(act), id, b[0])
+		}
+
+		for i := 1; i < len(b)/2; i++ {
+			if _, err := w3gs.DeserializeActions(b[:i], w3gs.Encoding{}); err == nil {
+				t.Fatalf("Expected error for truncated %v", reflect.TypeOf(act))
+			}
+		}
+	}
+
+	if _, err := w3gs.DeserializeActions([]byte{0xFF}, w3gs.Encoding{}); err != w3gs.ErrUnknownAction {
+		t.Fatal("Expected ErrUnknownAction")
+	}
+}
+
+func TestActionsVersion(t *testing.T) {
+	var acts = []w3gs.Action{
+		&w3gs.Ability{Flags: 0x42, ItemID: 0x68706561},
+		&w3gs.SelectSubgroup{Subgroup: 1},
+		&w3gs.SelectGroundItem{Unknown: 4, Item: w3gs.ObjectID{1, 2}},
+		&w3gs.HeroSkillSubmenu{},
+	}
+
+	var tests = []struct {
+		enc  w3gs.Encoding
+		data []byte
+	}{
+		{w3gs.Encoding{GameVersion: 6}, []byte{
+			0x10, 0x42, 0x61, 0x65, 0x70, 0x68,
+			0x19, 0x01,
+			0x1B, 0x04, 0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00,
+			0x65,
+		}},
+		{w3gs.Encoding{GameVersion: 13}, []byte{
+			0x10, 0x42, 0x00, 0x61, 0x65, 0x70, 0x68, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x19, 0x01,
+			0x1B, 0x04, 0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00,
+			0x66,
+		}},
+	}
+
+	for _, tc := range tests {
+		b, err := w3gs.SerializeActions(acts, tc.enc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, tc.data) {
+			t.Fatalf("Unexpected encoding for version %d: % x", tc.enc.GameVersion, b)
+		}
+
+		res, err := w3gs.DeserializeActions(b, tc.enc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(res, acts) {
+			t.Fatalf("Actions not equal after encoding for version %d", tc.enc.GameVersion)
+		}
+	}
+}
+
+func TestTarget(t *testing.T) {
+	var b = []byte{
+		0x11, 0x42, 0x00, 0x61, 0x65, 0x70, 0x68, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+		0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+	}
+
+	res, err := w3gs.DeserializeActions(b, w3gs.Encoding{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := res[0].(*w3gs.AbilityTargetPos); a.HasTarget || a.TargetX != 0 || a.TargetY != 0 || a.ItemID.String() != "hpea" {
+		t.Fatal("Expected no target", a)
+	}
+	if s := w3gs.ItemID(0x000D0003).String(); s != "0x000D0003" || !w3gs.ItemID(0x000D0003).IsOrder() {
+		t.Fatal("Unexpected order ID string", s)
+	}
+}
+
+func TestItemID(t *testing.T) {
+	var tests = []struct {
+		id   w3gs.ItemID
+		typ  w3gs.ObjectType
+		name string
+	}{
+		{0x68706561, w3gs.ObjectUnit, "Peasant"},
+		{0x48616D67, w3gs.ObjectHero, "Archmage"},
+		{0x68686F75, w3gs.ObjectBuilding, "Farm"},
+		{0x526F7374, w3gs.ObjectUpgrade, "Shaman Adept Training"},
+		{0x52787878, w3gs.ObjectUpgrade, "Rxxx"},
+		{0x41486274, w3gs.ObjectAbility, "AHbt"},
+		{0x000D0003, w3gs.ObjectUnknown, "0x000D0003"},
+	}
+	for _, tc := range tests {
+		if tc.id.Type() != tc.typ || tc.id.Name() != tc.name {
+			t.Fatalf("Unexpected type or name for %v: %v %v", tc.id, tc.id.Type(), tc.id.Name())
+		}
+	}
+}
+
+func TestFourCC(t *testing.T) {
+	var code = w3gs.ParseFourCC("hpea")
+	if code != 0x68706561 {
+		t.Fatalf("Unexpected code 0x%08X", code)
+	}
+
+	// Stored in reversed byte order
+	var b, _ = w3gs.SerializeActions([]w3gs.Action{&w3gs.Ability{ItemID: w3gs.ItemID(code)}}, w3gs.Encoding{})
+	if string(b[3:7]) != "aeph" {
+		t.Fatal("Unexpected byte order", b[3:7])
+	}
+
+	if s := w3gs.FourCC(code); s != "hpea" {
+		t.Fatal("Unexpected FourCC", s)
+	}
+	if w3gs.ParseFourCC("hpeas") != 0 {
+		t.Fatal("Expected 0 for invalid length")
+	}
+	if n, ok := w3gs.ObjectName(w3gs.ParseFourCC("stwp")); !ok || n != "Scroll of Town Portal" {
+		t.Fatal("Unexpected name", n)
+	}
+	if _, ok := w3gs.ObjectName(w3gs.ParseFourCC("xxxx")); ok {
+		t.Fatal("Expected unknown object")
+	}
+}
@@ -0,0 +1,74 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3gs
+
+import (
+	"image/color"
+	"strings"
+)
+
+// ObserverColor is used for observers and color indices out of range
+var ObserverColor = color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xFF}
+
+var playerColors = [...]struct {
+	name string
+	rgb  color.RGBA
+}{
+	{"red", color.RGBA{R: 0xFF, G: 0x03, B: 0x03, A: 0xFF}},
+	{"blue", color.RGBA{R: 0x00, G: 0x42, B: 0xFF, A: 0xFF}},
+	{"teal", color.RGBA{R: 0x1C, G: 0xE6, B: 0xB9, A: 0xFF}},
+	{"purple", color.RGBA{R: 0x54, G: 0x00, B: 0x81, A: 0xFF}},
+	{"yellow", color.RGBA{R: 0xFF, G: 0xFC, B: 0x00, A: 0xFF}},
+	{"orange", color.RGBA{R: 0xFE, G: 0x8A, B: 0x0E, A: 0xFF}},
+	{"green", color.RGBA{R: 0x20, G: 0xC0, B: 0x00, A: 0xFF}},
+	{"pink", color.RGBA{R: 0xE5, G: 0x5B, B: 0xB0, A: 0xFF}},
+	{"gray", color.RGBA{R: 0x95, G: 0x96, B: 0x97, A: 0xFF}},
+	{"light blue", color.RGBA{R: 0x7E, G: 0xBF, B: 0xF1, A: 0xFF}},
+	{"dark green", color.RGBA{R: 0x10, G: 0x62, B: 0x46, A: 0xFF}},
+	{"brown", color.RGBA{R: 0x4E, G: 0x2A, B: 0x04, A: 0xFF}},
+
+	// Reforged (1.29+)
+	{"maroon", color.RGBA{R: 0x9B, G: 0x00, B: 0x00, A: 0xFF}},
+	{"navy", color.RGBA{R: 0x00, G: 0x00, B: 0xC3, A: 0xFF}},
+	{"turquoise", color.RGBA{R: 0x00, G: 0xEA, B: 0xFF, A: 0xFF}},
+	{"violet", color.RGBA{R: 0xBE, G: 0x00, B: 0xFE, A: 0xFF}},
+	{"wheat", color.RGBA{R: 0xEB, G: 0xCD, B: 0x87, A: 0xFF}},
+	{"peach", color.RGBA{R: 0xF8, G: 0xA4, B: 0x8B, A: 0xFF}},
+	{"mint", color.RGBA{R: 0xBF, G: 0xFF, B: 0x80, A: 0xFF}},
+	{"lavender", color.RGBA{R: 0xDC, G: 0xB9, B: 0xEB, A: 0xFF}},
+	{"coal", color.RGBA{R: 0x28, G: 0x28, B: 0x28, A: 0xFF}},
+	{"snow", color.RGBA{R: 0xEB, G: 0xF0, B: 0xFF, A: 0xFF}},
+	{"emerald", color.RGBA{R: 0x00, G: 0x78, B: 0x1E, A: 0xFF}},
+	{"peanut", color.RGBA{R: 0xA4, G: 0x6F, B: 0x33, A: 0xFF}},
+}
+
+// PlayerColor returns the name and RGB value of color index (SlotData.Color)
+// Indices without player color return "observer" and ObserverColor.
+func PlayerColor(index uint8) (string, color.RGBA) {
+	if int(index) >= len(playerColors) {
+		return "observer", ObserverColor
+	}
+	return playerColors[index].name, playerColors[index].rgb
+}
+
+// PlayerColor returns the name and RGB value of color index for the game version,
+// i.e. color 12 is an observer before 1.29 (see MaxPlayers)
+func (e Encoding) PlayerColor(index uint8) (string, color.RGBA) {
+	if index >= e.MaxPlayers() {
+		return "observer", ObserverColor
+	}
+	return PlayerColor(index)
+}
+
+// PlayerColorIndex returns the color index for name (case and whitespace insensitive, i.e. "LightBlue")
+func PlayerColorIndex(name string) (uint8, bool) {
+	name = strings.ToLower(strings.Join(strings.Fields(name), ""))
+	for i, c := range playerColors {
+		if strings.Replace(c.name, " ", "", -1) == name {
+			return uint8(i), true
+		}
+	}
+	return 0, false
+}
@@ -7,22 +7,28 @@ package w3gs
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/nielsAD/gowarcraft3/protocol"
 )
 
 // Errors
 var (
-	ErrNoFactory         = errors.New("w3gs: Invalid w3gs packet (empty factory)")
-	ErrNoProtocolSig     = errors.New("w3gs: Invalid w3gs packet (no signature found)")
-	ErrInvalidPacketSize = errors.New("w3gs: Invalid packet size")
-	ErrInvalidChecksum   = errors.New("w3gs: Checksum invalid")
-	ErrUnexpectedConst   = errors.New("w3gs: Unexpected constant value")
+	ErrNoFactory          = errors.New("w3gs: Invalid w3gs packet (empty factory)")
+	ErrNoProtocolSig      = errors.New("w3gs: Invalid w3gs packet (no signature found)")
+	ErrInvalidPacketSize  = errors.New("w3gs: Invalid packet size")
+	ErrInvalidChecksum    = errors.New("w3gs: Checksum invalid")
+	ErrUnexpectedConst    = errors.New("w3gs: Unexpected constant value")
+	ErrUnknownAction      = errors.New("w3gs: Unknown action")
+	ErrInvalidGameVersion = errors.New("w3gs: Invalid game version")
 )
 
 // CurrentGameVersion used by stable release
 const CurrentGameVersion uint32 = 10032
 
+// ReforgedVersionOffset is added to the patch number in the game version of Reforged clients
+const ReforgedVersionOffset = 10000
+
 // ProtocolSig is the W3GS magic number used in the packet header.
 const ProtocolSig = 0xF7
 
@@ -82,6 +88,20 @@ var (
 	ProductTFT  = protocol.DString("W3XP") // TFT
 )
 
+// ProductName returns the full name of game product p ("" if unknown)
+func ProductName(p protocol.DWordString) string {
+	switch p {
+	case ProductDemo:
+		return "Demo"
+	case ProductROC:
+		return "Reign of Chaos"
+	case ProductTFT:
+		return "The Frozen Throne"
+	default:
+		return ""
+	}
+}
+
 // SlotLayout enum
 type SlotLayout uint8
 
@@ -152,34 +172,86 @@ const (
 	RaceSelectable RacePref = 0x40
 )
 
+var raceNames = []struct {
+	race RacePref
+	name string
+}{
+	{RaceHuman, "Human"},
+	{RaceOrc, "Orc"},
+	{RaceNightElf, "Nightelf"},
+	{RaceUndead, "Undead"},
+	{RaceDemon, "Demon"},
+	{RaceRandom, "Random"},
+}
+
 func (r RacePref) String() string {
-	if r&(RaceMask|RaceSelectable) != r {
+	if r&(RaceMask|RaceSelectable) != r || r&RaceMask == 0 {
 		return fmt.Sprintf("RacePref(0x%02X)", uint8(r))
 	}
 
 	var res string
-	switch r & RaceMask {
-	case RaceHuman:
-		res = "Human"
-	case RaceOrc:
-		res = "Orc"
-	case RaceNightElf:
-		res = "Nightelf"
-	case RaceUndead:
-		res = "Undead"
-	case RaceDemon:
-		res = "Demon"
-	case RaceRandom:
-		res = "Random"
-	default:
-		return fmt.Sprintf("RacePref(0x%02X)", uint8(r))
+	for _, n := range raceNames {
+		if r&n.race != 0 {
+			res += "|" + n.name
+		}
 	}
+	res = res[1:]
+
 	if r&RaceSelectable != 0 {
 		res += "(Selectable)"
 	}
 	return res
 }
 
+// Flags returns the names of the individual races in r (including Selectable)
+func (r RacePref) Flags() []string {
+	if r == 0 {
+		return nil
+	}
+
+	var res []string
+	for _, n := range raceNames {
+		if r&n.race != 0 {
+			res = append(res, n.name)
+		}
+	}
+	if r&RaceSelectable != 0 {
+		res = append(res, "Selectable")
+	}
+	if rest := r &^ (RaceMask | RaceSelectable); rest != 0 {
+		res = append(res, fmt.Sprintf("RacePref(0x%02X)", uint8(rest)))
+	}
+	return res
+}
+
+// NewRacePref combines race with the random and selectable flags
+// Flags in race are ignored, only random and fixed determine them
+func NewRacePref(race RacePref, random bool, fixed bool) RacePref {
+	var res = race & RaceMask &^ RaceRandom
+	if random {
+		res |= RaceRandom
+	}
+	if !fixed {
+		res |= RaceSelectable
+	}
+	return res
+}
+
+// Race returns the race bits of r without the random and selectable flags (0 if no race is set)
+func (r RacePref) Race() RacePref {
+	return r & RaceMask &^ RaceRandom
+}
+
+// IsRandom returns true if the random flag is set
+func (r RacePref) IsRandom() bool {
+	return r&RaceRandom != 0
+}
+
+// IsFixed returns true if the race cannot be changed in the lobby (selectable flag not set)
+func (r RacePref) IsFixed() bool {
+	return r&RaceSelectable == 0
+}
+
 // AI difficulty enum
 type AI uint8
 
@@ -308,10 +380,23 @@ const (
 	ScopeAllies    MessageScope = 0x01
 	ScopeObservers MessageScope = 0x02
 
-	// Directed chat to player N = Scope - ChatDirected, if Scope >= ChatDirected
+	// Directed chat to the player in slot N = Scope - ScopeDirected, if Scope >= ScopeDirected
 	ScopeDirected MessageScope = 0x03
 )
 
+// ScopeToSlot returns the scope for chat directed to the player in slot (index in SlotInfo.Slots).
+func ScopeToSlot(slot uint8) MessageScope {
+	return ScopeDirected + MessageScope(slot)
+}
+
+// Slot returns the slot index of the recipient of directed chat.
+func (s MessageScope) Slot() (uint8, bool) {
+	if s < ScopeDirected {
+		return 0, false
+	}
+	return uint8(s - ScopeDirected), true
+}
+
 func (s MessageScope) String() string {
 	switch s {
 	case ScopeAll:
@@ -321,7 +406,7 @@ func (s MessageScope) String() string {
 	case ScopeObservers:
 		return "Observers"
 	default:
-		return fmt.Sprintf("ToPlayer(%d)", uint32(s-ScopeDirected))
+		return fmt.Sprintf("ToSlot(%d)", uint32(s-ScopeDirected))
 	}
 }
 
@@ -355,76 +440,89 @@ const (
 	SettingRandomRace    GameSettingFlags = 0x04000000
 )
 
-func (f GameSettingFlags) String() string {
-	var res string
-	switch f & SettingSpeedMask {
-	case SettingSpeedSlow:
-		res = "SpeedSlow"
-	case SettingSpeedNormal:
-		res = "SpeedNormal"
-	case SettingSpeedFast:
-		res = "SpeedFast"
-	default:
-		return fmt.Sprintf("GameSettingFlags(0x%07X)", uint32(f))
-	}
+var settingNames = []struct {
+	mask GameSettingFlags
+	flag GameSettingFlags
+	name string
+}{
+	{SettingSpeedMask, SettingSpeedSlow, "SpeedSlow"},
+	{SettingSpeedMask, SettingSpeedNormal, "SpeedNormal"},
+	{SettingSpeedMask, SettingSpeedFast, "SpeedFast"},
+	{SettingTerrainMask, SettingTerrainHidden, "TerrainHidden"},
+	{SettingTerrainMask, SettingTerrainExplored, "TerrainExplored"},
+	{SettingTerrainMask, SettingTerrainVisible, "TerrainVisible"},
+	{SettingTerrainMask, SettingTerrainDefault, "TerrainDefault"},
+	{SettingObsMask, SettingObsNone, "ObsNone"},
+	{SettingObsMask, SettingObsEnabled, "ObsEnabled"},
+	{SettingObsMask, SettingObsOnDefeat, "ObsOnDefeat"},
+	{SettingObsMask, SettingObsFull, "ObsFull"},
+	{SettingObsMask, SettingObsReferees, "ObsReferees"},
+	{SettingObsMask, SettingObsReferees | SettingObsEnabled, "ObsReferees"},
+}
 
-	switch f & SettingTerrainMask {
-	case SettingTerrainHidden:
-		res += "|TerrainHidden"
-	case SettingTerrainExplored:
-		res += "|TerrainExplored"
-	case SettingTerrainVisible:
-		res += "|TerrainVisible"
-	case SettingTerrainDefault:
-		res += "|TerrainDefault"
-	case 0:
-		// No terrain setting
-	default:
-		return fmt.Sprintf("GameSettingFlags(0x%07X)", uint32(f))
+var settingBits = []struct {
+	flag GameSettingFlags
+	name string
+}{
+	{SettingTeamsTogether, "TeamsTogether"},
+	{SettingTeamsFixed, "TeamsFixed"},
+	{SettingSharedControl, "SharedControl"},
+	{SettingRandomHero, "RandomHero"},
+	{SettingRandomRace, "RandomRace"},
+}
+
+// names returns the names of the individual settings in f, or nil if speed, terrain, or observers are invalid
+func (f GameSettingFlags) names() []string {
+	var res []string
+	for _, m := range []GameSettingFlags{SettingSpeedMask, SettingTerrainMask, SettingObsMask} {
+		var v = f & m
+		if m == SettingTerrainMask && v == 0 {
+			// No terrain setting
+			continue
+		}
+
+		var found = false
+		for _, n := range settingNames {
+			if n.mask == m && n.flag == v {
+				res = append(res, n.name)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+
+	f &^= SettingSpeedMask | SettingTerrainMask | SettingObsMask
+	for _, b := range settingBits {
+		if f&b.flag != 0 {
+			res = append(res, b.name)
+			f &^= b.flag
+		}
 	}
 
-	switch f & SettingObsMask {
-	case SettingObsNone:
-		res += "|ObsNone"
-	case SettingObsEnabled:
-		res += "|ObsEnabled"
-	case SettingObsOnDefeat:
-		res += "|ObsOnDefeat"
-	case SettingObsFull:
-		res += "|ObsFull"
-	case SettingObsReferees, SettingObsReferees | SettingObsEnabled:
-		res += "|ObsReferees"
-	default:
-		return fmt.Sprintf("GameSettingFlags(0x%07X)", uint32(f))
+	if f != 0 {
+		res = append(res, fmt.Sprintf("GameSettingFlags(0x%02X)", uint32(f)))
 	}
 
-	f &= ^(SettingSpeedMask | SettingTerrainMask | SettingObsMask)
+	return res
+}
 
-	if f&SettingTeamsTogether != 0 {
-		res += "|TeamsTogether"
-		f &= ^SettingTeamsTogether
-	}
-	if f&SettingTeamsFixed != 0 {
-		res += "|TeamsFixed"
-		f &= ^SettingTeamsFixed
-	}
-	if f&SettingSharedControl != 0 {
-		res += "|SharedControl"
-		f &= ^SettingSharedControl
-	}
-	if f&SettingRandomHero != 0 {
-		res += "|RandomHero"
-		f &= ^SettingRandomHero
-	}
-	if f&SettingRandomRace != 0 {
-		res += "|RandomRace"
-		f &= ^SettingRandomRace
+func (f GameSettingFlags) String() string {
+	var res = f.names()
+	if res == nil {
+		return fmt.Sprintf("GameSettingFlags(0x%07X)", uint32(f))
 	}
+	return strings.Join(res, "|")
+}
 
-	if f != 0 {
-		res += fmt.Sprintf("|GameSettingFlags(0x%02X)", uint32(f))
+// Flags returns the names of the individual settings in f (see String)
+func (f GameSettingFlags) Flags() []string {
+	var res = f.names()
+	if res == nil {
+		return []string{f.String()}
 	}
-
 	return res
 }
 
@@ -569,6 +667,65 @@ func (f GameFlags) String() string {
 	return res
 }
 
+// Flags returns the names of the individual flags in f
+func (f GameFlags) Flags() []string {
+	if f == 0 {
+		return nil
+	}
+	return strings.Split(f.String(), "|")
+}
+
+// Type returns the game type of f (i.e. GameFlagCustomGame or GameFlagLadder1v1)
+func (f GameFlags) Type() GameFlags {
+	return f & GameFlagTypeMask
+}
+
+// Groups of mutually exclusive game flags
+var gameFlagGroups = []GameFlags{
+	GameFlagTypeMask,
+	GameFlagCreatorMask,
+	GameFlagMapTypeMask,
+	GameFlagSizeMask,
+	GameFlagObsMask,
+}
+
+// Has returns true if all flags in flag are set in f
+// Grouped flags (i.e. game type, see Type) must match exactly
+func (f GameFlags) Has(flag GameFlags) bool {
+	for _, m := range gameFlagGroups {
+		if g := flag & m; g != 0 {
+			if f&m != g {
+				return false
+			}
+			flag &^= m
+		}
+	}
+	return f&flag == flag
+}
+
+// With returns f with flag set, replacing the grouped flags (i.e. game type) that flag contains
+func (f GameFlags) With(flag GameFlags) GameFlags {
+	for _, m := range gameFlagGroups {
+		if flag&m != 0 {
+			f &^= m
+		}
+	}
+	return f | flag
+}
+
+// Without returns f with flag cleared, clearing grouped flags (i.e. game type) only if they match exactly
+func (f GameFlags) Without(flag GameFlags) GameFlags {
+	for _, m := range gameFlagGroups {
+		if g := flag & m; g != 0 {
+			if f&m == g {
+				f &^= m
+			}
+			flag &^= m
+		}
+	}
+	return f &^ flag
+}
+
 // PlayerExtraType enum
 type PlayerExtraType uint8
 
@@ -582,10 +739,14 @@ const (
 
 func (t PlayerExtraType) String() string {
 	switch t {
+	case PlayerExtra2:
+		return "Extra2"
 	case PlayerProfile:
 		return "BNetProfile"
 	case PlayerSkins:
 		return "Skins"
+	case PlayerExtra5:
+		return "Extra5"
 	default:
 		return fmt.Sprintf("PlayerExtraType(0x%02X)", uint8(t))
 	}
@@ -616,3 +777,93 @@ func (r ProfileRealm) String() string {
 		return fmt.Sprintf("ProfileRealm(%d)", r)
 	}
 }
+
+// Locale identifiers (Windows LCID) of the languages supported by Warcraft III
+const (
+	LocaleCzech              uint32 = 0x0405 // csCZ
+	LocaleGerman             uint32 = 0x0407 // deDE
+	LocaleEnglishUS          uint32 = 0x0409 // enUS
+	LocaleEnglishUK          uint32 = 0x0809 // enGB
+	LocaleSpanish            uint32 = 0x040A // esES
+	LocaleSpanishMexico      uint32 = 0x080A // esMX
+	LocaleFrench             uint32 = 0x040C // frFR
+	LocaleItalian            uint32 = 0x0410 // itIT
+	LocaleKorean             uint32 = 0x0412 // koKR
+	LocalePolish             uint32 = 0x0415 // plPL
+	LocalePortugueseBrazil   uint32 = 0x0416 // ptBR
+	LocaleRussian            uint32 = 0x0419 // ruRU
+	LocaleChineseSimplified  uint32 = 0x0804 // zhCN
+	LocaleChineseTraditional uint32 = 0x0404 // zhTW
+)
+
+// LocaleCode returns the Warcraft III locale code (i.e. "enUS") for a locale identifier, or "" if unknown
+func LocaleCode(id uint32) string {
+	switch id {
+	case LocaleCzech:
+		return "csCZ"
+	case LocaleGerman:
+		return "deDE"
+	case LocaleEnglishUS:
+		return "enUS"
+	case LocaleEnglishUK:
+		return "enGB"
+	case LocaleSpanish:
+		return "esES"
+	case LocaleSpanishMexico:
+		return "esMX"
+	case LocaleFrench:
+		return "frFR"
+	case LocaleItalian:
+		return "itIT"
+	case LocaleKorean:
+		return "koKR"
+	case LocalePolish:
+		return "plPL"
+	case LocalePortugueseBrazil:
+		return "ptBR"
+	case LocaleRussian:
+		return "ruRU"
+	case LocaleChineseSimplified:
+		return "zhCN"
+	case LocaleChineseTraditional:
+		return "zhTW"
+	default:
+		return ""
+	}
+}
+
+// LanguageName returns a readable name (i.e. "English (US)") for a locale identifier
+func LanguageName(id uint32) string {
+	switch id {
+	case LocaleCzech:
+		return "Czech"
+	case LocaleGerman:
+		return "German"
+	case LocaleEnglishUS:
+		return "English (US)"
+	case LocaleEnglishUK:
+		return "English (UK)"
+	case LocaleSpanish:
+		return "Spanish"
+	case LocaleSpanishMexico:
+		return "Spanish (Mexico)"
+	case LocaleFrench:
+		return "French"
+	case LocaleItalian:
+		return "Italian"
+	case LocaleKorean:
+		return "Korean"
+	case LocalePolish:
+		return "Polish"
+	case LocalePortugueseBrazil:
+		return "Portuguese (Brazil)"
+	case LocaleRussian:
+		return "Russian"
+	case LocaleChineseSimplified:
+		return "Chinese (Simplified)"
+	case LocaleChineseTraditional:
+		return "Chinese (Traditional)"
+	default:
+		return fmt.Sprintf("Language(0x%04X)", id)
+	}
+}
@@ -0,0 +1,295 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3gs_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+func TestConstString(t *testing.T) {
+	var names = []struct {
+		val  fmt.Stringer
+		name string
+	}{
+		{w3gs.LayoutMelee, "Melee"},
+		{w3gs.LayoutCustomForces, "CustomForces"},
+		{w3gs.LayoutFixedPlayerSettings, "Melee(Fixed)"},
+		{w3gs.LayoutCustomForces | w3gs.LayoutFixedPlayerSettings, "CustomForces(Fixed)"},
+		{w3gs.LayoutLadder, "Melee(Ladder)"},
+		{w3gs.SlotLayout(0x10), "SlotLayout(0x10)"},
+
+		{w3gs.SlotOpen, "Open"},
+		{w3gs.SlotClosed, "Closed"},
+		{w3gs.SlotOccupied, "Occupied"},
+		{w3gs.SlotStatus(0x03), "SlotStatus(0x03)"},
+
+		{w3gs.RaceHuman, "Human"},
+		{w3gs.RaceOrc, "Orc"},
+		{w3gs.RaceNightElf, "Nightelf"},
+		{w3gs.RaceUndead, "Undead"},
+		{w3gs.RaceDemon, "Demon"},
+		{w3gs.RaceRandom, "Random"},
+		{w3gs.RaceRandom | w3gs.RaceSelectable, "Random(Selectable)"},
+		{w3gs.RaceHuman | w3gs.RaceUndead, "Human|Undead"},
+		{w3gs.RaceSelectable, "RacePref(0x40)"},
+		{w3gs.RacePref(0x80), "RacePref(0x80)"},
+
+		{w3gs.ComputerEasy, "Easy"},
+		{w3gs.ComputerNormal, "Normal"},
+		{w3gs.ComputerInsane, "Insane"},
+		{w3gs.AI(0x03), "AI(0x03)"},
+
+		{w3gs.RejectJoinInvalid, "JoinInvalid"},
+		{w3gs.RejectJoinFull, "GameFull"},
+		{w3gs.RejectJoinStarted, "GameStarted"},
+		{w3gs.RejectJoinWrongKey, "WrongKey"},
+		{w3gs.RejectReason(0x01), "RejectReason(0x01)"},
+
+		{w3gs.LeaveDisconnect, "Disconnect"},
+		{w3gs.LeaveLost, "Lost"},
+		{w3gs.LeaveLostBuildings, "LostBuildings"},
+		{w3gs.LeaveWon, "Won"},
+		{w3gs.LeaveDraw, "Draw"},
+		{w3gs.LeaveObserver, "Observer"},
+		{w3gs.LeaveInvalidSaveGame, "InvalidSaveGame"},
+		{w3gs.LeaveLobby, "Lobby"},
+		{w3gs.LeaveReason(0x02), "LeaveReason(0x02)"},
+
+		{w3gs.MsgChat, "Chat"},
+		{w3gs.MsgTeamChange, "TeamChange"},
+		{w3gs.MsgColorChange, "ColorChange"},
+		{w3gs.MsgRaceChange, "RaceChange"},
+		{w3gs.MsgHandicapChange, "HandicapChange"},
+		{w3gs.MsgChatExtra, "ChatExtra"},
+		{w3gs.MessageType(0x15), "MessageType(0x15)"},
+
+		{w3gs.ScopeAll, "All"},
+		{w3gs.ScopeAllies, "Allies"},
+		{w3gs.ScopeObservers, "Observers"},
+		{w3gs.ScopeToSlot(2), "ToSlot(2)"},
+
+		{w3gs.SettingSpeedFast | w3gs.SettingTerrainDefault | w3gs.SettingObsNone | w3gs.SettingTeamsTogether | w3gs.SettingTeamsFixed, "SpeedFast|TerrainDefault|ObsNone|TeamsTogether|TeamsFixed"},
+		{w3gs.SettingSpeedSlow | w3gs.SettingTerrainHidden | w3gs.SettingObsEnabled | w3gs.SettingSharedControl, "SpeedSlow|TerrainHidden|ObsEnabled|SharedControl"},
+		{w3gs.SettingSpeedNormal | w3gs.SettingTerrainExplored | w3gs.SettingObsOnDefeat | w3gs.SettingRandomHero, "SpeedNormal|TerrainExplored|ObsOnDefeat|RandomHero"},
+		{w3gs.SettingTerrainVisible | w3gs.SettingObsFull | w3gs.SettingRandomRace, "SpeedSlow|TerrainVisible|ObsFull|RandomRace"},
+		{w3gs.SettingObsReferees, "SpeedSlow|ObsReferees"},
+		{w3gs.GameSettingFlags(0x03), "GameSettingFlags(0x0000003)"},
+
+		{w3gs.GameFlagCustomGame, "Custom"},
+		{w3gs.GameFlagSinglePlayer, "SinglePlayer"},
+		{w3gs.GameFlagLadder1v1, "Ladder1v1"},
+		{w3gs.GameFlagLadder2v2, "Ladder2v2"},
+		{w3gs.GameFlagLadder3v3, "Ladder3v3"},
+		{w3gs.GameFlagLadder4v4, "Ladder4v4"},
+		{w3gs.GameFlagSavedGame, "SavedGame"},
+		{w3gs.GameFlagCustomGame | w3gs.GameFlagSignedMap | w3gs.GameFlagPrivateGame, "Custom|SignedMap|Private"},
+		{w3gs.GameFlagCreatorUser | w3gs.GameFlagSizeSmall | w3gs.GameFlagMapTypeMelee | w3gs.GameFlagObsFull, "CreatorUser|SizeSmall|MapTypeMelee|ObsFull"},
+		{w3gs.GameFlagCreatorBlizzard | w3gs.GameFlagSizeMedium | w3gs.GameFlagMapTypeScenario | w3gs.GameFlagObsOnDefeat, "CreatorBlizzard|SizeMedium|MapTypeScenario|ObsOnDefeat"},
+		{w3gs.GameFlagCreatorMask | w3gs.GameFlagSizeMask | w3gs.GameFlagMapTypeMask | w3gs.GameFlagObsMask, "CreatorAny|SizeAny|MapTypeAny|ObsAny"},
+		{w3gs.GameFlagSizeLarge | w3gs.GameFlagObsNone, "SizeLarge|ObsNone"},
+		{w3gs.GameFlags(0x02), "GameFlags(0x02)"},
+		{w3gs.GameFlags(0x06), "GameFlags(0x000006)"},
+
+		{w3gs.PlayerExtra2, "Extra2"},
+		{w3gs.PlayerProfile, "BNetProfile"},
+		{w3gs.PlayerSkins, "Skins"},
+		{w3gs.PlayerExtra5, "Extra5"},
+		{w3gs.PlayerExtraType(0x01), "PlayerExtraType(0x01)"},
+
+		{w3gs.RealmOffline, "Offline"},
+		{w3gs.RealmAmericas, "Americas"},
+		{w3gs.RealmEurope, "Europe"},
+		{w3gs.RealmAsia, "Asia"},
+		{w3gs.ProfileRealm(1), "ProfileRealm(1)"},
+	}
+
+	for _, n := range names {
+		if n.val.String() != n.name {
+			t.Fatalf("%T(%v).String() == %q, expected %q", n.val, n.val, n.val.String(), n.name)
+		}
+		if s := fmt.Sprintf("%v", n.val); s != n.name {
+			t.Fatalf("%%v of %T == %q, expected %q", n.val, s, n.name)
+		}
+	}
+}
+
+func TestConstFlags(t *testing.T) {
+	if f := (w3gs.RaceHuman | w3gs.RaceOrc).Flags(); !reflect.DeepEqual(f, []string{"Human", "Orc"}) {
+		t.Fatalf("Unexpected RacePref.Flags() %v", f)
+	}
+	if f := (w3gs.RaceUndead | w3gs.RaceSelectable | 0x80).Flags(); !reflect.DeepEqual(f, []string{"Undead", "Selectable", "RacePref(0x80)"}) {
+		t.Fatalf("Unexpected RacePref.Flags() %v", f)
+	}
+	if f := w3gs.RacePref(0).Flags(); f != nil {
+		t.Fatalf("Unexpected RacePref.Flags() %v", f)
+	}
+	if f := (w3gs.SettingSpeedFast | w3gs.SettingTeamsFixed).Flags(); !reflect.DeepEqual(f, []string{"SpeedFast", "ObsNone", "TeamsFixed"}) {
+		t.Fatalf("Unexpected GameSettingFlags.Flags() %v", f)
+	}
+	if f := (w3gs.SettingSpeedNormal | w3gs.SettingObsFull | 0x80).Flags(); !reflect.DeepEqual(f, []string{"SpeedNormal", "ObsFull", "GameSettingFlags(0x80)"}) {
+		t.Fatalf("Unexpected GameSettingFlags.Flags() %v", f)
+	}
+	for _, s := range []w3gs.GameSettingFlags{
+		0,
+		0x20000,
+		w3gs.SettingSpeedFast | w3gs.SettingTerrainDefault | w3gs.SettingObsReferees | w3gs.SettingObsEnabled | w3gs.SettingTeamsFixed,
+		w3gs.SettingSpeedNormal | w3gs.SettingTerrainVisible | w3gs.SettingObsFull | w3gs.SettingRandomRace | 0x80,
+		w3gs.SettingSpeedMask,
+		w3gs.SettingTerrainMask,
+	} {
+		if f := strings.Join(s.Flags(), "|"); f != s.String() {
+			t.Fatalf("GameSettingFlags.Flags() %v does not match String() %v", f, s.String())
+		}
+	}
+	if f := (w3gs.GameFlagCustomGame | w3gs.GameFlagSignedMap).Flags(); !reflect.DeepEqual(f, []string{"Custom", "SignedMap"}) {
+		t.Fatalf("Unexpected GameFlags.Flags() %v", f)
+	}
+	if f := w3gs.GameFlags(0).Flags(); len(f) != 0 {
+		t.Fatalf("Unexpected GameFlags.Flags() %v", f)
+	}
+}
+
+func TestGameFlags(t *testing.T) {
+	var flags = []struct {
+		flag w3gs.GameFlags
+		name string
+	}{
+		{w3gs.GameFlagCustomGame, "Custom"},
+		{w3gs.GameFlagSinglePlayer, "SinglePlayer"},
+		{w3gs.GameFlagLadder1v1, "Ladder1v1"},
+		{w3gs.GameFlagLadder2v2, "Ladder2v2"},
+		{w3gs.GameFlagLadder3v3, "Ladder3v3"},
+		{w3gs.GameFlagLadder4v4, "Ladder4v4"},
+		{w3gs.GameFlagSavedGame, "SavedGame"},
+		{w3gs.GameFlagSignedMap, "SignedMap"},
+		{w3gs.GameFlagPrivateGame, "Private"},
+		{w3gs.GameFlagCreatorUser, "CreatorUser"},
+		{w3gs.GameFlagCreatorBlizzard, "CreatorBlizzard"},
+		{w3gs.GameFlagMapTypeMelee, "MapTypeMelee"},
+		{w3gs.GameFlagMapTypeScenario, "MapTypeScenario"},
+		{w3gs.GameFlagSizeSmall, "SizeSmall"},
+		{w3gs.GameFlagSizeMedium, "SizeMedium"},
+		{w3gs.GameFlagSizeLarge, "SizeLarge"},
+		{w3gs.GameFlagObsFull, "ObsFull"},
+		{w3gs.GameFlagObsOnDefeat, "ObsOnDefeat"},
+		{w3gs.GameFlagObsNone, "ObsNone"},
+	}
+
+	for _, tc := range flags {
+		var f = w3gs.GameFlags(0).With(tc.flag)
+		if !f.Has(tc.flag) || f.String() != tc.name {
+			t.Fatalf("Unexpected flags for %s: %v", tc.name, f)
+		}
+		if f := f.Without(tc.flag); f != 0 || f.Has(tc.flag) {
+			t.Fatalf("Unexpected flags after Without(%s): %v", tc.name, f)
+		}
+	}
+
+	var f = w3gs.GameFlagSinglePlayer.With(w3gs.GameFlagSignedMap).With(w3gs.GameFlagMapTypeMelee)
+	if f.Type() != w3gs.GameFlagSinglePlayer || f.Has(w3gs.GameFlagCustomGame) || !f.Has(w3gs.GameFlagSignedMap|w3gs.GameFlagMapTypeMelee) {
+		t.Fatalf("Unexpected flags %v", f)
+	}
+	if f = f.With(w3gs.GameFlagLadder1v1); f.String() != "Ladder1v1|SignedMap|MapTypeMelee" {
+		t.Fatalf("Expected game type to be replaced: %v", f)
+	}
+	if f = f.Without(w3gs.GameFlagCustomGame); f.Type() != w3gs.GameFlagLadder1v1 {
+		t.Fatalf("Expected game type to be kept: %v", f)
+	}
+	if f = f.Without(w3gs.GameFlagLadder1v1 | w3gs.GameFlagSignedMap); f != w3gs.GameFlagMapTypeMelee {
+		t.Fatalf("Unexpected flags %v", f)
+	}
+
+	f = w3gs.GameFlagCustomGame | w3gs.GameFlagCreatorUser | w3gs.GameFlagMapTypeMelee | w3gs.GameFlagSizeSmall | w3gs.GameFlagObsFull
+	f = f.With(w3gs.GameFlagCreatorBlizzard | w3gs.GameFlagMapTypeScenario | w3gs.GameFlagSizeLarge | w3gs.GameFlagObsNone)
+	if f.String() != "Custom|CreatorBlizzard|SizeLarge|MapTypeScenario|ObsNone" {
+		t.Fatalf("Expected grouped flags to be replaced: %v", f)
+	}
+	if f.Has(w3gs.GameFlagSizeLarge|w3gs.GameFlagSizeSmall) || !f.Has(w3gs.GameFlagSizeLarge|w3gs.GameFlagObsNone) {
+		t.Fatalf("Unexpected flags %v", f)
+	}
+	if f = f.Without(w3gs.GameFlagSizeSmall | w3gs.GameFlagObsNone); f.String() != "Custom|CreatorBlizzard|SizeLarge|MapTypeScenario" {
+		t.Fatalf("Expected only matching groups to be cleared: %v", f)
+	}
+}
+
+func TestLocale(t *testing.T) {
+	if s := w3gs.LocaleCode(w3gs.LocaleEnglishUS); s != "enUS" {
+		t.Fatal("Unexpected locale code", s)
+	}
+	if s := w3gs.LanguageName(1031); s != "German" {
+		t.Fatal("Unexpected language name", s)
+	}
+	if s := w3gs.LocaleCode(0); s != "" {
+		t.Fatal("Expected empty locale code", s)
+	}
+	if s := w3gs.LanguageName(0x1234); s != "Language(0x1234)" {
+		t.Fatal("Unexpected language name", s)
+	}
+}
+
+func TestPlayerColor(t *testing.T) {
+	for i := 0; i < 24; i++ {
+		name, rgb := w3gs.PlayerColor(uint8(i))
+		if name == "observer" || rgb.A != 0xFF {
+			t.Fatal("Unexpected color", i, name, rgb)
+		}
+		if idx, ok := w3gs.PlayerColorIndex(name); !ok || idx != uint8(i) {
+			t.Fatal("PlayerColorIndex mismatch", name, idx, i)
+		}
+	}
+
+	if idx, ok := w3gs.PlayerColorIndex("LightBlue"); !ok || idx != 9 {
+		t.Fatal("Expected light blue", idx)
+	}
+	if _, ok := w3gs.PlayerColorIndex("observer"); ok {
+		t.Fatal("Expected no index for observer")
+	}
+	if name, rgb := w3gs.PlayerColor(255); name != "observer" || rgb != w3gs.ObserverColor {
+		t.Fatal("Expected observer for out of range index", name)
+	}
+
+	var old = w3gs.Encoding{GameVersion: 26}
+	if name, _ := old.PlayerColor(12); name != "observer" {
+		t.Fatal("Expected observer for color 12 before 1.29", name)
+	}
+	var rf = w3gs.Encoding{GameVersion: 31}
+	if name, _ := rf.PlayerColor(12); name != "maroon" {
+		t.Fatal("Expected maroon for color 12 since 1.29", name)
+	}
+	if name, _ := rf.PlayerColor(24); name != "observer" {
+		t.Fatal("Expected observer for color 24 since 1.29", name)
+	}
+}
+
+func TestRacePref(t *testing.T) {
+	for _, race := range []w3gs.RacePref{0, w3gs.RaceHuman, w3gs.RaceOrc, w3gs.RaceNightElf, w3gs.RaceUndead, w3gs.RaceDemon} {
+		for _, random := range []bool{false, true} {
+			for _, fixed := range []bool{false, true} {
+				var r = w3gs.NewRacePref(race, random, fixed)
+				if r.Race() != race || r.IsRandom() != random || r.IsFixed() != fixed {
+					t.Fatalf("RacePref mismatch for %v (random: %v, fixed: %v): %v", race, random, fixed, r)
+				}
+			}
+		}
+	}
+
+	if r := w3gs.NewRacePref(w3gs.RaceOrc, true, false); r != w3gs.RaceOrc|w3gs.RaceRandom|w3gs.RaceSelectable || r.String() != "Orc|Random(Selectable)" {
+		t.Fatal("Unexpected RacePref", r)
+	}
+	if r := w3gs.NewRacePref(w3gs.RaceHuman|w3gs.RaceRandom|w3gs.RaceSelectable|0x80, false, true); r != w3gs.RaceHuman || r.IsRandom() || r.Race() != w3gs.RaceHuman {
+		t.Fatal("Expected input flags to be masked", r)
+	}
+	if r := w3gs.NewRacePref(w3gs.RaceRandom, true, true); r != w3gs.RaceRandom || !r.IsRandom() || r.Race() != 0 {
+		t.Fatal("Expected random race", r)
+	}
+	if r := w3gs.RaceUndead | w3gs.RaceSelectable; r == w3gs.RaceUndead || r.Race() != w3gs.RaceUndead {
+		t.Fatal("Expected Race() to ignore selectable flag", r)
+	}
+}
@@ -4,6 +4,11 @@
 
 package w3gs
 
+import (
+	"fmt"
+	"reflect"
+)
+
 // PacketFactory returns a struct of the appropiate type for a packet ID
 type PacketFactory interface {
 	NewPacket(pid uint8, enc *Encoding) Packet
@@ -13,6 +18,7 @@ type PacketFactory interface {
 type FactoryFunc func(enc *Encoding) Packet
 
 // MapFactory implements PacketFactory using a map
+// It is safe for concurrent use as long as the map is not modified.
 type MapFactory map[uint8]FactoryFunc
 
 // NewPacket implements PacketFactory interface
@@ -24,12 +30,36 @@ func (f MapFactory) NewPacket(pid uint8, enc *Encoding) Packet {
 	return fun(enc)
 }
 
+// Types returns the (dereferenced) packet type for each ID in f
+func (f MapFactory) Types(enc *Encoding) map[uint8]reflect.Type {
+	var res = make(map[uint8]reflect.Type, len(f))
+	for pid, fun := range f {
+		res[pid] = reflect.TypeOf(fun(enc)).Elem()
+	}
+	return res
+}
+
+// KnownPackets returns the packet type for each ID in DefaultFactory
+func KnownPackets() map[uint8]reflect.Type {
+	return DefaultFactory.Types(&Encoding{})
+}
+
+// PacketName returns the name of the packet type for pid in DefaultFactory (hexadecimal ID if unknown)
+func PacketName(pid uint8) string {
+	fun, ok := DefaultFactory[pid]
+	if !ok {
+		return fmt.Sprintf("0x%02X", pid)
+	}
+	return reflect.TypeOf(fun(&Encoding{})).Elem().Name()
+}
+
 type cacheKey struct {
 	enc Encoding
 	pid uint8
 }
 
 // CacheFactory implements a PacketFactory that will only create a type once
+// It is not safe for concurrent use, so create one per decoder.
 type CacheFactory struct {
 	factory PacketFactory
 	cache   map[cacheKey]Packet
@@ -0,0 +1,31 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+//go:build go1.18
+// +build go1.18
+
+package w3gs_test
+
+import (
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/protocol/w3gs"
+)
+
+func FuzzW3GSPacket(f *testing.F) {
+	var versions = []uint32{0, 22, 26, 29, 32}
+	for _, gv := range versions {
+		for _, pkt := range packets {
+			if b, err := w3gs.Serialize(pkt, w3gs.Encoding{GameVersion: gv}); err == nil {
+				f.Add(b)
+			}
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		for _, gv := range versions {
+			w3gs.Deserialize(b, w3gs.Encoding{GameVersion: gv})
+		}
+	})
+}
@@ -0,0 +1,326 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3gs
+
+import "fmt"
+
+// ObjectType enum
+type ObjectType uint8
+
+// Object types
+const (
+	ObjectUnknown ObjectType = iota
+	ObjectUnit
+	ObjectHero
+	ObjectBuilding
+	ObjectUpgrade
+	ObjectAbility
+	ObjectItem
+)
+
+func (t ObjectType) String() string {
+	switch t {
+	case ObjectUnknown:
+		return "Unknown"
+	case ObjectUnit:
+		return "Unit"
+	case ObjectHero:
+		return "Hero"
+	case ObjectBuilding:
+		return "Building"
+	case ObjectUpgrade:
+		return "Upgrade"
+	case ObjectAbility:
+		return "Ability"
+	case ObjectItem:
+		return "Item"
+	default:
+		return fmt.Sprintf("ObjectType(%d)", uint8(t))
+	}
+}
+
+type objectInfo struct {
+	Type ObjectType
+	Name string
+}
+
+// FourCC converts a four character code to its string representation.
+// Codes are stored in reversed byte order, i.e. "hpea" is stored as "aeph" (0x68706561).
+func FourCC(code uint32) string {
+	return string([]byte{byte(code >> 24), byte(code >> 16), byte(code >> 8), byte(code)})
+}
+
+// ParseFourCC is the inverse of FourCC, returns 0 if len(s) != 4
+func ParseFourCC(s string) uint32 {
+	if len(s) != 4 {
+		return 0
+	}
+	return uint32(s[0])<<24 | uint32(s[1])<<16 | uint32(s[2])<<8 | uint32(s[3])
+}
+
+func fourcc(s string) ItemID {
+	return ItemID(ParseFourCC(s))
+}
+
+// Base game (melee) objects
+var objects = map[ItemID]objectInfo{
+	// Human
+	fourcc("hpea"): {ObjectUnit, "Peasant"},
+	fourcc("hfoo"): {ObjectUnit, "Footman"},
+	fourcc("hkni"): {ObjectUnit, "Knight"},
+	fourcc("hrif"): {ObjectUnit, "Rifleman"},
+	fourcc("hmtm"): {ObjectUnit, "Mortar Team"},
+	fourcc("hgyr"): {ObjectUnit, "Flying Machine"},
+	fourcc("hgry"): {ObjectUnit, "Gryphon Rider"},
+	fourcc("hmpr"): {ObjectUnit, "Priest"},
+	fourcc("hsor"): {ObjectUnit, "Sorceress"},
+	fourcc("hspt"): {ObjectUnit, "Spell Breaker"},
+	fourcc("hdhw"): {ObjectUnit, "Dragonhawk Rider"},
+	fourcc("hmtt"): {ObjectUnit, "Siege Engine"},
+	fourcc("Hamg"): {ObjectHero, "Archmage"},
+	fourcc("Hmkg"): {ObjectHero, "Mountain King"},
+	fourcc("Hpal"): {ObjectHero, "Paladin"},
+	fourcc("Hblm"): {ObjectHero, "Blood Mage"},
+	fourcc("htow"): {ObjectBuilding, "Town Hall"},
+	fourcc("hkee"): {ObjectBuilding, "Keep"},
+	fourcc("hcas"): {ObjectBuilding, "Castle"},
+	fourcc("hhou"): {ObjectBuilding, "Farm"},
+	fourcc("halt"): {ObjectBuilding, "Altar of Kings"},
+	fourcc("hbar"): {ObjectBuilding, "Barracks"},
+	fourcc("hlum"): {ObjectBuilding, "Lumber Mill"},
+	fourcc("hbla"): {ObjectBuilding, "Blacksmith"},
+	fourcc("harm"): {ObjectBuilding, "Workshop"},
+	fourcc("hars"): {ObjectBuilding, "Arcane Sanctum"},
+	fourcc("hgra"): {ObjectBuilding, "Gryphon Aviary"},
+	fourcc("hvlt"): {ObjectBuilding, "Arcane Vault"},
+	fourcc("hwtw"): {ObjectBuilding, "Scout Tower"},
+	fourcc("hgtw"): {ObjectBuilding, "Guard Tower"},
+	fourcc("hctw"): {ObjectBuilding, "Cannon Tower"},
+	fourcc("hatw"): {ObjectBuilding, "Arcane Tower"},
+
+	// Orc
+	fourcc("opeo"): {ObjectUnit, "Peon"},
+	fourcc("ogru"): {ObjectUnit, "Grunt"},
+	fourcc("ohun"): {ObjectUnit, "Troll Headhunter"},
+	fourcc("ocat"): {ObjectUnit, "Demolisher"},
+	fourcc("oshm"): {ObjectUnit, "Shaman"},
+	fourcc("odoc"): {ObjectUnit, "Troll Witch Doctor"},
+	fourcc("ospw"): {ObjectUnit, "Spirit Walker"},
+	fourcc("orai"): {ObjectUnit, "Raider"},
+	fourcc("okod"): {ObjectUnit, "Kodo Beast"},
+	fourcc("owyv"): {ObjectUnit, "Wind Rider"},
+	fourcc("otbr"): {ObjectUnit, "Troll Batrider"},
+	fourcc("otau"): {ObjectUnit, "Tauren"},
+	fourcc("Obla"): {ObjectHero, "Blademaster"},
+	fourcc("Ofar"): {ObjectHero, "Far Seer"},
+	fourcc("Otch"): {ObjectHero, "Tauren Chieftain"},
+	fourcc("Oshd"): {ObjectHero, "Shadow Hunter"},
+	fourcc("ogre"): {ObjectBuilding, "Great Hall"},
+	fourcc("ostr"): {ObjectBuilding, "Stronghold"},
+	fourcc("ofrt"): {ObjectBuilding, "Fortress"},
+	fourcc("otrb"): {ObjectBuilding, "Orc Burrow"},
+	fourcc("oalt"): {ObjectBuilding, "Altar of Storms"},
+	fourcc("obar"): {ObjectBuilding, "Barracks"},
+	fourcc("ofor"): {ObjectBuilding, "War Mill"},
+	fourcc("osld"): {ObjectBuilding, "Spirit Lodge"},
+	fourcc("obea"): {ObjectBuilding, "Beastiary"},
+	fourcc("otto"): {ObjectBuilding, "Tauren Totem"},
+	fourcc("ovln"): {ObjectBuilding, "Voodoo Lounge"},
+	fourcc("owtw"): {ObjectBuilding, "Watch Tower"},
+
+	// Night Elf
+	fourcc("ewsp"): {ObjectUnit, "Wisp"},
+	fourcc("earc"): {ObjectUnit, "Archer"},
+	fourcc("esen"): {ObjectUnit, "Huntress"},
+	fourcc("ebal"): {ObjectUnit, "Glaive Thrower"},
+	fourcc("edry"): {ObjectUnit, "Dryad"},
+	fourcc("edoc"): {ObjectUnit, "Druid of the Claw"},
+	fourcc("edot"): {ObjectUnit, "Druid of the Talon"},
+	fourcc("emtg"): {ObjectUnit, "Mountain Giant"},
+	fourcc("ehip"): {ObjectUnit, "Hippogryph"},
+	fourcc("efdr"): {ObjectUnit, "Faerie Dragon"},
+	fourcc("echm"): {ObjectUnit, "Chimaera"},
+	fourcc("Edem"): {ObjectHero, "Demon Hunter"},
+	fourcc("Ekee"): {ObjectHero, "Keeper of the Grove"},
+	fourcc("Emoo"): {ObjectHero, "Priestess of the Moon"},
+	fourcc("Ewar"): {ObjectHero, "Warden"},
+	fourcc("etol"): {ObjectBuilding, "Tree of Life"},
+	fourcc("etoa"): {ObjectBuilding, "Tree of Ages"},
+	fourcc("etoe"): {ObjectBuilding, "Tree of Eternity"},
+	fourcc("emow"): {ObjectBuilding, "Moon Well"},
+	fourcc("eate"): {ObjectBuilding, "Altar of Elders"},
+	fourcc("eaom"): {ObjectBuilding, "Ancient of War"},
+	fourcc("eaoe"): {ObjectBuilding, "Ancient of Lore"},
+	fourcc("eaow"): {ObjectBuilding, "Ancient of Wind"},
+	fourcc("edob"): {ObjectBuilding, "Hunter's Hall"},
+	fourcc("etrp"): {ObjectBuilding, "Ancient Protector"},
+	fourcc("edos"): {ObjectBuilding, "Chimaera Roost"},
+	fourcc("eden"): {ObjectBuilding, "Ancient of Wonders"},
+
+	// Undead
+	fourcc("uaco"): {ObjectUnit, "Acolyte"},
+	fourcc("ushd"): {ObjectUnit, "Shade"},
+	fourcc("ugho"): {ObjectUnit, "Ghoul"},
+	fourcc("ucry"): {ObjectUnit, "Crypt Fiend"},
+	fourcc("ugar"): {ObjectUnit, "Gargoyle"},
+	fourcc("umtw"): {ObjectUnit, "Meat Wagon"},
+	fourcc("uabo"): {ObjectUnit, "Abomination"},
+	fourcc("unec"): {ObjectUnit, "Necromancer"},
+	fourcc("uban"): {ObjectUnit, "Banshee"},
+	fourcc("uobs"): {ObjectUnit, "Obsidian Statue"},
+	fourcc("ufro"): {ObjectUnit, "Frost Wyrm"},
+	fourcc("Udea"): {ObjectHero, "Death Knight"},
+	fourcc("Ulic"): {ObjectHero, "Lich"},
+	fourcc("Udre"): {ObjectHero, "Dreadlord"},
+	fourcc("Ucrl"): {ObjectHero, "Crypt Lord"},
+	fourcc("unpl"): {ObjectBuilding, "Necropolis"},
+	fourcc("unp1"): {ObjectBuilding, "Halls of the Dead"},
+	fourcc("unp2"): {ObjectBuilding, "Black Citadel"},
+	fourcc("uzig"): {ObjectBuilding, "Ziggurat"},
+	fourcc("uzg1"): {ObjectBuilding, "Spirit Tower"},
+	fourcc("uzg2"): {ObjectBuilding, "Nerubian Tower"},
+	fourcc("uaod"): {ObjectBuilding, "Altar of Darkness"},
+	fourcc("usep"): {ObjectBuilding, "Crypt"},
+	fourcc("ugrv"): {ObjectBuilding, "Graveyard"},
+	fourcc("utod"): {ObjectBuilding, "Temple of the Damned"},
+	fourcc("uslh"): {ObjectBuilding, "Slaughterhouse"},
+	fourcc("usap"): {ObjectBuilding, "Sacrificial Pit"},
+	fourcc("ubon"): {ObjectBuilding, "Boneyard"},
+	fourcc("utom"): {ObjectBuilding, "Tomb of Relics"},
+
+	// Neutral heroes
+	fourcc("Nalc"): {ObjectHero, "Alchemist"},
+	fourcc("Nngs"): {ObjectHero, "Naga Sea Witch"},
+	fourcc("Ntin"): {ObjectHero, "Tinker"},
+	fourcc("Nbst"): {ObjectHero, "Beastmaster"},
+	fourcc("Npbm"): {ObjectHero, "Pandaren Brewmaster"},
+	fourcc("Nbrn"): {ObjectHero, "Dark Ranger"},
+	fourcc("Nplh"): {ObjectHero, "Pit Lord"},
+	fourcc("Nfir"): {ObjectHero, "Firelord"},
+
+	// Human upgrades
+	fourcc("Rhme"): {ObjectUpgrade, "Iron Forged Swords"},
+	fourcc("Rhar"): {ObjectUpgrade, "Iron Plating"},
+	fourcc("Rhra"): {ObjectUpgrade, "Black Gunpowder"},
+	fourcc("Rhla"): {ObjectUpgrade, "Studded Leather Armor"},
+	fourcc("Rhac"): {ObjectUpgrade, "Improved Masonry"},
+	fourcc("Rhde"): {ObjectUpgrade, "Defend"},
+	fourcc("Rhan"): {ObjectUpgrade, "Animal War Training"},
+	fourcc("Rhpt"): {ObjectUpgrade, "Priest Adept Training"},
+	fourcc("Rhst"): {ObjectUpgrade, "Sorceress Adept Training"},
+	fourcc("Rhlh"): {ObjectUpgrade, "Improved Lumber Harvesting"},
+	fourcc("Rhri"): {ObjectUpgrade, "Long Rifles"},
+	fourcc("Rhse"): {ObjectUpgrade, "Magic Sentry"},
+	fourcc("Rhfl"): {ObjectUpgrade, "Flare"},
+	fourcc("Rhfs"): {ObjectUpgrade, "Fragmentation Shards"},
+	fourcc("Rhgb"): {ObjectUpgrade, "Flying Machine Bombs"},
+	fourcc("Rhss"): {ObjectUpgrade, "Control Magic"},
+	fourcc("Rhhb"): {ObjectUpgrade, "Storm Hammers"},
+	fourcc("Rhcd"): {ObjectUpgrade, "Cloud"},
+
+	// Orc upgrades
+	fourcc("Rome"): {ObjectUpgrade, "Steel Melee Weapons"},
+	fourcc("Rora"): {ObjectUpgrade, "Steel Ranged Weapons"},
+	fourcc("Roar"): {ObjectUpgrade, "Steel Armor"},
+	fourcc("Rwdm"): {ObjectUpgrade, "War Drums Damage Increase"},
+	fourcc("Ropg"): {ObjectUpgrade, "Pillage"},
+	fourcc("Robs"): {ObjectUpgrade, "Berserker Strength"},
+	fourcc("Rows"): {ObjectUpgrade, "Pulverize"},
+	fourcc("Rost"): {ObjectUpgrade, "Shaman Adept Training"},
+	fourcc("Rowd"): {ObjectUpgrade, "Witch Doctor Adept Training"},
+	fourcc("Rowt"): {ObjectUpgrade, "Spirit Walker Adept Training"},
+	fourcc("Roen"): {ObjectUpgrade, "Ensnare"},
+	fourcc("Rovs"): {ObjectUpgrade, "Envenomed Spears"},
+	fourcc("Rolf"): {ObjectUpgrade, "Liquid Fire"},
+	fourcc("Rotr"): {ObjectUpgrade, "Troll Regeneration"},
+
+	// Night Elf upgrades
+	fourcc("Resm"): {ObjectUpgrade, "Strength of the Moon"},
+	fourcc("Resw"): {ObjectUpgrade, "Strength of the Wild"},
+	fourcc("Rema"): {ObjectUpgrade, "Moon Armor"},
+	fourcc("Rerh"): {ObjectUpgrade, "Reinforced Hides"},
+	fourcc("Reuv"): {ObjectUpgrade, "Ultravision"},
+	fourcc("Renb"): {ObjectUpgrade, "Nature's Blessing"},
+	fourcc("Reib"): {ObjectUpgrade, "Improved Bows"},
+	fourcc("Remk"): {ObjectUpgrade, "Marksmanship"},
+	fourcc("Resc"): {ObjectUpgrade, "Sentinel"},
+	fourcc("Remg"): {ObjectUpgrade, "Upgrade Moon Glaive"},
+	fourcc("Redt"): {ObjectUpgrade, "Druid of the Talon Adept Training"},
+	fourcc("Redc"): {ObjectUpgrade, "Druid of the Claw Adept Training"},
+	fourcc("Resi"): {ObjectUpgrade, "Abolish Magic"},
+	fourcc("Reht"): {ObjectUpgrade, "Hippogryph Taming"},
+
+	// Undead upgrades
+	fourcc("Rume"): {ObjectUpgrade, "Unholy Strength"},
+	fourcc("Rura"): {ObjectUpgrade, "Creature Attack"},
+	fourcc("Ruar"): {ObjectUpgrade, "Unholy Armor"},
+	fourcc("Rucr"): {ObjectUpgrade, "Creature Carapace"},
+	fourcc("Ruac"): {ObjectUpgrade, "Cannibalize"},
+	fourcc("Rugf"): {ObjectUpgrade, "Ghoul Frenzy"},
+	fourcc("Ruwb"): {ObjectUpgrade, "Web"},
+	fourcc("Rusf"): {ObjectUpgrade, "Stone Form"},
+	fourcc("Rune"): {ObjectUpgrade, "Necromancer Adept Training"},
+	fourcc("Ruba"): {ObjectUpgrade, "Banshee Adept Training"},
+	fourcc("Rufb"): {ObjectUpgrade, "Freezing Breath"},
+	fourcc("Rusl"): {ObjectUpgrade, "Skeletal Longevity"},
+	fourcc("Rupc"): {ObjectUpgrade, "Disease Cloud"},
+	fourcc("Rusm"): {ObjectUpgrade, "Skeletal Mastery"},
+	fourcc("Rubu"): {ObjectUpgrade, "Burrow"},
+	fourcc("Ruex"): {ObjectUpgrade, "Exhume Corpses"},
+
+	// Shop items
+	fourcc("stwp"): {ObjectItem, "Scroll of Town Portal"},
+	fourcc("phea"): {ObjectItem, "Potion of Healing"},
+	fourcc("pman"): {ObjectItem, "Potion of Mana"},
+	fourcc("pghe"): {ObjectItem, "Potion of Greater Healing"},
+	fourcc("pgma"): {ObjectItem, "Potion of Greater Mana"},
+	fourcc("plcl"): {ObjectItem, "Lesser Clarity Potion"},
+	fourcc("pnvl"): {ObjectItem, "Potion of Invulnerability"},
+	fourcc("hslv"): {ObjectItem, "Healing Salve"},
+	fourcc("shea"): {ObjectItem, "Scroll of Healing"},
+	fourcc("sreg"): {ObjectItem, "Scroll of Regeneration"},
+	fourcc("spro"): {ObjectItem, "Scroll of Protection"},
+	fourcc("bspd"): {ObjectItem, "Boots of Speed"},
+	fourcc("dust"): {ObjectItem, "Dust of Appearance"},
+	fourcc("ankh"): {ObjectItem, "Ankh of Reincarnation"},
+	fourcc("stel"): {ObjectItem, "Staff of Teleportation"},
+	fourcc("ssan"): {ObjectItem, "Staff of Sanctuary"},
+	fourcc("tret"): {ObjectItem, "Tome of Retraining"},
+	fourcc("moon"): {ObjectItem, "Moonstone"},
+	fourcc("cnob"): {ObjectItem, "Circlet of Nobility"},
+	fourcc("rnec"): {ObjectItem, "Rod of Necromancy"},
+}
+
+// ObjectName returns the display name for a base game object code
+func ObjectName(code uint32) (string, bool) {
+	o, ok := objects[ItemID(code)]
+	return o.Name, ok
+}
+
+// Type of the object, upgrades and abilities are recognized by their 'R' and 'A' prefix
+func (id ItemID) Type() ObjectType {
+	if o, ok := objects[id]; ok {
+		return o.Type
+	}
+	if !id.IsObject() {
+		return ObjectUnknown
+	}
+	switch id >> 24 {
+	case 'R':
+		return ObjectUpgrade
+	case 'A':
+		return ObjectAbility
+	default:
+		return ObjectUnknown
+	}
+}
+
+// Name of the object (String() if unknown)
+func (id ItemID) Name() string {
+	if o, ok := objects[id]; ok {
+		return o.Name
+	}
+	return id.String()
+}
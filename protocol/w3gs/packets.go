@@ -5,9 +5,14 @@
 package w3gs
 
 import (
+	"encoding/binary"
+	"fmt"
 	"hash/crc32"
 	"io"
 	"math"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/dedis/protobuf"
 	"github.com/nielsAD/gowarcraft3/protocol"
@@ -474,6 +479,69 @@ type SlotData struct {
 	Handicap       uint8
 }
 
+// OccupiedSlots returns (pointers to) all occupied slots, in slot order
+func (pkt *SlotInfo) OccupiedSlots() []*SlotData {
+	var res []*SlotData
+	for i := range pkt.Slots {
+		if pkt.Slots[i].SlotStatus == SlotOccupied {
+			res = append(res, &pkt.Slots[i])
+		}
+	}
+	return res
+}
+
+// SlotByPlayerID returns (a pointer to) the occupied slot of a human player
+func (pkt *SlotInfo) SlotByPlayerID(id uint8) (*SlotData, bool) {
+	for i := range pkt.Slots {
+		var s = &pkt.Slots[i]
+		if s.PlayerID == id && s.SlotStatus == SlotOccupied && !s.Computer {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// ValidHandicap returns true if h is a handicap that can be selected in the lobby (50, 60, .., 100)
+func ValidHandicap(h uint8) bool {
+	return h >= 50 && h <= 100 && h%10 == 0
+}
+
+// IsHuman returns true if the slot is occupied by a human player
+func (s *SlotData) IsHuman() bool {
+	return s.SlotStatus == SlotOccupied && !s.Computer
+}
+
+// IsComputer returns true if the slot is occupied by a computer player
+func (s *SlotData) IsComputer() bool {
+	return s.SlotStatus == SlotOccupied && s.Computer
+}
+
+// AIDifficulty returns the difficulty of a computer player (false if the slot is not occupied by a computer)
+func (s *SlotData) AIDifficulty() (AI, bool) {
+	if !s.IsComputer() {
+		return 0, false
+	}
+	return s.ComputerType, true
+}
+
+// IsObserver returns true if the slot is occupied by an observer or referee (see Encoding.MaxPlayers)
+func (s *SlotData) IsObserver(maxPlayers uint8) bool {
+	return s.SlotStatus == SlotOccupied && s.Team >= maxPlayers
+}
+
+// NormalizeColors assigns sequential colors (starting at 0) to all occupied slots that are not in
+// the observer team (see Encoding.MaxPlayers). Open and closed slots, observers, and referees are left untouched.
+func (pkt *SlotInfo) NormalizeColors(maxPlayers uint8) {
+	var c = uint8(0)
+	for i := range pkt.Slots {
+		if pkt.Slots[i].SlotStatus != SlotOccupied || pkt.Slots[i].Team >= maxPlayers {
+			continue
+		}
+		pkt.Slots[i].Color = c
+		c++
+	}
+}
+
 // Serialize encodes the struct into its binary form.
 func (pkt *SlotInfo) Serialize(buf *protocol.Buffer, enc *Encoding) error {
 	buf.WriteUInt8(ProtocolSig)
@@ -527,6 +595,10 @@ func (pkt *SlotInfo) SerializeContent(buf *protocol.Buffer, enc *Encoding) {
 
 // DeserializeContent decodes the binary data generated by SerializeContent.
 func (pkt *SlotInfo) DeserializeContent(buf *protocol.Buffer, enc *Encoding) error {
+	if buf.Size() < 2 {
+		return ErrInvalidPacketSize
+	}
+
 	var dataSize = int(buf.ReadUInt16())
 	if dataSize == 0 {
 		pkt.Slots = pkt.Slots[:0]
@@ -546,7 +618,7 @@ func (pkt *SlotInfo) DeserializeContent(buf *protocol.Buffer, enc *Encoding) err
 		slotSize = (dataSize - 7) / numSlots
 	}
 
-	if dataSize != 7+numSlots*slotSize {
+	if slotSize < 7 || dataSize != 7+numSlots*slotSize {
 		return ErrInvalidPacketSize
 	}
 
@@ -616,9 +688,14 @@ type PlayerInfo struct {
 
 // Serialize encodes the struct into its binary form.
 func (pkt *PlayerInfo) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	var size = 44 + len(pkt.PlayerName)
+	if size > protocol.MaxPacketSize {
+		return protocol.ErrSizeOverflow
+	}
+
 	buf.WriteUInt8(ProtocolSig)
 	buf.WriteUInt8(PidPlayerInfo)
-	buf.WriteUInt16(uint16(44 + len(pkt.PlayerName)))
+	buf.WriteUInt16(uint16(size))
 
 	buf.WriteUInt32(pkt.JoinCounter)
 	buf.WriteUInt8(pkt.PlayerID)
@@ -1154,6 +1231,13 @@ func (pkt *GameAction) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 //
 // Informs the client about an action in-game.
 //
+// The host collects the GameAction packets it receives from clients during a time slot and
+// sends them to every client with the sender's player ID, together with the time the game
+// should advance (the send interval). Clients only advance the game when a time slot arrives,
+// an empty time slot keeps the game running. Actions that do not fit in a single packet are
+// sent in fragments (W3GS_INCOMING_ACTION2) followed by a regular time slot.
+// Replays store exactly these packets as TimeSlot records, minus the checksum.
+//
 // Format:
 //
 //    (UINT16) Send interval
@@ -1252,12 +1336,17 @@ func (pkt *TimeSlot) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 		pkt.Actions[i].PlayerID = buf.ReadUInt8()
 
 		var subsize = int(buf.ReadUInt16())
-		if size < subsize {
+		if size < 3+subsize {
 			return ErrInvalidPacketSize
 		}
 		size -= 3 + subsize
 
-		pkt.Actions[i].Data = append(pkt.Actions[i].Data[:0], buf.ReadBlob(subsize)...)
+		if enc.LazyActions {
+			var data = buf.ReadBlob(subsize)
+			pkt.Actions[i].Data = data[:subsize:subsize]
+		} else {
+			pkt.Actions[i].Data = append(pkt.Actions[i].Data[:0], buf.ReadBlob(subsize)...)
+		}
 		i++
 	}
 
@@ -1268,10 +1357,58 @@ func (pkt *TimeSlot) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 	return nil
 }
 
+// Materialize copies action data that references a decode buffer (see Encoding.LazyActions)
+// into memory owned by pkt.
+func (pkt *TimeSlot) Materialize() {
+	var n = 0
+	for i := range pkt.Actions {
+		n += len(pkt.Actions[i].Data)
+	}
+
+	var b = make([]byte, 0, n)
+	for i := range pkt.Actions {
+		if pkt.Actions[i].Data == nil {
+			continue
+		}
+		var s = len(b)
+		b = append(b, pkt.Actions[i].Data...)
+		pkt.Actions[i].Data = b[s:len(b):len(b)]
+	}
+}
+
+// NewIncomingAction creates a time slot that advances the game by tick milliseconds
+// and executes actions in order.
+func NewIncomingAction(tick uint16, actions []PlayerAction) *TimeSlot {
+	return &TimeSlot{
+		TimeIncrementMS: tick,
+		Actions:         actions,
+	}
+}
+
+// Checksum returns the CRC-16 checksum of the action data as sent in the packet header
+// (the lower 16 bits of the CRC-32 of the serialized actions).
+func (pkt *TimeSlot) Checksum() uint16 {
+	var crc uint32
+	var buf [3]byte
+	for _, a := range pkt.Actions {
+		buf[0] = a.PlayerID
+		binary.LittleEndian.PutUint16(buf[1:], uint16(len(a.Data)))
+		crc = crc32.Update(crc, crc32.IEEETable, buf[:])
+		crc = crc32.Update(crc, crc32.IEEETable, a.Data)
+	}
+	return uint16(crc)
+}
+
 // TimeSlotAck implements the [0x27] W3GS_OUTGOING_KEEPALIVE packet (C -> S).
 //
 // This is sent to the host from each client.
 //
+// Clients acknowledge every TimeSlot with a checksum of their game state after processing
+// it. The checksum is computed by the game simulation and cannot be derived from the packets,
+// but it is equal for all clients in sync. Hosts compare the checksums of all clients per
+// time slot to detect desyncs. Replays store the checksums of the recording client as
+// TimeSlotAck records.
+//
 // Format:
 //
 //    (UINT8)  Unknown
@@ -1395,7 +1532,7 @@ func (pkt *Desync) DeserializeContent(buf *protocol.Buffer, enc *Encoding) error
 //    For Flag 0x14:
 //       (UINT8) NewVal(Handicap)
 //    For Flag 0x20:
-//       (UINT32) Message scope (0x00 all, 0x01 allies, 0x02 observers, else directed to N-0x03)
+//       (UINT32) Message scope (0x00 all, 0x01 allies, 0x02 observers, else directed to slot N-0x03)
 //       (STRING) Message
 //
 type Message struct {
@@ -1409,18 +1546,21 @@ type Message struct {
 
 // Serialize encodes the struct into its binary form.
 func (pkt *Message) Serialize(buf *protocol.Buffer, enc *Encoding) error {
-	buf.WriteUInt8(ProtocolSig)
-	buf.WriteUInt8(PidChatToHost)
-
+	var size = 8 + len(pkt.RecipientIDs)
 	switch pkt.Type {
 	case MsgChatExtra:
-		buf.WriteUInt16(uint16(12 + len(pkt.RecipientIDs) + len(pkt.Content)))
+		size += 4 + len(pkt.Content)
 	case MsgChat:
-		buf.WriteUInt16(uint16(8 + len(pkt.RecipientIDs) + len(pkt.Content)))
-	default:
-		buf.WriteUInt16(uint16(8 + len(pkt.RecipientIDs)))
+		size += len(pkt.Content)
+	}
+	if size > protocol.MaxPacketSize || len(pkt.RecipientIDs) > 0xFF {
+		return protocol.ErrSizeOverflow
 	}
 
+	buf.WriteUInt8(ProtocolSig)
+	buf.WriteUInt8(PidChatToHost)
+	buf.WriteUInt16(uint16(size))
+
 	buf.WriteUInt8(uint8(len(pkt.RecipientIDs)))
 	buf.WriteBlob(pkt.RecipientIDs)
 	buf.WriteUInt8(pkt.SenderID)
@@ -1485,6 +1625,60 @@ func (pkt *Message) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 	return nil
 }
 
+// NewChatToAll creates an in-game chat message visible to all players.
+func NewChatToAll(sender uint8, text string) *Message {
+	return &Message{
+		SenderID: sender,
+		Type:     MsgChatExtra,
+		Scope:    ScopeAll,
+		Content:  text,
+	}
+}
+
+// NewChatToAllies creates an in-game chat message visible to the allies of sender.
+func NewChatToAllies(sender uint8, text string) *Message {
+	return &Message{
+		SenderID: sender,
+		Type:     MsgChatExtra,
+		Scope:    ScopeAllies,
+		Content:  text,
+	}
+}
+
+// NewChatToObservers creates an in-game chat message visible to observers.
+func NewChatToObservers(sender uint8, text string) *Message {
+	return &Message{
+		SenderID: sender,
+		Type:     MsgChatExtra,
+		Scope:    ScopeObservers,
+		Content:  text,
+	}
+}
+
+// NewChatToPlayer creates an in-game chat message directed to player target that occupies slot.
+func NewChatToPlayer(sender uint8, target uint8, slot uint8, text string) *Message {
+	return &Message{
+		RecipientIDs: []uint8{target},
+		SenderID:     sender,
+		Type:         MsgChatExtra,
+		Scope:        ScopeToSlot(slot),
+		Content:      text,
+	}
+}
+
+// ScopeString returns a readable representation of the message scope.
+// Lobby chat has no scope and is reported as "Lobby", other message types as "".
+func (pkt *Message) ScopeString() string {
+	switch pkt.Type {
+	case MsgChatExtra:
+		return pkt.Scope.String()
+	case MsgChat:
+		return "Lobby"
+	default:
+		return ""
+	}
+}
+
 // MessageRelay implements the [0x0F] W3GS_CHAT_FROM_HOST packet (S -> C).
 //
 // This is sent to the clients to print a message on the screen from another player.
@@ -1629,6 +1823,86 @@ func (gv *GameVersion) DeserializeContent(buf *protocol.Buffer, enc *Encoding) {
 	gv.Version = buf.ReadUInt32()
 }
 
+// String returns a human readable version, i.e. "TFT 1.26" or "TFT 1.32"
+// Versions without a 1.xx equivalent are written as raw version number.
+func (gv *GameVersion) String() string {
+	var v string
+	switch {
+	case gv.Version < 30:
+		v = fmt.Sprintf("1.%02d", gv.Version)
+	case gv.Version >= ReforgedVersionOffset+30 && gv.Version < ReforgedVersionOffset+100:
+		v = fmt.Sprintf("1.%02d", gv.Version-ReforgedVersionOffset)
+	default:
+		v = strconv.FormatUint(uint64(gv.Version), 10)
+	}
+
+	var p string
+	switch gv.Product {
+	case ProductROC:
+		p = "ROC"
+	case ProductTFT:
+		p = "TFT"
+	case ProductDemo:
+		p = "Demo"
+	default:
+		p = gv.Product.String()
+	}
+	if p == "" {
+		return v
+	}
+	return p + " " + v
+}
+
+// ParseGameVersion is the inverse of GameVersion.String
+//
+// Product is either a name (ROC/TFT/Demo) or a four character code (WAR3/W3XP/W3DM), and
+// defaults to TFT if omitted. Version is either "1.xx" (ReforgedVersionOffset is added
+// from 1.30 onwards) or the raw version number.
+func ParseGameVersion(s string) (GameVersion, error) {
+	var res = GameVersion{Product: ProductTFT}
+
+	var f = strings.Fields(s)
+	switch len(f) {
+	case 1:
+	case 2:
+		switch strings.ToUpper(f[0]) {
+		case "ROC":
+			res.Product = ProductROC
+		case "TFT":
+			res.Product = ProductTFT
+		case "DEMO":
+			res.Product = ProductDemo
+		default:
+			if len(f[0]) > 4 {
+				return GameVersion{}, ErrInvalidGameVersion
+			}
+			res.Product = protocol.DString(f[0])
+		}
+		f = f[1:]
+	default:
+		return GameVersion{}, ErrInvalidGameVersion
+	}
+
+	if strings.HasPrefix(f[0], "1.") {
+		v, err := strconv.ParseUint(f[0][2:], 10, 32)
+		if err != nil || v >= 100 {
+			return GameVersion{}, ErrInvalidGameVersion
+		}
+		if v >= 30 {
+			v += ReforgedVersionOffset
+		}
+		res.Version = uint32(v)
+	} else {
+		v, err := strconv.ParseUint(f[0], 10, 32)
+		if err != nil {
+			return GameVersion{}, ErrInvalidGameVersion
+		}
+		res.Version = uint32(v)
+	}
+
+	return res, nil
+}
+
 // GameSettings stores the settings of a created game.
 //
 // Flags:
@@ -1823,9 +2097,14 @@ type GameInfo struct {
 
 // Serialize encodes the struct into its binary form.
 func (pkt *GameInfo) Serialize(buf *protocol.Buffer, enc *Encoding) error {
+	var size = 44 + len(pkt.GameName) + pkt.GameSettings.Size()
+	if size > protocol.MaxPacketSize {
+		return protocol.ErrSizeOverflow
+	}
+
 	buf.WriteUInt8(ProtocolSig)
 	buf.WriteUInt8(PidGameInfo)
-	buf.WriteUInt16(uint16(44 + len(pkt.GameName) + pkt.GameSettings.Size()))
+	buf.WriteUInt16(uint16(size))
 
 	pkt.GameVersion.SerializeContent(buf, enc)
 	buf.WriteUInt32(pkt.HostCounter)
@@ -1883,6 +2162,41 @@ func (pkt *GameInfo) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 	return nil
 }
 
+// ValidateJoin checks if a join request was made for this game.
+// Returns the reason to send in RejectJoin if the request should be denied.
+func (pkt *GameInfo) ValidateJoin(join *Join) (RejectReason, bool) {
+	if join.HostCounter != pkt.HostCounter || join.EntryKey != pkt.EntryKey {
+		return RejectJoinWrongKey, false
+	}
+	if len(join.PlayerName) == 0 || len(join.PlayerName) > 15 {
+		return RejectJoinInvalid, false
+	}
+	return 0, true
+}
+
+// String returns a one-line summary of the game
+func (pkt *GameInfo) String() string {
+	return fmt.Sprintf("%s [%s %dx%d] %d/%d slots, %v, %v %d",
+		pkt.GameName, pkt.GameSettings.MapPath, pkt.GameSettings.MapWidth, pkt.GameSettings.MapHeight,
+		pkt.SlotsUsed, pkt.SlotsTotal, pkt.GameFlags, pkt.Product, pkt.Version)
+}
+
+// Describe returns a multi-line, human-readable description of the game with all settings decoded
+func (pkt *GameInfo) Describe() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Game:     %s\n", pkt.GameName)
+	fmt.Fprintf(&b, "Host:     %s\n", pkt.GameSettings.HostName)
+	fmt.Fprintf(&b, "Map:      %s (%dx%d)\n", pkt.GameSettings.MapPath, pkt.GameSettings.MapWidth, pkt.GameSettings.MapHeight)
+	fmt.Fprintf(&b, "Map hash: xoro 0x%08X, sha1 %x\n", pkt.GameSettings.MapXoro, pkt.GameSettings.MapSha1)
+	fmt.Fprintf(&b, "Settings: %v\n", pkt.GameSettings.GameSettingFlags)
+	fmt.Fprintf(&b, "Flags:    %v\n", pkt.GameFlags)
+	fmt.Fprintf(&b, "Slots:    %d used, %d available, %d total\n", pkt.SlotsUsed, pkt.SlotsAvailable, pkt.SlotsTotal)
+	fmt.Fprintf(&b, "Version:  %v %d\n", pkt.Product, pkt.Version)
+	fmt.Fprintf(&b, "Entry:    host counter %d, entry key %d, port %d\n", pkt.HostCounter, pkt.EntryKey, pkt.GamePort)
+	fmt.Fprintf(&b, "Uptime:   %v\n", time.Duration(pkt.UptimeSec)*time.Second)
+	return b.String()
+}
+
 // CreateGame implements the [0x31] W3GS_CreateGame packet (S -> C).
 //
 // Notifies the local area network that a game was created.
@@ -2098,7 +2412,7 @@ func (pkt *PeerSet) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 //    (UINT32)     File size
 //    (UINT32)     File CRC hash
 //    (UINT32)     Map XOR/RotateLeft hash
-//     (UINT8)[20] Map SHA-1 hash
+//     (UINT8)[20] Map SHA-1 hash (version >= 1.23)
 //
 type MapCheck struct {
 	FilePath string
@@ -2112,22 +2426,36 @@ type MapCheck struct {
 func (pkt *MapCheck) Serialize(buf *protocol.Buffer, enc *Encoding) error {
 	buf.WriteUInt8(ProtocolSig)
 	buf.WriteUInt8(PidMapCheck)
-	buf.WriteUInt16(uint16(41 + len(pkt.FilePath)))
+	buf.WriteUInt16(uint16(pkt.size(enc) + len(pkt.FilePath)))
 
 	buf.WriteUInt32(1)
 	buf.WriteCString(pkt.FilePath)
 	buf.WriteUInt32(pkt.FileSize)
 	buf.WriteUInt32(pkt.FileCRC)
 	buf.WriteUInt32(pkt.MapXoro)
-	buf.WriteBlob(pkt.MapSha1[:])
+	if mapCheckSha1(enc) {
+		buf.WriteBlob(pkt.MapSha1[:])
+	}
 
 	return nil
 }
 
+func mapCheckSha1(enc *Encoding) bool {
+	return enc.GameVersion == 0 || enc.GameVersion >= 23
+}
+
+func (pkt *MapCheck) size(enc *Encoding) int {
+	if mapCheckSha1(enc) {
+		return 41
+	}
+	return 21
+}
+
 // Deserialize decodes the binary data generated by Serialize.
 func (pkt *MapCheck) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 	var size = readPacketSize(buf)
-	if size < 41 {
+	var base = pkt.size(enc)
+	if size < base {
 		return ErrInvalidPacketSize
 	}
 
@@ -2140,14 +2468,18 @@ func (pkt *MapCheck) Deserialize(buf *protocol.Buffer, enc *Encoding) error {
 		return err
 	}
 
-	if size != 41+len(pkt.FilePath) {
+	if size != base+len(pkt.FilePath) {
 		return ErrInvalidPacketSize
 	}
 
 	pkt.FileSize = buf.ReadUInt32()
 	pkt.FileCRC = buf.ReadUInt32()
 	pkt.MapXoro = buf.ReadUInt32()
-	copy(pkt.MapSha1[:], buf.ReadBlob(20))
+	if mapCheckSha1(enc) {
+		copy(pkt.MapSha1[:], buf.ReadBlob(20))
+	} else {
+		pkt.MapSha1 = [20]byte{}
+	}
 
 	return nil
 }
@@ -2402,7 +2734,7 @@ func (pkt *MapPartError) Deserialize(buf *protocol.Buffer, enc *Encoding) error
 //     (STRING) Battletag
 //     (STRING) Clan
 //     (STRING) Portrait
-//      (UINT8) Team
+//     (UINT32) Realm
 //     (STRING) Unknown
 //
 //   For each player (sub type 0x04, encoded with protobuf):
@@ -2431,7 +2763,7 @@ type PlayerExtra struct {
 //     (STRING) Battletag
 //     (STRING) Clan
 //     (STRING) Portrait
-//      (UINT8) Team
+//     (UINT32) Realm
 //     (STRING) Unknown
 //
 type PlayerDataProfile struct {
@@ -5,8 +5,11 @@ package w3gs_test
 
 import (
 	"bytes"
+	"encoding/hex"
+	"errors"
 	"net"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/nielsAD/gowarcraft3/protocol"
@@ -38,376 +41,688 @@ var sd = []w3gs.SlotData{
 	},
 }
 
-func TestPackets(t *testing.T) {
-	var types = []w3gs.Packet{
-		&w3gs.UnknownPacket{
-			ID:   255,
-			Blob: []byte{w3gs.ProtocolSig, 255, 4, 0},
-		},
-		&w3gs.Ping{},
-		&w3gs.Ping{
-			Payload: 444,
-		},
-		&w3gs.Pong{},
-		&w3gs.Pong{
-			Ping: w3gs.Ping{Payload: 999},
-		},
-		&w3gs.PeerPing{},
-		&w3gs.PeerPing{
-			Payload:   123,
-			PeerSet:   protocol.BS32(true, false, true),
-			GameTicks: 789,
-		},
-		&w3gs.PeerPong{},
-		&w3gs.PeerPong{
-			Ping: w3gs.Ping{Payload: 1011},
-		},
+var packets = []w3gs.Packet{
+	&w3gs.UnknownPacket{
+		ID:   255,
+		Blob: []byte{w3gs.ProtocolSig, 255, 4, 0},
+	},
+	&w3gs.Ping{},
+	&w3gs.Ping{
+		Payload: 444,
+	},
+	&w3gs.Pong{},
+	&w3gs.Pong{
+		Ping: w3gs.Ping{Payload: 999},
+	},
+	&w3gs.PeerPing{},
+	&w3gs.PeerPing{
+		Payload:   123,
+		PeerSet:   protocol.BS32(true, false, true),
+		GameTicks: 789,
+	},
+	&w3gs.PeerPong{},
+	&w3gs.PeerPong{
+		Ping: w3gs.Ping{Payload: 1011},
+	},
 
-		&w3gs.Join{},
-		&w3gs.Join{
-			HostCounter: 1,
-			EntryKey:    2,
-			ListenPort:  3,
-			JoinCounter: 4,
-			PlayerName:  "Grubby",
-			InternalAddr: protocol.SockAddr{
-				Port: 6,
-				IP:   net.IP{7, 8, 9, 10},
-			},
-		},
-		&w3gs.RejectJoin{},
-		&w3gs.RejectJoin{
-			Reason: w3gs.RejectJoinWrongKey,
-		},
-		&w3gs.SlotInfoJoin{},
-		&w3gs.SlotInfoJoin{
-			SlotInfo: w3gs.SlotInfo{
-				Slots:      sd,
-				RandomSeed: 10,
-				SlotLayout: w3gs.LayoutMelee,
-				NumPlayers: 12,
-			},
-			PlayerID: 13,
-			ExternalAddr: protocol.SockAddr{
-				Port: 14,
-				IP:   net.IP{15, 16, 17, 18},
-			},
+	&w3gs.Join{},
+	&w3gs.Join{
+		HostCounter: 1,
+		EntryKey:    2,
+		ListenPort:  3,
+		JoinCounter: 4,
+		PlayerName:  "Grubby",
+		InternalAddr: protocol.SockAddr{
+			Port: 6,
+			IP:   net.IP{7, 8, 9, 10},
 		},
-		&w3gs.SlotInfo{},
-		&w3gs.SlotInfo{
-			Slots: sd,
+	},
+	&w3gs.RejectJoin{},
+	&w3gs.RejectJoin{
+		Reason: w3gs.RejectJoinWrongKey,
+	},
+	&w3gs.SlotInfoJoin{},
+	&w3gs.SlotInfoJoin{
+		SlotInfo: w3gs.SlotInfo{
+			Slots:      sd,
+			RandomSeed: 10,
+			SlotLayout: w3gs.LayoutMelee,
+			NumPlayers: 12,
+		},
+		PlayerID: 13,
+		ExternalAddr: protocol.SockAddr{
+			Port: 14,
+			IP:   net.IP{15, 16, 17, 18},
 		},
-		&w3gs.PlayerInfo{},
-		&w3gs.PlayerInfo{
-			JoinCounter: 1,
-			PlayerID:    2,
-			PlayerName:  "Moon",
-			ExternalAddr: protocol.SockAddr{
-				Port: 4,
-				IP:   net.IP{5, 6, 7, 8},
-			},
-			InternalAddr: protocol.SockAddr{
-				Port: 9,
-				IP:   net.IP{10, 11, 12, 13},
-			},
+	},
+	&w3gs.SlotInfo{},
+	&w3gs.SlotInfo{
+		Slots: sd,
+	},
+	&w3gs.PlayerInfo{},
+	&w3gs.PlayerInfo{
+		JoinCounter: 1,
+		PlayerID:    2,
+		PlayerName:  "Moon",
+		ExternalAddr: protocol.SockAddr{
+			Port: 4,
+			IP:   net.IP{5, 6, 7, 8},
+		},
+		InternalAddr: protocol.SockAddr{
+			Port: 9,
+			IP:   net.IP{10, 11, 12, 13},
 		},
+	},
 
-		&w3gs.Leave{},
-		&w3gs.Leave{
-			Reason: w3gs.LeaveLost,
-		},
-		&w3gs.LeaveAck{},
-		&w3gs.PlayerKicked{},
-		&w3gs.PlayerKicked{
-			Leave: w3gs.Leave{Reason: w3gs.LeaveLobby},
-		},
-		&w3gs.PlayerLeft{},
-		&w3gs.PlayerLeft{
-			PlayerID: 1,
-			Reason:   w3gs.LeaveLost,
-		},
+	&w3gs.Leave{},
+	&w3gs.Leave{
+		Reason: w3gs.LeaveLost,
+	},
+	&w3gs.LeaveAck{},
+	&w3gs.PlayerKicked{},
+	&w3gs.PlayerKicked{
+		Leave: w3gs.Leave{Reason: w3gs.LeaveLobby},
+	},
+	&w3gs.PlayerLeft{},
+	&w3gs.PlayerLeft{
+		PlayerID: 1,
+		Reason:   w3gs.LeaveLost,
+	},
 
-		&w3gs.CountDownStart{},
-		&w3gs.CountDownEnd{},
-		&w3gs.GameLoaded{},
-		&w3gs.PlayerLoaded{},
-		&w3gs.PlayerLoaded{
-			PlayerID: 12,
-		},
-		&w3gs.GameOver{},
-		&w3gs.GameOver{
-			PlayerID: 34,
-		},
+	&w3gs.CountDownStart{},
+	&w3gs.CountDownEnd{},
+	&w3gs.GameLoaded{},
+	&w3gs.PlayerLoaded{},
+	&w3gs.PlayerLoaded{
+		PlayerID: 12,
+	},
+	&w3gs.GameOver{},
+	&w3gs.GameOver{
+		PlayerID: 34,
+	},
 
-		&w3gs.StartLag{},
-		&w3gs.StartLag{
-			Players: []w3gs.LagPlayer{
-				w3gs.LagPlayer{PlayerID: 1, LagDurationMS: 2},
-				w3gs.LagPlayer{PlayerID: 3, LagDurationMS: 4},
-				w3gs.LagPlayer{PlayerID: 5, LagDurationMS: 6},
-			},
-		},
-		&w3gs.StopLag{},
-		&w3gs.StopLag{
-			LagPlayer: w3gs.LagPlayer{PlayerID: 1, LagDurationMS: 2},
+	&w3gs.StartLag{},
+	&w3gs.StartLag{
+		Players: []w3gs.LagPlayer{
+			w3gs.LagPlayer{PlayerID: 1, LagDurationMS: 2},
+			w3gs.LagPlayer{PlayerID: 3, LagDurationMS: 4},
+			w3gs.LagPlayer{PlayerID: 5, LagDurationMS: 6},
 		},
-		&w3gs.DropLaggers{},
+	},
+	&w3gs.StopLag{},
+	&w3gs.StopLag{
+		LagPlayer: w3gs.LagPlayer{PlayerID: 1, LagDurationMS: 2},
+	},
+	&w3gs.DropLaggers{},
 
-		&w3gs.GameAction{},
-		&w3gs.GameAction{
-			Data: []byte{2, 3, 4, 5, 6, 7, 8, 9},
-		},
-		&w3gs.TimeSlot{},
-		&w3gs.TimeSlot{
-			Fragment:        false,
-			TimeIncrementMS: 50,
-			Actions: []w3gs.PlayerAction{
-				w3gs.PlayerAction{PlayerID: 1, Data: make([]byte, 23)},
-				w3gs.PlayerAction{PlayerID: 12, Data: make([]byte, 3)},
-			},
-		},
-		&w3gs.TimeSlot{
-			Fragment:        true,
-			TimeIncrementMS: 50,
-			Actions: []w3gs.PlayerAction{
-				w3gs.PlayerAction{PlayerID: 1, Data: make([]byte, 23)},
-				w3gs.PlayerAction{PlayerID: 12, Data: make([]byte, 3)},
-			},
-		},
-		&w3gs.TimeSlotAck{},
-		&w3gs.TimeSlotAck{
-			Checksum: 456,
+	&w3gs.GameAction{},
+	&w3gs.GameAction{
+		Data: []byte{2, 3, 4, 5, 6, 7, 8, 9},
+	},
+	&w3gs.TimeSlot{},
+	&w3gs.TimeSlot{
+		Fragment:        false,
+		TimeIncrementMS: 50,
+		Actions: []w3gs.PlayerAction{
+			w3gs.PlayerAction{PlayerID: 1, Data: make([]byte, 23)},
+			w3gs.PlayerAction{PlayerID: 12, Data: make([]byte, 3)},
 		},
-		&w3gs.Desync{},
-		&w3gs.Desync{
-			Unknown1:       987,
-			Checksum:       789,
-			PlayersInState: []uint8{1, 2, 3},
+	},
+	&w3gs.TimeSlot{
+		Fragment:        true,
+		TimeIncrementMS: 50,
+		Actions: []w3gs.PlayerAction{
+			w3gs.PlayerAction{PlayerID: 1, Data: make([]byte, 23)},
+			w3gs.PlayerAction{PlayerID: 12, Data: make([]byte, 3)},
 		},
+	},
+	&w3gs.TimeSlotAck{},
+	&w3gs.TimeSlotAck{
+		Checksum: 456,
+	},
+	&w3gs.Desync{},
+	&w3gs.Desync{
+		Unknown1:       987,
+		Checksum:       789,
+		PlayersInState: []uint8{1, 2, 3},
+	},
 
-		&w3gs.Message{},
-		&w3gs.Message{
+	&w3gs.Message{},
+	&w3gs.Message{
+		RecipientIDs: []uint8{1, 2, 3},
+		SenderID:     4,
+		Type:         w3gs.MsgChat,
+		Content:      "Tremble before me",
+	},
+	&w3gs.Message{
+		RecipientIDs: []uint8{1, 2, 3},
+		SenderID:     4,
+		Type:         w3gs.MsgColorChange,
+		NewVal:       5,
+	},
+	&w3gs.MessageRelay{},
+	&w3gs.MessageRelay{
+		Message: w3gs.Message{
 			RecipientIDs: []uint8{1, 2, 3},
 			SenderID:     4,
 			Type:         w3gs.MsgChat,
-			Content:      "Tremble before me",
+			Content:      "I come from the darkness of the pit",
 		},
-		&w3gs.Message{
-			RecipientIDs: []uint8{1, 2, 3},
+	},
+	&w3gs.MessageRelay{
+		Message: w3gs.Message{
+			RecipientIDs: []uint8{1},
+			SenderID:     2,
+			Type:         w3gs.MsgChat,
+			Content:      "|cffff0000The |CFF00FF00Scourge|r|n awaits\u3000you|R",
+		},
+	},
+	&w3gs.MessageRelay{
+		Message: w3gs.Message{
+			RecipientIDs: []uint8{1, 2},
 			SenderID:     4,
-			Type:         w3gs.MsgColorChange,
-			NewVal:       5,
+			Type:         w3gs.MsgChatExtra,
+			Scope:        w3gs.ScopeAllies,
+			Content:      "Pitiful",
 		},
-		&w3gs.MessageRelay{},
-		&w3gs.MessageRelay{
-			Message: w3gs.Message{
-				RecipientIDs: []uint8{1, 2, 3},
-				SenderID:     4,
-				Type:         w3gs.MsgChat,
-				Content:      "I come from the darkness of the pit",
-			},
+	},
+	&w3gs.PeerMessage{},
+	&w3gs.PeerMessage{
+		Message: w3gs.Message{
+			RecipientIDs: []uint8{1, 2, 3},
+			SenderID:     4,
+			Type:         w3gs.MsgChat,
+			Content:      "You fail to amuse me",
 		},
-		&w3gs.MessageRelay{
-			Message: w3gs.Message{
-				RecipientIDs: []uint8{1, 2},
-				SenderID:     4,
-				Type:         w3gs.MsgChatExtra,
-				Scope:        w3gs.ScopeAllies,
-				Content:      "Pitiful",
-			},
+	},
+
+	&w3gs.SearchGame{},
+	&w3gs.SearchGame{
+		GameVersion: w3gs.GameVersion{
+			Product: w3gs.ProductDemo,
+			Version: 666,
 		},
-		&w3gs.PeerMessage{},
-		&w3gs.PeerMessage{
-			Message: w3gs.Message{
-				RecipientIDs: []uint8{1, 2, 3},
-				SenderID:     4,
-				Type:         w3gs.MsgChat,
-				Content:      "You fail to amuse me",
-			},
+		HostCounter: 1,
+	},
+	&w3gs.GameInfo{},
+	&w3gs.GameInfo{
+		GameVersion: w3gs.GameVersion{
+			Product: w3gs.ProductROC,
+			Version: 1,
+		},
+		HostCounter: 2,
+		EntryKey:    112233,
+		GameName:    "game1",
+		GameSettings: w3gs.GameSettings{
+			GameSettingFlags: w3gs.SettingSpeedNormal,
+			MapWidth:         1,
+			MapHeight:        2,
+			MapXoro:          3,
+			MapPath:          "4",
+			HostName:         "5",
+		},
+		SlotsTotal:     24,
+		GameFlags:      w3gs.GameFlagCustomGame,
+		SlotsUsed:      1,
+		SlotsAvailable: 24,
+		UptimeSec:      8,
+		GamePort:       9,
+	},
+	&w3gs.CreateGame{},
+	&w3gs.CreateGame{
+		GameVersion: w3gs.GameVersion{
+			Product: w3gs.ProductTFT,
+			Version: 2,
 		},
+		HostCounter: 3,
+	},
+	&w3gs.RefreshGame{},
+	&w3gs.RefreshGame{
+		HostCounter:    1,
+		SlotsUsed:      2,
+		SlotsAvailable: 3,
+	},
+	&w3gs.DecreateGame{},
+	&w3gs.DecreateGame{
+		HostCounter: 777,
+	},
 
-		&w3gs.SearchGame{},
-		&w3gs.SearchGame{
-			GameVersion: w3gs.GameVersion{
-				Product: w3gs.ProductDemo,
-				Version: 666,
-			},
-			HostCounter: 1,
-		},
-		&w3gs.GameInfo{},
-		&w3gs.GameInfo{
-			GameVersion: w3gs.GameVersion{
-				Product: w3gs.ProductROC,
-				Version: 1,
-			},
-			HostCounter: 2,
-			EntryKey:    112233,
-			GameName:    "game1",
-			GameSettings: w3gs.GameSettings{
-				GameSettingFlags: w3gs.SettingSpeedNormal,
-				MapWidth:         1,
-				MapHeight:        2,
-				MapXoro:          3,
-				MapPath:          "4",
-				HostName:         "5",
+	&w3gs.PeerConnect{},
+	&w3gs.PeerConnect{
+		JoinCounter: 1,
+		EntryKey:    2,
+		PlayerID:    3,
+		PeerSet:     protocol.BS32(false, true, false),
+	},
+	&w3gs.PeerSet{},
+	&w3gs.PeerSet{
+		PeerSet: protocol.BS16(true, false, true),
+	},
+
+	&w3gs.MapCheck{},
+	&w3gs.MapCheck{
+		FilePath: "Maps\\BootyBay.w3x",
+		FileSize: 2,
+		FileCRC:  3,
+		MapXoro:  4,
+		MapSha1:  [20]byte{5, 6, 7},
+	},
+	&w3gs.StartDownload{},
+	&w3gs.StartDownload{
+		PlayerID: 111,
+	},
+	&w3gs.MapState{},
+	&w3gs.MapState{
+		Ready:    true,
+		FileSize: 2,
+	},
+	&w3gs.MapPart{},
+	&w3gs.MapPart{
+		RecipientID: 1,
+		SenderID:    2,
+		ChunkPos:    3,
+		Data:        []byte{5, 6, 7, 8, 9},
+	},
+	&w3gs.MapPartOK{},
+	&w3gs.MapPartOK{
+		RecipientID: 1,
+		SenderID:    2,
+		ChunkPos:    3,
+	},
+	&w3gs.MapPartError{},
+	&w3gs.PlayerExtra{},
+	&w3gs.PlayerExtra{
+		Type: w3gs.PlayerProfile,
+		Profiles: []w3gs.PlayerDataProfile{
+			w3gs.PlayerDataProfile{
+				PlayerID:  1,
+				BattleTag: "niels#1234",
+				Clan:      "clan",
+				Portrait:  "p051",
+				Realm:     w3gs.RealmEurope,
+				Unknown1:  "",
 			},
-			SlotsTotal:     24,
-			GameFlags:      w3gs.GameFlagCustomGame,
-			SlotsUsed:      1,
-			SlotsAvailable: 24,
-			UptimeSec:      8,
-			GamePort:       9,
 		},
-		&w3gs.CreateGame{},
-		&w3gs.CreateGame{
-			GameVersion: w3gs.GameVersion{
-				Product: w3gs.ProductTFT,
-				Version: 2,
+	},
+	&w3gs.PlayerExtra{
+		Type: w3gs.PlayerSkins,
+		Skins: []w3gs.PlayerDataSkins{
+			w3gs.PlayerDataSkins{
+				PlayerID: 3,
+				Skins: []w3gs.PlayerDataSkin{
+					w3gs.PlayerDataSkin{
+						Unit:       1164207469,
+						Skin:       1164207462,
+						Collection: "w3-standard",
+					},
+					w3gs.PlayerDataSkin{
+						Unit:       1164666213,
+						Skin:       1164665701,
+						Collection: "w3-sow-skins",
+					},
+				},
 			},
-			HostCounter: 3,
-		},
-		&w3gs.RefreshGame{},
-		&w3gs.RefreshGame{
-			HostCounter:    1,
-			SlotsUsed:      2,
-			SlotsAvailable: 3,
-		},
-		&w3gs.DecreateGame{},
-		&w3gs.DecreateGame{
-			HostCounter: 777,
 		},
+	},
+}
 
-		&w3gs.PeerConnect{},
-		&w3gs.PeerConnect{
-			JoinCounter: 1,
-			EntryKey:    2,
-			PlayerID:    3,
-			PeerSet:     protocol.BS32(false, true, false),
-		},
-		&w3gs.PeerSet{},
-		&w3gs.PeerSet{
-			PeerSet: protocol.BS16(true, false, true),
-		},
+func TestPackets(t *testing.T) {
+	for _, gv := range []uint32{0, 26, 29, 32} {
+		var enc = w3gs.Encoding{GameVersion: gv}
+		for _, pkt := range packets {
+			var err error
+			var buf = protocol.Buffer{}
+
+			if err = pkt.Serialize(&buf, &enc); err != nil {
+				t.Log(reflect.TypeOf(pkt))
+				t.Fatal(err)
+			}
 
-		&w3gs.MapCheck{},
-		&w3gs.MapCheck{
-			FilePath: "Maps\\BootyBay.w3x",
-			FileSize: 2,
-			FileCRC:  3,
-			MapXoro:  4,
-		},
-		&w3gs.StartDownload{},
-		&w3gs.StartDownload{
-			PlayerID: 111,
-		},
-		&w3gs.MapState{},
-		&w3gs.MapState{
-			Ready:    true,
-			FileSize: 2,
-		},
-		&w3gs.MapPart{},
-		&w3gs.MapPart{
-			RecipientID: 1,
-			SenderID:    2,
-			ChunkPos:    3,
-			Data:        []byte{5, 6, 7, 8, 9},
-		},
-		&w3gs.MapPartOK{},
-		&w3gs.MapPartOK{
-			RecipientID: 1,
-			SenderID:    2,
-			ChunkPos:    3,
-		},
-		&w3gs.MapPartError{},
-		&w3gs.PlayerExtra{},
-		&w3gs.PlayerExtra{
-			Type: w3gs.PlayerProfile,
-			Profiles: []w3gs.PlayerDataProfile{
-				w3gs.PlayerDataProfile{
-					PlayerID:  1,
-					BattleTag: "niels#1234",
-					Clan:      "clan",
-					Portrait:  "p051",
-					Realm:     w3gs.RealmEurope,
-					Unknown1:  "",
-				},
+			var buf2 = protocol.Buffer{}
+			if _, err = w3gs.Write(&buf2, pkt, enc); err != nil {
+				t.Log(reflect.TypeOf(pkt))
+				t.Fatal(err)
+			}
+
+			if bytes.Compare(buf.Bytes, buf2.Bytes) != 0 {
+				t.Fatalf("encoder.Write != packet.Serialize %v", reflect.TypeOf(pkt))
+			}
+
+			var pkt2, _, e = w3gs.Read(&buf, enc)
+			if e != nil {
+				t.Log(reflect.TypeOf(pkt))
+				t.Fatal(e)
+			}
+			if buf.Size() > 0 {
+				t.Fatalf("decoder.Read size mismatch for %v", reflect.TypeOf(pkt))
+			}
+			if reflect.TypeOf(pkt2) != reflect.TypeOf(pkt) {
+				t.Fatalf("decoder.Read type mismatch %v != %v", reflect.TypeOf(pkt2), reflect.TypeOf(pkt))
+			}
+			if !reflect.DeepEqual(pkt, pkt2) {
+				t.Logf("I: %+v", pkt)
+				t.Logf("O: %+v", pkt2)
+				t.Errorf("decoder.Read value mismatch for %v", reflect.TypeOf(pkt))
+			}
+
+			err = pkt.Deserialize(&protocol.Buffer{Bytes: make([]byte, 0)}, &enc)
+			if err != w3gs.ErrInvalidPacketSize {
+				t.Fatalf("ErrInvalidPacketSize expected for %v", reflect.TypeOf(pkt))
+			}
+
+			err = pkt.Deserialize(&protocol.Buffer{Bytes: make([]byte, 2048)}, &enc)
+			if err != w3gs.ErrInvalidPacketSize && err != w3gs.ErrInvalidChecksum {
+				switch pkt.(type) {
+				case *w3gs.UnknownPacket:
+					// Whitelisted
+				default:
+					t.Fatalf("ErrInvalidPacketSize expected for %v", reflect.TypeOf(pkt))
+				}
+
+			}
+		}
+	}
+}
+
+func TestMapCheckVersion(t *testing.T) {
+	var pkt = w3gs.MapCheck{
+		FilePath: "Maps\\BootyBay.w3x",
+		FileSize: 2,
+		FileCRC:  3,
+		MapXoro:  4,
+		MapSha1:  [20]byte{5, 6, 7},
+	}
+
+	old, err := w3gs.Serialize(&pkt, w3gs.Encoding{GameVersion: 22})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cur, err := w3gs.Serialize(&pkt, w3gs.Encoding{GameVersion: 23})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cur)-len(old) != 20 {
+		t.Fatalf("Expected SHA-1 to be omitted for version 22 (%d != %d)", len(cur), len(old))
+	}
+
+	pkt2, _, err := w3gs.Deserialize(old, w3gs.Encoding{GameVersion: 22})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := pkt2.(*w3gs.MapCheck); m.MapSha1 != [20]byte{} || m.MapXoro != pkt.MapXoro || m.FilePath != pkt.FilePath {
+		t.Fatalf("Unexpected MapCheck for version 22: %+v", m)
+	}
+	if _, _, err := w3gs.Deserialize(old, w3gs.Encoding{GameVersion: 26}); !errors.Is(err, w3gs.ErrInvalidPacketSize) {
+		t.Fatal("Expected ErrInvalidPacketSize when decoding old MapCheck as new")
+	}
+}
+
+func TestPlayerExtraProtobuf(t *testing.T) {
+	// Battle.net profiles as sent by a Reforged client
+	var raw, _ = hex.DecodeString(
+		"f7595600034d0000000a2508031211d0a1d0b5d180d0bdd0b8d0ba23323635331a04636c616e220470303532281432000a" +
+			"2408021210546865426947734c65655023323230381a04636c616e22047030323928143200")
+
+	var pkt = w3gs.PlayerExtra{
+		Type: w3gs.PlayerProfile,
+		Profiles: []w3gs.PlayerDataProfile{
+			w3gs.PlayerDataProfile{
+				PlayerID:  3,
+				BattleTag: "Серник#2653",
+				Clan:      "clan",
+				Portrait:  "p052",
+				Realm:     w3gs.RealmEurope,
 			},
-		},
-		&w3gs.PlayerExtra{
-			Type: w3gs.PlayerSkins,
-			Skins: []w3gs.PlayerDataSkins{
-				w3gs.PlayerDataSkins{
-					PlayerID: 3,
-					Skins: []w3gs.PlayerDataSkin{
-						w3gs.PlayerDataSkin{
-							Unit:       1164207469,
-							Skin:       1164207462,
-							Collection: "w3-standard",
-						},
-						w3gs.PlayerDataSkin{
-							Unit:       1164666213,
-							Skin:       1164665701,
-							Collection: "w3-sow-skins",
-						},
-					},
-				},
+			w3gs.PlayerDataProfile{
+				PlayerID:  2,
+				BattleTag: "TheBiGsLeeP#2208",
+				Clan:      "clan",
+				Portrait:  "p029",
+				Realm:     w3gs.RealmEurope,
 			},
 		},
 	}
 
-	for _, pkt := range types {
-		var err error
-		var buf = protocol.Buffer{}
-		var enc = w3gs.Encoding{}
+	b, err := w3gs.Serialize(&pkt, w3gs.Encoding{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, raw) {
+		t.Fatalf("Serialize mismatch:\n%x\n%x", b, raw)
+	}
 
-		if err = pkt.Serialize(&buf, &enc); err != nil {
-			t.Log(reflect.TypeOf(pkt))
-			t.Fatal(err)
-		}
+	pkt2, _, err := w3gs.Deserialize(raw, w3gs.Encoding{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&pkt, pkt2) {
+		t.Fatalf("Deserialize mismatch: %+v", pkt2)
+	}
+}
 
-		var buf2 = protocol.Buffer{}
-		if _, err = w3gs.Write(&buf2, pkt, enc); err != nil {
-			t.Log(reflect.TypeOf(pkt))
-			t.Fatal(err)
+func TestValidateJoin(t *testing.T) {
+	var info = w3gs.GameInfo{HostCounter: 1, EntryKey: 0xDEADBEEF}
+
+	if r, ok := info.ValidateJoin(&w3gs.Join{HostCounter: 1, EntryKey: 0xDEADBEEF, PlayerName: "Moon"}); !ok {
+		t.Fatalf("Expected join to be accepted, got %v", r)
+	}
+	if r, ok := info.ValidateJoin(&w3gs.Join{HostCounter: 2, EntryKey: 0xDEADBEEF, PlayerName: "Moon"}); ok || r != w3gs.RejectJoinWrongKey {
+		t.Fatal("Expected RejectJoinWrongKey for invalid HostCounter")
+	}
+	if r, ok := info.ValidateJoin(&w3gs.Join{HostCounter: 1, EntryKey: 0, PlayerName: "Moon"}); ok || r != w3gs.RejectJoinWrongKey {
+		t.Fatal("Expected RejectJoinWrongKey for invalid EntryKey")
+	}
+	if r, ok := info.ValidateJoin(&w3gs.Join{HostCounter: 1, EntryKey: 0xDEADBEEF}); ok || r != w3gs.RejectJoinInvalid {
+		t.Fatal("Expected RejectJoinInvalid for empty PlayerName")
+	}
+}
+
+func TestGameInfoString(t *testing.T) {
+	var info = w3gs.GameInfo{
+		GameVersion: w3gs.GameVersion{Product: w3gs.ProductTFT, Version: 26},
+		HostCounter: 1,
+		EntryKey:    123456789,
+		GameName:    "Test Game",
+		GameSettings: w3gs.GameSettings{
+			GameSettingFlags: w3gs.SettingSpeedFast | w3gs.SettingTerrainDefault | w3gs.SettingObsNone | w3gs.SettingTeamsTogether | w3gs.SettingTeamsFixed,
+			MapWidth:         116,
+			MapHeight:        84,
+			MapPath:          "Maps/FrozenThrone/(2)EchoIsles.w3x",
+			HostName:         "gowarcraft3",
+		},
+		SlotsTotal:     2,
+		SlotsUsed:      1,
+		SlotsAvailable: 2,
+		UptimeSec:      90,
+		GamePort:       6112,
+		GameFlags:      w3gs.GameFlagCustomGame | w3gs.GameFlagSignedMap,
+	}
+
+	if s := info.String(); s != "Test Game [Maps/FrozenThrone/(2)EchoIsles.w3x 116x84] 1/2 slots, Custom|SignedMap, W3XP 26" {
+		t.Fatalf("Unexpected String() %q", s)
+	}
+
+	var desc = info.Describe()
+	for _, s := range []string{"Game:     Test Game\n", "Host:     gowarcraft3\n", "SpeedFast|", "Flags:    Custom|SignedMap\n", "1 used, 2 available, 2 total", "port 6112", "Uptime:   1m30s\n"} {
+		if !strings.Contains(desc, s) {
+			t.Fatalf("Describe() is missing %q:\n%s", s, desc)
 		}
+	}
+}
 
-		if bytes.Compare(buf.Bytes, buf2.Bytes) != 0 {
-			t.Fatalf("encoder.Write != packet.Serialize %v", reflect.TypeOf(pkt))
+func TestGameVersion(t *testing.T) {
+	for _, p := range []protocol.DWordString{w3gs.ProductROC, w3gs.ProductTFT, w3gs.ProductDemo, protocol.DString("W3XX")} {
+		for _, v := range []uint32{0, 1, 9, 26, 29, 30, 31, 10029, 10030, w3gs.CurrentGameVersion, 10099, 19999} {
+			var gv = w3gs.GameVersion{Product: p, Version: v}
+			res, err := w3gs.ParseGameVersion(gv.String())
+			if err != nil {
+				t.Fatal(gv.String(), err)
+			}
+			if res != gv {
+				t.Fatalf("Game version %q not equal after parsing: %+v", gv.String(), res)
+			}
 		}
+	}
 
-		var pkt2, _, e = w3gs.Read(&buf, enc)
-		if e != nil {
-			t.Log(reflect.TypeOf(pkt))
-			t.Fatal(e)
+	var tests = []struct {
+		s  string
+		gv w3gs.GameVersion
+	}{
+		{"TFT 1.26", w3gs.GameVersion{Product: w3gs.ProductTFT, Version: 26}},
+		{"roc 1.01", w3gs.GameVersion{Product: w3gs.ProductROC, Version: 1}},
+		{"WAR3 1.26", w3gs.GameVersion{Product: w3gs.ProductROC, Version: 26}},
+		{"1.32", w3gs.GameVersion{Product: w3gs.ProductTFT, Version: 10032}},
+		{"TFT 1.30", w3gs.GameVersion{Product: w3gs.ProductTFT, Version: 10030}},
+		{"TFT 1.29", w3gs.GameVersion{Product: w3gs.ProductTFT, Version: 29}},
+		{"10032", w3gs.GameVersion{Product: w3gs.ProductTFT, Version: 10032}},
+		{"ROC 29", w3gs.GameVersion{Product: w3gs.ProductROC, Version: 29}},
+	}
+	for _, tc := range tests {
+		if gv, err := w3gs.ParseGameVersion(tc.s); err != nil || gv != tc.gv {
+			t.Fatalf("Unexpected result for %q: %+v %v", tc.s, gv, err)
 		}
-		if buf.Size() > 0 {
-			t.Fatalf("decoder.Read size mismatch for %v", reflect.TypeOf(pkt))
+	}
+
+	if s := (&w3gs.GameVersion{Product: w3gs.ProductTFT, Version: 10032}).String(); s != "TFT 1.32" {
+		t.Fatal("Unexpected String()", s)
+	}
+	for _, s := range []string{"", "TFT", "TFT 2.26", "TFT 1.x", "W3XPP 1.26", "TFT 1.26 extra", "10032 Reforged", "TFT 1.100"} {
+		if _, err := w3gs.ParseGameVersion(s); err != w3gs.ErrInvalidGameVersion {
+			t.Fatalf("Expected ErrInvalidGameVersion for %q", s)
 		}
-		if reflect.TypeOf(pkt2) != reflect.TypeOf(pkt) {
-			t.Fatalf("decoder.Read type mismatch %v != %v", reflect.TypeOf(pkt2), reflect.TypeOf(pkt))
+	}
+}
+
+func TestTimeSlotLazy(t *testing.T) {
+	var pkt = w3gs.TimeSlot{
+		TimeIncrementMS: 100,
+		Actions: []w3gs.PlayerAction{
+			{PlayerID: 1, Data: []byte{1, 2, 3}},
+			{PlayerID: 2},
+			{PlayerID: 3, Data: []byte{4, 5}},
+		},
+	}
+
+	b, err := w3gs.Serialize(&pkt, w3gs.Encoding{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lazy w3gs.TimeSlot
+	if err := lazy.Deserialize(&protocol.Buffer{Bytes: b}, &w3gs.Encoding{LazyActions: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lazy, pkt) {
+		t.Fatal("TimeSlot not equal after lazy decoding")
+	}
+	if _ = append(lazy.Actions[0].Data, 0xFF); b[14] != 2 {
+		t.Fatal("Append to lazy data overwrote buffer")
+	}
+
+	b[len(b)-1] = 0xFF
+	if lazy.Actions[2].Data[1] != 0xFF {
+		t.Fatal("Expected lazy data to reference buffer")
+	}
+
+	b[len(b)-1] = 5
+	lazy.Materialize()
+	b[len(b)-1] = 0xFF
+	if !reflect.DeepEqual(lazy, pkt) {
+		t.Fatal("TimeSlot not equal after Materialize")
+	}
+}
+
+func TestIncomingAction(t *testing.T) {
+	var pkt = w3gs.NewIncomingAction(100, []w3gs.PlayerAction{
+		{PlayerID: 1, Data: []byte{1, 2, 3}},
+		{PlayerID: 2},
+	})
+
+	b, err := w3gs.Serialize(pkt, w3gs.Encoding{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crc := pkt.Checksum(); crc != uint16(b[6])|uint16(b[7])<<8 {
+		t.Fatal("Checksum does not match serialized packet", crc, b[6:8])
+	}
+	if !bytes.Equal(b, []byte{0xF7, 0x0C, 0x11, 0x00, 0x64, 0x00, 0x4B, 0xFC, 0x01, 0x03, 0x00, 0x01, 0x02, 0x03, 0x02, 0x00, 0x00}) {
+		t.Fatal("Unexpected serialization", b)
+	}
+
+	var empty = w3gs.NewIncomingAction(250, nil)
+	if empty.Checksum() != 0 {
+		t.Fatal("Expected zero checksum for empty time slot")
+	}
+}
+
+func TestSizeOverflow(t *testing.T) {
+	var long = strings.Repeat("x", protocol.MaxPacketSize)
+	for _, pkt := range []w3gs.Packet{
+		w3gs.NewChatToAll(1, long),
+		&w3gs.MessageRelay{Message: *w3gs.NewChatToPlayer(1, 2, 1, long)},
+		&w3gs.PlayerInfo{PlayerName: long},
+		&w3gs.GameInfo{GameName: long},
+	} {
+		var buf protocol.Buffer
+		if err := pkt.Serialize(&buf, &w3gs.Encoding{}); err != protocol.ErrSizeOverflow || buf.Size() != 0 {
+			t.Fatalf("Expected ErrSizeOverflow for %T, got %v", pkt, err)
 		}
-		if !reflect.DeepEqual(pkt, pkt2) {
-			t.Logf("I: %+v", pkt)
-			t.Logf("O: %+v", pkt2)
-			t.Errorf("decoder.Read value mismatch for %v", reflect.TypeOf(pkt))
+		if _, err := w3gs.Serialize(pkt, w3gs.Encoding{}); err != protocol.ErrSizeOverflow {
+			t.Fatalf("Expected ErrSizeOverflow for %T, got %v", pkt, err)
 		}
+	}
 
-		err = pkt.Deserialize(&protocol.Buffer{Bytes: make([]byte, 0)}, &enc)
-		if err != w3gs.ErrInvalidPacketSize {
-			t.Fatalf("ErrInvalidPacketSize expected for %v", reflect.TypeOf(pkt))
-		}
+	// Encoder checks the size of packets that do not validate themselves
+	var pkt = w3gs.UnknownPacket{ID: 0xFF, Blob: make([]byte, protocol.MaxPacketSize)}
+	if _, err := w3gs.Serialize(&pkt, w3gs.Encoding{}); err != protocol.ErrSizeOverflow {
+		t.Fatal("Expected ErrSizeOverflow from encoder", err)
+	}
 
-		err = pkt.Deserialize(&protocol.Buffer{Bytes: make([]byte, 2048)}, &enc)
-		if err != w3gs.ErrInvalidPacketSize && err != w3gs.ErrInvalidChecksum {
-			switch pkt.(type) {
-			case *w3gs.UnknownPacket:
-				// Whitelisted
-			default:
-				t.Fatalf("ErrInvalidPacketSize expected for %v", reflect.TypeOf(pkt))
-			}
+	pkt.Blob = pkt.Blob[:protocol.MaxPacketSize-4]
+	if _, err := w3gs.Serialize(&pkt, w3gs.Encoding{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChatBuilders(t *testing.T) {
+	var msg = []*w3gs.Message{
+		w3gs.NewChatToAll(1, "all"),
+		w3gs.NewChatToAllies(1, "allies"),
+		w3gs.NewChatToObservers(1, "observers"),
+		w3gs.NewChatToPlayer(1, 4, 3, "player"),
+	}
+	var scope = []string{"All", "Allies", "Observers", "ToSlot(3)"}
 
+	for i, m := range msg {
+		if m.SenderID != 1 || m.Type != w3gs.MsgChatExtra {
+			t.Fatalf("Invalid message %+v", m)
+		}
+		if m.ScopeString() != scope[i] {
+			t.Fatalf("ScopeString() == %q, expected %q", m.ScopeString(), scope[i])
+		}
+
+		var buf = protocol.Buffer{}
+		if err := m.Serialize(&buf, &w3gs.Encoding{}); err != nil {
+			t.Fatal(err)
+		}
+		var res w3gs.Message
+		if err := res.Deserialize(&buf, &w3gs.Encoding{}); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(m, &res) {
+			t.Fatalf("Round-trip mismatch %+v != %+v", m, &res)
 		}
 	}
+
+	if s, ok := msg[3].Scope.Slot(); !ok || s != 3 || !reflect.DeepEqual(msg[3].RecipientIDs, []uint8{4}) {
+		t.Fatal("Expected directed scope to player 4 in slot 3")
+	}
+	if _, ok := msg[1].Scope.Slot(); ok {
+		t.Fatal("Expected ScopeAllies not to be directed")
+	}
+	if (&w3gs.Message{Type: w3gs.MsgChat}).ScopeString() != "Lobby" {
+		t.Fatal("Expected lobby chat scope")
+	}
 }
 
 func BenchmarkSerialize(b *testing.B) {
@@ -483,3 +798,128 @@ func BenchmarkCreateAndDeserialize(b *testing.B) {
 		res.Deserialize(&dec, &enc)
 	}
 }
+
+func TestSlotInfoHelpers(t *testing.T) {
+	var info = w3gs.SlotInfo{
+		Slots: []w3gs.SlotData{
+			w3gs.SlotData{PlayerID: 1, SlotStatus: w3gs.SlotOccupied, Team: 0, Color: 5},
+			w3gs.SlotData{PlayerID: 0, SlotStatus: w3gs.SlotOccupied, Computer: true, Team: 1, Color: 7},
+			w3gs.SlotData{PlayerID: 0, SlotStatus: w3gs.SlotOpen, Team: 1, Color: 9},
+			w3gs.SlotData{PlayerID: 2, SlotStatus: w3gs.SlotOccupied, Team: 12, Color: 12},
+		},
+	}
+
+	if occ := info.OccupiedSlots(); len(occ) != 3 || occ[2] != &info.Slots[3] {
+		t.Fatal("Unexpected occupied slots", occ)
+	}
+	if s, ok := info.SlotByPlayerID(2); !ok || s != &info.Slots[3] {
+		t.Fatal("Expected slot 3 for player 2")
+	}
+	if _, ok := info.SlotByPlayerID(0); ok {
+		t.Fatal("Expected computer slot not to be returned")
+	}
+
+	if !info.Slots[0].IsHuman() || info.Slots[0].IsComputer() || !info.Slots[1].IsComputer() || info.Slots[2].IsHuman() || info.Slots[2].IsComputer() {
+		t.Fatal("Unexpected human/computer slots")
+	}
+	info.Slots[1].ComputerType = w3gs.ComputerInsane
+	if ai, ok := info.Slots[1].AIDifficulty(); !ok || ai != w3gs.ComputerInsane || ai.String() != "Insane" {
+		t.Fatal("Expected insane computer", ai)
+	}
+	if _, ok := info.Slots[0].AIDifficulty(); ok {
+		t.Fatal("Expected no AI difficulty for human player")
+	}
+	for h, valid := range map[uint8]bool{0: false, 40: false, 50: true, 60: true, 75: false, 100: true, 110: false} {
+		if w3gs.ValidHandicap(h) != valid {
+			t.Fatal("Unexpected handicap validation", h)
+		}
+	}
+
+	info.NormalizeColors(w3gs.Encoding{GameVersion: 26}.MaxPlayers())
+	for i, c := range []uint8{0, 1, 9, 12} {
+		if info.Slots[i].Color != c {
+			t.Fatalf("Slot %d: expected color %d, got %d", i, c, info.Slots[i].Color)
+		}
+	}
+
+	if (w3gs.Encoding{}).MaxPlayers() != 24 || (w3gs.Encoding{GameVersion: 28}).MaxPlayers() != 12 {
+		t.Fatal("Unexpected MaxPlayers")
+	}
+}
+
+func TestObserverJoin(t *testing.T) {
+	var vectors = []struct {
+		gv  uint32
+		hex string
+	}{
+		{26, "f7043000190002016402000000410164026402000c0c6001640403020101010200000000000000000000000000000000"},
+		{29, "f70430001900020164020000004101640264020018186001640403020101010200000000000000000000000000000000"},
+	}
+
+	for _, v := range vectors {
+		var enc = w3gs.Encoding{GameVersion: v.gv}
+		var obs = enc.MaxPlayers()
+		var pkt = w3gs.SlotInfoJoin{
+			SlotInfo: w3gs.SlotInfo{
+				Slots: []w3gs.SlotData{
+					{PlayerID: 1, DownloadStatus: 100, SlotStatus: w3gs.SlotOccupied, Race: w3gs.RaceHuman | w3gs.RaceSelectable, ComputerType: w3gs.ComputerNormal, Handicap: 100},
+					{PlayerID: 2, DownloadStatus: 100, SlotStatus: w3gs.SlotOccupied, Team: obs, Color: obs, Race: w3gs.RaceRandom | w3gs.RaceSelectable, ComputerType: w3gs.ComputerNormal, Handicap: 100},
+				},
+				RandomSeed: 0x01020304,
+				SlotLayout: w3gs.LayoutCustomForces,
+				NumPlayers: 1,
+			},
+			PlayerID: 2,
+		}
+
+		b, err := w3gs.Serialize(&pkt, enc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := hex.EncodeToString(b); s != v.hex {
+			t.Fatalf("Unexpected SlotInfoJoin for version %d: %s", v.gv, s)
+		}
+
+		pkt2, _, err := w3gs.Deserialize(b, enc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(&pkt, pkt2) {
+			t.Fatalf("SlotInfoJoin mismatch for version %d", v.gv)
+		}
+
+		var join = pkt2.(*w3gs.SlotInfoJoin)
+		slot, ok := join.SlotByPlayerID(join.PlayerID)
+		if !ok || !slot.IsObserver(obs) {
+			t.Fatalf("Expected joining player to be observer for version %d", v.gv)
+		}
+		if join.Slots[0].IsObserver(obs) {
+			t.Fatalf("Expected player slot for version %d", v.gv)
+		}
+		if name, _ := enc.PlayerColor(slot.Color); name != "observer" {
+			t.Fatalf("Expected observer color for version %d, got %s", v.gv, name)
+		}
+	}
+
+	var leave = []struct {
+		pkt w3gs.Packet
+		hex string
+	}{
+		{&w3gs.PlayerKicked{Leave: w3gs.Leave{Reason: w3gs.LeaveLobby}}, "f71c08000d000000"},
+		{&w3gs.PlayerLeft{PlayerID: 2, Reason: w3gs.LeaveObserver}, "f7070900020b000000"},
+		{&w3gs.Leave{Reason: w3gs.LeaveObserver}, "f72108000b000000"},
+	}
+	for _, v := range leave {
+		b, err := w3gs.Serialize(v.pkt, w3gs.Encoding{GameVersion: 29})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := hex.EncodeToString(b); s != v.hex {
+			t.Fatalf("Unexpected %v: %s", reflect.TypeOf(v.pkt), s)
+		}
+		pkt, _, err := w3gs.Deserialize(b, w3gs.Encoding{GameVersion: 29})
+		if err != nil || !reflect.DeepEqual(pkt, v.pkt) {
+			t.Fatalf("%v mismatch: %v", reflect.TypeOf(v.pkt), err)
+		}
+	}
+}
@@ -30,6 +30,9 @@ func (enc *Encoder) Serialize(p Packet) ([]byte, error) {
 	if err := p.Serialize(&enc.buf, &enc.Encoding); err != nil {
 		return nil, err
 	}
+	if enc.buf.Size() > protocol.MaxPacketSize {
+		return nil, protocol.ErrSizeOverflow
+	}
 	return enc.buf.Bytes, nil
 }
 
@@ -47,6 +50,11 @@ func (enc *Encoder) Write(w io.Writer, p Packet) (int, error) {
 type Decoder struct {
 	Encoding
 	PacketFactory
+
+	// Strict makes Deserialize return a *protocol.TrailingDataError if b holds more bytes
+	// than the packet. Leave unset when b is framed externally and may hold multiple packets.
+	Strict bool
+
 	bufRaw protocol.Buffer
 	bufDes protocol.Buffer
 }
@@ -82,7 +90,10 @@ func (dec *Decoder) Deserialize(b []byte) (Packet, int, error) {
 
 	var n = size - dec.bufDes.Size()
 	if err != nil {
-		return nil, n, err
+		return nil, n, &protocol.PacketError{ID: b[1], Offset: n, Underlying: err}
+	}
+	if dec.Strict && n < size {
+		return nil, n, &protocol.PacketError{ID: b[1], Offset: n, Underlying: &protocol.TrailingDataError{N: size - n}}
 	}
 
 	return pkt, n, nil
@@ -132,7 +143,7 @@ func (dec *Decoder) Read(r io.Reader) (Packet, int, error) {
 		return nil, n, err
 	}
 	if m != n {
-		return nil, n, ErrInvalidPacketSize
+		return nil, n, &protocol.PacketError{ID: b[1], Offset: m, Underlying: ErrInvalidPacketSize}
 	}
 
 	return p, n, nil
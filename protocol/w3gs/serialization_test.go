@@ -5,8 +5,10 @@
 package w3gs_test
 
 import (
+	"errors"
 	"io"
 	"net"
+	"reflect"
 	"testing"
 
 	"github.com/nielsAD/gowarcraft3/protocol"
@@ -29,14 +31,57 @@ func TestDeserialize(t *testing.T) {
 	if _, _, e := w3gs.Deserialize([]byte{w3gs.ProtocolSig, 3, 0}, w3gs.Encoding{}); e != w3gs.ErrNoProtocolSig {
 		t.Fatal("ErrNoProtocolSig expected if size < 4")
 	}
-	if _, _, e := w3gs.Deserialize([]byte{w3gs.ProtocolSig, 255, 255, 0}, w3gs.Encoding{}); e != w3gs.ErrInvalidPacketSize {
+	if _, _, e := w3gs.Deserialize([]byte{w3gs.ProtocolSig, 255, 255, 0}, w3gs.Encoding{}); !errors.Is(e, w3gs.ErrInvalidPacketSize) {
 		t.Fatal("ErrInvalidPacketSize expected if bytes invalid size", e)
 	}
+	if _, _, e := w3gs.Deserialize([]byte{w3gs.ProtocolSig, w3gs.PidPingFromHost, 6, 0, 1, 2}, w3gs.Encoding{}); e != nil {
+		var perr *protocol.PacketError
+		if !errors.As(e, &perr) || perr.ID != w3gs.PidPingFromHost || perr.Offset != 4 {
+			t.Fatal("PacketError expected if packet truncated", e)
+		}
+	} else {
+		t.Fatal("Error expected if packet truncated")
+	}
 	if _, _, e := w3gs.Read(&protocol.Buffer{Bytes: []byte{w3gs.ProtocolSig, 255, 255, 0}}, w3gs.Encoding{}); e != io.ErrUnexpectedEOF {
 		t.Fatal("ErrUnexpectedEOF expected if reader invalid size", e)
 	}
 }
 
+func TestStrict(t *testing.T) {
+	var b = []byte{w3gs.ProtocolSig, w3gs.PidPingFromHost, 8, 0, 1, 2, 3, 4, 5, 6}
+
+	var dec = w3gs.NewDecoder(w3gs.Encoding{}, nil)
+	if _, n, e := dec.Deserialize(b); e != nil || n != 8 {
+		t.Fatal("Expected trailing data to be accepted if not strict", e, n)
+	}
+
+	dec.Strict = true
+	_, _, e := dec.Deserialize(b)
+	var terr *protocol.TrailingDataError
+	if !errors.Is(e, protocol.ErrTrailingData) || !errors.As(e, &terr) || terr.N != 2 {
+		t.Fatal("Expected TrailingDataError", e)
+	}
+	if _, n, e := dec.Deserialize(b[:8]); e != nil || n != 8 {
+		t.Fatal("Expected exact packet to be accepted", e, n)
+	}
+}
+
+func TestKnownPackets(t *testing.T) {
+	var known = w3gs.KnownPackets()
+	if len(known) != len(w3gs.DefaultFactory) {
+		t.Fatal("KnownPackets size mismatch")
+	}
+	if known[w3gs.PidSlotInfo] != reflect.TypeOf(w3gs.SlotInfo{}) {
+		t.Fatal("Unexpected type for PidSlotInfo", known[w3gs.PidSlotInfo])
+	}
+	if s := w3gs.PacketName(w3gs.PidChatToHost); s != "Message" {
+		t.Fatal("Unexpected name for PidChatToHost", s)
+	}
+	if s := w3gs.PacketName(0xFF); s != "0xFF" {
+		t.Fatal("Unexpected name for unknown packet", s)
+	}
+}
+
 func BenchmarkEncoder(b *testing.B) {
 	var pkt = w3gs.SlotInfo{
 		Slots: sd,
@@ -29,8 +29,27 @@ type Packet interface {
 }
 
 // Encoding options for (de)serialization
+//
+// GameVersion selects the wire format of version-sensitive packets (0 means latest):
+//
+//    MapCheck: map SHA-1 hash only for version >= 1.23
+//    Actions:  see DeserializeActions
+//
+// LazyActions makes TimeSlot reference the action data in the decode buffer instead of copying it.
+// The data is only valid until the buffer is reused (i.e. the next call to Read), see TimeSlot.Materialize.
+//
 type Encoding struct {
 	GameVersion uint32
+	LazyActions bool
+}
+
+// MaxPlayers returns the number of playable (non-observer) slots for the game version.
+// Observers and referees are placed in team MaxPlayers().
+func (e Encoding) MaxPlayers() uint8 {
+	if e.GameVersion != 0 && e.GameVersion < 29 {
+		return 12
+	}
+	return 24
 }
 
 // DefaultFactory maps packet ID to matching type